@@ -2,6 +2,7 @@ package main
 
 import (
 	"clinicplus/internal/shared/config"
+	"clinicplus/internal/shared/db"
 	"clinicplus/internal/shared/middleware"
 	"clinicplus/internal/shared/observability"
 	"clinicplus/pkg/cron"
@@ -20,9 +21,6 @@ func main() {
 	cleanup := observability.InitTracing("clinicplus-api", "1.0.0")
 	defer cleanup()
 
-	// Start cron jobs
-	cron.StartCronJobs()
-	
 	// Get port from environment
 	port := config.GetServerPort()
 	serverAddr := fmt.Sprintf(":%s", port)
@@ -32,6 +30,9 @@ func main() {
 	r := server.NewServer()
 	handler := middleware.SetupCORS(r)
 
+	// Start cron jobs once the database is connected
+	cron.StartCronJobs(db.DB)
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Server listening on %s", serverAddr)
@@ -0,0 +1,208 @@
+// internal/attendance/handler.go
+package attendance
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type AttendanceHandler struct {
+	service AttendanceService
+}
+
+func NewAttendanceHandler(service AttendanceService) *AttendanceHandler {
+	return &AttendanceHandler{service: service}
+}
+
+// GetExceptions lists absences and missing clock-outs between ?from= and
+// ?to= (YYYY-MM-DD), by default over the last 7 days
+func (h *AttendanceHandler) GetExceptions(w http.ResponseWriter, r *http.Request) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -6)
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse("2006-01-02", value); err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid from date, expected YYYY-MM-DD", nil)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse("2006-01-02", value); err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid to date, expected YYYY-MM-DD", nil)
+			return
+		}
+	}
+
+	exceptions, err := h.service.GetExceptions(from, to)
+	if err != nil {
+		if err == ErrInvalidRange {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+			return
+		}
+		log.Printf("Error fetching attendance exceptions: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve attendance exceptions", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, exceptions, nil, nil)
+}
+
+// RequestCorrection asks for an attendance record to be corrected, or added
+// when attendance_id is left out
+func (h *AttendanceHandler) RequestCorrection(w http.ResponseWriter, r *http.Request) {
+	h.correct(w, r, h.service.RequestCorrection, "Failed to create correction request")
+}
+
+// CorrectAttendance corrects or adds an attendance record without waiting
+// for approval
+func (h *AttendanceHandler) CorrectAttendance(w http.ResponseWriter, r *http.Request) {
+	h.correct(w, r, h.service.CorrectAttendance, "Failed to correct attendance")
+}
+
+// GetCorrections lists the user's correction requests, or an employee's for
+// managers passing employee_id
+func (h *AttendanceHandler) GetCorrections(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+	employeeID := user.EmployeeID
+	if raw := r.URL.Query().Get("employee_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+			return
+		}
+		employeeID = uint(id)
+	}
+	if !iam.CanAccessEmployee(user, employeeID) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's correction requests", nil)
+		return
+	}
+
+	requests, err := h.service.GetCorrections(employeeID)
+	if err != nil {
+		sendCorrectionError(w, err, "Failed to retrieve correction requests")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, requests, nil, nil)
+}
+
+func (h *AttendanceHandler) GetPendingCorrections(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+
+	requests, err := h.service.GetPendingCorrections(user)
+	if err != nil {
+		sendCorrectionError(w, err, "Failed to retrieve pending correction requests")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, requests, nil, nil)
+}
+
+func (h *AttendanceHandler) ApproveCorrection(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.ApproveCorrection, "Failed to approve correction request")
+}
+
+func (h *AttendanceHandler) RejectCorrection(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.RejectCorrection, "Failed to reject correction request")
+}
+
+func (h *AttendanceHandler) CancelCorrection(w http.ResponseWriter, r *http.Request) {
+	id, ok := correctionID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, err := h.service.CancelCorrection(id, user)
+	if err != nil {
+		sendCorrectionError(w, err, "Failed to cancel correction request")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, nil)
+}
+
+// correct decodes a correction from the body and hands it to step
+func (h *AttendanceHandler) correct(w http.ResponseWriter, r *http.Request, step func(*iam.User, CorrectionRequest) (*CorrectionRequest, error), fallback string) {
+	var request CorrectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	created, err := step(user, request)
+	if err != nil {
+		sendCorrectionError(w, err, fallback)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+// decide runs a manager's decision, which takes the request ID and an
+// optional note
+func (h *AttendanceHandler) decide(w http.ResponseWriter, r *http.Request, step func(uint, *iam.User, string) (*CorrectionRequest, error), fallback string) {
+	id, ok := correctionID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("Error decoding request body: %v", err)
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+			return
+		}
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, err := step(id, user, body.Note)
+	if err != nil {
+		sendCorrectionError(w, err, fallback)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, nil)
+}
+
+func correctionID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid correction request ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid correction request ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func sendCorrectionError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrCorrectionNotFound), errors.Is(err, ErrRecordNotFound),
+		errors.Is(err, employee.ErrEmployeeNotFound), errors.Is(err, employee.ErrShiftNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case errors.Is(err, ErrInvalidCorrection):
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case errors.Is(err, ErrRecordExists), errors.Is(err, ErrAlreadyRequested), errors.Is(err, ErrInvalidStatus),
+		errors.Is(err, employee.ErrAttendanceLocked):
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	case errors.Is(err, ErrNotAllowed):
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
@@ -0,0 +1,58 @@
+package attendance
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	CorrectionPending   = "pending" // Waiting for manager approval
+	CorrectionApproved  = "approved"
+	CorrectionRejected  = "rejected"
+	CorrectionCancelled = "cancelled"
+)
+
+// Policy sets when missing clock-ins and clock-outs are reported
+type Policy struct {
+	Grace     time.Duration // How long after a shift ends it is checked
+	AutoClose bool          // Close a missing clock-out at the end of the shift
+}
+
+// CheckResult reports what an attendance check found
+type CheckResult struct {
+	Absences         int `json:"absences"`
+	MissingClockOuts int `json:"missing_clock_outs"`
+	AutoClosed       int `json:"auto_closed"`
+}
+
+// Exception is an attendance record of an absence or a missing clock-out
+type Exception struct {
+	employee.Attendance
+	EmployeeName string `json:"employee_name"`
+	ShiftName    string `json:"shift_name"`
+}
+
+// CorrectionRequest asks to fix the clock-in or clock-out time of an
+// attendance record, or to add the record of a shift that was never clocked
+// in to. Approved corrections are applied to the record, which keeps the
+// clocked values alongside the corrected ones.
+type CorrectionRequest struct {
+	gorm.Model
+	Status       string         `gorm:"not null;index" json:"status"`
+	EmployeeID   uint           `gorm:"not null;index" json:"employee_id"`
+	AttendanceID *uint          `gorm:"index" json:"attendance_id"` // Record corrected; nil adds one
+	ShiftID      uint           `gorm:"not null" json:"shift_id"`
+	Date         time.Time      `gorm:"type:date;not null" json:"date"`
+	ClockInTime  utils.NullTime `json:"clock_in_time"`  // Corrected clock-in; unset keeps the record's
+	ClockOutTime utils.NullTime `json:"clock_out_time"` // Corrected clock-out; unset keeps the record's
+	Reason       string         `gorm:"not null" json:"reason"`
+	RequestedBy  uint           `json:"requested_by"` // iam.User who asked for the correction
+
+	ApproverID   *uint          `json:"approver_id"` // Employee's manager; nil means any manager
+	DecidedBy    *uint          `json:"decided_by"`  // iam.User who approved or rejected
+	DecidedAt    utils.NullTime `json:"decided_at"`
+	DecisionNote string         `json:"decision_note"`
+}
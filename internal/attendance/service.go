@@ -0,0 +1,638 @@
+// internal/attendance/service.go
+package attendance
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/holiday"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/config"
+	"clinicplus/internal/shared/utils"
+	"clinicplus/internal/timesheet"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// lookbackDays is how many days before today assignments are checked, so a
+// check that did not run for a while still finds the shifts it missed
+const lookbackDays = 2
+
+var (
+	ErrInvalidRange       = errors.New("invalid range: to must not be before from")
+	ErrCorrectionNotFound = errors.New("correction request not found")
+	ErrRecordNotFound     = errors.New("attendance record not found")
+	ErrInvalidCorrection  = errors.New("invalid correction")
+	ErrRecordExists       = errors.New("shift already has an attendance record; correct that record instead")
+	ErrAlreadyRequested   = errors.New("shift already has a pending correction request")
+	ErrNotAllowed         = errors.New("not allowed to act on this correction request")
+	ErrInvalidStatus      = errors.New("correction request can no longer be changed")
+)
+
+type AttendanceService interface {
+	CheckAttendance(now time.Time) (*CheckResult, error)
+	GetExceptions(from, to time.Time) ([]Exception, error)
+
+	RequestCorrection(user *iam.User, request CorrectionRequest) (*CorrectionRequest, error)
+	CorrectAttendance(user *iam.User, request CorrectionRequest) (*CorrectionRequest, error)
+	GetCorrections(employeeID uint) ([]CorrectionRequest, error)
+	GetPendingCorrections(approver *iam.User) ([]CorrectionRequest, error)
+	ApproveCorrection(id uint, approver *iam.User, note string) (*CorrectionRequest, error)
+	RejectCorrection(id uint, approver *iam.User, note string) (*CorrectionRequest, error)
+	CancelCorrection(id uint, user *iam.User) (*CorrectionRequest, error)
+}
+
+type attendanceService struct {
+	db            *gorm.DB
+	employees     employee.EmployeeService
+	holidays      holiday.HolidayService
+	notifications notification.NotificationService
+	policy        Policy
+}
+
+func NewAttendanceService(db *gorm.DB, employees employee.EmployeeService, holidays holiday.HolidayService, notifications notification.NotificationService, policy Policy) AttendanceService {
+	return &attendanceService{db: db, employees: employees, holidays: holidays, notifications: notifications, policy: policy}
+}
+
+// PolicyFromConfig builds the policy from the environment
+func PolicyFromConfig() Policy {
+	return Policy{
+		Grace:     time.Duration(config.GetAttendanceCheckGraceMinutes()) * time.Minute,
+		AutoClose: config.GetAttendanceAutoClose(),
+	}
+}
+
+// CheckAttendance looks at shifts that ended at least the grace period ago.
+// Assigned employees who never clocked in get an absent record; records
+// still open are flagged as missing their clock-out, and closed at the shift
+// end when the policy says so. The employee and their manager are notified
+// of each. Records are only flagged once, so the check can run often.
+func (s *attendanceService) CheckAttendance(now time.Time) (*CheckResult, error) {
+	result := &CheckResult{}
+	if err := s.detectAbsences(now, result); err != nil {
+		return result, err
+	}
+	if err := s.detectMissingClockOuts(now, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// GetExceptions lists absences and missing clock-outs dated from to to
+func (s *attendanceService) GetExceptions(from, to time.Time) ([]Exception, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidRange
+	}
+
+	var records []employee.Attendance
+	err := s.db.Preload("Employee").Preload("Shift").
+		Where("date BETWEEN ? AND ? AND status IN (?)", from, to,
+			[]string{employee.AttendanceStatusAbsent, employee.AttendanceStatusMissingClockOut}).
+		Order("date, employee_id").Find(&records).Error
+	if err != nil {
+		log.Printf("Error fetching attendance exceptions: %v", err)
+		return nil, err
+	}
+
+	exceptions := make([]Exception, 0, len(records))
+	for _, record := range records {
+		exceptions = append(exceptions, Exception{Attendance: record, EmployeeName: record.Employee.Name, ShiftName: record.Shift.Name})
+	}
+	return exceptions, nil
+}
+
+func (s *attendanceService) detectAbsences(now time.Time, result *CheckResult) error {
+	today := now.UTC().Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, -lookbackDays)
+
+	var assignments []employee.EmployeeShift
+	if err := s.db.Preload("Shift").Preload("Employee").
+		Where("start_date <= ? AND end_date >= ?", today, from).
+		Find(&assignments).Error; err != nil {
+		log.Printf("Error fetching shift assignments: %v", err)
+		return err
+	}
+	if len(assignments) == 0 {
+		return nil
+	}
+
+	var records []employee.Attendance
+	if err := s.db.Where("date BETWEEN ? AND ?", from.AddDate(0, 0, -1), today).Find(&records).Error; err != nil {
+		log.Printf("Error fetching attendance records: %v", err)
+		return err
+	}
+	recorded := make(map[string]bool, len(records))
+	for _, record := range records {
+		recorded[recordKey(record.EmployeeID, record.ShiftID, record.Date)] = true
+	}
+
+	// Public holidays each employee has off, unless the holiday is kept as
+	// a working day
+	daysOff := make(map[uint]map[time.Time]bool)
+	for _, assignment := range assignments {
+		if !assignment.Employee.CanWork() {
+			continue
+		}
+		if _, ok := daysOff[assignment.EmployeeID]; !ok {
+			holidays, err := s.holidays.HolidaysFor(assignment.Employee, from, today)
+			if err != nil {
+				return err
+			}
+			daysOff[assignment.EmployeeID] = make(map[time.Time]bool)
+			for _, observed := range holidays {
+				if !observed.WorkingDay {
+					daysOff[assignment.EmployeeID][dateOf(observed.Date)] = true
+				}
+			}
+		}
+		for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+			if day.Before(dateOf(assignment.StartDate)) || day.After(dateOf(assignment.EndDate)) || daysOff[assignment.EmployeeID][day] {
+				continue
+			}
+			window, ok := assignment.Shift.OccurrenceOn(day)
+			// Assignments made after the shift started, e.g. by a swap settled
+			// afterwards, could not have been clocked in to
+			if !ok || now.Before(window.End.Add(s.policy.Grace)) || assignment.CreatedAt.After(window.Start) {
+				continue
+			}
+			if recorded[recordKey(assignment.EmployeeID, assignment.ShiftID, day)] {
+				continue
+			}
+			if locked, err := timesheet.SignedOff(s.db, assignment.EmployeeID, day, day); err != nil {
+				return err
+			} else if locked {
+				continue
+			}
+
+			absence := employee.Attendance{
+				EmployeeID:  assignment.EmployeeID,
+				ShiftID:     assignment.ShiftID,
+				Date:        day,
+				ClockInTime: window.Start, // The scheduled start, as there was no clock-in
+				Status:      employee.AttendanceStatusAbsent,
+			}
+			if err := s.db.Omit("Employee", "Shift", "Breaks").Create(&absence).Error; err != nil {
+				log.Printf("Error recording absence: %v", err)
+				return err
+			}
+			recorded[recordKey(assignment.EmployeeID, assignment.ShiftID, day)] = true
+			result.Absences++
+
+			when := fmt.Sprintf("%s on %s", assignment.Shift.Name, day.Format("Mon 2006-01-02"))
+			s.notify(assignment.Employee, "attendance_absence",
+				"No clock-in for "+when,
+				fmt.Sprintf("You were assigned to %s from %s to %s but did not clock in, so you are marked absent.",
+					when, window.Start.Format("15:04"), window.End.Format("15:04")),
+				fmt.Sprintf("%s did not clock in for %s and is marked absent.", assignment.Employee.Name, when))
+		}
+	}
+	return nil
+}
+
+func (s *attendanceService) detectMissingClockOuts(now time.Time, result *CheckResult) error {
+	var open []employee.Attendance
+	if err := s.db.Preload("Shift").Preload("Employee").
+		Where("status = ? AND clock_out_time IS NULL", employee.AttendanceStatusPresent).
+		Find(&open).Error; err != nil {
+		log.Printf("Error fetching open attendance records: %v", err)
+		return err
+	}
+
+	for _, record := range open {
+		// Overrides may clock in to a shift on a day it does not run; such a
+		// record ends a shift's length after the clock-in
+		end := record.ClockInTime.Add(record.Shift.Duration())
+		if window, ok := record.Shift.OccurrenceOn(record.Date); ok && window.End.After(record.ClockInTime) {
+			end = window.End
+		}
+		if now.Before(end.Add(s.policy.Grace)) {
+			continue
+		}
+
+		var closeAt *time.Time
+		if s.policy.AutoClose {
+			closeAt = &end
+		}
+		if _, err := s.employees.FlagMissingClockOut(record.ID, closeAt); err != nil {
+			if errors.Is(err, employee.ErrNotClockedIn) {
+				continue // Clocked out meanwhile
+			}
+			return err
+		}
+		result.MissingClockOuts++
+
+		location := record.Shift.Location()
+		when := fmt.Sprintf("%s on %s", record.Shift.Name, record.Date.Format("Mon 2006-01-02"))
+		body := fmt.Sprintf("You clocked in for %s at %s but did not clock out.", when, record.ClockInTime.In(location).Format("15:04"))
+		if closeAt != nil {
+			result.AutoClosed++
+			body += fmt.Sprintf(" The record was closed at the end of the shift, %s.", end.In(location).Format("15:04"))
+		}
+		s.notify(record.Employee, "attendance_missing_clock_out", "No clock-out for "+when, body,
+			fmt.Sprintf("%s did not clock out of %s.", record.Employee.Name, when))
+	}
+	return nil
+}
+
+// RequestCorrection asks the employee's manager to correct an attendance
+// record, or to add one for a shift that was not clocked in to. The employee
+// defaults to the user's.
+func (s *attendanceService) RequestCorrection(user *iam.User, request CorrectionRequest) (*CorrectionRequest, error) {
+	if request.EmployeeID == 0 {
+		request.EmployeeID = user.EmployeeID
+	}
+	if !iam.CanAccessEmployee(user, request.EmployeeID) {
+		return nil, ErrNotAllowed
+	}
+	emp, _, err := s.prepare(s.db, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending int
+	s.db.Model(&CorrectionRequest{}).
+		Where("employee_id = ? AND shift_id = ? AND date = ? AND status = ?", request.EmployeeID, request.ShiftID, request.Date, CorrectionPending).
+		Count(&pending)
+	if pending > 0 {
+		return nil, ErrAlreadyRequested
+	}
+
+	s.reset(&request, user, emp)
+	if err := s.db.Create(&request).Error; err != nil {
+		log.Printf("Error creating correction request: %v", err)
+		return nil, err
+	}
+
+	if request.ApproverID != nil {
+		s.notifications.Notify(*request.ApproverID, "attendance_correction",
+			fmt.Sprintf("%s asked to correct their attendance", emp.Name),
+			s.describe(&request)+request.Reason)
+	}
+	return &request, nil
+}
+
+// CorrectAttendance is an admin's correction, recorded as a request and
+// applied straight away
+func (s *attendanceService) CorrectAttendance(user *iam.User, request CorrectionRequest) (*CorrectionRequest, error) {
+	emp, _, err := s.prepare(s.db, &request)
+	if err != nil {
+		return nil, err
+	}
+	if emp.ID == user.EmployeeID {
+		return nil, ErrNotAllowed
+	}
+
+	s.reset(&request, user, emp)
+	if err := s.approve(&request, user, request.Reason); err != nil {
+		return nil, err
+	}
+
+	s.notifications.Notify(request.EmployeeID, "attendance_corrected",
+		"Your attendance was corrected", s.describe(&request)+request.Reason)
+	return &request, nil
+}
+
+// GetCorrections returns an employee's correction requests, latest first
+func (s *attendanceService) GetCorrections(employeeID uint) ([]CorrectionRequest, error) {
+	var requests []CorrectionRequest
+	if err := s.db.Where("employee_id = ?", employeeID).Order("created_at DESC").Find(&requests).Error; err != nil {
+		log.Printf("Error fetching correction requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// GetPendingCorrections returns the pending requests the user is expected to
+// decide on. Admins see every one.
+func (s *attendanceService) GetPendingCorrections(approver *iam.User) ([]CorrectionRequest, error) {
+	var requests []CorrectionRequest
+	query := s.db.Where("status = ?", CorrectionPending)
+	if approver.Role != iam.RoleAdmin {
+		query = query.Where("approver_id = ? OR approver_id IS NULL", approver.EmployeeID)
+	}
+
+	if err := query.Order("date").Find(&requests).Error; err != nil {
+		log.Printf("Error fetching pending correction requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ApproveCorrection checks the correction again against the record as it is
+// now and applies it
+func (s *attendanceService) ApproveCorrection(id uint, approver *iam.User, note string) (*CorrectionRequest, error) {
+	request, err := s.pendingRequestFor(id, approver)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.approve(request, approver, note); err != nil {
+		return nil, err
+	}
+
+	s.notifyDecision(request, "approved", note)
+	return request, nil
+}
+
+func (s *attendanceService) RejectCorrection(id uint, approver *iam.User, note string) (*CorrectionRequest, error) {
+	request, err := s.pendingRequestFor(id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	decide(request, approver, CorrectionRejected, note)
+	if err := s.db.Save(request).Error; err != nil {
+		log.Printf("Error saving correction request: %v", err)
+		return nil, err
+	}
+
+	s.notifyDecision(request, "rejected", note)
+	return request, nil
+}
+
+// CancelCorrection withdraws a request that has not been decided yet
+func (s *attendanceService) CancelCorrection(id uint, user *iam.User) (*CorrectionRequest, error) {
+	request, err := s.correction(id)
+	if err != nil {
+		return nil, err
+	}
+	if !iam.CanAccessEmployee(user, request.EmployeeID) {
+		return nil, ErrNotAllowed
+	}
+	if request.Status != CorrectionPending {
+		return nil, ErrInvalidStatus
+	}
+
+	request.Status = CorrectionCancelled
+	if err := s.db.Save(request).Error; err != nil {
+		log.Printf("Error saving correction request: %v", err)
+		return nil, err
+	}
+	return request, nil
+}
+
+// prepare checks a correction and fills in the record it applies to, if
+// any, with its shift and date. An absence is corrected in place rather than
+// getting a second record. Times left unset keep the record's, and the
+// resulting record must clock in before it clocks out, around the shift date
+// and not in the future.
+func (s *attendanceService) prepare(db *gorm.DB, request *CorrectionRequest) (*employee.Employee, *employee.Attendance, error) {
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		return nil, nil, fmt.Errorf("%w: a reason is required", ErrInvalidCorrection)
+	}
+
+	var emp employee.Employee
+	if err := db.First(&emp, request.EmployeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, nil, err
+	}
+
+	var record *employee.Attendance
+	if request.AttendanceID != nil {
+		record = &employee.Attendance{}
+		if err := db.First(record, *request.AttendanceID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, nil, ErrRecordNotFound
+			}
+			log.Printf("Error fetching attendance record: %v", err)
+			return nil, nil, err
+		}
+		if record.EmployeeID != request.EmployeeID {
+			return nil, nil, ErrRecordNotFound
+		}
+	} else {
+		if request.Date.IsZero() {
+			return nil, nil, fmt.Errorf("%w: a date is required to add a record", ErrInvalidCorrection)
+		}
+		request.Date = dateOf(request.Date)
+		var shift employee.Shift
+		if err := db.First(&shift, request.ShiftID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, nil, employee.ErrShiftNotFound
+			}
+			log.Printf("Error fetching shift: %v", err)
+			return nil, nil, err
+		}
+
+		var existing employee.Attendance
+		err := db.Where("employee_id = ? AND shift_id = ? AND date = ?", request.EmployeeID, request.ShiftID, request.Date).First(&existing).Error
+		switch {
+		case err == nil && existing.Status == employee.AttendanceStatusAbsent:
+			record = &existing
+		case err == nil:
+			return nil, nil, ErrRecordExists
+		case !gorm.IsRecordNotFoundError(err):
+			log.Printf("Error fetching attendance record: %v", err)
+			return nil, nil, err
+		}
+	}
+
+	clockIn, clockOut := request.ClockInTime, request.ClockOutTime
+	if record == nil {
+		locked, err := timesheet.SignedOff(db, request.EmployeeID, request.Date, request.Date)
+		if err != nil {
+			return nil, nil, err
+		}
+		if locked {
+			return nil, nil, employee.ErrAttendanceLocked
+		}
+	} else {
+		if record.Locked {
+			return nil, nil, employee.ErrAttendanceLocked
+		}
+		if record.Status == employee.AttendanceStatusOnLeave {
+			return nil, nil, fmt.Errorf("%w: the employee was on leave", ErrInvalidCorrection)
+		}
+		request.AttendanceID = &record.ID
+		request.ShiftID = record.ShiftID
+		request.Date = dateOf(record.Date)
+	}
+	if record == nil || record.Status == employee.AttendanceStatusAbsent {
+		if !clockIn.Valid {
+			return nil, nil, fmt.Errorf("%w: a clock-in time is required", ErrInvalidCorrection)
+		}
+	} else {
+		if !clockIn.Valid && !clockOut.Valid {
+			return nil, nil, fmt.Errorf("%w: a clock-in or clock-out time is required", ErrInvalidCorrection)
+		}
+		if !clockIn.Valid {
+			clockIn = utils.NullTime{NullTime: sql.NullTime{Time: record.ClockInTime, Valid: true}}
+		}
+		if !clockOut.Valid {
+			clockOut = record.ClockOutTime
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case clockIn.Time.After(now) || (clockOut.Valid && clockOut.Time.After(now)):
+		return nil, nil, fmt.Errorf("%w: times must not be in the future", ErrInvalidCorrection)
+	case clockOut.Valid && !clockOut.Time.After(clockIn.Time):
+		return nil, nil, fmt.Errorf("%w: clock-out must be after clock-in", ErrInvalidCorrection)
+	// The date is the shift's local date, which may be a day off UTC
+	case clockIn.Time.Before(request.Date.AddDate(0, 0, -1)) || !clockIn.Time.Before(request.Date.AddDate(0, 0, 2)):
+		return nil, nil, fmt.Errorf("%w: clock-in must be on the shift date", ErrInvalidCorrection)
+	}
+	return &emp, record, nil
+}
+
+// approve checks the correction again within a transaction and applies it
+// to the record, which keeps the values it had before its first correction
+func (s *attendanceService) approve(request *CorrectionRequest, approver *iam.User, note string) error {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return tx.Error
+	}
+
+	_, record, err := s.prepare(tx, request)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Save(request).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error saving correction request: %v", err)
+		return err
+	}
+
+	if record == nil {
+		record = &employee.Attendance{EmployeeID: request.EmployeeID, ShiftID: request.ShiftID, Date: request.Date}
+	} else if !record.ManualEntry {
+		record.OriginalStatus = record.Status
+		if record.Status != employee.AttendanceStatusAbsent {
+			record.OriginalClockInTime = utils.NullTime{NullTime: sql.NullTime{Time: record.ClockInTime, Valid: true}}
+		}
+		record.OriginalClockOutTime = record.ClockOutTime
+	}
+	if request.ClockInTime.Valid {
+		record.ClockInTime = request.ClockInTime.Time
+	}
+	if request.ClockOutTime.Valid {
+		record.ClockOutTime = request.ClockOutTime
+	}
+	// A missing clock-out stays flagged until the correction supplies one
+	if record.ClockOutTime.Valid || record.Status != employee.AttendanceStatusMissingClockOut {
+		record.Status = employee.AttendanceStatusPresent
+	}
+	record.ManualEntry = true
+	record.CorrectionID = &request.ID
+	if err := s.employees.SaveAttendance(tx, record); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	request.AttendanceID = &record.ID
+	decide(request, approver, CorrectionApproved, note)
+	if err := tx.Save(request).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error saving correction request: %v", err)
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return err
+	}
+	return nil
+}
+
+// reset makes a request a new pending one from the user, routed to the
+// employee's manager
+func (s *attendanceService) reset(request *CorrectionRequest, user *iam.User, emp *employee.Employee) {
+	request.ID = 0
+	request.Status = CorrectionPending
+	request.RequestedBy = user.ID
+	request.ApproverID = emp.ManagerID
+	request.DecidedBy, request.DecidedAt, request.DecisionNote = nil, utils.NullTime{}, ""
+}
+
+func (s *attendanceService) correction(id uint) (*CorrectionRequest, error) {
+	var request CorrectionRequest
+	if err := s.db.First(&request, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCorrectionNotFound
+		}
+		log.Printf("Error fetching correction request: %v", err)
+		return nil, err
+	}
+	return &request, nil
+}
+
+// pendingRequestFor loads a pending request and checks the user may decide
+// on it: the employee's manager, or any admin. Requests without a manager
+// can be decided by any manager. Nobody decides on their own attendance.
+func (s *attendanceService) pendingRequestFor(id uint, approver *iam.User) (*CorrectionRequest, error) {
+	request, err := s.correction(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != CorrectionPending {
+		return nil, ErrInvalidStatus
+	}
+	if request.EmployeeID == approver.EmployeeID {
+		return nil, ErrNotAllowed
+	}
+
+	if approver.Role == iam.RoleAdmin {
+		return request, nil
+	}
+	isManager := request.ApproverID != nil && *request.ApproverID == approver.EmployeeID
+	isFallback := request.ApproverID == nil && approver.IsPrivileged()
+	if !isManager && !isFallback {
+		return nil, ErrNotAllowed
+	}
+	return request, nil
+}
+
+func (s *attendanceService) notifyDecision(request *CorrectionRequest, decision, note string) {
+	s.notifications.Notify(request.EmployeeID, "attendance_correction_decision",
+		fmt.Sprintf("Your attendance correction was %s", decision), s.describe(request)+note)
+}
+
+// describe summarizes a correction for notifications, in the shift's time zone
+func (s *attendanceService) describe(request *CorrectionRequest) string {
+	var shift employee.Shift
+	s.db.First(&shift, request.ShiftID)
+	location := shift.Location()
+
+	description := fmt.Sprintf("%s on %s", shift.Name, request.Date.Format("2006-01-02"))
+	if request.ClockInTime.Valid {
+		description += ", clock-in " + request.ClockInTime.Time.In(location).Format("15:04")
+	}
+	if request.ClockOutTime.Valid {
+		description += ", clock-out " + request.ClockOutTime.Time.In(location).Format("15:04")
+	}
+	return description + ". "
+}
+
+func decide(request *CorrectionRequest, approver *iam.User, status, note string) {
+	request.Status = status
+	request.DecidedBy = &approver.ID
+	request.DecidedAt = utils.NullTime{NullTime: sql.NullTime{Time: time.Now().UTC(), Valid: true}}
+	request.DecisionNote = note
+}
+
+// notify tells the employee, and their manager if they have one
+func (s *attendanceService) notify(emp employee.Employee, kind, subject, body, managerBody string) {
+	s.notifications.Notify(emp.ID, kind, subject, body)
+	if emp.ManagerID != nil {
+		s.notifications.Notify(*emp.ManagerID, kind, fmt.Sprintf("%s: %s", emp.Name, subject), managerBody)
+	}
+}
+
+func recordKey(employeeID, shiftID uint, date time.Time) string {
+	return fmt.Sprintf("%d/%d/%s", employeeID, shiftID, date.Format("2006-01-02"))
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,441 @@
+package attendance
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/holiday"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/testdb"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// at returns a time on a day in March 2024, in UTC
+func at(d, hour, minute int) time.Time {
+	return time.Date(2024, time.March, d, hour, minute, 0, 0, time.UTC)
+}
+
+func day(d int) time.Time { return at(d, 0, 0) }
+
+func clocked(t time.Time) utils.NullTime {
+	return utils.NullTime{NullTime: sql.NullTime{Time: t, Valid: true}}
+}
+
+// records stands in for the employee service, recording the attendance
+// records it is asked to flag and save
+type records struct {
+	employee.EmployeeService
+	flagged []*time.Time // Close time of each flagged record; nil when left open
+	flagErr error
+	saved   *employee.Attendance
+}
+
+func (r *records) FlagMissingClockOut(attendanceID uint, closeAt *time.Time) (*employee.Attendance, error) {
+	if r.flagErr != nil {
+		return nil, r.flagErr
+	}
+	r.flagged = append(r.flagged, closeAt)
+	return &employee.Attendance{}, nil
+}
+
+func (r *records) SaveAttendance(tx *gorm.DB, attendance *employee.Attendance) error {
+	saved := *attendance
+	r.saved = &saved
+	if attendance.ID == 0 {
+		attendance.ID = 12
+	}
+	return nil
+}
+
+// holidays serves a fixed list of holidays to every employee
+type holidays struct {
+	holiday.HolidayService
+	days []holiday.Holiday
+}
+
+func (h holidays) HolidaysFor(emp employee.Employee, from, to time.Time) ([]holiday.Holiday, error) {
+	return h.days, nil
+}
+
+var (
+	// The shift runs 08:00 to 10:00 UTC on Tuesdays and Wednesdays
+	shiftRow       = []interface{}{1, "Early", "08:00", "10:00", "UTC", "tue,wed"}
+	shiftColumns   = []string{"id", "name", "start_time", "end_time", "timezone", "days_of_week"}
+	employeeRow    = []interface{}{5, "Ana", employee.EmploymentStatusActive, 8}
+	employeeColumn = []string{"id", "name", "employment_status", "manager_id"}
+)
+
+func TestCheckAttendanceAbsences(t *testing.T) {
+	now := at(6, 12, 0) // A Wednesday
+
+	tests := []struct {
+		name      string
+		grace     time.Duration
+		status    string
+		created   time.Time // When the assignment was made
+		clockedIn bool
+		holiday   *holiday.Holiday
+		signedOff bool
+		want      int // Absences recorded, on the 5th and 6th
+	}{
+		{name: "never clocked in", want: 2},
+		{name: "clocked in", clockedIn: true, want: 1},
+		{name: "public holiday off", holiday: &holiday.Holiday{Date: day(5)}, want: 1},
+		{name: "public holiday kept as a working day", holiday: &holiday.Holiday{Date: day(5), WorkingDay: true}, want: 2},
+		{name: "within the grace period", grace: 3 * time.Hour, want: 1},
+		{name: "assigned after the shift started", created: at(5, 9, 0), want: 1},
+		{name: "suspended", status: employee.EmploymentStatusSuspended},
+		{name: "signed-off timesheet", signedOff: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			created := tt.created
+			if created.IsZero() {
+				created = day(1)
+			}
+			fake.Returns(`FROM "employee_shifts"`, []string{"id", "employee_id", "shift_id", "start_date", "end_date", "created_at"},
+				[]interface{}{7, 5, 1, day(1), day(31), created})
+			fake.Returns(`FROM "shifts"`, shiftColumns, shiftRow)
+			status := tt.status
+			if status == "" {
+				status = employee.EmploymentStatusActive
+			}
+			fake.Returns(`FROM "employees"`, employeeColumn, []interface{}{5, "Ana", status, 8})
+			if tt.clockedIn {
+				fake.Returns(`date BETWEEN`, []string{"id", "employee_id", "shift_id", "date", "status"},
+					[]interface{}{11, 5, 1, day(5), employee.AttendanceStatusPresent})
+			}
+			if tt.signedOff {
+				fake.Returns(`FROM "timesheets"`, []string{"count"}, []interface{}{1})
+			}
+			var days []holiday.Holiday
+			if tt.holiday != nil {
+				days = append(days, *tt.holiday)
+			}
+			service := NewAttendanceService(db, &records{}, holidays{days: days}, notification.NewNotificationService(db), Policy{Grace: tt.grace})
+
+			result, err := service.CheckAttendance(now)
+			if err != nil {
+				t.Fatalf("CheckAttendance: %v", err)
+			}
+			if result.Absences != tt.want {
+				t.Errorf("recorded %d absences, want %d", result.Absences, tt.want)
+			}
+
+			absences := fake.Find(`INSERT INTO "attendances"`)
+			if len(absences) != tt.want {
+				t.Fatalf("saved %d absences, want %d", len(absences), tt.want)
+			}
+			for _, absence := range absences {
+				if !absence.Has(employee.AttendanceStatusAbsent) || !absence.Has(at(6, 8, 0)) && !absence.Has(at(5, 8, 0)) {
+					t.Errorf("absence saved with %v, want the scheduled start", absence.Args)
+				}
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 2*tt.want {
+				t.Errorf("sent %d notifications, want the employee and their manager told of each absence", len(notifications))
+			}
+		})
+	}
+}
+
+func TestCheckAttendanceMissingClockOuts(t *testing.T) {
+	now := at(6, 12, 0)
+
+	tests := []struct {
+		name      string
+		date      time.Time
+		clockIn   time.Time
+		grace     time.Duration
+		autoClose bool
+		flagErr   error
+		want      *time.Time // Close time; nil leaves the record open
+		wantFlag  bool
+	}{
+		{name: "left open", date: day(6), clockIn: at(6, 8, 0), wantFlag: true},
+		{name: "closed at the shift end", date: day(6), clockIn: at(6, 8, 0), autoClose: true, want: timePtr(at(6, 10, 0)), wantFlag: true},
+		{name: "within the grace period", date: day(6), clockIn: at(6, 8, 0), grace: 3 * time.Hour},
+		{name: "clocked out meanwhile", date: day(6), clockIn: at(6, 8, 0), flagErr: employee.ErrNotClockedIn},
+		{
+			// Mondays are not scheduled, so the record ends a shift's length
+			// after the clock-in
+			name: "override on an unscheduled day", date: day(4), clockIn: at(4, 13, 0), autoClose: true,
+			want: timePtr(at(4, 15, 0)), wantFlag: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`clock_out_time IS NULL`, []string{"id", "employee_id", "shift_id", "date", "clock_in_time", "status"},
+				[]interface{}{11, 5, 1, tt.date, tt.clockIn, employee.AttendanceStatusPresent})
+			fake.Returns(`FROM "shifts"`, shiftColumns, shiftRow)
+			fake.Returns(`FROM "employees"`, employeeColumn, employeeRow)
+			employees := &records{flagErr: tt.flagErr}
+			service := NewAttendanceService(db, employees, holidays{}, notification.NewNotificationService(db), Policy{Grace: tt.grace, AutoClose: tt.autoClose})
+
+			result, err := service.CheckAttendance(now)
+			if err != nil {
+				t.Fatalf("CheckAttendance: %v", err)
+			}
+			if flagged := len(employees.flagged) == 1; flagged != tt.wantFlag || (result.MissingClockOuts == 1) != tt.wantFlag {
+				t.Fatalf("flagged %v with %d reported, want flagged: %v", employees.flagged, result.MissingClockOuts, tt.wantFlag)
+			}
+			if !tt.wantFlag {
+				return
+			}
+			closeAt := employees.flagged[0]
+			if (closeAt == nil) != (tt.want == nil) || closeAt != nil && !closeAt.Equal(*tt.want) {
+				t.Errorf("closed at %v, want %v", closeAt, tt.want)
+			}
+			if (result.AutoClosed == 1) != (tt.want != nil) {
+				t.Errorf("reported %d auto-closed", result.AutoClosed)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 2 {
+				t.Errorf("sent %d notifications, want the employee and their manager", len(notifications))
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+var attendanceColumns = []string{"id", "employee_id", "shift_id", "date", "clock_in_time", "clock_out_time", "status", "locked", "manual_entry"}
+
+func TestRequestCorrection(t *testing.T) {
+	user := &iam.User{Model: gorm.Model{ID: 4}, EmployeeID: 5, Role: iam.RoleEmployee}
+	record := uint(11)
+	present := []interface{}{11, 5, 1, day(4), at(4, 8, 5), at(4, 16, 0), employee.AttendanceStatusPresent, false, false}
+
+	tests := []struct {
+		name      string
+		request   CorrectionRequest
+		record    []interface{} // The attendance record found, if any
+		pending   bool
+		signedOff bool
+		wantErr   error
+		wantDate  time.Time
+	}{
+		{
+			name:    "clock-out of a record",
+			request: CorrectionRequest{AttendanceID: &record, ClockOutTime: clocked(at(4, 17, 0)), Reason: "Stayed late"},
+			record:  present, wantDate: day(4),
+		},
+		{
+			name:     "shift never clocked in to",
+			request:  CorrectionRequest{ShiftID: 1, Date: at(5, 9, 0), ClockInTime: clocked(at(5, 8, 0)), Reason: "Forgot to clock in"},
+			wantDate: day(5),
+		},
+		{
+			name:     "absence corrected in place",
+			request:  CorrectionRequest{ShiftID: 1, Date: day(4), ClockInTime: clocked(at(4, 8, 0)), Reason: "Forgot to clock in"},
+			record:   []interface{}{11, 5, 1, day(4), at(4, 8, 0), nil, employee.AttendanceStatusAbsent, false, false},
+			wantDate: day(4),
+		},
+		{
+			name:    "another employee's attendance",
+			request: CorrectionRequest{EmployeeID: 6, AttendanceID: &record, ClockOutTime: clocked(at(4, 17, 0)), Reason: "Stayed late"},
+			wantErr: ErrNotAllowed,
+		},
+		{
+			name:    "without a reason",
+			request: CorrectionRequest{AttendanceID: &record, ClockOutTime: clocked(at(4, 17, 0)), Reason: "  "},
+			record:  present, wantErr: ErrInvalidCorrection,
+		},
+		{
+			name:    "record of another employee",
+			request: CorrectionRequest{AttendanceID: &record, ClockOutTime: clocked(at(4, 17, 0)), Reason: "Stayed late"},
+			record:  []interface{}{11, 6, 1, day(4), at(4, 8, 5), at(4, 16, 0), employee.AttendanceStatusPresent, false, false},
+			wantErr: ErrRecordNotFound,
+		},
+		{
+			name:    "adding a record the shift already has",
+			request: CorrectionRequest{ShiftID: 1, Date: day(4), ClockInTime: clocked(at(4, 8, 0)), Reason: "Forgot to clock in"},
+			record:  present, wantErr: ErrRecordExists,
+		},
+		{
+			name:    "adding a record without a clock-in",
+			request: CorrectionRequest{ShiftID: 1, Date: day(5), ClockOutTime: clocked(at(5, 10, 0)), Reason: "Forgot to clock in"},
+			wantErr: ErrInvalidCorrection,
+		},
+		{
+			name:    "locked record",
+			request: CorrectionRequest{AttendanceID: &record, ClockOutTime: clocked(at(4, 17, 0)), Reason: "Stayed late"},
+			record:  []interface{}{11, 5, 1, day(4), at(4, 8, 5), at(4, 16, 0), employee.AttendanceStatusPresent, true, false},
+			wantErr: employee.ErrAttendanceLocked,
+		},
+		{
+			name:      "adding to a signed-off timesheet",
+			request:   CorrectionRequest{ShiftID: 1, Date: day(5), ClockInTime: clocked(at(5, 8, 0)), Reason: "Forgot to clock in"},
+			signedOff: true, wantErr: employee.ErrAttendanceLocked,
+		},
+		{
+			name:    "clock-out before the clock-in",
+			request: CorrectionRequest{AttendanceID: &record, ClockOutTime: clocked(at(4, 8, 0)), Reason: "Left early"},
+			record:  present, wantErr: ErrInvalidCorrection,
+		},
+		{
+			name:    "in the future",
+			request: CorrectionRequest{AttendanceID: &record, ClockOutTime: clocked(time.Now().Add(time.Hour)), Reason: "Staying late"},
+			record:  present, wantErr: ErrInvalidCorrection,
+		},
+		{
+			name:    "clock-in on another day",
+			request: CorrectionRequest{ShiftID: 1, Date: day(5), ClockInTime: clocked(at(8, 8, 0)), Reason: "Forgot to clock in"},
+			wantErr: ErrInvalidCorrection,
+		},
+		{
+			name:    "already requested",
+			request: CorrectionRequest{AttendanceID: &record, ClockOutTime: clocked(at(4, 17, 0)), Reason: "Stayed late"},
+			record:  present, pending: true, wantErr: ErrAlreadyRequested,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "employees"`, employeeColumn, employeeRow)
+			fake.Returns(`FROM "shifts"`, shiftColumns, shiftRow)
+			if tt.record != nil {
+				fake.Returns(`FROM "attendances"`, attendanceColumns, tt.record)
+			}
+			if tt.pending {
+				fake.Returns(`FROM "correction_requests"`, []string{"count"}, []interface{}{1})
+			}
+			if tt.signedOff {
+				fake.Returns(`FROM "timesheets"`, []string{"count"}, []interface{}{1})
+			}
+			service := NewAttendanceService(db, &records{}, holidays{}, notification.NewNotificationService(db), Policy{})
+
+			request, err := service.RequestCorrection(user, tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequestCorrection error %v, want %v", err, tt.wantErr)
+			}
+			if saved := fake.Ran(`INSERT INTO "correction_requests"`); saved != (tt.wantErr == nil) {
+				t.Errorf("request saved: %v", saved)
+			}
+			if err != nil {
+				return
+			}
+
+			if request.Status != CorrectionPending || request.EmployeeID != 5 || request.RequestedBy != user.ID {
+				t.Errorf("request saved as %s for employee %d by %d", request.Status, request.EmployeeID, request.RequestedBy)
+			}
+			if request.ShiftID != 1 || !request.Date.Equal(tt.wantDate) || (request.AttendanceID != nil) != (tt.record != nil) {
+				t.Errorf("request for shift %d on %s, record %v", request.ShiftID, request.Date, request.AttendanceID)
+			}
+			if request.ApproverID == nil || *request.ApproverID != 8 {
+				t.Errorf("request routed to %v, want the employee's manager", request.ApproverID)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 1 || !notifications[0].Has(8) {
+				t.Errorf("manager notified with %+v", notifications)
+			}
+		})
+	}
+}
+
+func TestApproveCorrection(t *testing.T) {
+	manager := &iam.User{Model: gorm.Model{ID: 2}, EmployeeID: 8, Role: iam.RoleManager}
+
+	tests := []struct {
+		name     string
+		approver *iam.User
+		manager  interface{} // The request's approver; nil lets any manager decide
+		status   string
+		record   []interface{}
+		clockIn  interface{} // The corrected clock-in, if any
+		wantErr  error
+		want     string // Status of the corrected record
+		original string // Status kept from before the correction
+	}{
+		{
+			name: "by the employee's manager", approver: manager, manager: 8, status: CorrectionPending,
+			record: []interface{}{11, 5, 1, day(4), at(4, 8, 5), at(4, 16, 0), employee.AttendanceStatusPresent, false, false},
+			want:   employee.AttendanceStatusPresent, original: employee.AttendanceStatusPresent,
+		},
+		{
+			name: "by an admin", approver: &iam.User{Model: gorm.Model{ID: 1}, EmployeeID: 1, Role: iam.RoleAdmin}, manager: 8, status: CorrectionPending,
+			record: []interface{}{11, 5, 1, day(4), at(4, 8, 5), at(4, 16, 0), employee.AttendanceStatusPresent, false, false},
+			want:   employee.AttendanceStatusPresent, original: employee.AttendanceStatusPresent,
+		},
+		{
+			name: "by any manager when the employee has none", approver: &iam.User{Model: gorm.Model{ID: 3}, EmployeeID: 9, Role: iam.RoleManager},
+			status: CorrectionPending,
+			record: []interface{}{11, 5, 1, day(4), at(4, 8, 5), at(4, 16, 0), employee.AttendanceStatusPresent, false, false},
+			want:   employee.AttendanceStatusPresent, original: employee.AttendanceStatusPresent,
+		},
+		{
+			name: "missing clock-out supplied", approver: manager, manager: 8, status: CorrectionPending,
+			record: []interface{}{11, 5, 1, day(4), at(4, 8, 5), nil, employee.AttendanceStatusMissingClockOut, false, false},
+			want:   employee.AttendanceStatusPresent, original: employee.AttendanceStatusMissingClockOut,
+		},
+		{
+			name: "absence corrected", approver: manager, manager: 8, status: CorrectionPending, clockIn: at(4, 8, 0),
+			record: []interface{}{11, 5, 1, day(4), at(4, 8, 0), nil, employee.AttendanceStatusAbsent, false, false},
+			want:   employee.AttendanceStatusPresent, original: employee.AttendanceStatusAbsent,
+		},
+		{
+			name: "by another manager", approver: &iam.User{Model: gorm.Model{ID: 3}, EmployeeID: 9, Role: iam.RoleManager}, manager: 8,
+			status: CorrectionPending, wantErr: ErrNotAllowed,
+		},
+		{
+			name: "of the approver's own attendance", approver: &iam.User{Model: gorm.Model{ID: 6}, EmployeeID: 5, Role: iam.RoleAdmin},
+			status: CorrectionPending, wantErr: ErrNotAllowed,
+		},
+		{name: "already decided", approver: manager, manager: 8, status: CorrectionRejected, wantErr: ErrInvalidStatus},
+		{
+			name: "record locked since the request", approver: manager, manager: 8, status: CorrectionPending,
+			record:  []interface{}{11, 5, 1, day(4), at(4, 8, 5), at(4, 16, 0), employee.AttendanceStatusPresent, true, false},
+			wantErr: employee.ErrAttendanceLocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "correction_requests"`,
+				[]string{"id", "status", "employee_id", "attendance_id", "shift_id", "date", "clock_in_time", "clock_out_time", "reason", "requested_by", "approver_id"},
+				[]interface{}{3, tt.status, 5, 11, 1, day(4), tt.clockIn, at(4, 17, 0), "Stayed late", 4, tt.manager})
+			fake.Returns(`FROM "attendances"`, attendanceColumns, tt.record)
+			fake.Returns(`FROM "employees"`, employeeColumn, employeeRow)
+			fake.Returns(`FROM "shifts"`, shiftColumns, shiftRow)
+			employees := &records{}
+			service := NewAttendanceService(db, employees, holidays{}, notification.NewNotificationService(db), Policy{})
+
+			request, err := service.ApproveCorrection(3, tt.approver, "Confirmed with the ward")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ApproveCorrection error %v, want %v", err, tt.wantErr)
+			}
+			if applied := employees.saved != nil; applied != (tt.wantErr == nil) {
+				t.Errorf("correction applied: %v", applied)
+			}
+			if err != nil {
+				return
+			}
+
+			if request.Status != CorrectionApproved || request.DecidedBy == nil || *request.DecidedBy != tt.approver.ID {
+				t.Errorf("request decided as %s by %v", request.Status, request.DecidedBy)
+			}
+			saved := employees.saved
+			if saved.Status != tt.want || saved.OriginalStatus != tt.original || !saved.ManualEntry || saved.CorrectionID == nil || *saved.CorrectionID != 3 {
+				t.Errorf("record saved as %s (was %s), manual %v, correction %v", saved.Status, saved.OriginalStatus, saved.ManualEntry, saved.CorrectionID)
+			}
+			if !saved.ClockOutTime.Valid || !saved.ClockOutTime.Time.Equal(at(4, 17, 0)) {
+				t.Errorf("clock-out corrected to %v", saved.ClockOutTime)
+			}
+			// Absences had no clock-in to keep
+			if kept := saved.OriginalClockInTime.Valid; kept != (tt.original != employee.AttendanceStatusAbsent) {
+				t.Errorf("original clock-in kept as %v", saved.OriginalClockInTime)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 1 || !notifications[0].Has(5) {
+				t.Errorf("employee notified with %+v", notifications)
+			}
+		})
+	}
+}
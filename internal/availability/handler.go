@@ -0,0 +1,203 @@
+// internal/availability/handler.go
+package availability
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type AvailabilityHandler struct {
+	service AvailabilityService
+}
+
+func NewAvailabilityHandler(service AvailabilityService) *AvailabilityHandler {
+	return &AvailabilityHandler{service: service}
+}
+
+func (h *AvailabilityHandler) GetWindows(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	windows, err := h.service.GetWindows(employeeID)
+	if err != nil {
+		log.Printf("Error fetching availability windows: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve availability", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, windows, nil, nil)
+}
+
+func (h *AvailabilityHandler) CreateWindow(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	var window AvailabilityWindow
+	if err := json.NewDecoder(r.Body).Decode(&window); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreateWindow(employeeID, window)
+	if err != nil {
+		sendAvailabilityError(w, err, "Failed to create availability window")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *AvailabilityHandler) UpdateWindow(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["window_id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid availability window ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid availability window ID", nil)
+		return
+	}
+
+	var window AvailabilityWindow
+	if err := json.NewDecoder(r.Body).Decode(&window); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateWindow(employeeID, uint(id), window)
+	if err != nil {
+		sendAvailabilityError(w, err, "Failed to update availability window")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
+
+func (h *AvailabilityHandler) DeleteWindow(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["window_id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid availability window ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid availability window ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteWindow(employeeID, uint(id)); err != nil {
+		sendAvailabilityError(w, err, "Failed to delete availability window")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Availability window deleted successfully",
+	})
+}
+
+func (h *AvailabilityHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := h.service.GetPreferences(employeeID)
+	if err != nil {
+		log.Printf("Error fetching shift preferences: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve shift preferences", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, preferences, nil, nil)
+}
+
+func (h *AvailabilityHandler) SetPreference(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	var preference ShiftPreference
+	if err := json.NewDecoder(r.Body).Decode(&preference); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	saved, err := h.service.SetPreference(employeeID, preference)
+	if err != nil {
+		sendAvailabilityError(w, err, "Failed to save shift preference")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, saved, nil, nil)
+}
+
+func (h *AvailabilityHandler) DeletePreference(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["preference_id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid shift preference ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid shift preference ID", nil)
+		return
+	}
+
+	if err := h.service.DeletePreference(employeeID, uint(id)); err != nil {
+		sendAvailabilityError(w, err, "Failed to delete shift preference")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Shift preference deleted successfully",
+	})
+}
+
+// authorizeEmployee lets employees manage their own availability and
+// managers anyone's
+func authorizeEmployee(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	employeeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return 0, false
+	}
+
+	user, _ := iam.UserFromContext(r.Context())
+	if !iam.CanAccessEmployee(user, uint(employeeID)) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's availability", nil)
+		return 0, false
+	}
+	return uint(employeeID), true
+}
+
+func sendAvailabilityError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrWindowNotFound), errors.Is(err, ErrPreferenceNotFound),
+		errors.Is(err, employee.ErrEmployeeNotFound), errors.Is(err, employee.ErrShiftNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case errors.Is(err, ErrInvalidWindow), errors.Is(err, ErrInvalidPreference),
+		errors.Is(err, employee.ErrInvalidDaysOfWeek), errors.Is(err, employee.ErrInvalidTimezone):
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
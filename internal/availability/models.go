@@ -0,0 +1,74 @@
+package availability
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	EnforcementWarn  = "warn"
+	EnforcementBlock = "block"
+)
+
+const (
+	PreferencePrefer = "prefer"
+	PreferenceAvoid  = "avoid"
+)
+
+// AvailabilityWindow marks time an employee cannot work. One-off windows
+// cover every day from StartDate to EndDate; recurring windows repeat on
+// DaysOfWeek from StartDate, until EndDate when set.
+type AvailabilityWindow struct {
+	gorm.Model
+	EmployeeID uint           `gorm:"not null;index" json:"employee_id"`
+	Recurring  bool           `json:"recurring"`
+	DaysOfWeek string         `json:"days_of_week"` // Recurring windows only, e.g., sat,sun
+	StartDate  time.Time      `gorm:"type:date;not null" json:"start_date"`
+	EndDate    utils.NullTime `gorm:"type:date" json:"end_date"`
+	StartTime  string         `gorm:"type:varchar(5)" json:"start_time"` // HH:MM; empty with EndTime for the whole day
+	EndTime    string         `gorm:"type:varchar(5)" json:"end_time"`   // Before StartTime for windows running overnight
+	Timezone   string         `gorm:"not null;default:'UTC'" json:"timezone"`
+	Reason     string         `json:"reason"`
+}
+
+// ShiftPreference records whether an employee likes or would rather avoid a
+// shift. The auto-scheduler takes it into account.
+type ShiftPreference struct {
+	gorm.Model
+	EmployeeID uint   `gorm:"not null;unique_index:idx_shift_preference" json:"employee_id"`
+	ShiftID    uint   `gorm:"not null;unique_index:idx_shift_preference" json:"shift_id"`
+	Preference string `gorm:"not null" json:"preference"` // prefer or avoid
+	Note       string `json:"note"`
+}
+
+// appliesOn reports whether the window is in effect on the calendar date
+func (w AvailabilityWindow) appliesOn(date time.Time) bool {
+	if date.Before(w.StartDate) || (w.EndDate.Valid && date.After(w.EndDate.Time)) {
+		return false
+	}
+	return !w.Recurring || w.asShift().RunsOn(date.Weekday())
+}
+
+// occurrenceOn returns the unavailable time starting on the calendar date
+func (w AvailabilityWindow) occurrenceOn(date time.Time) (employee.ShiftWindow, bool) {
+	if !w.appliesOn(date) {
+		return employee.ShiftWindow{}, false
+	}
+	return w.asShift().OccurrenceOn(date)
+}
+
+// asShift describes the window as a shift so it can share the shift's
+// time-of-day, weekday and overnight handling
+func (w AvailabilityWindow) asShift() employee.Shift {
+	shift := employee.Shift{StartTime: w.StartTime, EndTime: w.EndTime, Timezone: w.Timezone, DaysOfWeek: w.DaysOfWeek}
+	if shift.StartTime == "" {
+		shift.StartTime, shift.EndTime = "00:00", "00:00"
+	}
+	if !w.Recurring {
+		shift.DaysOfWeek = "mon,tue,wed,thu,fri,sat,sun"
+	}
+	return shift
+}
@@ -0,0 +1,293 @@
+// internal/availability/service.go
+package availability
+
+import (
+	"clinicplus/internal/employee"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrWindowNotFound     = errors.New("availability window not found")
+	ErrPreferenceNotFound = errors.New("shift preference not found")
+	ErrInvalidWindow      = errors.New("availability window needs HH:MM start and end times or neither, and an end date not before its start")
+	ErrInvalidPreference  = errors.New("preference must be prefer or avoid")
+)
+
+type AvailabilityService interface {
+	GetWindows(employeeID uint) ([]AvailabilityWindow, error)
+	CreateWindow(employeeID uint, window AvailabilityWindow) (*AvailabilityWindow, error)
+	UpdateWindow(employeeID, id uint, window AvailabilityWindow) (*AvailabilityWindow, error)
+	DeleteWindow(employeeID, id uint) error
+
+	GetPreferences(employeeID uint) ([]ShiftPreference, error)
+	SetPreference(employeeID uint, preference ShiftPreference) (*ShiftPreference, error)
+	DeletePreference(employeeID, id uint) error
+
+	// CheckAssignment makes the service an employee.AssignmentGuard
+	CheckAssignment(db *gorm.DB, emp employee.Employee, assignment employee.EmployeeShift) error
+}
+
+type availabilityService struct {
+	db          *gorm.DB
+	enforcement string
+}
+
+// NewAvailabilityService creates the service. With EnforcementBlock, shifts
+// in a window the employee marked unavailable are rejected; otherwise they
+// are assigned with a warning.
+func NewAvailabilityService(db *gorm.DB, enforcement string) AvailabilityService {
+	return &availabilityService{db: db, enforcement: enforcement}
+}
+
+func (s *availabilityService) GetWindows(employeeID uint) ([]AvailabilityWindow, error) {
+	var windows []AvailabilityWindow
+	if err := s.db.Where("employee_id = ?", employeeID).Order("start_date, start_time").Find(&windows).Error; err != nil {
+		log.Printf("Error fetching availability windows: %v", err)
+		return nil, err
+	}
+	return windows, nil
+}
+
+func (s *availabilityService) CreateWindow(employeeID uint, window AvailabilityWindow) (*AvailabilityWindow, error) {
+	emp, err := s.employee(employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.normalizeWindow(&window, emp); err != nil {
+		return nil, err
+	}
+
+	window.ID = 0
+	window.EmployeeID = employeeID
+	if err := s.db.Create(&window).Error; err != nil {
+		log.Printf("Error creating availability window: %v", err)
+		return nil, err
+	}
+	return &window, nil
+}
+
+func (s *availabilityService) UpdateWindow(employeeID, id uint, window AvailabilityWindow) (*AvailabilityWindow, error) {
+	var existing AvailabilityWindow
+	if err := s.db.Where("id = ? AND employee_id = ?", id, employeeID).First(&existing).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrWindowNotFound
+		}
+		log.Printf("Error fetching availability window: %v", err)
+		return nil, err
+	}
+	emp, err := s.employee(employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.normalizeWindow(&window, emp); err != nil {
+		return nil, err
+	}
+
+	window.ID = existing.ID
+	window.CreatedAt = existing.CreatedAt
+	window.EmployeeID = employeeID
+	if err := s.db.Save(&window).Error; err != nil {
+		log.Printf("Error updating availability window: %v", err)
+		return nil, err
+	}
+	return &window, nil
+}
+
+func (s *availabilityService) DeleteWindow(employeeID, id uint) error {
+	result := s.db.Where("id = ? AND employee_id = ?", id, employeeID).Delete(&AvailabilityWindow{})
+	if result.Error != nil {
+		log.Printf("Error deleting availability window: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWindowNotFound
+	}
+	return nil
+}
+
+func (s *availabilityService) GetPreferences(employeeID uint) ([]ShiftPreference, error) {
+	var preferences []ShiftPreference
+	if err := s.db.Where("employee_id = ?", employeeID).Order("shift_id").Find(&preferences).Error; err != nil {
+		log.Printf("Error fetching shift preferences: %v", err)
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// SetPreference records the employee's preference for a shift, replacing any
+// previous one for the same shift
+func (s *availabilityService) SetPreference(employeeID uint, preference ShiftPreference) (*ShiftPreference, error) {
+	if preference.Preference != PreferencePrefer && preference.Preference != PreferenceAvoid {
+		return nil, ErrInvalidPreference
+	}
+	if _, err := s.employee(employeeID); err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&employee.Shift{}, preference.ShiftID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrShiftNotFound
+		}
+		log.Printf("Error fetching shift: %v", err)
+		return nil, err
+	}
+
+	var existing ShiftPreference
+	err := s.db.Where("employee_id = ? AND shift_id = ?", employeeID, preference.ShiftID).First(&existing).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		log.Printf("Error fetching shift preference: %v", err)
+		return nil, err
+	}
+
+	preference.ID = existing.ID
+	preference.CreatedAt = existing.CreatedAt
+	preference.EmployeeID = employeeID
+	if err := s.db.Save(&preference).Error; err != nil {
+		log.Printf("Error saving shift preference: %v", err)
+		return nil, err
+	}
+	return &preference, nil
+}
+
+// DeletePreference removes the row outright so the shift can be given a
+// preference again without hitting the unique index
+func (s *availabilityService) DeletePreference(employeeID, id uint) error {
+	result := s.db.Unscoped().Where("id = ? AND employee_id = ?", id, employeeID).Delete(&ShiftPreference{})
+	if result.Error != nil {
+		log.Printf("Error deleting shift preference: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPreferenceNotFound
+	}
+	return nil
+}
+
+// CheckAssignment flags assignments that put the employee on a shift during
+// a window they marked unavailable
+func (s *availabilityService) CheckAssignment(db *gorm.DB, emp employee.Employee, assignment employee.EmployeeShift) error {
+	shift := assignment.Shift
+	if shift.ID == 0 {
+		if err := s.db.First(&shift, assignment.ShiftID).Error; err != nil {
+			log.Printf("Error fetching shift: %v", err)
+			return err
+		}
+	}
+
+	var windows []AvailabilityWindow
+	err := s.db.Where("employee_id = ? AND start_date <= ? AND (end_date IS NULL OR end_date >= ?)",
+		emp.ID, assignment.EndDate.AddDate(0, 0, 1), assignment.StartDate.AddDate(0, 0, -1)).
+		Find(&windows).Error
+	if err != nil {
+		log.Printf("Error fetching availability windows: %v", err)
+		return err
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+
+	for day := dateOf(assignment.StartDate); !day.After(dateOf(assignment.EndDate)); day = day.AddDate(0, 0, 1) {
+		occurrence, ok := shift.OccurrenceOn(day)
+		if !ok {
+			continue
+		}
+		for _, window := range windows {
+			if !window.blocks(occurrence) {
+				continue
+			}
+
+			sentinel := employee.ErrAssignmentWarning
+			if s.enforcement == EnforcementBlock {
+				sentinel = employee.ErrAssignmentRejected
+			}
+			reason := ""
+			if window.Reason != "" {
+				reason = fmt.Sprintf(" (%s)", window.Reason)
+			}
+			return fmt.Errorf("%w: %s is unavailable for %s on %s%s", sentinel, emp.Name, shift.Name, day.Format("2006-01-02"), reason)
+		}
+	}
+	return nil
+}
+
+// blocks reports whether the window takes up any of the shift occurrence.
+// The days around it are checked too, for windows and shifts running
+// overnight.
+func (w AvailabilityWindow) blocks(occurrence employee.ShiftWindow) bool {
+	location := w.asShift().Location()
+	first := dateOf(occurrence.Start.In(location)).AddDate(0, 0, -1)
+	last := dateOf(occurrence.End.In(location))
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if unavailable, ok := w.occurrenceOn(day); ok && unavailable.Overlaps(occurrence) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *availabilityService) employee(id uint) (*employee.Employee, error) {
+	var emp employee.Employee
+	if err := s.db.First(&emp, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+	return &emp, nil
+}
+
+// normalizeWindow validates a window and fills in defaults: one-off windows
+// end on their start date and the timezone is the employee's location's
+func (s *availabilityService) normalizeWindow(window *AvailabilityWindow, emp *employee.Employee) error {
+	if (window.StartTime == "") != (window.EndTime == "") {
+		return ErrInvalidWindow
+	}
+	for _, clock := range []string{window.StartTime, window.EndTime} {
+		if _, err := time.Parse("15:04", clock); clock != "" && err != nil {
+			return ErrInvalidWindow
+		}
+	}
+
+	window.StartDate = dateOf(window.StartDate)
+	if window.EndDate.Valid {
+		window.EndDate.Time = dateOf(window.EndDate.Time)
+	} else if !window.Recurring {
+		window.EndDate.Time, window.EndDate.Valid = window.StartDate, true
+	}
+	if window.EndDate.Valid && window.EndDate.Time.Before(window.StartDate) {
+		return ErrInvalidWindow
+	}
+
+	if window.Recurring {
+		days, err := employee.NormalizeDaysOfWeek(window.DaysOfWeek)
+		if err != nil {
+			return err
+		}
+		window.DaysOfWeek = days
+	} else {
+		window.DaysOfWeek = ""
+	}
+
+	if window.Timezone == "" {
+		window.Timezone = "UTC"
+		if emp.LocationID != nil {
+			var location employee.Location
+			if err := s.db.First(&location, *emp.LocationID).Error; err == nil {
+				window.Timezone = location.Timezone
+			}
+		}
+	}
+	if _, err := time.LoadLocation(window.Timezone); err != nil {
+		return employee.ErrInvalidTimezone
+	}
+	return nil
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
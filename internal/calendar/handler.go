@@ -0,0 +1,116 @@
+// internal/calendar/handler.go
+package calendar
+
+import (
+	"bytes"
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/roster"
+	"clinicplus/internal/shared/utils"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type CalendarHandler struct {
+	service CalendarService
+}
+
+func NewCalendarHandler(service CalendarService) *CalendarHandler {
+	return &CalendarHandler{service: service}
+}
+
+// IssueToken returns a new feed token and the subscription URLs using it.
+// Any previous token stops working.
+func (h *CalendarHandler) IssueToken(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+
+	token, err := h.service.IssueToken(user)
+	if err != nil {
+		log.Printf("Error issuing feed token: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to issue calendar feed token", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, map[string]interface{}{
+		"token":         token,
+		"employee_feed": fmt.Sprintf("/calendar/employees/%d.ics?token=%s", user.EmployeeID, token),
+	}, nil, nil)
+}
+
+func (h *CalendarHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+
+	if err := h.service.RevokeToken(user); err != nil {
+		log.Printf("Error revoking feed token: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to revoke calendar feed token", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Calendar feed token revoked",
+	})
+}
+
+// EmployeeFeed serves an employee's shifts as iCalendar, authenticated by the
+// token query parameter
+func (h *CalendarHandler) EmployeeFeed(w http.ResponseWriter, r *http.Request) {
+	h.serveFeed(w, r, "employee", h.service.EmployeeFeed)
+}
+
+// LocationFeed serves a location's roster as iCalendar, authenticated by the
+// token query parameter
+func (h *CalendarHandler) LocationFeed(w http.ResponseWriter, r *http.Request) {
+	h.serveFeed(w, r, "location", h.service.LocationFeed)
+}
+
+func (h *CalendarHandler) serveFeed(w http.ResponseWriter, r *http.Request, kind string,
+	feed func(user *iam.User, id uint, w io.Writer) error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid %s ID: %v", kind, err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, fmt.Sprintf("Invalid %s ID", kind), nil)
+		return
+	}
+
+	user, err := h.service.Authenticate(r.URL.Query().Get("token"))
+	if err != nil {
+		sendCalendarError(w, err, "Failed to authenticate calendar feed")
+		return
+	}
+
+	// Buffer the feed so errors can still be reported as JSON
+	var buf bytes.Buffer
+	if err := feed(user, uint(id), &buf); err != nil {
+		sendCalendarError(w, err, "Failed to generate calendar feed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", fmt.Sprintf("%s-%d.ics", kind, id)))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Error writing %s calendar feed %d: %v", kind, id, err)
+	}
+}
+
+func sendCalendarError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case ErrInvalidFeedToken:
+		utils.SendJSONResponse(w, http.StatusUnauthorized, nil, err.Error(), nil)
+	case ErrNotAllowed:
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, err.Error(), nil)
+	case ErrLocationNotFound, employee.ErrEmployeeNotFound:
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case roster.ErrInvalidRange:
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
@@ -0,0 +1,16 @@
+package calendar
+
+import (
+	"clinicplus/internal/shared/utils"
+
+	"github.com/jinzhu/gorm"
+)
+
+// FeedToken authenticates calendar subscriptions, which cannot send bearer
+// tokens. Only a hash is stored; the token itself is shown once when issued.
+type FeedToken struct {
+	gorm.Model
+	UserID     uint           `gorm:"not null;unique_index" json:"user_id"`
+	TokenHash  string         `gorm:"not null;unique_index" json:"-"`
+	LastUsedAt utils.NullTime `json:"last_used_at"`
+}
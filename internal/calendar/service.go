@@ -0,0 +1,252 @@
+// internal/calendar/service.go
+package calendar
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/roster"
+	"clinicplus/internal/shared/utils"
+	"clinicplus/pkg/ical"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	productID = "-//ClinicPlus//Schedules//EN"
+
+	// Feeds cover two weeks back and six weeks ahead, within the roster's range limit
+	feedPastDays   = 14
+	feedFutureDays = 45
+)
+
+var (
+	ErrInvalidFeedToken = errors.New("invalid calendar feed token")
+	ErrNotAllowed       = errors.New("not allowed to access this calendar")
+	ErrLocationNotFound = errors.New("location not found")
+)
+
+type CalendarService interface {
+	IssueToken(user *iam.User) (string, error)
+	RevokeToken(user *iam.User) error
+	Authenticate(token string) (*iam.User, error)
+	EmployeeFeed(user *iam.User, employeeID uint, w io.Writer) error
+	LocationFeed(user *iam.User, locationID uint, w io.Writer) error
+}
+
+type calendarService struct {
+	db      *gorm.DB
+	rosters roster.RosterService
+}
+
+func NewCalendarService(db *gorm.DB, rosters roster.RosterService) CalendarService {
+	return &calendarService{db: db, rosters: rosters}
+}
+
+// IssueToken creates a new feed token for the user, replacing any previous
+// one so that old subscription URLs stop working
+func (s *calendarService) IssueToken(user *iam.User) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		log.Printf("Error generating feed token: %v", err)
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return "", tx.Error
+	}
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&FeedToken{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error removing previous feed token: %v", err)
+		return "", err
+	}
+	if err := tx.Create(&FeedToken{UserID: user.ID, TokenHash: hashToken(token)}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error creating feed token: %v", err)
+		return "", err
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeToken disables the user's subscription URLs
+func (s *calendarService) RevokeToken(user *iam.User) error {
+	if err := s.db.Unscoped().Where("user_id = ?", user.ID).Delete(&FeedToken{}).Error; err != nil {
+		log.Printf("Error revoking feed token: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Authenticate returns the enabled user a feed token was issued to
+func (s *calendarService) Authenticate(token string) (*iam.User, error) {
+	if token == "" {
+		return nil, ErrInvalidFeedToken
+	}
+
+	var feedToken FeedToken
+	if err := s.db.Where("token_hash = ?", hashToken(token)).First(&feedToken).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrInvalidFeedToken
+		}
+		log.Printf("Error fetching feed token: %v", err)
+		return nil, err
+	}
+
+	var user iam.User
+	if err := s.db.Where("id = ? AND disabled = ?", feedToken.UserID, false).First(&user).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrInvalidFeedToken
+		}
+		log.Printf("Error fetching user: %v", err)
+		return nil, err
+	}
+
+	now := utils.NullTime{NullTime: sql.NullTime{Time: time.Now(), Valid: true}}
+	s.db.Model(&feedToken).UpdateColumn("last_used_at", now)
+	return &user, nil
+}
+
+// EmployeeFeed writes one event per shift the employee is scheduled on.
+// Shifts on approved leave are kept as cancelled events.
+func (s *calendarService) EmployeeFeed(user *iam.User, employeeID uint, w io.Writer) error {
+	if !iam.CanAccessEmployee(user, employeeID) {
+		return ErrNotAllowed
+	}
+
+	from, to := feedWindow()
+	schedule, err := s.rosters.GetEmployeeSchedule(employeeID, from, to)
+	if err != nil {
+		return err
+	}
+	var emp employee.Employee
+	if err := s.db.First(&emp, employeeID).Error; err != nil {
+		log.Printf("Error fetching employee: %v", err)
+		return err
+	}
+	locations, err := s.locationNames()
+	if err != nil {
+		return err
+	}
+
+	calendar := ical.Calendar{ProductID: productID, Name: fmt.Sprintf("%s shifts", emp.Name), Events: []ical.Event{}}
+	for _, entry := range schedule.Entries {
+		event := ical.Event{
+			UID:      fmt.Sprintf("employee-%d-shift-%d-%s@clinicplus", employeeID, entry.ShiftID, entry.Date.Format("20060102")),
+			Summary:  entry.ShiftName,
+			Start:    entry.Start,
+			End:      entry.End,
+			Location: locationName(locations, entry.LocationID),
+			Status:   "CONFIRMED",
+		}
+		if entry.OnLeave {
+			event.Summary += " (on leave)"
+			event.Status = "CANCELLED"
+		}
+		calendar.Events = append(calendar.Events, event)
+	}
+	if len(schedule.Entries) > 0 {
+		calendar.Timezone = schedule.Entries[0].Start.Location().String()
+	}
+	return ical.Write(w, calendar)
+}
+
+// LocationFeed writes one event per shift occurrence at the location, listing
+// who is working it. Managers may subscribe to any location, employees to
+// their own.
+func (s *calendarService) LocationFeed(user *iam.User, locationID uint, w io.Writer) error {
+	var location employee.Location
+	if err := s.db.First(&location, locationID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrLocationNotFound
+		}
+		log.Printf("Error fetching location: %v", err)
+		return err
+	}
+	if !user.IsPrivileged() {
+		var emp employee.Employee
+		if err := s.db.First(&emp, user.EmployeeID).Error; err != nil || emp.LocationID == nil || *emp.LocationID != locationID {
+			return ErrNotAllowed
+		}
+	}
+
+	from, to := feedWindow()
+	rota, err := s.rosters.GetRoster(roster.Filter{From: from, To: to, LocationID: &locationID})
+	if err != nil {
+		return err
+	}
+
+	calendar := ical.Calendar{ProductID: productID, Name: fmt.Sprintf("%s roster", location.Name), Timezone: location.Timezone, Events: []ical.Event{}}
+	for _, day := range rota.Days {
+		for _, slot := range day.Slots {
+			var names []string
+			for _, rostered := range slot.Employees {
+				if rostered.OnLeave {
+					names = append(names, rostered.Name+" (on leave)")
+				} else {
+					names = append(names, rostered.Name)
+				}
+			}
+
+			summary := fmt.Sprintf("%s (%d staff)", slot.ShiftName, slot.Available)
+			if slot.Gap {
+				summary = fmt.Sprintf("%s (unstaffed)", slot.ShiftName)
+			}
+			calendar.Events = append(calendar.Events, ical.Event{
+				UID:         fmt.Sprintf("location-%d-shift-%d-%s@clinicplus", locationID, slot.ShiftID, day.Date.Format("20060102")),
+				Summary:     summary,
+				Description: strings.Join(names, "\n"),
+				Start:       slot.Start,
+				End:         slot.End,
+				Location:    location.Name,
+				Status:      "CONFIRMED",
+			})
+		}
+	}
+	return ical.Write(w, calendar)
+}
+
+func (s *calendarService) locationNames() (map[uint]string, error) {
+	var locations []employee.Location
+	if err := s.db.Find(&locations).Error; err != nil {
+		log.Printf("Error fetching locations: %v", err)
+		return nil, err
+	}
+	names := make(map[uint]string, len(locations))
+	for _, location := range locations {
+		names[location.ID] = location.Name
+	}
+	return names, nil
+}
+
+func locationName(names map[uint]string, id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return names[*id]
+}
+
+func feedWindow() (time.Time, time.Time) {
+	today := time.Now().UTC()
+	return today.AddDate(0, 0, -feedPastDays), today.AddDate(0, 0, feedFutureDays)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
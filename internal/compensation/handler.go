@@ -0,0 +1,248 @@
+// internal/compensation/handler.go
+package compensation
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type CompensationHandler struct {
+	service CompensationService
+}
+
+func NewCompensationHandler(service CompensationService) *CompensationHandler {
+	return &CompensationHandler{service: service}
+}
+
+func (h *CompensationHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	records, err := h.service.GetHistory(employeeID)
+	if err != nil {
+		log.Printf("Error fetching compensation history: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve compensation history", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, records, nil, nil)
+}
+
+// GetCurrent returns the record in effect today, or on the date given by the
+// date query parameter (YYYY-MM-DD)
+func (h *CompensationHandler) GetCurrent(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	date := time.Now().UTC()
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid date, expected YYYY-MM-DD", nil)
+			return
+		}
+		date = parsed
+	}
+
+	record, err := h.service.CompensationOn(employeeID, date)
+	if err != nil {
+		sendCompensationError(w, err, "Failed to retrieve compensation")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, record, nil, nil)
+}
+
+func (h *CompensationHandler) RequestChange(w http.ResponseWriter, r *http.Request) {
+	employeeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	var request CompensationChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	created, err := h.service.RequestChange(uint(employeeID), request, user)
+	if err != nil {
+		sendCompensationError(w, err, "Failed to request compensation change")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *CompensationHandler) GetChangeRequests(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	requests, err := h.service.GetChangeRequests(employeeID)
+	if err != nil {
+		log.Printf("Error fetching compensation change requests: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve compensation change requests", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, requests, nil, nil)
+}
+
+func (h *CompensationHandler) GetPendingChangeRequests(w http.ResponseWriter, r *http.Request) {
+	requests, err := h.service.GetPendingChangeRequests()
+	if err != nil {
+		log.Printf("Error fetching pending compensation change requests: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve pending compensation change requests", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, requests, nil, nil)
+}
+
+func (h *CompensationHandler) ApproveChange(w http.ResponseWriter, r *http.Request) {
+	id, note, ok := decisionRequest(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, record, err := h.service.ApproveChange(id, user, note)
+	if err != nil {
+		sendCompensationError(w, err, "Failed to approve compensation change")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, map[string]interface{}{
+		"record": record,
+	})
+}
+
+func (h *CompensationHandler) RejectChange(w http.ResponseWriter, r *http.Request) {
+	id, note, ok := decisionRequest(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, err := h.service.RejectChange(id, user, note)
+	if err != nil {
+		sendCompensationError(w, err, "Failed to reject compensation change")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, nil)
+}
+
+// ChangeReport lists compensation changes effective between the from and to
+// query parameters (YYYY-MM-DD). The period defaults to the last twelve months.
+func (h *CompensationHandler) ChangeReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	to := time.Now().UTC()
+	from := to.AddDate(-1, 0, 0)
+
+	if fromStr := query.Get("from"); fromStr != "" {
+		parsed, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid from date, expected YYYY-MM-DD", nil)
+			return
+		}
+		from = parsed
+	}
+	if toStr := query.Get("to"); toStr != "" {
+		parsed, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid to date, expected YYYY-MM-DD", nil)
+			return
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "to must not be before from", nil)
+		return
+	}
+
+	entries, err := h.service.ChangeReport(from, to)
+	if err != nil {
+		log.Printf("Error building compensation change report: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to build compensation change report", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, entries, nil, map[string]interface{}{
+		"from": from.Format("2006-01-02"),
+		"to":   to.Format("2006-01-02"),
+	})
+}
+
+// decisionRequest parses the request ID and optional note for approve/reject
+func decisionRequest(w http.ResponseWriter, r *http.Request) (uint, string, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid compensation change request ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid compensation change request ID", nil)
+		return 0, "", false
+	}
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+			return 0, "", false
+		}
+	}
+	return uint(id), body.Note, true
+}
+
+// authorizeEmployee parses the employee ID from the path and checks the
+// authenticated user may see that employee's compensation
+func authorizeEmployee(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	employeeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return 0, false
+	}
+
+	user, _ := iam.UserFromContext(r.Context())
+	if !iam.CanAccessEmployee(user, uint(employeeID)) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's compensation", nil)
+		return 0, false
+	}
+	return uint(employeeID), true
+}
+
+func sendCompensationError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case employee.ErrEmployeeNotFound, ErrCompensationNotFound, ErrChangeRequestNotFound:
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case ErrInvalidCurrency, ErrInvalidFrequency, ErrInvalidComponent, ErrMissingBase:
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case ErrEffectiveDateConflict, ErrPendingChange, ErrInvalidStatus:
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	case ErrNotAllowed:
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
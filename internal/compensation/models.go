@@ -0,0 +1,131 @@
+package compensation
+
+import (
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ComponentBase      = "base"
+	ComponentAllowance = "allowance"
+)
+
+const (
+	FrequencyHourly   = "hourly"
+	FrequencyWeekly   = "weekly"
+	FrequencyBiweekly = "biweekly"
+	FrequencyMonthly  = "monthly"
+	FrequencyAnnual   = "annual"
+)
+
+const (
+	RequestStatusPending  = "Pending"
+	RequestStatusApproved = "Approved"
+	RequestStatusRejected = "Rejected"
+)
+
+// PayComponent is one line of a pay package, e.g. base pay or a housing allowance
+type PayComponent struct {
+	Kind   string          `json:"kind" gorm:"not null"` // base or allowance
+	Name   string          `json:"name" gorm:"not null"` // e.g., Base salary, Night duty allowance
+	Amount decimal.Decimal `json:"amount" gorm:"type:numeric(14,2);not null"`
+}
+
+// CompensationRecord is an employee's pay package from EffectiveDate until
+// EndDate, or open-ended while it is the latest record
+type CompensationRecord struct {
+	gorm.Model
+	EmployeeID      uint                    `gorm:"not null;index" json:"employee_id"`
+	EffectiveDate   time.Time               `gorm:"type:date;not null" json:"effective_date"`
+	EndDate         utils.NullTime          `gorm:"type:date" json:"end_date"`
+	Currency        string                  `gorm:"type:char(3);not null" json:"currency"` // ISO 4217, e.g., INR, USD
+	PayFrequency    string                  `gorm:"not null" json:"pay_frequency"`
+	Reason          string                  `json:"reason"`
+	ChangeRequestID uint                    `json:"change_request_id"`
+	Components      []CompensationComponent `gorm:"foreignkey:RecordID" json:"components"`
+}
+
+type CompensationComponent struct {
+	gorm.Model
+	RecordID uint `gorm:"not null;index" json:"record_id"`
+	PayComponent
+}
+
+// CompensationChangeRequest proposes a new pay package. It takes effect only
+// once approved by someone other than the requester.
+type CompensationChangeRequest struct {
+	gorm.Model
+	EmployeeID    uint                     `gorm:"not null;index" json:"employee_id"`
+	EffectiveDate time.Time                `gorm:"type:date;not null" json:"effective_date"`
+	Currency      string                   `gorm:"type:char(3);not null" json:"currency"`
+	PayFrequency  string                   `gorm:"not null" json:"pay_frequency"`
+	Reason        string                   `json:"reason"`
+	Status        string                   `gorm:"not null;index" json:"status"`
+	RequestedBy   uint                     `json:"requested_by"` // iam.User
+	DecidedBy     *uint                    `json:"decided_by"`   // iam.User
+	DecidedAt     utils.NullTime           `json:"decided_at"`
+	DecisionNote  string                   `json:"decision_note"`
+	Components    []ChangeRequestComponent `gorm:"foreignkey:ChangeRequestID" json:"components"`
+}
+
+type ChangeRequestComponent struct {
+	gorm.Model
+	ChangeRequestID uint `gorm:"not null;index" json:"change_request_id"`
+	PayComponent
+}
+
+// Total sums all components of the record
+func (r CompensationRecord) Total() decimal.Decimal {
+	total := decimal.Zero
+	for _, component := range r.Components {
+		total = total.Add(component.Amount)
+	}
+	return total
+}
+
+// Base sums the base pay components of the record
+func (r CompensationRecord) Base() decimal.Decimal {
+	total := decimal.Zero
+	for _, component := range r.Components {
+		if component.Kind == ComponentBase {
+			total = total.Add(component.Amount)
+		}
+	}
+	return total
+}
+
+// PeriodsPerYear returns how many pay periods of the record's frequency fall in a year
+func (r CompensationRecord) PeriodsPerYear() decimal.Decimal {
+	switch r.PayFrequency {
+	case FrequencyWeekly:
+		return decimal.NewFromInt(52)
+	case FrequencyBiweekly:
+		return decimal.NewFromInt(26)
+	case FrequencyMonthly:
+		return decimal.NewFromInt(12)
+	case FrequencyAnnual:
+		return decimal.NewFromInt(1)
+	}
+	return decimal.Zero
+}
+
+// HourlyRate returns the base pay per hour. Salaried pay is spread over
+// 52 weeks of weeklyHours.
+func (r CompensationRecord) HourlyRate(weeklyHours decimal.Decimal) decimal.Decimal {
+	if r.PayFrequency == FrequencyHourly {
+		return r.Base()
+	}
+	if !weeklyHours.IsPositive() {
+		return decimal.Zero
+	}
+	return r.Base().Mul(r.PeriodsPerYear()).Div(weeklyHours.Mul(decimal.NewFromInt(52)))
+}
+
+// DailyAmount returns the amount earned per calendar day for salaried pay.
+// Hourly records have no daily amount.
+func (r CompensationRecord) DailyAmount(amount decimal.Decimal) decimal.Decimal {
+	return amount.Mul(r.PeriodsPerYear()).Div(decimal.NewFromInt(365))
+}
@@ -0,0 +1,437 @@
+// internal/compensation/service.go
+package compensation
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrCompensationNotFound  = errors.New("no compensation record for this date")
+	ErrChangeRequestNotFound = errors.New("compensation change request not found")
+	ErrInvalidCurrency       = errors.New("currency must be a three-letter ISO 4217 code")
+	ErrInvalidFrequency      = errors.New("invalid pay frequency")
+	ErrInvalidComponent      = errors.New("pay components need a kind, a name and a non-negative amount")
+	ErrMissingBase           = errors.New("at least one base pay component is required")
+	ErrEffectiveDateConflict = errors.New("effective date must be after the latest compensation record")
+	ErrPendingChange         = errors.New("employee already has a pending compensation change")
+	ErrNotAllowed            = errors.New("not allowed to act on this employee's compensation")
+	ErrInvalidStatus         = errors.New("compensation change request can no longer be changed")
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+var frequencies = map[string]bool{
+	FrequencyHourly:   true,
+	FrequencyWeekly:   true,
+	FrequencyBiweekly: true,
+	FrequencyMonthly:  true,
+	FrequencyAnnual:   true,
+}
+
+// ChangeReportEntry describes one compensation change that took effect in
+// the reported period, compared with the record it replaced
+type ChangeReportEntry struct {
+	EmployeeID      uint             `json:"employee_id"`
+	EmployeeName    string           `json:"employee_name"`
+	Designation     string           `json:"designation"`
+	EffectiveDate   time.Time        `json:"effective_date"`
+	Currency        string           `json:"currency"`
+	PayFrequency    string           `json:"pay_frequency"`
+	PreviousTotal   *decimal.Decimal `json:"previous_total"` // nil for an employee's first record
+	NewTotal        decimal.Decimal  `json:"new_total"`
+	Change          *decimal.Decimal `json:"change"`
+	ChangePercent   *decimal.Decimal `json:"change_percent"`
+	Reason          string           `json:"reason"`
+	ChangeRequestID uint             `json:"change_request_id"`
+}
+
+type CompensationService interface {
+	GetHistory(employeeID uint) ([]CompensationRecord, error)
+	CompensationOn(employeeID uint, date time.Time) (*CompensationRecord, error)
+
+	RequestChange(employeeID uint, request CompensationChangeRequest, requester *iam.User) (*CompensationChangeRequest, error)
+	GetChangeRequests(employeeID uint) ([]CompensationChangeRequest, error)
+	GetPendingChangeRequests() ([]CompensationChangeRequest, error)
+	ApproveChange(id uint, approver *iam.User, note string) (*CompensationChangeRequest, *CompensationRecord, error)
+	RejectChange(id uint, approver *iam.User, note string) (*CompensationChangeRequest, error)
+
+	SyncSalaries(now time.Time) (int, error)
+	ChangeReport(from, to time.Time) ([]ChangeReportEntry, error)
+}
+
+type compensationService struct {
+	db            *gorm.DB
+	notifications notification.NotificationService
+}
+
+func NewCompensationService(db *gorm.DB, notifications notification.NotificationService) CompensationService {
+	return &compensationService{db: db, notifications: notifications}
+}
+
+func (s *compensationService) GetHistory(employeeID uint) ([]CompensationRecord, error) {
+	var records []CompensationRecord
+	if err := s.db.Preload("Components").Where("employee_id = ?", employeeID).Order("effective_date DESC").Find(&records).Error; err != nil {
+		log.Printf("Error fetching compensation history: %v", err)
+		return nil, err
+	}
+	return records, nil
+}
+
+// CompensationOn returns the record in effect for the employee on the given date
+func (s *compensationService) CompensationOn(employeeID uint, date time.Time) (*CompensationRecord, error) {
+	day := date.UTC().Truncate(24 * time.Hour)
+
+	var record CompensationRecord
+	err := s.db.Preload("Components").
+		Where("employee_id = ? AND effective_date <= ? AND (end_date IS NULL OR end_date >= ?)", employeeID, day, day).
+		Order("effective_date DESC").
+		First(&record).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCompensationNotFound
+		}
+		log.Printf("Error fetching compensation record: %v", err)
+		return nil, err
+	}
+	return &record, nil
+}
+
+// RequestChange proposes a new pay package for the employee. Admins may
+// propose changes for anyone, managers only for their direct reports.
+func (s *compensationService) RequestChange(employeeID uint, request CompensationChangeRequest, requester *iam.User) (*CompensationChangeRequest, error) {
+	var emp employee.Employee
+	if err := s.db.First(&emp, employeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+	if !canManage(requester, emp) {
+		return nil, ErrNotAllowed
+	}
+
+	request.EffectiveDate = request.EffectiveDate.UTC().Truncate(24 * time.Hour)
+	if err := validatePackage(request.Currency, request.PayFrequency, request.Components); err != nil {
+		return nil, err
+	}
+	if err := s.checkEffectiveDate(s.db, employeeID, request.EffectiveDate); err != nil {
+		return nil, err
+	}
+
+	var pending int
+	s.db.Model(&CompensationChangeRequest{}).Where("employee_id = ? AND status = ?", employeeID, RequestStatusPending).Count(&pending)
+	if pending > 0 {
+		return nil, ErrPendingChange
+	}
+
+	request.ID = 0
+	request.EmployeeID = employeeID
+	request.Status = RequestStatusPending
+	request.RequestedBy = requester.ID
+	request.DecidedBy = nil
+	request.DecidedAt = utils.NullTime{}
+	request.DecisionNote = ""
+	for i := range request.Components {
+		request.Components[i].ID = 0
+		request.Components[i].ChangeRequestID = 0
+	}
+
+	if err := s.db.Create(&request).Error; err != nil {
+		log.Printf("Error creating compensation change request: %v", err)
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (s *compensationService) GetChangeRequests(employeeID uint) ([]CompensationChangeRequest, error) {
+	var requests []CompensationChangeRequest
+	if err := s.db.Preload("Components").Where("employee_id = ?", employeeID).Order("created_at DESC").Find(&requests).Error; err != nil {
+		log.Printf("Error fetching compensation change requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (s *compensationService) GetPendingChangeRequests() ([]CompensationChangeRequest, error) {
+	var requests []CompensationChangeRequest
+	if err := s.db.Preload("Components").Where("status = ?", RequestStatusPending).Order("effective_date").Find(&requests).Error; err != nil {
+		log.Printf("Error fetching pending compensation change requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ApproveChange turns a pending request into a compensation record and closes
+// the record it supersedes. Changes already in effect are mirrored into
+// Employee.Salary straight away; future ones are picked up by SyncSalaries.
+func (s *compensationService) ApproveChange(id uint, approver *iam.User, note string) (*CompensationChangeRequest, *CompensationRecord, error) {
+	request, err := s.pendingRequestFor(id, approver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, nil, tx.Error
+	}
+
+	if err := s.checkEffectiveDate(tx, request.EmployeeID, request.EffectiveDate); err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	err = tx.Model(&CompensationRecord{}).
+		Where("employee_id = ? AND end_date IS NULL", request.EmployeeID).
+		Update("end_date", request.EffectiveDate.AddDate(0, 0, -1)).Error
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error closing previous compensation record: %v", err)
+		return nil, nil, err
+	}
+
+	record := CompensationRecord{
+		EmployeeID:      request.EmployeeID,
+		EffectiveDate:   request.EffectiveDate,
+		Currency:        request.Currency,
+		PayFrequency:    request.PayFrequency,
+		Reason:          request.Reason,
+		ChangeRequestID: request.ID,
+	}
+	for _, component := range request.Components {
+		record.Components = append(record.Components, CompensationComponent{PayComponent: component.PayComponent})
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error creating compensation record: %v", err)
+		return nil, nil, err
+	}
+
+	if !request.EffectiveDate.After(time.Now().UTC()) {
+		if err := mirrorSalary(tx, record); err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+	}
+
+	s.decide(request, approver, RequestStatusApproved, note)
+	if err := tx.Save(request).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error approving compensation change request: %v", err)
+		return nil, nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, nil, err
+	}
+
+	s.notifications.Notify(request.EmployeeID, "compensation_change",
+		"Your compensation has been updated",
+		fmt.Sprintf("New pay of %s %s (%s) effective %s", record.Total().StringFixed(2), record.Currency, record.PayFrequency, record.EffectiveDate.Format("2006-01-02")))
+
+	return request, &record, nil
+}
+
+func (s *compensationService) RejectChange(id uint, approver *iam.User, note string) (*CompensationChangeRequest, error) {
+	request, err := s.pendingRequestFor(id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	s.decide(request, approver, RequestStatusRejected, note)
+	if err := s.db.Save(request).Error; err != nil {
+		log.Printf("Error rejecting compensation change request: %v", err)
+		return nil, err
+	}
+	return request, nil
+}
+
+// SyncSalaries copies the base pay of each record in effect on now into the
+// legacy Employee.Salary field, so future-dated changes show up once they
+// take effect. It returns the number of employees updated.
+func (s *compensationService) SyncSalaries(now time.Time) (int, error) {
+	day := now.UTC().Truncate(24 * time.Hour)
+
+	var records []CompensationRecord
+	err := s.db.Preload("Components").
+		Where("effective_date <= ? AND (end_date IS NULL OR end_date >= ?)", day, day).
+		Find(&records).Error
+	if err != nil {
+		log.Printf("Error fetching current compensation records: %v", err)
+		return 0, err
+	}
+
+	updated := 0
+	for _, record := range records {
+		result := s.db.Model(&employee.Employee{}).
+			Where("id = ? AND salary <> ?", record.EmployeeID, record.Base().InexactFloat64()).
+			Update("salary", record.Base().InexactFloat64())
+		if result.Error != nil {
+			log.Printf("Error syncing salary: %v", result.Error)
+			return updated, result.Error
+		}
+		updated += int(result.RowsAffected)
+	}
+	return updated, nil
+}
+
+// ChangeReport lists the compensation records that took effect between from
+// and to (inclusive), each compared with the record it replaced
+func (s *compensationService) ChangeReport(from, to time.Time) ([]ChangeReportEntry, error) {
+	var records []CompensationRecord
+	err := s.db.Preload("Components").
+		Where("effective_date BETWEEN ? AND ?", from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)).
+		Order("effective_date, employee_id").
+		Find(&records).Error
+	if err != nil {
+		log.Printf("Error fetching compensation records for report: %v", err)
+		return nil, err
+	}
+
+	entries := make([]ChangeReportEntry, 0, len(records))
+	employees := make(map[uint]employee.Employee)
+	for _, record := range records {
+		emp, ok := employees[record.EmployeeID]
+		if !ok {
+			if err := s.db.Unscoped().First(&emp, record.EmployeeID).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+				log.Printf("Error fetching employee for report: %v", err)
+				return nil, err
+			}
+			employees[record.EmployeeID] = emp
+		}
+
+		entry := ChangeReportEntry{
+			EmployeeID:      record.EmployeeID,
+			EmployeeName:    emp.Name,
+			Designation:     emp.Designation,
+			EffectiveDate:   record.EffectiveDate,
+			Currency:        record.Currency,
+			PayFrequency:    record.PayFrequency,
+			NewTotal:        record.Total(),
+			Reason:          record.Reason,
+			ChangeRequestID: record.ChangeRequestID,
+		}
+
+		var previous CompensationRecord
+		err := s.db.Preload("Components").
+			Where("employee_id = ? AND effective_date < ?", record.EmployeeID, record.EffectiveDate).
+			Order("effective_date DESC").
+			First(&previous).Error
+		switch {
+		case err == nil:
+			// Totals are only comparable in the same currency and frequency
+			if previous.Currency == record.Currency && previous.PayFrequency == record.PayFrequency {
+				previousTotal := previous.Total()
+				change := entry.NewTotal.Sub(previousTotal)
+				entry.PreviousTotal = &previousTotal
+				entry.Change = &change
+				if !previousTotal.IsZero() {
+					percent := change.Div(previousTotal).Mul(decimal.NewFromInt(100)).Round(2)
+					entry.ChangePercent = &percent
+				}
+			}
+		case !gorm.IsRecordNotFoundError(err):
+			log.Printf("Error fetching previous compensation record: %v", err)
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// pendingRequestFor loads a pending request and checks the approver may decide
+// on it. Nobody decides on a change they requested or on their own pay.
+func (s *compensationService) pendingRequestFor(id uint, approver *iam.User) (*CompensationChangeRequest, error) {
+	var request CompensationChangeRequest
+	if err := s.db.Preload("Components").First(&request, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrChangeRequestNotFound
+		}
+		log.Printf("Error fetching compensation change request: %v", err)
+		return nil, err
+	}
+	if request.Status != RequestStatusPending {
+		return nil, ErrInvalidStatus
+	}
+	if approver.Role != iam.RoleAdmin || approver.ID == request.RequestedBy || approver.EmployeeID == request.EmployeeID {
+		return nil, ErrNotAllowed
+	}
+	return &request, nil
+}
+
+func (s *compensationService) decide(request *CompensationChangeRequest, approver *iam.User, status, note string) {
+	request.Status = status
+	request.DecidedBy = &approver.ID
+	request.DecidedAt = utils.NullTime{NullTime: sql.NullTime{Time: time.Now().UTC(), Valid: true}}
+	request.DecisionNote = note
+}
+
+// checkEffectiveDate makes sure records stay in order: a change can only take
+// effect after the employee's latest record
+func (s *compensationService) checkEffectiveDate(db *gorm.DB, employeeID uint, effectiveDate time.Time) error {
+	var later int
+	db.Model(&CompensationRecord{}).Where("employee_id = ? AND effective_date >= ?", employeeID, effectiveDate).Count(&later)
+	if later > 0 {
+		return ErrEffectiveDateConflict
+	}
+	return nil
+}
+
+// canManage reports whether the user may propose pay changes for the employee
+func canManage(user *iam.User, emp employee.Employee) bool {
+	if user.Role == iam.RoleAdmin {
+		return true
+	}
+	return user.Role == iam.RoleManager && emp.ManagerID != nil && *emp.ManagerID == user.EmployeeID
+}
+
+func validatePackage(currency, frequency string, components []ChangeRequestComponent) error {
+	if !currencyCode.MatchString(currency) {
+		return ErrInvalidCurrency
+	}
+	if !frequencies[frequency] {
+		return ErrInvalidFrequency
+	}
+
+	hasBase := false
+	for _, component := range components {
+		if component.Name == "" || component.Amount.IsNegative() {
+			return ErrInvalidComponent
+		}
+		switch component.Kind {
+		case ComponentBase:
+			hasBase = true
+		case ComponentAllowance:
+		default:
+			return ErrInvalidComponent
+		}
+	}
+	if !hasBase {
+		return ErrMissingBase
+	}
+	return nil
+}
+
+func mirrorSalary(tx *gorm.DB, record CompensationRecord) error {
+	err := tx.Model(&employee.Employee{}).Where("id = ?", record.EmployeeID).
+		Update("salary", record.Base().InexactFloat64()).Error
+	if err != nil {
+		log.Printf("Error syncing salary: %v", err)
+	}
+	return err
+}
@@ -0,0 +1,199 @@
+package compensation
+
+import (
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/testdb"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+// day returns a date in March 2024
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func component(kind, name, amount string) ChangeRequestComponent {
+	return ChangeRequestComponent{PayComponent: PayComponent{Kind: kind, Name: name, Amount: decimal.RequireFromString(amount)}}
+}
+
+var (
+	admin   = &iam.User{Model: gorm.Model{ID: 1}, EmployeeID: 1, Role: iam.RoleAdmin}
+	manager = &iam.User{Model: gorm.Model{ID: 2}, EmployeeID: 8, Role: iam.RoleManager}
+)
+
+func TestRequestChange(t *testing.T) {
+	valid := CompensationChangeRequest{
+		EffectiveDate: day(1), Currency: "USD", PayFrequency: FrequencyMonthly,
+		Components: []ChangeRequestComponent{component(ComponentBase, "Base salary", "5000"), component(ComponentAllowance, "Night duty", "250")},
+	}
+
+	tests := []struct {
+		name      string
+		requester *iam.User
+		change    func(*CompensationChangeRequest)
+		later     bool // The employee has a record effective on or after the change
+		pending   bool // The employee has a pending change
+		wantErr   error
+	}{
+		{name: "admin", requester: admin},
+		{name: "the employee's manager", requester: manager},
+		{name: "another manager", requester: &iam.User{Model: gorm.Model{ID: 3}, EmployeeID: 9, Role: iam.RoleManager}, wantErr: ErrNotAllowed},
+		{name: "the employee", requester: &iam.User{Model: gorm.Model{ID: 4}, EmployeeID: 5, Role: iam.RoleEmployee}, wantErr: ErrNotAllowed},
+		{name: "lower-case currency", requester: admin, change: func(r *CompensationChangeRequest) { r.Currency = "usd" }, wantErr: ErrInvalidCurrency},
+		{name: "unknown frequency", requester: admin, change: func(r *CompensationChangeRequest) { r.PayFrequency = "daily" }, wantErr: ErrInvalidFrequency},
+		{
+			name: "without base pay", requester: admin,
+			change:  func(r *CompensationChangeRequest) { r.Components = r.Components[1:] },
+			wantErr: ErrMissingBase,
+		},
+		{
+			name: "negative component", requester: admin,
+			change: func(r *CompensationChangeRequest) {
+				r.Components = []ChangeRequestComponent{component(ComponentBase, "Base salary", "-1")}
+			},
+			wantErr: ErrInvalidComponent,
+		},
+		{name: "before the latest record", requester: admin, later: true, wantErr: ErrEffectiveDateConflict},
+		{name: "with a pending change", requester: admin, pending: true, wantErr: ErrPendingChange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "employees"`, []string{"id", "manager_id"}, []interface{}{5, 8})
+			if tt.later {
+				fake.Returns(`FROM "compensation_records"`, []string{"count"}, []interface{}{1})
+			}
+			if tt.pending {
+				fake.Returns(`FROM "compensation_change_requests"`, []string{"count"}, []interface{}{1})
+			}
+			service := NewCompensationService(db, notification.NewNotificationService(db))
+
+			change := valid
+			change.Components = append([]ChangeRequestComponent(nil), valid.Components...)
+			if tt.change != nil {
+				tt.change(&change)
+			}
+			request, err := service.RequestChange(5, change, tt.requester)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequestChange error %v, want %v", err, tt.wantErr)
+			}
+			if saved := fake.Ran(`INSERT INTO "compensation_change_requests"`); saved != (tt.wantErr == nil) {
+				t.Errorf("request saved: %v", saved)
+			}
+			if err != nil {
+				return
+			}
+			if request.Status != RequestStatusPending || request.EmployeeID != 5 || request.RequestedBy != tt.requester.ID {
+				t.Errorf("request saved as %s for employee %d by %d", request.Status, request.EmployeeID, request.RequestedBy)
+			}
+			if components := fake.Find(`INSERT INTO "change_request_components"`); len(components) != 2 {
+				t.Errorf("saved %d components, want 2", len(components))
+			}
+		})
+	}
+}
+
+func TestApproveChange(t *testing.T) {
+	future := time.Now().UTC().AddDate(0, 1, 0).Truncate(24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		approver    *iam.User
+		effective   time.Time
+		status      string
+		wantErr     error
+		wantMirror  bool // Whether Employee.Salary is updated at once
+		requestedBy uint
+	}{
+		{name: "in effect", approver: admin, effective: day(1), status: RequestStatusPending, requestedBy: 2, wantMirror: true},
+		{name: "future-dated", approver: admin, effective: future, status: RequestStatusPending, requestedBy: 2},
+		{name: "by its requester", approver: admin, effective: day(1), status: RequestStatusPending, requestedBy: 1, wantErr: ErrNotAllowed},
+		{name: "by a manager", approver: manager, effective: day(1), status: RequestStatusPending, requestedBy: 1, wantErr: ErrNotAllowed},
+		{
+			name: "of the approver's own pay", approver: &iam.User{Model: gorm.Model{ID: 6}, EmployeeID: 5, Role: iam.RoleAdmin},
+			effective: day(1), status: RequestStatusPending, requestedBy: 2, wantErr: ErrNotAllowed,
+		},
+		{name: "already rejected", approver: admin, effective: day(1), status: RequestStatusRejected, requestedBy: 2, wantErr: ErrInvalidStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "compensation_change_requests"`, []string{"id", "employee_id", "effective_date", "currency", "pay_frequency", "status", "requested_by"},
+				[]interface{}{1, 5, tt.effective, "USD", FrequencyMonthly, tt.status, tt.requestedBy})
+			fake.Returns(`FROM "change_request_components"`, []string{"id", "change_request_id", "kind", "name", "amount"},
+				[]interface{}{1, 1, ComponentBase, "Base salary", "5000.00"},
+				[]interface{}{2, 1, ComponentAllowance, "Night duty", "250.00"})
+			service := NewCompensationService(db, notification.NewNotificationService(db))
+
+			request, record, err := service.ApproveChange(1, tt.approver, "Annual review")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ApproveChange error %v, want %v", err, tt.wantErr)
+			}
+			if created := fake.Ran(`INSERT INTO "compensation_records"`); created != (tt.wantErr == nil) {
+				t.Errorf("record created: %v", created)
+			}
+			if err != nil {
+				return
+			}
+
+			if request.Status != RequestStatusApproved || request.DecidedBy == nil || *request.DecidedBy != tt.approver.ID {
+				t.Errorf("request decided as %s by %v", request.Status, request.DecidedBy)
+			}
+			if !record.Total().Equal(decimal.NewFromInt(5250)) || len(record.Components) != 2 {
+				t.Errorf("record totals %s over %d components, want 5250 over 2", record.Total(), len(record.Components))
+			}
+			closed := fake.Find(`UPDATE "compensation_records" SET "end_date"`)
+			if len(closed) != 1 || !closed[0].Has(tt.effective.AddDate(0, 0, -1)) {
+				t.Errorf("previous record closed with %+v", closed)
+			}
+			salary := fake.Find(`UPDATE "employees" SET "salary"`)
+			if mirrored := len(salary) == 1 && salary[0].Has(5000.0); mirrored != tt.wantMirror {
+				t.Errorf("salary mirrored: %+v, want %v", salary, tt.wantMirror)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 1 || !notifications[0].Has(5) {
+				t.Errorf("employee notified with %+v", notifications)
+			}
+		})
+	}
+}
+
+func TestChangeReport(t *testing.T) {
+	db, fake := testdb.New(t)
+	recordColumns := []string{"id", "employee_id", "effective_date", "currency", "pay_frequency"}
+	fake.Returns(`effective_date BETWEEN`, recordColumns,
+		[]interface{}{2, 5, day(1), "USD", FrequencyMonthly},
+		[]interface{}{3, 6, day(15), "USD", FrequencyMonthly})
+	fake.Returns(`effective_date <`, recordColumns, []interface{}{1, 5, day(1).AddDate(-1, 0, 0), "USD", FrequencyMonthly})
+	componentColumns := []string{"id", "record_id", "kind", "name", "amount"}
+	fake.Returns(`"record_id" IN ($1,$2)`, componentColumns,
+		[]interface{}{2, 2, ComponentBase, "Base salary", "5000.00"},
+		[]interface{}{3, 3, ComponentBase, "Base salary", "3000.00"})
+	fake.Returns(`"record_id" IN ($1)`, componentColumns, []interface{}{1, 1, ComponentBase, "Base salary", "4000.00"})
+	fake.Returns(`FROM "employees"`, []string{"id", "name", "designation"}, []interface{}{5, "Ana", "Nurse"})
+	service := NewCompensationService(db, nil)
+
+	entries, err := service.ChangeReport(day(1), day(31))
+	if err != nil {
+		t.Fatalf("ChangeReport: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	raise := entries[0]
+	if raise.EmployeeName != "Ana" || !raise.NewTotal.Equal(decimal.NewFromInt(5000)) {
+		t.Errorf("first entry %+v", raise)
+	}
+	if raise.PreviousTotal == nil || !raise.PreviousTotal.Equal(decimal.NewFromInt(4000)) ||
+		raise.Change == nil || !raise.Change.Equal(decimal.NewFromInt(1000)) ||
+		raise.ChangePercent == nil || !raise.ChangePercent.Equal(decimal.NewFromInt(25)) {
+		t.Errorf("raise compared as %v -> %v (%v, %v%%)", raise.PreviousTotal, raise.NewTotal, raise.Change, raise.ChangePercent)
+	}
+}
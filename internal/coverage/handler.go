@@ -0,0 +1,147 @@
+// internal/coverage/handler.go
+package coverage
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/roster"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type CoverageHandler struct {
+	service CoverageService
+}
+
+func NewCoverageHandler(service CoverageService) *CoverageHandler {
+	return &CoverageHandler{service: service}
+}
+
+func (h *CoverageHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var rule CoverageRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreateRule(rule)
+	if err != nil {
+		sendCoverageError(w, err, "Failed to create coverage rule")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *CoverageHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.GetRules()
+	if err != nil {
+		log.Printf("Error fetching coverage rules: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve coverage rules", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, rules, nil, nil)
+}
+
+func (h *CoverageHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid coverage rule ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid coverage rule ID", nil)
+		return
+	}
+
+	var rule CoverageRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateRule(uint(id), rule)
+	if err != nil {
+		sendCoverageError(w, err, "Failed to update coverage rule")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
+
+func (h *CoverageHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid coverage rule ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid coverage rule ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteRule(uint(id)); err != nil {
+		sendCoverageError(w, err, "Failed to delete coverage rule")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Coverage rule deleted successfully",
+	})
+}
+
+// Report returns coverage for the from and to query parameters (YYYY-MM-DD,
+// defaulting to the coming week), optionally for one location
+func (h *CoverageHandler) Report(w http.ResponseWriter, r *http.Request) {
+	from := time.Now().UTC()
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		parsed, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid from date, expected YYYY-MM-DD", nil)
+			return
+		}
+		from = parsed
+	}
+	to := from.AddDate(0, 0, 6)
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		parsed, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid to date, expected YYYY-MM-DD", nil)
+			return
+		}
+		to = parsed
+	}
+
+	var locationID *uint
+	if locationStr := r.URL.Query().Get("location"); locationStr != "" {
+		parsed, err := strconv.ParseUint(locationStr, 10, 32)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid location ID", nil)
+			return
+		}
+		location := uint(parsed)
+		locationID = &location
+	}
+
+	report, err := h.service.Report(from, to, locationID)
+	if err != nil {
+		sendCoverageError(w, err, "Failed to retrieve coverage report")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, report, nil, nil)
+}
+
+func sendCoverageError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case ErrRuleNotFound, employee.ErrShiftNotFound:
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case ErrInvalidRule, roster.ErrInvalidRange:
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
@@ -0,0 +1,51 @@
+package coverage
+
+import (
+	"clinicplus/internal/employee"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CoverageRule is the minimum number of staff of a designation a shift needs,
+// e.g., 4 nurses for the Night shift at Location A
+type CoverageRule struct {
+	gorm.Model
+	ShiftID     uint   `gorm:"not null;index" json:"shift_id"`
+	LocationID  *uint  `gorm:"index" json:"location_id"`    // Nil applies wherever the shift runs
+	Designation string `gorm:"not null" json:"designation"` // e.g., Doctor, Nurse
+	MinStaff    int    `gorm:"not null" json:"min_staff"`
+
+	Shift employee.Shift `gorm:"foreignkey:ShiftID" json:"shift,omitempty"`
+}
+
+// CoverageAlert records that a shortfall was notified, so the daily check
+// only alerts again when it gets worse
+type CoverageAlert struct {
+	gorm.Model
+	RuleID   uint      `gorm:"not null;unique_index:idx_coverage_alert" json:"rule_id"`
+	Date     time.Time `gorm:"type:date;not null;unique_index:idx_coverage_alert" json:"date"`
+	Assigned int       `json:"assigned"`
+}
+
+// CoverageEntry compares a rule with the staff assigned on one day
+type CoverageEntry struct {
+	Date        time.Time `json:"date"`
+	RuleID      uint      `json:"rule_id"`
+	ShiftID     uint      `json:"shift_id"`
+	ShiftName   string    `json:"shift_name"`
+	LocationID  *uint     `json:"location_id"`
+	Designation string    `json:"designation"`
+	Start       time.Time `json:"start"`
+	Required    int       `json:"required"`
+	Assigned    int       `json:"assigned"`
+	Shortfall   int       `json:"shortfall"`
+}
+
+type CoverageReport struct {
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Entries    []CoverageEntry `json:"entries"`
+	Gaps       int             `json:"gaps"`        // Entries with a shortfall
+	StaffShort int             `json:"staff_short"` // Sum of shortfalls
+}
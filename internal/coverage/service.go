@@ -0,0 +1,276 @@
+// internal/coverage/service.go
+package coverage
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/roster"
+	"clinicplus/internal/shared/observability"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrRuleNotFound = errors.New("coverage rule not found")
+	ErrInvalidRule  = errors.New("coverage rule needs a designation and a minimum of at least one staff member")
+)
+
+type CoverageService interface {
+	CreateRule(rule CoverageRule) (*CoverageRule, error)
+	GetRules() ([]CoverageRule, error)
+	UpdateRule(id uint, rule CoverageRule) (*CoverageRule, error)
+	DeleteRule(id uint) error
+
+	Report(from, to time.Time, locationID *uint) (*CoverageReport, error)
+	DetectGaps(now time.Time, days int) (int, error)
+}
+
+type coverageService struct {
+	db            *gorm.DB
+	rosters       roster.RosterService
+	notifications notification.NotificationService
+}
+
+func NewCoverageService(db *gorm.DB, rosters roster.RosterService, notifications notification.NotificationService) CoverageService {
+	return &coverageService{db: db, rosters: rosters, notifications: notifications}
+}
+
+func (s *coverageService) CreateRule(rule CoverageRule) (*CoverageRule, error) {
+	if err := s.validateRule(&rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Omit("Shift").Create(&rule).Error; err != nil {
+		log.Printf("Error creating coverage rule: %v", err)
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *coverageService) GetRules() ([]CoverageRule, error) {
+	var rules []CoverageRule
+	if err := s.db.Preload("Shift").Order("shift_id, location_id, designation").Find(&rules).Error; err != nil {
+		log.Printf("Error fetching coverage rules: %v", err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (s *coverageService) UpdateRule(id uint, rule CoverageRule) (*CoverageRule, error) {
+	var existing CoverageRule
+	if err := s.db.First(&existing, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrRuleNotFound
+		}
+		log.Printf("Error fetching coverage rule: %v", err)
+		return nil, err
+	}
+	if err := s.validateRule(&rule); err != nil {
+		return nil, err
+	}
+
+	rule.ID = existing.ID
+	rule.CreatedAt = existing.CreatedAt
+	if err := s.db.Omit("Shift").Save(&rule).Error; err != nil {
+		log.Printf("Error updating coverage rule: %v", err)
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *coverageService) DeleteRule(id uint) error {
+	result := s.db.Delete(&CoverageRule{}, id)
+	if result.Error != nil {
+		log.Printf("Error deleting coverage rule: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// Report compares every coverage rule with the roster for each day of the
+// range. Staff on leave do not count towards coverage. With a location,
+// only that location's rules and the rules without a location are included.
+func (s *coverageService) Report(from, to time.Time, locationID *uint) (*CoverageReport, error) {
+	query := s.db.Preload("Shift")
+	if locationID != nil {
+		query = query.Where("location_id = ? OR location_id IS NULL", *locationID)
+	}
+	var rules []CoverageRule
+	if err := query.Find(&rules).Error; err != nil {
+		log.Printf("Error fetching coverage rules: %v", err)
+		return nil, err
+	}
+
+	rota, err := s.rosters.GetRoster(roster.Filter{From: from, To: to, LocationID: locationID})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CoverageReport{From: rota.From, To: rota.To, Entries: []CoverageEntry{}}
+	for _, day := range rota.Days {
+		for _, slot := range day.Slots {
+			for _, rule := range rules {
+				if rule.ShiftID != slot.ShiftID || !ruleApplies(rule, slot) {
+					continue
+				}
+
+				entry := CoverageEntry{
+					Date:        day.Date,
+					RuleID:      rule.ID,
+					ShiftID:     slot.ShiftID,
+					ShiftName:   slot.ShiftName,
+					LocationID:  rule.LocationID,
+					Designation: rule.Designation,
+					Start:       slot.Start,
+					Required:    rule.MinStaff,
+				}
+				for _, rostered := range slot.Employees {
+					if !rostered.OnLeave && strings.EqualFold(rostered.Designation, rule.Designation) && locatedAt(rule, slot, rostered) {
+						entry.Assigned++
+					}
+				}
+				if entry.Assigned < entry.Required {
+					entry.Shortfall = entry.Required - entry.Assigned
+					report.Gaps++
+					report.StaffShort += entry.Shortfall
+				}
+				report.Entries = append(report.Entries, entry)
+			}
+		}
+	}
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return report.Entries[i].Start.Before(report.Entries[j].Start)
+	})
+	return report, nil
+}
+
+// DetectGaps checks coverage for the coming days, publishes the shortfalls as
+// gauges and notifies managers and admins of new or worsening gaps. It
+// returns the number of gaps notified.
+func (s *coverageService) DetectGaps(now time.Time, days int) (int, error) {
+	from := now.UTC()
+	report, err := s.Report(from, from.AddDate(0, 0, days-1), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	type key struct{ location, designation string }
+	gauges := make(map[key][2]int)
+	var gaps []CoverageEntry
+	for _, entry := range report.Entries {
+		if entry.Shortfall == 0 {
+			continue
+		}
+		k := key{"any", strings.ToLower(entry.Designation)}
+		if entry.LocationID != nil {
+			k.location = fmt.Sprint(*entry.LocationID)
+		}
+		g := gauges[k]
+		gauges[k] = [2]int{g[0] + 1, g[1] + entry.Shortfall}
+		gaps = append(gaps, entry)
+	}
+	observability.ResetStaffingCoverage()
+	for k, g := range gauges {
+		observability.SetStaffingCoverage(k.location, k.designation, g[0], g[1])
+	}
+
+	if len(gaps) == 0 {
+		return 0, nil
+	}
+	recipients, err := s.recipients()
+	if err != nil {
+		return 0, err
+	}
+
+	alerts := 0
+	for _, gap := range gaps {
+		var alert CoverageAlert
+		err := s.db.Where("rule_id = ? AND date = ?", gap.RuleID, gap.Date).First(&alert).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			log.Printf("Error fetching coverage alert: %v", err)
+			return alerts, err
+		}
+		if alert.ID != 0 && gap.Assigned >= alert.Assigned {
+			continue
+		}
+
+		subject := fmt.Sprintf("%s on %s is short of %d %s", gap.ShiftName, gap.Date.Format("2006-01-02"), gap.Shortfall, gap.Designation)
+		body := fmt.Sprintf("%d of the %d %s required for %s starting %s are assigned.",
+			gap.Assigned, gap.Required, gap.Designation, gap.ShiftName, gap.Start.Format("2006-01-02 15:04 MST"))
+		for _, recipient := range recipients {
+			if err := s.notifications.Notify(recipient, "coverage_gap", subject, body); err != nil {
+				return alerts, err
+			}
+		}
+		alerts++
+
+		alert.RuleID = gap.RuleID
+		alert.Date = gap.Date
+		alert.Assigned = gap.Assigned
+		if err := s.db.Save(&alert).Error; err != nil {
+			log.Printf("Error saving coverage alert: %v", err)
+			return alerts, err
+		}
+	}
+	return alerts, nil
+}
+
+// recipients returns the employees behind enabled admin and manager accounts
+func (s *coverageService) recipients() ([]uint, error) {
+	var users []iam.User
+	if err := s.db.Where("role IN (?) AND disabled = ? AND employee_id <> 0", []string{iam.RoleAdmin, iam.RoleManager}, false).
+		Find(&users).Error; err != nil {
+		log.Printf("Error fetching managers: %v", err)
+		return nil, err
+	}
+
+	seen := make(map[uint]bool, len(users))
+	var recipients []uint
+	for _, user := range users {
+		if !seen[user.EmployeeID] {
+			seen[user.EmployeeID] = true
+			recipients = append(recipients, user.EmployeeID)
+		}
+	}
+	return recipients, nil
+}
+
+func (s *coverageService) validateRule(rule *CoverageRule) error {
+	rule.Designation = strings.TrimSpace(rule.Designation)
+	if rule.Designation == "" || rule.MinStaff < 1 {
+		return ErrInvalidRule
+	}
+	if err := s.db.First(&employee.Shift{}, rule.ShiftID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return employee.ErrShiftNotFound
+		}
+		log.Printf("Error fetching shift: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ruleApplies reports whether a location-bound rule can apply to the slot:
+// the shift runs at the rule's location or has no location of its own
+func ruleApplies(rule CoverageRule, slot roster.Slot) bool {
+	return rule.LocationID == nil || slot.LocationID == nil || *slot.LocationID == *rule.LocationID
+}
+
+// locatedAt reports whether a rostered employee counts towards a rule's
+// location. Staff on a shift without a location count where they work.
+func locatedAt(rule CoverageRule, slot roster.Slot, rostered roster.RosteredEmployee) bool {
+	if rule.LocationID == nil || slot.LocationID != nil {
+		return true
+	}
+	return rostered.LocationID != nil && *rostered.LocationID == *rule.LocationID
+}
@@ -0,0 +1,171 @@
+package coverage
+
+import (
+	"clinicplus/internal/notification"
+	"clinicplus/internal/roster"
+	"clinicplus/internal/shared/testdb"
+	"testing"
+	"time"
+)
+
+// day returns a date in March 2024
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func uintPtr(n uint) *uint { return &n }
+
+// rosters serves a fixed roster
+type rosters struct {
+	roster.RosterService
+	days []roster.Day
+}
+
+func (r rosters) GetRoster(filter roster.Filter) (*roster.Roster, error) {
+	return &roster.Roster{From: filter.From, To: filter.To, Days: r.days}, nil
+}
+
+func nurse(id uint) roster.RosteredEmployee {
+	return roster.RosteredEmployee{EmployeeID: id, Designation: "Nurse"}
+}
+
+var ruleColumns = []string{"id", "shift_id", "location_id", "designation", "min_staff"}
+
+func TestReport(t *testing.T) {
+	onLeave := nurse(3)
+	onLeave.OnLeave = true
+	doctor := roster.RosteredEmployee{EmployeeID: 4, Designation: "Doctor"}
+	atWard := nurse(5)
+	atWard.LocationID = uintPtr(1)
+
+	tests := []struct {
+		name      string
+		rule      []interface{}
+		slot      roster.Slot
+		want      int // Staff counted towards the rule; -1 when the rule does not apply
+		shortfall int
+	}{
+		{
+			name: "fully staffed", rule: []interface{}{1, 1, nil, "Nurse", 2},
+			slot: roster.Slot{ShiftID: 1, Employees: []roster.RosteredEmployee{nurse(1), nurse(2)}},
+			want: 2,
+		},
+		{
+			name: "designations match regardless of case", rule: []interface{}{1, 1, nil, "nurse", 2},
+			slot: roster.Slot{ShiftID: 1, Employees: []roster.RosteredEmployee{nurse(1), nurse(2)}},
+			want: 2,
+		},
+		{
+			name: "staff on leave and other designations do not count", rule: []interface{}{1, 1, nil, "Nurse", 2},
+			slot: roster.Slot{ShiftID: 1, Employees: []roster.RosteredEmployee{nurse(1), onLeave, doctor}},
+			want: 1, shortfall: 1,
+		},
+		{
+			name: "another shift", rule: []interface{}{1, 2, nil, "Nurse", 2},
+			slot: roster.Slot{ShiftID: 1, Employees: []roster.RosteredEmployee{nurse(1)}},
+			want: -1,
+		},
+		{
+			name: "shift at another location", rule: []interface{}{1, 1, 1, "Nurse", 2},
+			slot: roster.Slot{ShiftID: 1, LocationID: uintPtr(2), Employees: []roster.RosteredEmployee{nurse(1)}},
+			want: -1,
+		},
+		{
+			name: "shift at the rule's location", rule: []interface{}{1, 1, 1, "Nurse", 2},
+			slot: roster.Slot{ShiftID: 1, LocationID: uintPtr(1), Employees: []roster.RosteredEmployee{nurse(1)}},
+			want: 1, shortfall: 1,
+		},
+		{
+			name: "shift without a location counts staff where they work", rule: []interface{}{1, 1, 1, "Nurse", 2},
+			slot: roster.Slot{ShiftID: 1, Employees: []roster.RosteredEmployee{nurse(1), atWard}},
+			want: 1, shortfall: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "coverage_rules"`, ruleColumns, tt.rule)
+			service := NewCoverageService(db, rosters{days: []roster.Day{{Date: day(4), Slots: []roster.Slot{tt.slot}}}}, nil)
+
+			report, err := service.Report(day(4), day(4), nil)
+			if err != nil {
+				t.Fatalf("Report: %v", err)
+			}
+			if tt.want < 0 {
+				if len(report.Entries) != 0 {
+					t.Errorf("rule applied: %+v", report.Entries)
+				}
+				return
+			}
+			if len(report.Entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(report.Entries))
+			}
+			entry := report.Entries[0]
+			if entry.Assigned != tt.want || entry.Shortfall != tt.shortfall {
+				t.Errorf("assigned %d short %d, want %d short %d", entry.Assigned, entry.Shortfall, tt.want, tt.shortfall)
+			}
+			wantGaps := 0
+			if tt.shortfall > 0 {
+				wantGaps = 1
+			}
+			if report.Gaps != wantGaps || report.StaffShort != tt.shortfall {
+				t.Errorf("report counts %d gaps %d short", report.Gaps, report.StaffShort)
+			}
+		})
+	}
+}
+
+func TestDetectGaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		assigned int         // Nurses rostered against a minimum of 2
+		alerted  interface{} // Nurses assigned when the gap was last notified; nil if never
+		want     int
+	}{
+		{name: "no gap", assigned: 2},
+		{name: "new gap", assigned: 1, want: 1},
+		{name: "already notified", assigned: 1, alerted: 1},
+		{name: "worse than notified", assigned: 0, alerted: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "coverage_rules"`, ruleColumns, []interface{}{1, 1, nil, "Nurse", 2})
+			if tt.alerted != nil {
+				fake.Returns(`FROM "coverage_alerts"`, []string{"id", "rule_id", "date", "assigned"}, []interface{}{7, 1, day(4), tt.alerted})
+			}
+			fake.Returns(`FROM "users"`, []string{"id", "employee_id", "role"},
+				[]interface{}{1, 8, "Admin"}, []interface{}{2, 9, "Manager"}, []interface{}{3, 8, "Manager"})
+			var staff []roster.RosteredEmployee
+			for i := 0; i < tt.assigned; i++ {
+				staff = append(staff, nurse(uint(i+1)))
+			}
+			slot := roster.Slot{ShiftID: 1, ShiftName: "Night", Start: day(4).Add(20 * time.Hour), Employees: staff}
+			service := NewCoverageService(db, rosters{days: []roster.Day{{Date: day(4), Slots: []roster.Slot{slot}}}}, notification.NewNotificationService(db))
+
+			alerts, err := service.DetectGaps(day(4), 7)
+			if err != nil {
+				t.Fatalf("DetectGaps: %v", err)
+			}
+			if alerts != tt.want {
+				t.Errorf("notified %d gaps, want %d", alerts, tt.want)
+			}
+
+			notifications := fake.Find(`INSERT INTO "notifications"`)
+			if len(notifications) != 2*tt.want {
+				t.Errorf("sent %d notifications, want one per manager for each gap", len(notifications))
+			}
+			for _, notification := range notifications {
+				if !notification.Has("coverage_gap") {
+					t.Errorf("notification sent with %v", notification.Args)
+				}
+			}
+			saved := append(fake.Find(`INSERT INTO "coverage_alerts"`), fake.Find(`UPDATE "coverage_alerts"`)...)
+			if len(saved) != tt.want || tt.want > 0 && !saved[0].Has(tt.assigned) {
+				t.Errorf("alerts saved with %+v, want %d assigned", saved, tt.assigned)
+			}
+		})
+	}
+}
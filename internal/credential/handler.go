@@ -0,0 +1,291 @@
+// internal/credential/handler.go
+package credential
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type CredentialHandler struct {
+	service CredentialService
+}
+
+func NewCredentialHandler(service CredentialService) *CredentialHandler {
+	return &CredentialHandler{service: service}
+}
+
+func (h *CredentialHandler) CreateCredentialType(w http.ResponseWriter, r *http.Request) {
+	var credentialType CredentialType
+	if err := json.NewDecoder(r.Body).Decode(&credentialType); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreateCredentialType(credentialType)
+	if err != nil {
+		log.Printf("Error creating credential type: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to create credential type", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *CredentialHandler) GetCredentialTypes(w http.ResponseWriter, r *http.Request) {
+	credentialTypes, err := h.service.GetCredentialTypes()
+	if err != nil {
+		log.Printf("Error fetching credential types: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve credential types", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, credentialTypes, nil, nil)
+}
+
+func (h *CredentialHandler) UpdateCredentialType(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid credential type ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid credential type ID", nil)
+		return
+	}
+
+	var credentialType CredentialType
+	if err := json.NewDecoder(r.Body).Decode(&credentialType); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateCredentialType(uint(id), credentialType)
+	if err != nil {
+		if err == ErrCredentialTypeNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Credential type not found", nil)
+		} else {
+			log.Printf("Error updating credential type: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to update credential type", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
+
+func (h *CredentialHandler) DeleteCredentialType(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid credential type ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid credential type ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteCredentialType(uint(id)); err != nil {
+		if err == ErrCredentialTypeNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Credential type not found", nil)
+		} else {
+			log.Printf("Error deleting credential type: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to delete credential type", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Credential type deleted successfully",
+	})
+}
+
+func (h *CredentialHandler) AddRequirement(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid credential type ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid credential type ID", nil)
+		return
+	}
+
+	var request struct {
+		Designation string `json:"designation"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Designation == "" {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	requirement, err := h.service.AddRequirement(uint(id), request.Designation)
+	if err != nil {
+		if err == ErrCredentialTypeNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Credential type not found", nil)
+		} else {
+			log.Printf("Error adding credential requirement: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to add credential requirement", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, requirement, nil, nil)
+}
+
+func (h *CredentialHandler) RemoveRequirement(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid credential type ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid credential type ID", nil)
+		return
+	}
+
+	if err := h.service.RemoveRequirement(uint(id), vars["designation"]); err != nil {
+		log.Printf("Error removing credential requirement: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to remove credential requirement", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Credential requirement removed successfully",
+	})
+}
+
+func (h *CredentialHandler) CreateCredential(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	employeeID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	var credential Credential
+	if err := json.NewDecoder(r.Body).Decode(&credential); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreateCredential(uint(employeeID), credential)
+	if err != nil {
+		switch err {
+		case employee.ErrEmployeeNotFound:
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Employee not found", nil)
+		case ErrCredentialTypeNotFound:
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Unknown credential type", nil)
+		default:
+			log.Printf("Error creating credential: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to create credential", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *CredentialHandler) GetEmployeeCredentials(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	employeeID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+	if !iam.CanAccessEmployee(user, uint(employeeID)) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's credentials", nil)
+		return
+	}
+
+	credentials, err := h.service.GetEmployeeCredentials(uint(employeeID))
+	if err != nil {
+		log.Printf("Error fetching credentials: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve credentials", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, credentials, nil, nil)
+}
+
+func (h *CredentialHandler) UpdateCredential(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid credential ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid credential ID", nil)
+		return
+	}
+
+	var credential Credential
+	if err := json.NewDecoder(r.Body).Decode(&credential); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateCredential(uint(id), credential)
+	if err != nil {
+		if err == ErrCredentialNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Credential not found", nil)
+		} else {
+			log.Printf("Error updating credential: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to update credential", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
+
+func (h *CredentialHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid credential ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid credential ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteCredential(uint(id)); err != nil {
+		if err == ErrCredentialNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Credential not found", nil)
+		} else {
+			log.Printf("Error deleting credential: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to delete credential", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Credential deleted successfully",
+	})
+}
+
+func (h *CredentialHandler) GetExpiringCredentials(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed < 0 {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid days parameter", nil)
+			return
+		}
+		days = parsed
+	}
+
+	credentials, err := h.service.GetExpiringCredentials(time.Duration(days) * 24 * time.Hour)
+	if err != nil {
+		log.Printf("Error fetching expiring credentials: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve expiring credentials", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, credentials, nil, map[string]interface{}{
+		"days": days,
+	})
+}
@@ -0,0 +1,51 @@
+package credential
+
+import (
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	StatusValid    = "Valid"
+	StatusExpiring = "Expiring"
+	StatusExpired  = "Expired"
+)
+
+type CredentialType struct {
+	gorm.Model
+	Name         string                  `json:"name" gorm:"unique;not null"` // e.g., Medical Registration, BLS, ACLS
+	Description  string                  `json:"description"`
+	Requirements []CredentialRequirement `gorm:"foreignkey:CredentialTypeID" json:"requirements"`
+}
+
+// CredentialRequirement marks a credential type as mandatory for a designation.
+// Employees with that designation cannot be assigned shifts without it.
+type CredentialRequirement struct {
+	gorm.Model
+	CredentialTypeID uint   `gorm:"not null;unique_index:idx_credential_requirement" json:"credential_type_id"`
+	Designation      string `gorm:"not null;unique_index:idx_credential_requirement" json:"designation"` // e.g., Doctor, Nurse
+}
+
+type Credential struct {
+	gorm.Model
+	EmployeeID       uint           `gorm:"not null;index" json:"employee_id"`
+	CredentialTypeID uint           `gorm:"not null" json:"credential_type_id"`
+	IssuingBody      string         `json:"issuing_body"`
+	Number           string         `json:"number"`
+	IssueDate        time.Time      `gorm:"type:date" json:"issue_date"`
+	ExpiryDate       utils.NullTime `gorm:"type:date" json:"expiry_date"` // Null for credentials that never expire
+	DocumentURL      string         `json:"document_url"`
+	Status           string         `gorm:"not null;default:'Valid'" json:"status"`
+	AlertedWindow    int            `json:"-"` // Smallest expiry window (days) already alerted on
+
+	CredentialType CredentialType `gorm:"foreignkey:CredentialTypeID" json:"credential_type"`
+}
+
+// ValidBetween reports whether the credential is valid for the whole period
+// from start to end: issued by the start and not expiring before the end
+func (c Credential) ValidBetween(start, end time.Time) bool {
+	issued := c.IssueDate.IsZero() || !c.IssueDate.After(start)
+	return issued && (!c.ExpiryDate.Valid || !c.ExpiryDate.Time.Before(end))
+}
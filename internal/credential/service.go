@@ -0,0 +1,363 @@
+// internal/credential/service.go
+package credential
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/notification"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrCredentialTypeNotFound = errors.New("credential type not found")
+	ErrCredentialNotFound     = errors.New("credential not found")
+)
+
+type CredentialService interface {
+	CreateCredentialType(credentialType CredentialType) (*CredentialType, error)
+	GetCredentialTypes() ([]CredentialType, error)
+	UpdateCredentialType(id uint, credentialType CredentialType) (*CredentialType, error)
+	DeleteCredentialType(id uint) error
+	AddRequirement(credentialTypeID uint, designation string) (*CredentialRequirement, error)
+	RemoveRequirement(credentialTypeID uint, designation string) error
+
+	CreateCredential(employeeID uint, credential Credential) (*Credential, error)
+	GetEmployeeCredentials(employeeID uint) ([]Credential, error)
+	UpdateCredential(id uint, credential Credential) (*Credential, error)
+	DeleteCredential(id uint) error
+	GetExpiringCredentials(within time.Duration) ([]Credential, error)
+
+	FlagExpiringCredentials(now time.Time, windows []int) (int, error)
+	CheckAssignment(db *gorm.DB, emp employee.Employee, assignment employee.EmployeeShift) error
+}
+
+type credentialService struct {
+	db            *gorm.DB
+	notifications notification.NotificationService
+}
+
+func NewCredentialService(db *gorm.DB, notifications notification.NotificationService) CredentialService {
+	return &credentialService{db: db, notifications: notifications}
+}
+
+func (s *credentialService) CreateCredentialType(credentialType CredentialType) (*CredentialType, error) {
+	if err := s.db.Create(&credentialType).Error; err != nil {
+		log.Printf("Error creating credential type: %v", err)
+		return nil, err
+	}
+	return &credentialType, nil
+}
+
+func (s *credentialService) GetCredentialTypes() ([]CredentialType, error) {
+	var credentialTypes []CredentialType
+	if err := s.db.Preload("Requirements").Find(&credentialTypes).Error; err != nil {
+		log.Printf("Error fetching credential types: %v", err)
+		return nil, err
+	}
+	return credentialTypes, nil
+}
+
+func (s *credentialService) UpdateCredentialType(id uint, credentialType CredentialType) (*CredentialType, error) {
+	var existing CredentialType
+	if err := s.db.First(&existing, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCredentialTypeNotFound
+		}
+		log.Printf("Error fetching credential type: %v", err)
+		return nil, err
+	}
+
+	existing.Name = credentialType.Name
+	existing.Description = credentialType.Description
+	if err := s.db.Save(&existing).Error; err != nil {
+		log.Printf("Error updating credential type: %v", err)
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// DeleteCredentialType removes the type for good, so its unique name can be
+// used again
+func (s *credentialService) DeleteCredentialType(id uint) error {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return tx.Error
+	}
+
+	result := tx.Unscoped().Delete(&CredentialType{}, id)
+	if result.Error != nil {
+		tx.Rollback()
+		log.Printf("Error deleting credential type: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrCredentialTypeNotFound
+	}
+
+	// Requirements on a removed type can no longer be satisfied, so drop them too
+	if err := tx.Unscoped().Where("credential_type_id = ?", id).Delete(&CredentialRequirement{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error deleting credential requirements: %v", err)
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *credentialService) AddRequirement(credentialTypeID uint, designation string) (*CredentialRequirement, error) {
+	if err := s.db.First(&CredentialType{}, credentialTypeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCredentialTypeNotFound
+		}
+		log.Printf("Error fetching credential type: %v", err)
+		return nil, err
+	}
+
+	requirement := CredentialRequirement{
+		CredentialTypeID: credentialTypeID,
+		Designation:      designation,
+	}
+	if err := s.db.Where(requirement).FirstOrCreate(&requirement).Error; err != nil {
+		log.Printf("Error adding credential requirement: %v", err)
+		return nil, err
+	}
+	return &requirement, nil
+}
+
+func (s *credentialService) RemoveRequirement(credentialTypeID uint, designation string) error {
+	// Hard delete, as the unique index would refuse adding the requirement again
+	err := s.db.Unscoped().Where("credential_type_id = ? AND designation = ?", credentialTypeID, designation).
+		Delete(&CredentialRequirement{}).Error
+	if err != nil {
+		log.Printf("Error removing credential requirement: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *credentialService) CreateCredential(employeeID uint, credential Credential) (*Credential, error) {
+	if err := s.db.First(&employee.Employee{}, employeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+
+	if err := s.db.First(&CredentialType{}, credential.CredentialTypeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCredentialTypeNotFound
+		}
+		log.Printf("Error fetching credential type: %v", err)
+		return nil, err
+	}
+
+	credential.EmployeeID = employeeID
+	credential.Status = statusOn(credential, time.Now().UTC(), 0)
+	if err := s.db.Create(&credential).Error; err != nil {
+		log.Printf("Error creating credential: %v", err)
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (s *credentialService) GetEmployeeCredentials(employeeID uint) ([]Credential, error) {
+	var credentials []Credential
+	if err := s.db.Preload("CredentialType").Where("employee_id = ?", employeeID).Find(&credentials).Error; err != nil {
+		log.Printf("Error fetching credentials: %v", err)
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func (s *credentialService) UpdateCredential(id uint, credential Credential) (*Credential, error) {
+	var existing Credential
+	if err := s.db.First(&existing, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCredentialNotFound
+		}
+		log.Printf("Error fetching credential: %v", err)
+		return nil, err
+	}
+
+	// A renewal moves the expiry date, so alerts start over for the new date
+	if credential.ExpiryDate.Valid != existing.ExpiryDate.Valid || !credential.ExpiryDate.Time.Equal(existing.ExpiryDate.Time) {
+		existing.AlertedWindow = 0
+	}
+
+	existing.IssuingBody = credential.IssuingBody
+	existing.Number = credential.Number
+	existing.IssueDate = credential.IssueDate
+	existing.ExpiryDate = credential.ExpiryDate
+	existing.DocumentURL = credential.DocumentURL
+	existing.Status = statusOn(existing, time.Now().UTC(), 0)
+
+	if err := s.db.Save(&existing).Error; err != nil {
+		log.Printf("Error updating credential: %v", err)
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (s *credentialService) DeleteCredential(id uint) error {
+	result := s.db.Delete(&Credential{}, id)
+	if result.Error != nil {
+		log.Printf("Error deleting credential: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// GetExpiringCredentials returns credentials that have expired or will expire within the given duration
+func (s *credentialService) GetExpiringCredentials(within time.Duration) ([]Credential, error) {
+	var credentials []Credential
+	cutoff := time.Now().UTC().Add(within)
+
+	err := s.db.Preload("CredentialType").
+		Where("expiry_date IS NOT NULL AND expiry_date <= ?", cutoff).
+		Order("expiry_date").
+		Find(&credentials).Error
+	if err != nil {
+		log.Printf("Error fetching expiring credentials: %v", err)
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// FlagExpiringCredentials updates credential statuses and notifies holders once
+// per alert window. Windows are days before expiry, e.g. 60, 30, 7. It returns
+// the number of alerts sent.
+func (s *credentialService) FlagExpiringCredentials(now time.Time, windows []int) (int, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	maxWindow := 0
+	for _, window := range windows {
+		if window > maxWindow {
+			maxWindow = window
+		}
+	}
+
+	var credentials []Credential
+	err := s.db.Preload("CredentialType").
+		Where("expiry_date IS NOT NULL AND expiry_date <= ? AND status <> ?", today.AddDate(0, 0, maxWindow), StatusExpired).
+		Find(&credentials).Error
+	if err != nil {
+		log.Printf("Error fetching credentials for expiry check: %v", err)
+		return 0, err
+	}
+
+	alerts := 0
+	for _, credential := range credentials {
+		daysLeft := int(credential.ExpiryDate.Time.Sub(today).Hours() / 24)
+		window := alertWindow(daysLeft, windows)
+		status := statusOn(credential, today, maxWindow)
+
+		notify := status == StatusExpired ||
+			(window > 0 && (credential.AlertedWindow == 0 || window < credential.AlertedWindow))
+		if notify {
+			subject := fmt.Sprintf("%s expires on %s", credential.CredentialType.Name, credential.ExpiryDate.Time.Format("2006-01-02"))
+			if status == StatusExpired {
+				subject = fmt.Sprintf("%s expired on %s", credential.CredentialType.Name, credential.ExpiryDate.Time.Format("2006-01-02"))
+			}
+			body := fmt.Sprintf("Credential %s issued by %s needs to be renewed.", credential.Number, credential.IssuingBody)
+			if err := s.notifications.Notify(credential.EmployeeID, "credential_expiry", subject, body); err != nil {
+				return alerts, err
+			}
+			alerts++
+			if window > 0 {
+				credential.AlertedWindow = window
+			}
+		}
+
+		credential.Status = status
+		if err := s.db.Model(&credential).Updates(map[string]interface{}{
+			"status":         credential.Status,
+			"alerted_window": credential.AlertedWindow,
+		}).Error; err != nil {
+			log.Printf("Error flagging credential %d: %v", credential.ID, err)
+			return alerts, err
+		}
+	}
+
+	return alerts, nil
+}
+
+// CheckAssignment implements employee.AssignmentGuard. It rejects assignments
+// for employees missing a credential their designation requires, or holding one
+// that expires before the assignment ends.
+func (s *credentialService) CheckAssignment(db *gorm.DB, emp employee.Employee, assignment employee.EmployeeShift) error {
+	var requirements []CredentialRequirement
+	if err := db.Where("LOWER(designation) = ?", strings.ToLower(emp.Designation)).Find(&requirements).Error; err != nil {
+		log.Printf("Error fetching credential requirements: %v", err)
+		return err
+	}
+	if len(requirements) == 0 {
+		return nil
+	}
+
+	var credentials []Credential
+	if err := db.Where("employee_id = ?", emp.ID).Find(&credentials).Error; err != nil {
+		log.Printf("Error fetching employee credentials: %v", err)
+		return err
+	}
+
+	for _, requirement := range requirements {
+		satisfied := false
+		for _, credential := range credentials {
+			if credential.CredentialTypeID == requirement.CredentialTypeID && credential.ValidBetween(assignment.StartDate, assignment.EndDate) {
+				satisfied = true
+				break
+			}
+		}
+		if !satisfied {
+			var credentialType CredentialType
+			if err := db.First(&credentialType, requirement.CredentialTypeID).Error; err != nil {
+				log.Printf("Error fetching credential type: %v", err)
+				return err
+			}
+			return fmt.Errorf("%w: %s requires a %s credential valid from %s through %s",
+				employee.ErrAssignmentRejected, emp.Designation, credentialType.Name,
+				assignment.StartDate.Format("2006-01-02"), assignment.EndDate.Format("2006-01-02"))
+		}
+	}
+
+	return nil
+}
+
+// statusOn classifies a credential as of the given day
+func statusOn(credential Credential, today time.Time, expiringWithinDays int) string {
+	if !credential.ExpiryDate.Valid {
+		return StatusValid
+	}
+	if credential.ExpiryDate.Time.Before(today) {
+		return StatusExpired
+	}
+	if !credential.ExpiryDate.Time.After(today.AddDate(0, 0, expiringWithinDays)) {
+		return StatusExpiring
+	}
+	return StatusValid
+}
+
+// alertWindow returns the smallest configured window containing daysLeft, or 0 if none does
+func alertWindow(daysLeft int, windows []int) int {
+	window := 0
+	for _, w := range windows {
+		if daysLeft <= w && (window == 0 || w < window) {
+			window = w
+		}
+	}
+	return window
+}
@@ -0,0 +1,96 @@
+package credential
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/shared/testdb"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// day returns a date in March 2024
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCheckAssignment(t *testing.T) {
+	nurse := employee.Employee{Designation: "Nurse"}
+	nurse.ID = 5
+	assignment := employee.EmployeeShift{EmployeeID: 5, StartDate: day(4), EndDate: day(10)}
+
+	tests := []struct {
+		name        string
+		requirement bool
+		credentials [][]interface{} // credential_type_id, issue_date, expiry_date
+		wantErr     string
+	}{
+		{name: "designation without requirements"},
+		{
+			name:        "valid for the whole assignment",
+			requirement: true,
+			credentials: [][]interface{}{{1, day(1), day(10)}},
+		},
+		{
+			name:        "without an expiry date",
+			requirement: true,
+			credentials: [][]interface{}{{1, day(4), nil}},
+		},
+		{
+			name:        "without an issue date",
+			requirement: true,
+			credentials: [][]interface{}{{1, nil, day(31)}},
+		},
+		{
+			name:        "missing",
+			requirement: true,
+			wantErr:     "Nurse requires a BLS credential valid from 2024-03-04 through 2024-03-10",
+		},
+		{
+			name:        "of another type",
+			requirement: true,
+			credentials: [][]interface{}{{2, day(1), day(31)}},
+			wantErr:     "requires a BLS credential",
+		},
+		{
+			name:        "expires during the assignment",
+			requirement: true,
+			credentials: [][]interface{}{{1, day(1), day(9)}},
+			wantErr:     "requires a BLS credential",
+		},
+		{
+			name:        "issued during the assignment",
+			requirement: true,
+			credentials: [][]interface{}{{1, day(5), day(31)}},
+			wantErr:     "requires a BLS credential",
+		},
+		{
+			name:        "one of several covers the assignment",
+			requirement: true,
+			credentials: [][]interface{}{{1, day(5), day(31)}, {1, day(1), day(20)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			if tt.requirement {
+				fake.Returns(`FROM "credential_requirements"`, []string{"id", "credential_type_id", "designation"}, []interface{}{1, 1, "Nurse"})
+			}
+			fake.Returns(`FROM "credentials"`, []string{"credential_type_id", "issue_date", "expiry_date"}, tt.credentials...)
+			fake.Returns(`FROM "credential_types"`, []string{"id", "name"}, []interface{}{1, "BLS"})
+			service := NewCredentialService(db, nil)
+
+			err := service.CheckAssignment(db, nurse, assignment)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckAssignment: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, employee.ErrAssignmentRejected) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CheckAssignment error %v, want a rejection containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
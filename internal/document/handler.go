@@ -0,0 +1,317 @@
+// internal/document/handler.go
+package document
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type DocumentHandler struct {
+	service DocumentService
+	maxSize int64
+}
+
+func NewDocumentHandler(service DocumentService, maxSize int64) *DocumentHandler {
+	return &DocumentHandler{service: service, maxSize: maxSize}
+}
+
+func (h *DocumentHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category DocumentCategory
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreateCategory(category)
+	if err != nil {
+		log.Printf("Error creating document category: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to create document category", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *DocumentHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.service.GetCategories()
+	if err != nil {
+		log.Printf("Error fetching document categories: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve document categories", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, categories, nil, nil)
+}
+
+func (h *DocumentHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid category ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid category ID", nil)
+		return
+	}
+
+	var category DocumentCategory
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateCategory(uint(id), category)
+	if err != nil {
+		if err == ErrCategoryNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Document category not found", nil)
+		} else {
+			log.Printf("Error updating document category: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to update document category", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
+
+func (h *DocumentHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid category ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid category ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteCategory(uint(id)); err != nil {
+		if err == ErrCategoryNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Document category not found", nil)
+		} else {
+			log.Printf("Error deleting document category: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to delete document category", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Document category deleted successfully",
+	})
+}
+
+func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := h.authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	// Leave headroom for the other multipart fields
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.SendJSONResponse(w, http.StatusRequestEntityTooLarge, nil, ErrFileTooLarge.Error(), nil)
+			return
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid multipart form", nil)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Missing file", nil)
+		return
+	}
+	defer file.Close()
+
+	categoryID, err := strconv.ParseUint(r.FormValue("category_id"), 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid category ID", nil)
+		return
+	}
+
+	upload := Upload{
+		CategoryID: uint(categoryID),
+		FileName:   header.Filename,
+		Size:       header.Size,
+		Content:    file,
+		UploadedBy: user.ID,
+	}
+	if expiry := r.FormValue("expiry_date"); expiry != "" {
+		date, err := time.Parse("2006-01-02", expiry)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid expiry date, expected YYYY-MM-DD", nil)
+			return
+		}
+		upload.ExpiryDate.Time = date
+		upload.ExpiryDate.Valid = true
+	}
+
+	document, err := h.service.UploadDocument(r.Context(), employeeID, upload)
+	if err != nil {
+		switch {
+		case errors.Is(err, employee.ErrEmployeeNotFound):
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Employee not found", nil)
+		case errors.Is(err, ErrCategoryNotFound), errors.Is(err, ErrExpiryRequired):
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+		case errors.Is(err, ErrFileTooLarge):
+			utils.SendJSONResponse(w, http.StatusRequestEntityTooLarge, nil, err.Error(), nil)
+		case errors.Is(err, ErrUnsupportedContentType):
+			utils.SendJSONResponse(w, http.StatusUnsupportedMediaType, nil, err.Error(), nil)
+		default:
+			log.Printf("Error uploading document: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to upload document", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, document, nil, nil)
+}
+
+func (h *DocumentHandler) GetDocuments(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := h.authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	documents, err := h.service.GetDocuments(employeeID)
+	if err != nil {
+		log.Printf("Error fetching documents: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve documents", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, documents, nil, nil)
+}
+
+func (h *DocumentHandler) DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := h.authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	documentID, err := strconv.ParseUint(mux.Vars(r)["documentID"], 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid document ID", nil)
+		return
+	}
+
+	document, err := h.service.GetDocument(employeeID, uint(documentID))
+	if err != nil {
+		if err == ErrDocumentNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Document not found", nil)
+		} else {
+			log.Printf("Error fetching document: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve document", nil)
+		}
+		return
+	}
+
+	body, err := h.service.OpenDocument(r.Context(), document)
+	if err != nil {
+		if err == ErrDocumentNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Document not found", nil)
+		} else {
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve document", nil)
+		}
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", document.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(document.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", document.FileName))
+	w.Header().Set("X-Checksum-Sha256", document.Checksum)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Error streaming document %d: %v", document.ID, err)
+	}
+}
+
+func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+	if user == nil || !user.IsPrivileged() {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to delete documents", nil)
+		return
+	}
+
+	vars := mux.Vars(r)
+	employeeID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+	documentID, err := strconv.ParseUint(vars["documentID"], 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid document ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteDocument(r.Context(), uint(employeeID), uint(documentID)); err != nil {
+		if err == ErrDocumentNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Document not found", nil)
+		} else {
+			log.Printf("Error deleting document: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to delete document", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Document deleted successfully",
+	})
+}
+
+func (h *DocumentHandler) GetExpiringDocuments(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed < 0 {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid days parameter", nil)
+			return
+		}
+		days = parsed
+	}
+
+	documents, err := h.service.GetExpiringDocuments(time.Duration(days) * 24 * time.Hour)
+	if err != nil {
+		log.Printf("Error fetching expiring documents: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve expiring documents", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, documents, nil, map[string]interface{}{
+		"days": days,
+	})
+}
+
+// authorizeEmployee parses the employee ID from the path and checks the
+// authenticated user may access that employee's documents
+func (h *DocumentHandler) authorizeEmployee(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	employeeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return 0, false
+	}
+
+	user, _ := iam.UserFromContext(r.Context())
+	if !iam.CanAccessEmployee(user, uint(employeeID)) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's documents", nil)
+		return 0, false
+	}
+
+	return uint(employeeID), true
+}
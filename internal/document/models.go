@@ -0,0 +1,29 @@
+package document
+
+import (
+	"clinicplus/internal/shared/utils"
+
+	"github.com/jinzhu/gorm"
+)
+
+type DocumentCategory struct {
+	gorm.Model
+	Name           string `json:"name" gorm:"unique;not null"` // e.g., Contract, ID, Certificate
+	Description    string `json:"description"`
+	RequiresExpiry bool   `json:"requires_expiry"` // Uploads in this category must carry an expiry date
+}
+
+type Document struct {
+	gorm.Model
+	EmployeeID  uint           `gorm:"not null;index" json:"employee_id"`
+	CategoryID  uint           `gorm:"not null" json:"category_id"`
+	FileName    string         `gorm:"not null" json:"file_name"`
+	ContentType string         `gorm:"not null" json:"content_type"`
+	Size        int64          `gorm:"not null" json:"size"`
+	Checksum    string         `gorm:"not null" json:"checksum"` // Hex-encoded SHA-256 of the content
+	StorageKey  string         `gorm:"not null;unique" json:"-"`
+	ExpiryDate  utils.NullTime `gorm:"type:date" json:"expiry_date"`
+	UploadedBy  uint           `json:"uploaded_by"` // iam.User ID
+
+	Category DocumentCategory `gorm:"foreignkey:CategoryID" json:"category"`
+}
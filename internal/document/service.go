@@ -0,0 +1,283 @@
+// internal/document/service.go
+package document
+
+import (
+	"bufio"
+	"clinicplus/internal/employee"
+	"clinicplus/internal/shared/utils"
+	"clinicplus/pkg/storage"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrDocumentNotFound       = errors.New("document not found")
+	ErrCategoryNotFound       = errors.New("document category not found")
+	ErrFileTooLarge           = errors.New("file exceeds the maximum upload size")
+	ErrUnsupportedContentType = errors.New("unsupported file type")
+	ErrExpiryRequired         = errors.New("documents in this category require an expiry date")
+)
+
+// allowedContentTypes lists the sniffed content types accepted for upload
+var allowedContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+// Upload describes a file being attached to an employee
+type Upload struct {
+	CategoryID uint
+	FileName   string
+	Size       int64
+	Content    io.Reader
+	ExpiryDate utils.NullTime
+	UploadedBy uint
+}
+
+type DocumentService interface {
+	CreateCategory(category DocumentCategory) (*DocumentCategory, error)
+	GetCategories() ([]DocumentCategory, error)
+	UpdateCategory(id uint, category DocumentCategory) (*DocumentCategory, error)
+	DeleteCategory(id uint) error
+
+	UploadDocument(ctx context.Context, employeeID uint, upload Upload) (*Document, error)
+	GetDocuments(employeeID uint) ([]Document, error)
+	GetDocument(employeeID uint, id uint) (*Document, error)
+	OpenDocument(ctx context.Context, document *Document) (io.ReadCloser, error)
+	DeleteDocument(ctx context.Context, employeeID uint, id uint) error
+	GetExpiringDocuments(within time.Duration) ([]Document, error)
+}
+
+type documentService struct {
+	db      *gorm.DB
+	store   storage.Store
+	maxSize int64
+}
+
+func NewDocumentService(db *gorm.DB, store storage.Store, maxSize int64) DocumentService {
+	return &documentService{db: db, store: store, maxSize: maxSize}
+}
+
+func (s *documentService) CreateCategory(category DocumentCategory) (*DocumentCategory, error) {
+	if err := s.db.Create(&category).Error; err != nil {
+		log.Printf("Error creating document category: %v", err)
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (s *documentService) GetCategories() ([]DocumentCategory, error) {
+	var categories []DocumentCategory
+	if err := s.db.Find(&categories).Error; err != nil {
+		log.Printf("Error fetching document categories: %v", err)
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (s *documentService) UpdateCategory(id uint, category DocumentCategory) (*DocumentCategory, error) {
+	var existing DocumentCategory
+	if err := s.db.First(&existing, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCategoryNotFound
+		}
+		log.Printf("Error fetching document category: %v", err)
+		return nil, err
+	}
+
+	existing.Name = category.Name
+	existing.Description = category.Description
+	existing.RequiresExpiry = category.RequiresExpiry
+	if err := s.db.Save(&existing).Error; err != nil {
+		log.Printf("Error updating document category: %v", err)
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (s *documentService) DeleteCategory(id uint) error {
+	result := s.db.Delete(&DocumentCategory{}, id)
+	if result.Error != nil {
+		log.Printf("Error deleting document category: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// UploadDocument validates and stores a file, recording its SHA-256 checksum
+func (s *documentService) UploadDocument(ctx context.Context, employeeID uint, upload Upload) (*Document, error) {
+	if err := s.db.First(&employee.Employee{}, employeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+
+	var category DocumentCategory
+	if err := s.db.First(&category, upload.CategoryID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCategoryNotFound
+		}
+		log.Printf("Error fetching document category: %v", err)
+		return nil, err
+	}
+
+	if category.RequiresExpiry && !upload.ExpiryDate.Valid {
+		return nil, ErrExpiryRequired
+	}
+	if upload.Size <= 0 || upload.Size > s.maxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	// Trust the content, not the client-supplied header
+	content := bufio.NewReaderSize(upload.Content, 512)
+	head, err := content.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	contentType := http.DetectContentType(head)
+	if !allowedContentTypes[contentType] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+
+	key, err := newStorageKey(employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(io.LimitReader(content, s.maxSize+1), hash)}
+	if err := s.store.Put(ctx, key, counter, upload.Size, contentType); err != nil {
+		log.Printf("Error storing document: %v", err)
+		return nil, err
+	}
+	if counter.n != upload.Size {
+		s.store.Delete(ctx, key)
+		return nil, ErrFileTooLarge
+	}
+
+	document := Document{
+		EmployeeID:  employeeID,
+		CategoryID:  category.ID,
+		FileName:    filepath.Base(upload.FileName),
+		ContentType: contentType,
+		Size:        counter.n,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+		ExpiryDate:  upload.ExpiryDate,
+		UploadedBy:  upload.UploadedBy,
+	}
+
+	if err := s.db.Create(&document).Error; err != nil {
+		log.Printf("Error saving document: %v", err)
+		s.store.Delete(ctx, key)
+		return nil, err
+	}
+
+	document.Category = category
+	return &document, nil
+}
+
+func (s *documentService) GetDocuments(employeeID uint) ([]Document, error) {
+	var documents []Document
+	if err := s.db.Preload("Category").Where("employee_id = ?", employeeID).Order("created_at DESC").Find(&documents).Error; err != nil {
+		log.Printf("Error fetching documents: %v", err)
+		return nil, err
+	}
+	return documents, nil
+}
+
+func (s *documentService) GetDocument(employeeID uint, id uint) (*Document, error) {
+	var document Document
+	if err := s.db.Preload("Category").Where("employee_id = ?", employeeID).First(&document, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrDocumentNotFound
+		}
+		log.Printf("Error fetching document: %v", err)
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (s *documentService) OpenDocument(ctx context.Context, document *Document) (io.ReadCloser, error) {
+	body, err := s.store.Get(ctx, document.StorageKey)
+	if err != nil {
+		if err == storage.ErrObjectNotFound {
+			log.Printf("Document %d is missing from storage", document.ID)
+			return nil, ErrDocumentNotFound
+		}
+		log.Printf("Error reading document: %v", err)
+		return nil, err
+	}
+	return body, nil
+}
+
+func (s *documentService) DeleteDocument(ctx context.Context, employeeID uint, id uint) error {
+	document, err := s.GetDocument(employeeID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Delete(document).Error; err != nil {
+		log.Printf("Error deleting document: %v", err)
+		return err
+	}
+
+	if err := s.store.Delete(ctx, document.StorageKey); err != nil {
+		log.Printf("Error removing document %d from storage: %v", document.ID, err)
+	}
+	return nil
+}
+
+// GetExpiringDocuments returns documents that have expired or will expire within the given duration
+func (s *documentService) GetExpiringDocuments(within time.Duration) ([]Document, error) {
+	var documents []Document
+	cutoff := time.Now().UTC().Add(within)
+
+	err := s.db.Preload("Category").
+		Where("expiry_date IS NOT NULL AND expiry_date <= ?", cutoff).
+		Order("expiry_date").
+		Find(&documents).Error
+	if err != nil {
+		log.Printf("Error fetching expiring documents: %v", err)
+		return nil, err
+	}
+	return documents, nil
+}
+
+// newStorageKey returns a random, unguessable key for an employee's document
+func newStorageKey(employeeID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("employees/%d/documents/%s", employeeID, hex.EncodeToString(buf)), nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -2,11 +2,14 @@
 package employee
 
 import (
+	"clinicplus/internal/iam"
 	"clinicplus/internal/shared/utils"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -36,8 +39,18 @@ func (h *EmployeeHandler) GetEmployees(w http.ResponseWriter, r *http.Request) {
 		limit, _ = strconv.Atoi(limitStr)
 	}
 
+	// Filter by employment status: a comma-separated list, or "all"
+	var statuses []string
+	switch statusStr := r.URL.Query().Get("status"); statusStr {
+	case "":
+	case "all":
+		statuses = []string{EmploymentStatusActive, EmploymentStatusProbation, EmploymentStatusSuspended, EmploymentStatusOnLeave, EmploymentStatusTerminated}
+	default:
+		statuses = strings.Split(statusStr, ",")
+	}
+
 	// Fetch employees from the service
-	employees, total, err := h.service.GetEmployees(page, limit)
+	employees, total, err := h.service.GetEmployees(page, limit, statuses)
 	if err != nil {
 		log.Printf("Error fetching employees: %v", err)
 		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve employees", nil)
@@ -94,6 +107,10 @@ func (h *EmployeeHandler) CreateEmployee(w http.ResponseWriter, r *http.Request)
 
 	employee, err := h.service.CreateEmployee(createRequest.Employee)
 	if err != nil {
+		if errors.Is(err, ErrInvalidEmploymentStatus) {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "New employees must be active or on probation", nil)
+			return
+		}
 		log.Printf("Error creating employee: %v", err)
 		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to create employee", nil)
 		return
@@ -152,17 +169,12 @@ func (h *EmployeeHandler) DeleteEmployee(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := h.service.DeleteEmployee(id); err != nil {
-		if err.Error() == "employee not found" {
-			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Employee not found", nil)
-		} else {
-			log.Printf("Error deleting employee: %v", err)
-			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to delete employee", nil)
-		}
+		sendEmploymentError(w, err, "Failed to delete employee")
 		return
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
-		"message": "Employee deleted successfully",
+		"message": "Employee terminated successfully",
 	})
 }
 
@@ -221,8 +233,43 @@ func (h *EmployeeHandler) ClockInEmployee(w http.ResponseWriter, r *http.Request
 
 	attendance, err := h.service.ClockIn(uint(employeeID), request.ShiftID)
 	if err != nil {
-		log.Printf("Error clocking in employee: %v", err)
-		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to clock in", nil)
+		sendAttendanceError(w, err, "Failed to clock in")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, attendance, nil, nil)
+}
+
+// OverrideClockIn clocks an employee in despite the clock-in rules. It is for
+// managers only and records their reason on the attendance record.
+func (h *EmployeeHandler) OverrideClockIn(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	employeeID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	var request struct {
+		ShiftID        uint   `json:"shift_id"`
+		OverrideReason string `json:"override_reason"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+	if strings.TrimSpace(request.OverrideReason) == "" {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "An override reason is required", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	attendance, err := h.service.OverrideClockIn(uint(employeeID), request.ShiftID, strings.TrimSpace(request.OverrideReason), user.ID)
+	if err != nil {
+		sendAttendanceError(w, err, "Failed to clock in")
 		return
 	}
 
@@ -250,8 +297,57 @@ func (h *EmployeeHandler) ClockOutEmployee(w http.ResponseWriter, r *http.Reques
 
 	attendance, err := h.service.ClockOut(uint(employeeID), request.ShiftID)
 	if err != nil {
-		log.Printf("Error clocking out employee: %v", err)
-		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to clock out", nil)
+		sendAttendanceError(w, err, "Failed to clock out")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, attendance, nil, nil)
+}
+
+func (h *EmployeeHandler) StartBreak(w http.ResponseWriter, r *http.Request) {
+	h.changeBreak(w, r, true)
+}
+
+func (h *EmployeeHandler) EndBreak(w http.ResponseWriter, r *http.Request) {
+	h.changeBreak(w, r, false)
+}
+
+// changeBreak starts or ends a break; shift_id may be left out to use the
+// shift the employee is clocked in to, and paid only applies to starting one
+func (h *EmployeeHandler) changeBreak(w http.ResponseWriter, r *http.Request, start bool) {
+	vars := mux.Vars(r)
+	employeeID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+	if !iam.CanAccessEmployee(user, uint(employeeID)) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to record breaks for this employee", nil)
+		return
+	}
+
+	var request struct {
+		ShiftID uint `json:"shift_id"`
+		Paid    bool `json:"paid"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			log.Printf("Error decoding request body: %v", err)
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+			return
+		}
+	}
+
+	var attendance *Attendance
+	if start {
+		attendance, err = h.service.StartBreak(uint(employeeID), request.ShiftID, request.Paid)
+	} else {
+		attendance, err = h.service.EndBreak(uint(employeeID), request.ShiftID)
+	}
+	if err != nil {
+		sendAttendanceError(w, err, "Failed to record break")
 		return
 	}
 
@@ -268,8 +364,7 @@ func (h *EmployeeHandler) CreateShift(w http.ResponseWriter, r *http.Request) {
 
 	createdShift, err := h.service.CreateShift(shift)
 	if err != nil {
-		log.Printf("Error creating shift: %v", err)
-		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to create shift", nil)
+		sendShiftError(w, err, "Failed to create shift")
 		return
 	}
 
@@ -326,8 +421,7 @@ func (h *EmployeeHandler) UpdateShift(w http.ResponseWriter, r *http.Request) {
 
 	updatedShift, err := h.service.UpdateShift(uint(id), shift)
 	if err != nil {
-		log.Printf("Error updating shift: %v", err)
-		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to update shift", nil)
+		sendShiftError(w, err, "Failed to update shift")
 		return
 	}
 
@@ -354,6 +448,16 @@ func (h *EmployeeHandler) DeleteShift(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *EmployeeHandler) AssignShift(w http.ResponseWriter, r *http.Request) {
+	h.assignShift(w, r, false)
+}
+
+// OverrideAssignShift assigns a shift despite blocking working time rules. It
+// is for managers only and records their reason on the assignment.
+func (h *EmployeeHandler) OverrideAssignShift(w http.ResponseWriter, r *http.Request) {
+	h.assignShift(w, r, true)
+}
+
+func (h *EmployeeHandler) assignShift(w http.ResponseWriter, r *http.Request, override bool) {
 	vars := mux.Vars(r)
 	employeeID, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
@@ -363,9 +467,10 @@ func (h *EmployeeHandler) AssignShift(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var request struct {
-		ShiftID   uint      `json:"shift_id"`
-		StartDate time.Time `json:"start_date"`
-		EndDate   time.Time `json:"end_date"`
+		ShiftID        uint      `json:"shift_id"`
+		StartDate      time.Time `json:"start_date"`
+		EndDate        time.Time `json:"end_date"`
+		OverrideReason string    `json:"override_reason"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -374,12 +479,288 @@ func (h *EmployeeHandler) AssignShift(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	assignedShift, err := h.service.AssignShift(uint(employeeID), request.ShiftID, request.StartDate, request.EndDate)
+	var assignedShift *EmployeeShift
+	if override {
+		user, _ := iam.UserFromContext(r.Context())
+		if strings.TrimSpace(request.OverrideReason) == "" {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "An override reason is required", nil)
+			return
+		}
+		assignedShift, err = h.service.CreateAssignment(EmployeeShift{
+			EmployeeID:     uint(employeeID),
+			ShiftID:        request.ShiftID,
+			StartDate:      request.StartDate,
+			EndDate:        request.EndDate,
+			Source:         AssignmentSourceManual,
+			OverrideReason: strings.TrimSpace(request.OverrideReason),
+			OverriddenBy:   &user.ID,
+		})
+	} else {
+		assignedShift, err = h.service.AssignShift(uint(employeeID), request.ShiftID, request.StartDate, request.EndDate)
+	}
 	if err != nil {
-		log.Printf("Error assigning shift: %v", err)
-		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to assign shift", nil)
+		switch {
+		case errors.Is(err, ErrEmployeeNotFound):
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Employee not found", nil)
+		case errors.Is(err, ErrShiftNotFound):
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Shift not found", nil)
+		case errors.Is(err, ErrAssignmentRejected):
+			utils.SendJSONResponse(w, http.StatusUnprocessableEntity, nil, err.Error(), nil)
+		default:
+			log.Printf("Error assigning shift: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to assign shift", nil)
+		}
 		return
 	}
 
 	utils.SendJSONResponse(w, http.StatusCreated, assignedShift, nil, nil)
 }
+
+func (h *EmployeeHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	var location Location
+	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	createdLocation, err := h.service.CreateLocation(location)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTimezone) {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+		} else {
+			log.Printf("Error creating location: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to create location", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, createdLocation, nil, nil)
+}
+
+func (h *EmployeeHandler) GetLocations(w http.ResponseWriter, r *http.Request) {
+	locations, err := h.service.GetLocations()
+	if err != nil {
+		log.Printf("Error fetching locations: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve locations", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, locations, nil, nil)
+}
+
+func (h *EmployeeHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Printf("Invalid location ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid location ID", nil)
+		return
+	}
+
+	var location Location
+	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updatedLocation, err := h.service.UpdateLocation(uint(id), location)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrLocationNotFound):
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Location not found", nil)
+		case errors.Is(err, ErrInvalidTimezone):
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+		default:
+			log.Printf("Error updating location: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to update location", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updatedLocation, nil, nil)
+}
+
+func (h *EmployeeHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Printf("Invalid location ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid location ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteLocation(uint(id)); err != nil {
+		if errors.Is(err, ErrLocationNotFound) {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Location not found", nil)
+		} else {
+			log.Printf("Error deleting location: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to delete location", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Location deleted successfully",
+	})
+}
+
+func (h *EmployeeHandler) ChangeEmploymentStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	var request struct {
+		Status        string    `json:"status"`
+		EffectiveDate time.Time `json:"effective_date"`
+		Reason        string    `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	// Terminating is for admins only, as on the terminate route
+	if user, _ := iam.UserFromContext(r.Context()); request.Status == EmploymentStatusTerminated && (user == nil || user.Role != iam.RoleAdmin) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Only admins can terminate employees", nil)
+		return
+	}
+
+	employee, err := h.service.ChangeEmploymentStatus(id, request.Status, request.EffectiveDate, request.Reason)
+	if err != nil {
+		sendEmploymentError(w, err, "Failed to change employment status")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, employee, nil, nil)
+}
+
+func (h *EmployeeHandler) TerminateEmployee(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	var request struct {
+		TerminationDate time.Time `json:"termination_date"`
+		Reason          string    `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	employee, err := h.service.TerminateEmployee(id, request.TerminationDate, request.Reason)
+	if err != nil {
+		sendEmploymentError(w, err, "Failed to terminate employee")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, employee, nil, map[string]interface{}{
+		"message": "Employee terminated successfully",
+	})
+}
+
+func (h *EmployeeHandler) RehireEmployee(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	var request struct {
+		HireDate time.Time `json:"hire_date"`
+		Status   string    `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	employee, err := h.service.RehireEmployee(id, request.HireDate, request.Status)
+	if err != nil {
+		sendEmploymentError(w, err, "Failed to rehire employee")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, employee, nil, map[string]interface{}{
+		"message": "Employee rehired successfully",
+	})
+}
+
+func (h *EmployeeHandler) GetEmploymentHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	events, err := h.service.GetEmploymentHistory(id)
+	if err != nil {
+		sendEmploymentError(w, err, "Failed to retrieve employment history")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, events, nil, nil)
+}
+
+func sendEmploymentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrEmployeeNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, "Employee not found", nil)
+	case errors.Is(err, ErrInvalidEmploymentStatus):
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case errors.Is(err, ErrInvalidStatusTransition), errors.Is(err, ErrNotTerminated):
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
+
+func sendAttendanceError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrEmployeeNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, "Employee not found", nil)
+	case errors.Is(err, ErrShiftNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, "Shift not found", nil)
+	case errors.Is(err, ErrNotClockedIn):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case errors.Is(err, ErrAlreadyClockedIn), errors.Is(err, ErrBreakInProgress), errors.Is(err, ErrNoBreak),
+		errors.Is(err, ErrAttendanceLocked):
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	case errors.Is(err, ErrClockInRejected):
+		utils.SendJSONResponse(w, http.StatusUnprocessableEntity, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
+
+func sendShiftError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrShiftNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, "Shift not found", nil)
+	case errors.Is(err, ErrInvalidShiftTime), errors.Is(err, ErrInvalidDaysOfWeek), errors.Is(err, ErrInvalidBreak),
+		errors.Is(err, ErrInvalidTimezone), errors.Is(err, ErrLocationNotFound):
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
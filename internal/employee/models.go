@@ -2,16 +2,66 @@ package employee
 
 import (
 	"clinicplus/internal/shared/utils"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
+// Assignment sources say how an EmployeeShift was created
+const (
+	AssignmentSourceManual    = "manual"
+	AssignmentSourceRotation  = "rotation"
+	AssignmentSourceScheduler = "scheduler"
+	AssignmentSourceSwap      = "swap"
+	AssignmentSourceOpenShift = "open_shift"
+)
+
+const (
+	AttendanceStatusPresent         = "Present"
+	AttendanceStatusOnLeave         = "On Leave"
+	AttendanceStatusAbsent          = "Absent"            // Assigned but never clocked in
+	AttendanceStatusMissingClockOut = "Missing clock-out" // Still open, or closed at the shift end, after the shift ended
+)
+
+const (
+	PunctualityOnTime = "on_time"
+	PunctualityLate   = "late"
+)
+
+// AttendancePolicy bounds when employees may clock in for a shift and sets
+// the mandatory break rule
+type AttendancePolicy struct {
+	ClockInEarly       time.Duration // How long before the shift starts clocking in opens
+	ClockInGrace       time.Duration // How long after the start a clock-in still counts as on time
+	BreakRequiredAfter time.Duration // Worked time after which a break is mandatory; zero disables the rule
+	MinBreak           time.Duration // Shortest mandatory break
+	DeductMissedBreaks bool          // Deduct a mandatory break that was not taken from worked time
+}
+
+const (
+	EmploymentStatusActive     = "active"
+	EmploymentStatusProbation  = "on_probation"
+	EmploymentStatusSuspended  = "suspended"
+	EmploymentStatusOnLeave    = "on_leave"
+	EmploymentStatusTerminated = "terminated"
+)
+
+// employmentTransitions lists the statuses each status may move to through
+// ChangeEmploymentStatus. Leaving terminated is only possible by rehiring.
+var employmentTransitions = map[string][]string{
+	EmploymentStatusProbation: {EmploymentStatusActive, EmploymentStatusSuspended, EmploymentStatusOnLeave, EmploymentStatusTerminated},
+	EmploymentStatusActive:    {EmploymentStatusSuspended, EmploymentStatusOnLeave, EmploymentStatusTerminated},
+	EmploymentStatusSuspended: {EmploymentStatusActive, EmploymentStatusProbation, EmploymentStatusTerminated},
+	EmploymentStatusOnLeave:   {EmploymentStatusActive, EmploymentStatusProbation, EmploymentStatusTerminated},
+}
+
 type Employee struct {
 	gorm.Model
 	Name                     string          `json:"name"`
-	Designation              string          `json:"designation"` // e.g., Doctor, Nurse, Admin
-	Salary                   float64         `json:"salary"`
+	Designation              string          `json:"designation"`             // e.g., Doctor, Nurse, Admin
+	Department               string          `json:"department" gorm:"index"` // e.g., Emergency, Radiology
+	Salary                   float64         `json:"salary"`                  // Mirror of the current base pay; see internal/compensation
 	Email                    string          `json:"email" gorm:"unique"`
 	PhoneNumber              string          `json:"phone_number"`
 	HireDate                 time.Time       `json:"hire_date"` // Use time.Time for actual date handling
@@ -24,15 +74,157 @@ type Employee struct {
 	Children                 int             `json:"children"`
 	EmergencyContact         string          `json:"emergency_contact"`
 	EmergencyContactRelation string          `json:"emergency_contact_relation"`
+	ManagerID                *uint           `json:"manager_id"` // Reporting line; approves leave and other requests
+	LocationID               *uint           `json:"location_id"`
+	EmploymentStatus         string          `json:"employment_status" gorm:"not null;default:'active';index"`
+	TerminationDate          utils.NullTime  `json:"termination_date" gorm:"type:date"`
+	TerminationReason        string          `json:"termination_reason"`
 	Shifts                   []EmployeeShift `gorm:"foreignkey:EmployeeID"`
 }
 
+// CanWork reports whether the employee may be scheduled and clock in
+func (e Employee) CanWork() bool {
+	return e.EmploymentStatus == EmploymentStatusActive || e.EmploymentStatus == EmploymentStatusProbation
+}
+
+// EmploymentEvent records a change of employment status, so hires,
+// terminations and rehires stay on file after the employee record changes
+type EmploymentEvent struct {
+	gorm.Model
+	EmployeeID    uint      `gorm:"not null;index" json:"employee_id"`
+	FromStatus    string    `json:"from_status"` // Empty for the initial hire
+	ToStatus      string    `gorm:"not null" json:"to_status"`
+	EffectiveDate time.Time `gorm:"type:date;not null" json:"effective_date"`
+	Reason        string    `json:"reason"`
+}
+
+// Location is a clinic site. Its country, state and timezone drive holiday
+// calendars and local dates for the employees working there.
+type Location struct {
+	gorm.Model
+	Name     string `json:"name" gorm:"unique;not null"`
+	Address  string `json:"address"`
+	Country  string `json:"country"`
+	State    string `json:"state"`
+	Timezone string `json:"timezone" gorm:"not null;default:'UTC'"` // IANA name, e.g., Asia/Kolkata
+}
+
+// Shift is a template for a daily working window in local time. A shift whose
+// end is not after its start runs overnight and finishes the next day.
 type Shift struct {
 	gorm.Model
-	Name      string          `json:"name"`
-	StartTime time.Time       `json:"start_time"`
-	EndTime   time.Time       `json:"end_time"`
-	Employees []EmployeeShift `gorm:"foreignkey:ShiftID"` // Relationship with EmployeeShift
+	Name         string          `json:"name"`
+	StartTime    string          `json:"start_time" gorm:"type:varchar(5);not null"` // Local time of day, HH:MM
+	EndTime      string          `json:"end_time" gorm:"type:varchar(5);not null"`   // Local time of day, HH:MM
+	Timezone     string          `json:"timezone" gorm:"not null;default:'UTC'"`     // IANA name; defaults to the location's
+	DaysOfWeek   string          `json:"days_of_week" gorm:"not null;default:'mon,tue,wed,thu,fri,sat,sun'"`
+	BreakMinutes int             `json:"break_minutes" gorm:"not null;default:0"` // Unpaid break within the shift
+	LocationID   *uint           `json:"location_id"`
+	Employees    []EmployeeShift `gorm:"foreignkey:ShiftID"` // Relationship with EmployeeShift
+}
+
+// ShiftWindow is one occurrence of a shift in absolute time
+type ShiftWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Overnight reports whether the shift finishes on the day after it starts
+func (s Shift) Overnight() bool {
+	start, _ := parseClock(s.StartTime)
+	end, _ := parseClock(s.EndTime)
+	return end <= start
+}
+
+// Duration returns the scheduled length of the shift, breaks included
+func (s Shift) Duration() time.Duration {
+	start, _ := parseClock(s.StartTime)
+	end, _ := parseClock(s.EndTime)
+	if end <= start {
+		end += 24 * time.Hour
+	}
+	return end - start
+}
+
+// PaidDuration returns the scheduled length of the shift less its break
+func (s Shift) PaidDuration() time.Duration {
+	return s.Duration() - time.Duration(s.BreakMinutes)*time.Minute
+}
+
+// RunsOn reports whether the shift is scheduled to start on the weekday
+func (s Shift) RunsOn(weekday time.Weekday) bool {
+	for _, day := range strings.Split(s.DaysOfWeek, ",") {
+		if strings.TrimSpace(day) == weekdayNames[weekday] {
+			return true
+		}
+	}
+	return false
+}
+
+// Location returns the shift's timezone, falling back to UTC
+func (s Shift) Location() *time.Location {
+	if location, err := time.LoadLocation(s.Timezone); err == nil && s.Timezone != "" {
+		return location
+	}
+	return time.UTC
+}
+
+// OccurrenceOn returns the occurrence of the shift starting on the calendar
+// date of day, or false when the shift does not run on that weekday
+func (s Shift) OccurrenceOn(day time.Time) (ShiftWindow, bool) {
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	if !s.RunsOn(date.Weekday()) {
+		return ShiftWindow{}, false
+	}
+
+	start, _ := parseClock(s.StartTime)
+	end, _ := parseClock(s.EndTime)
+	endDay := date.Day()
+	if end <= start {
+		endDay++
+	}
+
+	location := s.Location()
+	return ShiftWindow{
+		Start: time.Date(date.Year(), date.Month(), date.Day(), int(start.Hours()), int(start.Minutes())%60, 0, 0, location),
+		End:   time.Date(date.Year(), date.Month(), endDay, int(end.Hours()), int(end.Minutes())%60, 0, 0, location),
+	}, true
+}
+
+// LocalDate returns the business date of t in the shift's timezone
+func (s Shift) LocalDate(t time.Time) time.Time {
+	local := t.In(s.Location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// OccurrenceAt returns the occurrence running at t, or starting within lead
+// after it, with the business date it starts on. An overnight occurrence
+// that began the day before is found too.
+func (s Shift) OccurrenceAt(t time.Time, lead time.Duration) (time.Time, ShiftWindow, bool) {
+	today := s.LocalDate(t)
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today, today.AddDate(0, 0, 1)} {
+		window, ok := s.OccurrenceOn(day)
+		if ok && !t.Before(window.Start.Add(-lead)) && t.Before(window.End) {
+			return day, window, true
+		}
+	}
+	return time.Time{}, ShiftWindow{}, false
+}
+
+// Overlaps reports whether the two windows share any time
+func (w ShiftWindow) Overlaps(other ShiftWindow) bool {
+	return w.Start.Before(other.End) && other.Start.Before(w.End)
+}
+
+// parseClock parses an HH:MM time of day into the offset from midnight
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
 }
 
 type Attendance struct {
@@ -42,10 +234,71 @@ type Attendance struct {
 	Date         time.Time      `gorm:"type:date;not null" json:"date"`
 	ClockInTime  time.Time      `json:"clock_in_time"`
 	ClockOutTime utils.NullTime `json:"clock_out_time"`
-	Status       string         `gorm:"not null" json:"status"` // e.g., Present/Absent
+	Status       string         `gorm:"not null" json:"status"` // e.g., Present/Absent/On Leave
+
+	Punctuality    string `json:"punctuality,omitempty"`     // on_time or late against the scheduled start
+	LateMinutes    int    `json:"late_minutes"`              // Minutes after the scheduled start the employee clocked in
+	OverrideReason string `json:"override_reason,omitempty"` // Why a manager clocked the employee in outside the rules
+	OverriddenBy   *uint  `json:"overridden_by,omitempty"`   // iam.User who overrode them
+
+	// Totals of the breaks, kept up to date as breaks end and on clock-out
+	UnpaidBreakMinutes   int    `json:"unpaid_break_minutes"`
+	DeductedBreakMinutes int    `json:"deducted_break_minutes"`    // Mandatory break not taken, deducted under BREAK_ENFORCEMENT=deduct
+	BreakViolation       string `json:"break_violation,omitempty"` // How the record breaks the mandatory break rule
+	NetWorkedMinutes     int    `json:"net_worked_minutes"`        // Clocked time less unpaid and deducted breaks
+
+	// Set by approved attendance corrections, which keep the clocked values
+	ManualEntry          bool           `json:"manual_entry"`            // Times were entered or changed by hand
+	CorrectionID         *uint          `json:"correction_id,omitempty"` // Latest correction applied
+	OriginalClockInTime  utils.NullTime `json:"original_clock_in_time"`  // Values before the first correction
+	OriginalClockOutTime utils.NullTime `json:"original_clock_out_time"`
+	OriginalStatus       string         `json:"original_status,omitempty"`
+
+	Locked      bool  `json:"locked"`                 // Signed off on a timesheet; no longer editable
+	TimesheetID *uint `json:"timesheet_id,omitempty"` // Timesheet that locked it
 
-	Employee Employee `gorm:"foreignkey:EmployeeID"` // Relationship with Employee
-	Shift    Shift    `gorm:"foreignkey:ShiftID"`    // Relationship with Shift
+	Employee Employee          `gorm:"foreignkey:EmployeeID"` // Relationship with Employee
+	Shift    Shift             `gorm:"foreignkey:ShiftID"`    // Relationship with Shift
+	Breaks   []AttendanceBreak `gorm:"foreignkey:AttendanceID" json:"breaks,omitempty"`
+}
+
+// AttendanceBreak is a break taken during a clocked-in shift. Unpaid breaks
+// are deducted from worked time.
+type AttendanceBreak struct {
+	gorm.Model
+	AttendanceID uint           `gorm:"not null;index" json:"attendance_id"`
+	Paid         bool           `json:"paid"`
+	StartTime    time.Time      `gorm:"not null" json:"start_time"`
+	EndTime      utils.NullTime `json:"end_time"`
+}
+
+// WorkedWindows returns the clocked time of a closed record less its unpaid
+// breaks, in order
+func (a Attendance) WorkedWindows() []ShiftWindow {
+	if !a.ClockOutTime.Valid || !a.ClockOutTime.Time.After(a.ClockInTime) {
+		return nil
+	}
+	windows := []ShiftWindow{{Start: a.ClockInTime, End: a.ClockOutTime.Time}}
+	for _, b := range a.Breaks {
+		if b.Paid || !b.EndTime.Valid {
+			continue
+		}
+		var rest []ShiftWindow
+		for _, window := range windows {
+			if !window.Overlaps(ShiftWindow{Start: b.StartTime, End: b.EndTime.Time}) {
+				rest = append(rest, window)
+				continue
+			}
+			if b.StartTime.After(window.Start) {
+				rest = append(rest, ShiftWindow{Start: window.Start, End: b.StartTime})
+			}
+			if b.EndTime.Time.Before(window.End) {
+				rest = append(rest, ShiftWindow{Start: b.EndTime.Time, End: window.End})
+			}
+		}
+		windows = rest
+	}
+	return windows
 }
 
 type EmployeeShift struct {
@@ -54,6 +307,12 @@ type EmployeeShift struct {
 	ShiftID    uint      `gorm:"not null;index:uniq_idx,unique" json:"shift_id"`    // Foreign key
 	StartDate  time.Time `gorm:"type:date;not null;index:uniq_idx,unique" json:"start_date"`
 	EndDate    time.Time `gorm:"type:date;not null;index:uniq_idx,unique" json:"end_date"`
+	Source     string    `gorm:"not null;default:'manual'" json:"source"` // manual, rotation, scheduler, swap or open_shift
+
+	RotationAssignmentID *uint    `gorm:"index" json:"rotation_assignment_id"` // Set on assignments generated from a rotation
+	OverrideReason       string   `json:"override_reason,omitempty"`           // Why a manager assigned it despite blocking working time rules
+	OverriddenBy         *uint    `json:"overridden_by,omitempty"`             // iam.User who overrode the rules
+	Warnings             []string `gorm:"-" json:"warnings,omitempty"`         // Guard warnings raised when the assignment was made
 
 	Employee Employee `gorm:"foreignkey:EmployeeID"`
 	Shift    Shift    `gorm:"foreignkey:ShiftID"` // Relationship with Shift
@@ -2,25 +2,79 @@
 package employee
 
 import (
+	"clinicplus/internal/shared/config"
 	"clinicplus/internal/shared/utils"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
+var (
+	ErrEmployeeNotFound = errors.New("employee not found")
+	ErrShiftNotFound    = errors.New("shift not found")
+	ErrLocationNotFound = errors.New("location not found")
+	ErrInvalidTimezone  = errors.New("invalid timezone")
+
+	ErrInvalidShiftTime  = errors.New("shift start and end times must be HH:MM")
+	ErrInvalidDaysOfWeek = errors.New("days of week must be names such as mon,tue,wed")
+	ErrInvalidBreak      = errors.New("break must be shorter than the shift")
+
+	ErrInvalidEmploymentStatus = errors.New("invalid employment status")
+	ErrInvalidStatusTransition = errors.New("employment status change not allowed")
+	ErrNotTerminated           = errors.New("only terminated employees can be rehired")
+	ErrAssignmentRejected      = errors.New("shift assignment rejected")
+	ErrAssignmentWarning       = errors.New("shift assignment warning")
+	ErrOccurrenceNotFound      = errors.New("shift is not assigned on that date")
+
+	ErrClockInRejected  = errors.New("clock-in rejected")
+	ErrAlreadyClockedIn = errors.New("employee is already clocked in for today for this shift")
+	ErrNotClockedIn     = errors.New("no open clock-in record found for this shift")
+	ErrBreakInProgress  = errors.New("a break is already in progress")
+	ErrNoBreak          = errors.New("no break in progress")
+	ErrAttendanceLocked = errors.New("attendance record is locked by a signed-off timesheet")
+)
+
+// AssignmentGuard is consulted by AssignShift before a new EmployeeShift is
+// saved. Guards reject an assignment by returning an error wrapping
+// ErrAssignmentRejected, or let it through with a warning by returning one
+// wrapping ErrAssignmentWarning. db is the transaction the assignment is made
+// in, so guards looking at other assignments see its uncommitted changes.
+type AssignmentGuard interface {
+	CheckAssignment(db *gorm.DB, employee Employee, assignment EmployeeShift) error
+}
+
+// LifecycleListener is notified inside the transaction that hires or
+// terminates an employee. Returning an error rolls the change back.
+type LifecycleListener interface {
+	EmployeeHired(tx *gorm.DB, employee Employee) error
+	EmployeeTerminated(tx *gorm.DB, employee Employee) error
+}
+
 type EmployeeService interface {
-	GetEmployees(page, limit int) ([]Employee, int, error)
+	GetEmployees(page, limit int, statuses []string) ([]Employee, int, error)
 	GetEmployee(id int) (*Employee, error)
 	CreateEmployee(employee Employee) (*Employee, error)
 	UpdateEmployee(id int, employee Employee) (*Employee, error)
 	DeleteEmployee(id int) error
+	ChangeEmploymentStatus(id int, status string, effectiveDate time.Time, reason string) (*Employee, error)
+	TerminateEmployee(id int, terminationDate time.Time, reason string) (*Employee, error)
+	ApplyScheduledTerminations(now time.Time) (int, error)
+	RehireEmployee(id int, hireDate time.Time, status string) (*Employee, error)
+	GetEmploymentHistory(id int) ([]EmploymentEvent, error)
 	SearchEmployees(query string, page, limit int) ([]Employee, int, error)
 
 	ClockIn(employeeID uint, shiftID uint) (*Attendance, error)
+	OverrideClockIn(employeeID uint, shiftID uint, reason string, overriddenBy uint) (*Attendance, error)
 	ClockOut(employeeID uint, shiftID uint) (*Attendance, error)
+	StartBreak(employeeID uint, shiftID uint, paid bool) (*Attendance, error)
+	EndBreak(employeeID uint, shiftID uint) (*Attendance, error)
+	FlagMissingClockOut(attendanceID uint, closeAt *time.Time) (*Attendance, error)
+	SaveAttendance(tx *gorm.DB, attendance *Attendance) error
 
 	CreateShift(shift Shift) (*Shift, error)
 	GetShift(id uint) (*ShiftWithEmployees, error)
@@ -28,27 +82,70 @@ type EmployeeService interface {
 	UpdateShift(id uint, shift Shift) (*Shift, error)
 	DeleteShift(id uint) error
 	AssignShift(employeeID uint, shiftID uint, startDate time.Time, endDate time.Time) (*EmployeeShift, error)
+	CreateAssignment(assignment EmployeeShift) (*EmployeeShift, error)
+	CreateAssignmentIn(tx *gorm.DB, assignment EmployeeShift) (*EmployeeShift, error)
+	ReassignOccurrences(tx *gorm.DB, reassignments []Reassignment) ([]EmployeeShift, error)
+	RemoveOccurrence(tx *gorm.DB, employeeID, shiftID uint, date time.Time) error
+	AddAssignmentGuard(guard AssignmentGuard)
+	AddLifecycleListener(listener LifecycleListener)
+
+	CreateLocation(location Location) (*Location, error)
+	GetLocations() ([]Location, error)
+	UpdateLocation(id uint, location Location) (*Location, error)
+	DeleteLocation(id uint) error
+}
+
+// Reassignment moves the occurrence of an assigned shift on one date to
+// another employee
+type Reassignment struct {
+	AssignmentID uint
+	Date         time.Time
+	EmployeeID   uint // Employee taking the occurrence over
+	Source       string
 }
 
 type employeeService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	policy    AttendancePolicy
+	guards    []AssignmentGuard
+	listeners []LifecycleListener
 }
 
-func NewEmployeeService(db *gorm.DB) EmployeeService {
-	return &employeeService{db: db}
+func NewEmployeeService(db *gorm.DB, policy AttendancePolicy) EmployeeService {
+	return &employeeService{db: db, policy: policy}
 }
 
-func (s *employeeService) GetEmployees(page, limit int) ([]Employee, int, error) {
+// AttendancePolicyFromConfig builds the attendance policy from the environment
+func AttendancePolicyFromConfig() AttendancePolicy {
+	return AttendancePolicy{
+		ClockInEarly:       time.Duration(config.GetClockInEarlyMinutes()) * time.Minute,
+		ClockInGrace:       time.Duration(config.GetClockInGraceMinutes()) * time.Minute,
+		BreakRequiredAfter: time.Duration(config.GetBreakRequiredAfterHours() * float64(time.Hour)),
+		MinBreak:           time.Duration(config.GetBreakMinMinutes()) * time.Minute,
+		DeductMissedBreaks: config.GetBreakEnforcement() == "deduct",
+	}
+}
+
+// GetEmployees lists employees with one of the given employment statuses.
+// Without statuses it lists current staff, i.e. everyone not terminated.
+func (s *employeeService) GetEmployees(page, limit int, statuses []string) ([]Employee, int, error) {
 	var employees []Employee
 	offset := (page - 1) * limit
 
-	if err := s.db.Offset(offset).Limit(limit).Find(&employees).Error; err != nil {
+	query := s.db.Model(&Employee{})
+	if len(statuses) > 0 {
+		query = query.Where("employment_status IN (?)", statuses)
+	} else {
+		query = query.Where("employment_status <> ?", EmploymentStatusTerminated)
+	}
+
+	if err := query.Offset(offset).Limit(limit).Find(&employees).Error; err != nil {
 		log.Printf("Error fetching employees: %v", err)
 		return nil, 0, err
 	}
 
 	var total int
-	s.db.Model(&Employee{}).Count(&total)
+	query.Count(&total)
 
 	return employees, total, nil
 }
@@ -57,7 +154,7 @@ func (s *employeeService) GetEmployee(id int) (*Employee, error) {
 	var employee Employee
 	if err := s.db.First(&employee, id).Error; err != nil {
 		if gorm.IsRecordNotFoundError(err) {
-			return nil, errors.New("employee not found")
+			return nil, ErrEmployeeNotFound
 		}
 		log.Printf("Error fetching employee: %v", err)
 		return nil, err
@@ -73,6 +170,17 @@ func (s *employeeService) CreateEmployee(employee Employee) (*Employee, error) {
 		return nil, tx.Error
 	}
 
+	// New hires start active or on probation
+	if employee.EmploymentStatus == "" {
+		employee.EmploymentStatus = EmploymentStatusActive
+	}
+	if employee.EmploymentStatus != EmploymentStatusActive && employee.EmploymentStatus != EmploymentStatusProbation {
+		tx.Rollback()
+		return nil, ErrInvalidEmploymentStatus
+	}
+	employee.TerminationDate = utils.NullTime{}
+	employee.TerminationReason = ""
+
 	// Create the employee
 	if err := tx.Create(&employee).Error; err != nil {
 		tx.Rollback()
@@ -80,6 +188,19 @@ func (s *employeeService) CreateEmployee(employee Employee) (*Employee, error) {
 		return nil, err
 	}
 
+	if err := recordEmploymentEvent(tx, employee.ID, "", employee.EmploymentStatus, dayOrToday(employee.HireDate), "Hired"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, listener := range s.listeners {
+		if err := listener.EmployeeHired(tx, employee); err != nil {
+			tx.Rollback()
+			log.Printf("Error running hire hooks: %v", err)
+			return nil, err
+		}
+	}
+
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("Error committing transaction: %v", err)
@@ -93,14 +214,21 @@ func (s *employeeService) UpdateEmployee(id int, employee Employee) (*Employee,
 	var existingEmployee Employee
 	if err := s.db.First(&existingEmployee, id).Error; err != nil {
 		if gorm.IsRecordNotFoundError(err) {
-			return nil, errors.New("employee not found")
+			return nil, ErrEmployeeNotFound
 		}
 		log.Printf("Error finding employee: %v", err)
 		return nil, err
 	}
 
-	// Preserve ID and update other fields
+	// Preserve ID and update other fields. Employment status only changes
+	// through the status, termination and rehire endpoints, and salary through
+	// an approved compensation change.
 	employee.ID = existingEmployee.ID
+	employee.CreatedAt = existingEmployee.CreatedAt
+	employee.Salary = existingEmployee.Salary
+	employee.EmploymentStatus = existingEmployee.EmploymentStatus
+	employee.TerminationDate = existingEmployee.TerminationDate
+	employee.TerminationReason = existingEmployee.TerminationReason
 
 	if err := s.db.Save(&employee).Error; err != nil {
 		log.Printf("Error updating employee: %v", err)
@@ -110,18 +238,238 @@ func (s *employeeService) UpdateEmployee(id int, employee Employee) (*Employee,
 	return &employee, nil
 }
 
+// DeleteEmployee terminates the employee effective today. Employee rows are
+// never removed, so attendance and history stay attached to them.
 func (s *employeeService) DeleteEmployee(id int) error {
-	result := s.db.Delete(&Employee{}, id)
-	if result.Error != nil {
-		log.Printf("Error deleting employee: %v", result.Error)
-		return result.Error
+	_, err := s.TerminateEmployee(id, time.Now().UTC(), "")
+	return err
+}
+
+// ChangeEmploymentStatus moves an employee to a new status, e.g. from
+// probation to active or into suspension
+func (s *employeeService) ChangeEmploymentStatus(id int, status string, effectiveDate time.Time, reason string) (*Employee, error) {
+	if status == EmploymentStatusTerminated {
+		return s.TerminateEmployee(id, effectiveDate, reason)
+	}
+	if _, ok := employmentTransitions[status]; !ok {
+		return nil, ErrInvalidEmploymentStatus
 	}
 
-	if result.RowsAffected == 0 {
-		return errors.New("employee not found")
+	employee, err := s.GetEmployee(id)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(employee.EmploymentStatus, status) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidStatusTransition, employee.EmploymentStatus, status)
 	}
 
-	return nil
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	from := employee.EmploymentStatus
+	employee.EmploymentStatus = status
+	if err := tx.Model(employee).Update("employment_status", status).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error changing employment status: %v", err)
+		return nil, err
+	}
+	if err := recordEmploymentEvent(tx, employee.ID, from, status, dayOrToday(effectiveDate), reason); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+	return employee, nil
+}
+
+// TerminateEmployee ends an employee's employment. Shift assignments past the
+// termination date are cut back, and lifecycle listeners run offboarding. A
+// termination date after today is only recorded; the employee keeps working
+// until ApplyScheduledTerminations terminates them on that date.
+func (s *employeeService) TerminateEmployee(id int, terminationDate time.Time, reason string) (*Employee, error) {
+	employee, err := s.GetEmployee(id)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(employee.EmploymentStatus, EmploymentStatusTerminated) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidStatusTransition, employee.EmploymentStatus, EmploymentStatusTerminated)
+	}
+
+	date := dayOrToday(terminationDate)
+	if date.After(dayOrToday(time.Time{})) {
+		return s.scheduleTermination(employee, date, reason)
+	}
+	return s.terminate(employee, date, reason)
+}
+
+// scheduleTermination records a future termination date without changing the
+// employee's status
+func (s *employeeService) scheduleTermination(employee *Employee, date time.Time, reason string) (*Employee, error) {
+	employee.TerminationDate = utils.NullTime{NullTime: sql.NullTime{Time: date, Valid: true}}
+	employee.TerminationReason = reason
+	if err := s.db.Model(employee).Updates(map[string]interface{}{
+		"termination_date":   employee.TerminationDate,
+		"termination_reason": employee.TerminationReason,
+	}).Error; err != nil {
+		log.Printf("Error scheduling termination: %v", err)
+		return nil, err
+	}
+	return employee, nil
+}
+
+// ApplyScheduledTerminations terminates the employees whose scheduled
+// termination date has arrived
+func (s *employeeService) ApplyScheduledTerminations(now time.Time) (int, error) {
+	var employees []Employee
+	err := s.db.Where("employment_status <> ? AND termination_date IS NOT NULL AND termination_date <= ?", EmploymentStatusTerminated, dateOf(now.UTC())).
+		Find(&employees).Error
+	if err != nil {
+		log.Printf("Error fetching scheduled terminations: %v", err)
+		return 0, err
+	}
+
+	terminated := 0
+	for i := range employees {
+		employee := &employees[i]
+		if _, err := s.terminate(employee, dateOf(employee.TerminationDate.Time), employee.TerminationReason); err != nil {
+			return terminated, err
+		}
+		terminated++
+	}
+	return terminated, nil
+}
+
+// terminate sets the employee's status to terminated effective date, cuts
+// back their shift assignments and runs the lifecycle listeners
+func (s *employeeService) terminate(employee *Employee, date time.Time, reason string) (*Employee, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	from := employee.EmploymentStatus
+	employee.EmploymentStatus = EmploymentStatusTerminated
+	employee.TerminationDate = utils.NullTime{NullTime: sql.NullTime{Time: date, Valid: true}}
+	employee.TerminationReason = reason
+	if err := tx.Model(employee).Updates(map[string]interface{}{
+		"employment_status":  employee.EmploymentStatus,
+		"termination_date":   employee.TerminationDate,
+		"termination_reason": employee.TerminationReason,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error terminating employee: %v", err)
+		return nil, err
+	}
+
+	// Drop assignments that start after the last working day and end the rest there
+	if err := tx.Where("employee_id = ? AND start_date > ?", employee.ID, date).Delete(&EmployeeShift{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error removing future shift assignments: %v", err)
+		return nil, err
+	}
+	if err := tx.Model(&EmployeeShift{}).Where("employee_id = ? AND end_date > ?", employee.ID, date).Update("end_date", date).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error ending shift assignments: %v", err)
+		return nil, err
+	}
+
+	if err := recordEmploymentEvent(tx, employee.ID, from, EmploymentStatusTerminated, date, reason); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, listener := range s.listeners {
+		if err := listener.EmployeeTerminated(tx, *employee); err != nil {
+			tx.Rollback()
+			log.Printf("Error running termination hooks: %v", err)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+	return employee, nil
+}
+
+// RehireEmployee brings a terminated employee back with a new hire date. The
+// earlier employment stays in the employment history.
+func (s *employeeService) RehireEmployee(id int, hireDate time.Time, status string) (*Employee, error) {
+	employee, err := s.GetEmployee(id)
+	if err != nil {
+		return nil, err
+	}
+	if employee.EmploymentStatus != EmploymentStatusTerminated {
+		return nil, ErrNotTerminated
+	}
+	if status == "" {
+		status = EmploymentStatusProbation
+	}
+	if status != EmploymentStatusActive && status != EmploymentStatusProbation {
+		return nil, ErrInvalidEmploymentStatus
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	employee.EmploymentStatus = status
+	employee.HireDate = dayOrToday(hireDate)
+	employee.TerminationDate = utils.NullTime{}
+	employee.TerminationReason = ""
+	if err := tx.Model(employee).Updates(map[string]interface{}{
+		"employment_status":  employee.EmploymentStatus,
+		"hire_date":          employee.HireDate,
+		"termination_date":   employee.TerminationDate,
+		"termination_reason": employee.TerminationReason,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error rehiring employee: %v", err)
+		return nil, err
+	}
+
+	if err := recordEmploymentEvent(tx, employee.ID, EmploymentStatusTerminated, status, employee.HireDate, "Rehired"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, listener := range s.listeners {
+		if err := listener.EmployeeHired(tx, *employee); err != nil {
+			tx.Rollback()
+			log.Printf("Error running hire hooks: %v", err)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+	return employee, nil
+}
+
+// GetEmploymentHistory returns the employee's status changes, oldest first
+func (s *employeeService) GetEmploymentHistory(id int) ([]EmploymentEvent, error) {
+	if _, err := s.GetEmployee(id); err != nil {
+		return nil, err
+	}
+
+	var events []EmploymentEvent
+	if err := s.db.Where("employee_id = ?", id).Order("effective_date, id").Find(&events).Error; err != nil {
+		log.Printf("Error fetching employment history: %v", err)
+		return nil, err
+	}
+	return events, nil
 }
 
 func (s *employeeService) SearchEmployees(query string, page, limit int) ([]Employee, int, error) {
@@ -153,85 +501,387 @@ func (s *employeeService) SearchEmployees(query string, page, limit int) ([]Empl
 	return employees, total, nil
 }
 
-// ClockIn allows an employee to clock in for a specific shift
+// ClockIn records an employee arriving for a shift they are assigned to.
+// Clocking in opens the policy's early window before an occurrence starts and
+// closes when it ends; arriving after the grace period marks the record late.
+// The record is dated by the business date the occurrence starts on in the
+// shift's timezone, so a night shift keeps the date it began on.
 func (s *employeeService) ClockIn(employeeID uint, shiftID uint) (*Attendance, error) {
-	var attendance Attendance
-	now := time.Now().UTC() // Get the current time in UTC
-	date := now.Truncate(24 * time.Hour)
+	now := time.Now().UTC()
 
-	// Check if the employee is already clocked in for today and the specified shift
-	err := s.db.Where("employee_id = ? AND date = ? AND shift_id = ?", employeeID, date, shiftID).First(&attendance).Error
-	if err == nil {
-		return nil, errors.New("employee is already clocked in for today for this shift")
+	employee, shift, err := s.clockInSubjects(employeeID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if !employee.CanWork() {
+		return nil, fmt.Errorf("%w: employee is %s", ErrClockInRejected, employee.EmploymentStatus)
 	}
 
-	// Create a new attendance record
-	attendance = Attendance{
-		EmployeeID:  employeeID,
-		ShiftID:     shiftID,                      // Associate the attendance with the shift
-		Date:        now.Truncate(24 * time.Hour), // Use today's date
-		ClockInTime: now,
-		ClockOutTime: utils.NullTime{
-			NullTime: sql.NullTime{
-				Valid: false, // Set to true to indicate that this time is valid
-			},
-		},
-		Status: "Present",
+	date, window, ok := shift.OccurrenceAt(now, s.policy.ClockInEarly)
+	if !ok {
+		today := shift.LocalDate(now)
+		window, runs := shift.OccurrenceOn(today)
+		switch {
+		case !runs:
+			return nil, fmt.Errorf("%w: %s does not run today", ErrClockInRejected, shift.Name)
+		case now.Before(window.Start):
+			return nil, fmt.Errorf("%w: clock-in for %s opens at %s", ErrClockInRejected, shift.Name,
+				window.Start.Add(-s.policy.ClockInEarly).In(shift.Location()).Format("15:04"))
+		default:
+			return nil, fmt.Errorf("%w: %s ended at %s", ErrClockInRejected, shift.Name, window.End.In(shift.Location()).Format("15:04"))
+		}
+	}
+
+	var assigned int
+	if err := s.db.Model(&EmployeeShift{}).
+		Where("employee_id = ? AND shift_id = ? AND start_date <= ? AND end_date >= ?", employeeID, shiftID, date, date).
+		Count(&assigned).Error; err != nil {
+		log.Printf("Error checking shift assignment: %v", err)
+		return nil, err
+	}
+	if assigned == 0 {
+		return nil, fmt.Errorf("%w: %s is not assigned to %s on %s", ErrClockInRejected, employee.Name, shift.Name, date.Format("2006-01-02"))
+	}
+	if err := s.checkNotClockedIn(employeeID, shiftID, date); err != nil {
+		return nil, err
 	}
 
+	attendance := s.newAttendance(employeeID, shiftID, date, now, window, true)
 	if err := s.db.Create(&attendance).Error; err != nil {
 		log.Printf("Error clocking in employee: %v", err)
 		return nil, err
 	}
+	return &attendance, nil
+}
+
+// OverrideClockIn lets a manager clock an employee in for an exception, such
+// as covering a shift they are not assigned to or arriving outside the
+// clock-in window. The reason is kept on the record. Employees who may not
+// work, e.g. suspended or terminated ones, cannot be clocked in.
+func (s *employeeService) OverrideClockIn(employeeID uint, shiftID uint, reason string, overriddenBy uint) (*Attendance, error) {
+	now := time.Now().UTC()
+
+	employee, shift, err := s.clockInSubjects(employeeID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if !employee.CanWork() {
+		return nil, fmt.Errorf("%w: employee is %s", ErrClockInRejected, employee.EmploymentStatus)
+	}
 
+	date, window, runs := shift.OccurrenceAt(now, s.policy.ClockInEarly)
+	if !runs {
+		date = shift.LocalDate(now)
+		window, runs = shift.OccurrenceOn(date)
+	}
+	if err := s.checkNotClockedIn(employeeID, shiftID, date); err != nil {
+		return nil, err
+	}
+
+	attendance := s.newAttendance(employeeID, shiftID, date, now, window, runs)
+	attendance.OverrideReason = reason
+	attendance.OverriddenBy = &overriddenBy
+	if err := s.db.Create(&attendance).Error; err != nil {
+		log.Printf("Error clocking in employee: %v", err)
+		return nil, err
+	}
 	return &attendance, nil
 }
 
-// ClockOut allows an employee to clock out for a specific shift
+// clockInSubjects loads the employee and shift of a clock-in
+func (s *employeeService) clockInSubjects(employeeID, shiftID uint) (*Employee, *Shift, error) {
+	var employee Employee
+	if err := s.db.First(&employee, employeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil, ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, nil, err
+	}
+	var shift Shift
+	if err := s.db.First(&shift, shiftID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil, ErrShiftNotFound
+		}
+		log.Printf("Error fetching shift: %v", err)
+		return nil, nil, err
+	}
+	return &employee, &shift, nil
+}
+
+// checkNotClockedIn makes sure the employee has no attendance record for the
+// shift on date yet
+func (s *employeeService) checkNotClockedIn(employeeID, shiftID uint, date time.Time) error {
+	var existing Attendance
+	err := s.db.Where("employee_id = ? AND date = ? AND shift_id = ?", employeeID, date, shiftID).First(&existing).Error
+	if err == nil {
+		switch existing.Status {
+		case AttendanceStatusOnLeave:
+			return fmt.Errorf("%w: employee is on leave", ErrClockInRejected)
+		case AttendanceStatusAbsent:
+			return fmt.Errorf("%w: employee was marked absent", ErrClockInRejected)
+		}
+		return ErrAlreadyClockedIn
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		log.Printf("Error fetching attendance record: %v", err)
+		return err
+	}
+	return nil
+}
+
+// newAttendance builds a present record clocked in at now, classified
+// against the occurrence window when the shift runs that day
+func (s *employeeService) newAttendance(employeeID, shiftID uint, date, now time.Time, window ShiftWindow, runs bool) Attendance {
+	attendance := Attendance{
+		EmployeeID:  employeeID,
+		ShiftID:     shiftID,
+		Date:        date,
+		ClockInTime: now,
+		Status:      AttendanceStatusPresent,
+	}
+	if runs {
+		attendance.Punctuality = PunctualityOnTime
+		if late := now.Sub(window.Start); late > s.policy.ClockInGrace {
+			attendance.Punctuality = PunctualityLate
+			attendance.LateMinutes = int(late / time.Minute)
+		}
+	}
+	return attendance
+}
+
+// ClockOut closes the employee's open attendance record for a shift. The
+// record is found by being open rather than by date, so a night shift can be
+// clocked out after midnight. A break still running ends with the shift.
 func (s *employeeService) ClockOut(employeeID uint, shiftID uint) (*Attendance, error) {
-	var attendance Attendance
-	now := time.Now().UTC() // Get the current time in UTC
-	date := now.Truncate(24 * time.Hour)
+	attendance, err := s.openAttendance(employeeID, shiftID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Find the attendance record for today and the specified shift
-	err := s.db.Where("employee_id = ? AND date = ? AND shift_id = ?", employeeID, date, shiftID).First(&attendance).Error
+	attendance.Status = AttendanceStatusPresent
+	if err := s.closeAttendance(attendance, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return attendance, nil
+}
+
+// FlagMissingClockOut marks an open attendance record whose shift is over as
+// missing its clock-out, closing it at closeAt when given
+func (s *employeeService) FlagMissingClockOut(attendanceID uint, closeAt *time.Time) (*Attendance, error) {
+	var attendance Attendance
+	err := s.db.Preload("Breaks", func(db *gorm.DB) *gorm.DB { return db.Order("start_time") }).
+		Where("clock_out_time IS NULL").First(&attendance, attendanceID).Error
 	if err != nil {
 		if gorm.IsRecordNotFoundError(err) {
-			return nil, errors.New("no clock-in record found for today for this shift")
+			return nil, ErrNotClockedIn
 		}
 		log.Printf("Error fetching attendance record: %v", err)
 		return nil, err
 	}
 
-	// Update the clock-out time and status
-	attendance.ClockOutTime = utils.NullTime{
-		NullTime: sql.NullTime{
-			Time:  now,  // Set to the current time or any specific time you want
-			Valid: true, // Set to true to indicate that this time is valid
-		},
+	if attendance.Locked {
+		return nil, ErrAttendanceLocked
+	}
+
+	attendance.Status = AttendanceStatusMissingClockOut
+	if closeAt == nil {
+		if err := s.db.Model(&attendance).UpdateColumn("status", attendance.Status).Error; err != nil {
+			log.Printf("Error flagging attendance record: %v", err)
+			return nil, err
+		}
+		return &attendance, nil
+	}
+
+	at := *closeAt
+	if at.Before(attendance.ClockInTime) {
+		at = attendance.ClockInTime
+	}
+	if err := s.closeAttendance(&attendance, at); err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+// SaveAttendance saves an attendance record changed by hand within tx,
+// recomputing its break totals against the new times. Locked records are
+// refused.
+func (s *employeeService) SaveAttendance(tx *gorm.DB, attendance *Attendance) error {
+	if attendance.Locked {
+		return ErrAttendanceLocked
+	}
+	if attendance.ID != 0 {
+		if err := tx.Where("attendance_id = ?", attendance.ID).Order("start_time").Find(&attendance.Breaks).Error; err != nil {
+			log.Printf("Error fetching breaks: %v", err)
+			return err
+		}
+	}
+	s.totalBreaks(attendance)
+	if err := tx.Omit("Breaks", "Employee", "Shift").Save(attendance).Error; err != nil {
+		log.Printf("Error saving attendance record: %v", err)
+		return err
 	}
-	attendance.Status = "Present" // Adjust status if needed
+	return nil
+}
 
-	if err := s.db.Save(&attendance).Error; err != nil {
+// closeAttendance clocks a record out at the given time, ending a running
+// break with it, and saves it with its status and break totals
+func (s *employeeService) closeAttendance(attendance *Attendance, at time.Time) error {
+	if attendance.Locked {
+		return ErrAttendanceLocked
+	}
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return tx.Error
+	}
+	for i := range attendance.Breaks {
+		if b := &attendance.Breaks[i]; !b.EndTime.Valid {
+			b.EndTime = utils.NullTime{NullTime: sql.NullTime{Time: at, Valid: true}}
+			if err := tx.Save(b).Error; err != nil {
+				tx.Rollback()
+				log.Printf("Error ending break: %v", err)
+				return err
+			}
+		}
+	}
+
+	attendance.ClockOutTime = utils.NullTime{NullTime: sql.NullTime{Time: at, Valid: true}}
+	s.totalBreaks(attendance)
+	if err := tx.Omit("Breaks", "Employee", "Shift").Save(attendance).Error; err != nil {
+		tx.Rollback()
 		log.Printf("Error clocking out employee: %v", err)
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return err
+	}
+	return nil
+}
+
+// StartBreak starts a paid or unpaid break within the employee's open
+// attendance record. A shiftID of 0 picks whichever shift they are clocked in
+// to.
+func (s *employeeService) StartBreak(employeeID uint, shiftID uint, paid bool) (*Attendance, error) {
+	attendance, err := s.openAttendance(employeeID, shiftID)
+	if err != nil {
 		return nil, err
 	}
+	for _, b := range attendance.Breaks {
+		if !b.EndTime.Valid {
+			return nil, ErrBreakInProgress
+		}
+	}
 
-	return &attendance, nil
+	b := AttendanceBreak{AttendanceID: attendance.ID, Paid: paid, StartTime: time.Now().UTC()}
+	if err := s.db.Create(&b).Error; err != nil {
+		log.Printf("Error starting break: %v", err)
+		return nil, err
+	}
+	attendance.Breaks = append(attendance.Breaks, b)
+	return attendance, nil
 }
 
-// CreateShift creates a new shift with overlapping constraints
-func (s *employeeService) CreateShift(shift Shift) (*Shift, error) {
-	// Check for overlapping shifts
-	var existingShifts []Shift
-	err := s.db.Where("start_time < ? AND end_time > ? AND id != ?", shift.EndTime, shift.StartTime, shift.ID).Find(&existingShifts).Error
+// EndBreak ends the running break of the employee's open attendance record
+func (s *employeeService) EndBreak(employeeID uint, shiftID uint) (*Attendance, error) {
+	attendance, err := s.openAttendance(employeeID, shiftID)
 	if err != nil {
-		log.Printf("Error checking for overlapping shifts: %v", err)
 		return nil, err
 	}
 
-	if len(existingShifts) > 0 {
-		return nil, errors.New("shift overlaps with existing shifts")
+	var running *AttendanceBreak
+	for i := range attendance.Breaks {
+		if !attendance.Breaks[i].EndTime.Valid {
+			running = &attendance.Breaks[i]
+		}
+	}
+	if running == nil {
+		return nil, ErrNoBreak
+	}
+	running.EndTime = utils.NullTime{NullTime: sql.NullTime{Time: time.Now().UTC(), Valid: true}}
+	if err := s.db.Save(running).Error; err != nil {
+		log.Printf("Error ending break: %v", err)
+		return nil, err
+	}
+
+	s.totalBreaks(attendance)
+	if err := s.db.Model(attendance).UpdateColumn("unpaid_break_minutes", attendance.UnpaidBreakMinutes).Error; err != nil {
+		log.Printf("Error updating attendance record: %v", err)
+		return nil, err
+	}
+	return attendance, nil
+}
+
+// openAttendance finds the employee's attendance record for a shift, or for
+// any shift when shiftID is 0, that is clocked in and not yet out. Records
+// flagged as missing their clock-out can still be clocked out.
+func (s *employeeService) openAttendance(employeeID uint, shiftID uint) (*Attendance, error) {
+	query := s.db.Preload("Breaks", func(db *gorm.DB) *gorm.DB { return db.Order("start_time") }).
+		Where("employee_id = ? AND status IN (?) AND clock_out_time IS NULL", employeeID,
+			[]string{AttendanceStatusPresent, AttendanceStatusMissingClockOut})
+	if shiftID != 0 {
+		query = query.Where("shift_id = ?", shiftID)
+	}
+
+	var attendance Attendance
+	if err := query.Order("clock_in_time DESC").First(&attendance).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrNotClockedIn
+		}
+		log.Printf("Error fetching attendance record: %v", err)
+		return nil, err
+	}
+	if attendance.Locked {
+		return nil, ErrAttendanceLocked
+	}
+	return &attendance, nil
+}
+
+// totalBreaks sums the ended breaks of a record and, once it is clocked out,
+// applies the mandatory break rule and computes the net worked time
+func (s *employeeService) totalBreaks(attendance *Attendance) {
+	var taken, unpaid time.Duration
+	for _, b := range attendance.Breaks {
+		if !b.EndTime.Valid {
+			continue
+		}
+		taken += b.EndTime.Time.Sub(b.StartTime)
+		if !b.Paid {
+			unpaid += b.EndTime.Time.Sub(b.StartTime)
+		}
+	}
+	attendance.UnpaidBreakMinutes = int(unpaid / time.Minute)
+	if !attendance.ClockOutTime.Valid {
+		return
+	}
+
+	var worked time.Duration
+	for _, window := range attendance.WorkedWindows() {
+		worked += window.End.Sub(window.Start)
+	}
+	attendance.BreakViolation, attendance.DeductedBreakMinutes = "", 0
+	if s.policy.BreakRequiredAfter > 0 && worked > s.policy.BreakRequiredAfter && taken < s.policy.MinBreak {
+		attendance.BreakViolation = fmt.Sprintf("worked %.1fh with %d min of breaks; %d min required after %gh",
+			worked.Hours(), int(taken/time.Minute), int(s.policy.MinBreak/time.Minute), s.policy.BreakRequiredAfter.Hours())
+		if s.policy.DeductMissedBreaks {
+			missed := s.policy.MinBreak - taken
+			if missed > worked {
+				missed = worked
+			}
+			attendance.DeductedBreakMinutes = int(missed / time.Minute)
+			worked -= missed
+		}
+	}
+	attendance.NetWorkedMinutes = int(worked / time.Minute)
+}
+
+// CreateShift creates a new shift template. Templates may overlap freely,
+// since parallel shifts are normal; overlaps only matter once an employee is
+// assigned, which AssignShift checks.
+func (s *employeeService) CreateShift(shift Shift) (*Shift, error) {
+	if err := s.normalizeShift(&shift); err != nil {
+		return nil, err
 	}
 
 	if err := s.db.Create(&shift).Error; err != nil {
@@ -270,17 +920,18 @@ func (s *employeeService) GetShift(id uint) (*ShiftWithEmployees, error) {
 	var employees []Employee
 	attendanceData := make(map[uint]interface{}) // Map to hold attendance data
 
-	now := time.Now() // Get the current time in UTC
-	date := now.Truncate(24 * time.Hour)
+	// Attendance of the occurrence running now, or else of today in the
+	// shift's timezone
+	now := time.Now().UTC()
+	date, _, ok := shift.OccurrenceAt(now, 0)
+	if !ok {
+		date = shift.LocalDate(now)
+	}
 
-	// Fetch attendance data for all employees in one query using a join
+	// Fetch attendance data for all employees in one query
 	var attendanceRecords []Attendance
 
-	err := s.db.Table("attendances").
-		Select("attendances.ID, attendances.employee_id, attendances.shift_id, attendances.date, attendances.clock_in_time, attendances.clock_out_time, attendances.status").
-		Joins("JOIN employee_shifts ON employee_shifts.employee_id = attendances.employee_id").
-		Where("employee_shifts.shift_id = ? AND attendances.date = ?", id, date).
-		Scan(&attendanceRecords).Error
+	err := s.db.Where("shift_id = ? AND date = ?", id, date).Find(&attendanceRecords).Error
 
 	if err != nil {
 		log.Printf("Error fetching attendance records: %v", err)
@@ -312,7 +963,20 @@ func (s *employeeService) GetShift(id uint) (*ShiftWithEmployees, error) {
 
 // UpdateShift updates an existing shift
 func (s *employeeService) UpdateShift(id uint, shift Shift) (*Shift, error) {
+	var existing Shift
+	if err := s.db.First(&existing, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrShiftNotFound
+		}
+		log.Printf("Error fetching shift: %v", err)
+		return nil, err
+	}
+	if err := s.normalizeShift(&shift); err != nil {
+		return nil, err
+	}
+
 	shift.ID = id // Ensure the ID is set for the update
+	shift.CreatedAt = existing.CreatedAt
 	if err := s.db.Save(&shift).Error; err != nil {
 		log.Printf("Error updating shift: %v", err)
 		return nil, err
@@ -331,32 +995,383 @@ func (s *employeeService) DeleteShift(id uint) error {
 
 // AssignShift assigns a shift to an employee
 func (s *employeeService) AssignShift(employeeID uint, shiftID uint, startDate time.Time, endDate time.Time) (*EmployeeShift, error) {
-	// Create a new EmployeeShift record
-	employeeShift := EmployeeShift{
+	return s.CreateAssignment(EmployeeShift{
 		EmployeeID: employeeID,
 		ShiftID:    shiftID,
 		StartDate:  startDate,
 		EndDate:    endDate,
+		Source:     AssignmentSourceManual,
+	})
+}
+
+// CreateAssignment saves a shift assignment after the same checks as
+// AssignShift. Other packages use it to create generated assignments.
+func (s *employeeService) CreateAssignment(employeeShift EmployeeShift) (*EmployeeShift, error) {
+	return s.createAssignment(s.db, employeeShift)
+}
+
+// CreateAssignmentIn is CreateAssignment within tx, for callers that must
+// roll the assignment back with their own changes
+func (s *employeeService) CreateAssignmentIn(tx *gorm.DB, employeeShift EmployeeShift) (*EmployeeShift, error) {
+	return s.createAssignment(tx, employeeShift)
+}
+
+// ReassignOccurrences moves single occurrences of assigned shifts to other
+// employees within tx. Every occurrence is first cut out of its assignment,
+// which is shortened or split around the date, and only then given to its new
+// employee as a one-day assignment with the usual checks, so two employees
+// can trade shifts that overlap each other.
+func (s *employeeService) ReassignOccurrences(tx *gorm.DB, reassignments []Reassignment) ([]EmployeeShift, error) {
+	shiftIDs := make([]uint, len(reassignments))
+	for i, reassignment := range reassignments {
+		var assignment EmployeeShift
+		if err := tx.Preload("Shift").First(&assignment, reassignment.AssignmentID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, ErrOccurrenceNotFound
+			}
+			log.Printf("Error fetching shift assignment: %v", err)
+			return nil, err
+		}
+		if err := cutOccurrence(tx, assignment, dayOrToday(reassignment.Date)); err != nil {
+			return nil, err
+		}
+		shiftIDs[i] = assignment.ShiftID
 	}
 
-	// Check for overlapping shifts
-	var existingShifts []EmployeeShift
-	err := s.db.Where("employee_id = ? AND shift_id = ? AND ((start_date <= ? AND end_date >= ?) OR (start_date >= ? AND start_date <= ?))",
-		employeeID, shiftID, endDate, startDate, startDate, endDate).Find(&existingShifts).Error
+	var created []EmployeeShift
+	for i, reassignment := range reassignments {
+		date := dayOrToday(reassignment.Date)
+		employeeShift, err := s.createAssignment(tx, EmployeeShift{
+			EmployeeID: reassignment.EmployeeID,
+			ShiftID:    shiftIDs[i],
+			StartDate:  date,
+			EndDate:    date,
+			Source:     reassignment.Source,
+		})
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, *employeeShift)
+	}
+	return created, nil
+}
+
+// RemoveOccurrence takes one occurrence of a shift away from an employee
+// within tx, cutting it out of whichever assignment covers the date
+func (s *employeeService) RemoveOccurrence(tx *gorm.DB, employeeID, shiftID uint, date time.Time) error {
+	date = dayOrToday(date)
+	var assignment EmployeeShift
+	err := tx.Preload("Shift").
+		Where("employee_id = ? AND shift_id = ? AND start_date <= ? AND end_date >= ?", employeeID, shiftID, date, date).
+		First(&assignment).Error
 	if err != nil {
-		log.Printf("Error checking for overlapping shifts: %v", err)
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrOccurrenceNotFound
+		}
+		log.Printf("Error fetching shift assignment: %v", err)
+		return err
+	}
+	return cutOccurrence(tx, assignment, date)
+}
+
+// createAssignment implements CreateAssignment against db, which may be a
+// transaction of the caller
+func (s *employeeService) createAssignment(db *gorm.DB, employeeShift EmployeeShift) (*EmployeeShift, error) {
+	employeeShift.StartDate, employeeShift.EndDate = dateOf(employeeShift.StartDate), dateOf(employeeShift.EndDate)
+	employeeID, shiftID := employeeShift.EmployeeID, employeeShift.ShiftID
+	startDate, endDate := employeeShift.StartDate, employeeShift.EndDate
+	if employeeShift.Source == "" {
+		employeeShift.Source = AssignmentSourceManual
+	}
+
+	var employee Employee
+	if err := db.First(&employee, employeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+
+	var shift Shift
+	if err := db.First(&shift, shiftID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrShiftNotFound
+		}
+		log.Printf("Error fetching shift: %v", err)
+		return nil, err
+	}
+	employeeShift.Shift = shift
+
+	// The same shift twice on a date is a duplicate, never a choice; overlaps
+	// between different shifts are a working time rule checked by the guards
+	var duplicate EmployeeShift
+	err := db.Where("employee_id = ? AND shift_id = ? AND start_date <= ? AND end_date >= ?", employeeID, shiftID, endDate, startDate).
+		First(&duplicate).Error
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s is already assigned from %s to %s", ErrAssignmentRejected,
+			shift.Name, duplicate.StartDate.Format("2006-01-02"), duplicate.EndDate.Format("2006-01-02"))
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		log.Printf("Error checking for duplicate shift assignments: %v", err)
 		return nil, err
 	}
 
-	if len(existingShifts) > 0 {
-		return nil, errors.New("shift overlaps with existing shifts for this employee")
+	if !employee.CanWork() {
+		return nil, fmt.Errorf("%w: employee is %s", ErrAssignmentRejected, employee.EmploymentStatus)
 	}
 
-	// Save the new EmployeeShift record
-	if err := s.db.Create(&employeeShift).Error; err != nil {
+	for _, guard := range s.guards {
+		if err := guard.CheckAssignment(db, employee, employeeShift); err != nil {
+			if errors.Is(err, ErrAssignmentWarning) {
+				employeeShift.Warnings = append(employeeShift.Warnings, err.Error())
+				continue
+			}
+			return nil, err
+		}
+	}
+
+	// Save the new EmployeeShift record, leaving the loaded shift untouched
+	if err := db.Omit("Shift", "Employee").Create(&employeeShift).Error; err != nil {
 		log.Printf("Error assigning shift: %v", err)
 		return nil, err
 	}
 
 	return &employeeShift, nil
 }
+
+// AddAssignmentGuard registers a guard that every new shift assignment must pass
+func (s *employeeService) AddAssignmentGuard(guard AssignmentGuard) {
+	s.guards = append(s.guards, guard)
+}
+
+// AddLifecycleListener registers a listener for hires and terminations
+func (s *employeeService) AddLifecycleListener(listener LifecycleListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// CreateLocation creates a new clinic location
+func (s *employeeService) CreateLocation(location Location) (*Location, error) {
+	if err := validateTimezone(&location); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&location).Error; err != nil {
+		log.Printf("Error creating location: %v", err)
+		return nil, err
+	}
+	return &location, nil
+}
+
+// GetLocations retrieves all locations
+func (s *employeeService) GetLocations() ([]Location, error) {
+	var locations []Location
+	if err := s.db.Find(&locations).Error; err != nil {
+		log.Printf("Error fetching locations: %v", err)
+		return nil, err
+	}
+	return locations, nil
+}
+
+// UpdateLocation updates an existing location
+func (s *employeeService) UpdateLocation(id uint, location Location) (*Location, error) {
+	var existing Location
+	if err := s.db.First(&existing, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrLocationNotFound
+		}
+		log.Printf("Error fetching location: %v", err)
+		return nil, err
+	}
+
+	if err := validateTimezone(&location); err != nil {
+		return nil, err
+	}
+
+	location.ID = existing.ID
+	location.CreatedAt = existing.CreatedAt
+	if err := s.db.Save(&location).Error; err != nil {
+		log.Printf("Error updating location: %v", err)
+		return nil, err
+	}
+	return &location, nil
+}
+
+// DeleteLocation deletes a location by ID
+func (s *employeeService) DeleteLocation(id uint) error {
+	result := s.db.Delete(&Location{}, id)
+	if result.Error != nil {
+		log.Printf("Error deleting location: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLocationNotFound
+	}
+	return nil
+}
+
+// normalizeShift validates a shift's times, days and break. The timezone
+// defaults to the shift's location, or UTC.
+func (s *employeeService) normalizeShift(shift *Shift) error {
+	start, err := parseClock(shift.StartTime)
+	if err != nil {
+		return ErrInvalidShiftTime
+	}
+	end, err := parseClock(shift.EndTime)
+	if err != nil {
+		return ErrInvalidShiftTime
+	}
+	shift.StartTime = fmt.Sprintf("%02d:%02d", int(start.Hours()), int(start.Minutes())%60)
+	shift.EndTime = fmt.Sprintf("%02d:%02d", int(end.Hours()), int(end.Minutes())%60)
+
+	if shift.Timezone == "" && shift.LocationID != nil {
+		var location Location
+		if err := s.db.First(&location, *shift.LocationID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return ErrLocationNotFound
+			}
+			log.Printf("Error fetching location: %v", err)
+			return err
+		}
+		shift.Timezone = location.Timezone
+	}
+	if shift.Timezone == "" {
+		shift.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(shift.Timezone); err != nil {
+		return ErrInvalidTimezone
+	}
+
+	days, err := NormalizeDaysOfWeek(shift.DaysOfWeek)
+	if err != nil {
+		return err
+	}
+	shift.DaysOfWeek = days
+
+	if shift.BreakMinutes < 0 || time.Duration(shift.BreakMinutes)*time.Minute >= shift.Duration() {
+		return ErrInvalidBreak
+	}
+	return nil
+}
+
+// NormalizeDaysOfWeek turns a comma-separated list of day names into the
+// canonical "mon,tue,..." form. An empty list means every day.
+func NormalizeDaysOfWeek(days string) (string, error) {
+	if strings.TrimSpace(days) == "" {
+		return "mon,tue,wed,thu,fri,sat,sun", nil
+	}
+
+	selected := make(map[string]bool)
+	for _, day := range strings.Split(days, ",") {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if len(day) > 3 {
+			day = day[:3]
+		}
+		known := false
+		for _, name := range weekdayNames {
+			known = known || name == day
+		}
+		if !known {
+			return "", ErrInvalidDaysOfWeek
+		}
+		selected[day] = true
+	}
+
+	var ordered []string
+	for _, name := range []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"} {
+		if selected[name] {
+			ordered = append(ordered, name)
+		}
+	}
+	return strings.Join(ordered, ","), nil
+}
+
+// cutOccurrence removes the occurrence on date from an assignment. The
+// assignment is deleted when that was its only day, shortened when the date is
+// its first or last day, and otherwise split in two around the date.
+func cutOccurrence(tx *gorm.DB, assignment EmployeeShift, date time.Time) error {
+	start, end := dayOrToday(assignment.StartDate), dayOrToday(assignment.EndDate)
+	if date.Before(start) || date.After(end) {
+		return ErrOccurrenceNotFound
+	}
+	if _, ok := assignment.Shift.OccurrenceOn(date); !ok {
+		return ErrOccurrenceNotFound
+	}
+
+	var err error
+	switch {
+	case start.Equal(end):
+		// Removed for good so the day can be assigned to the employee again
+		err = tx.Unscoped().Delete(&assignment).Error
+	case date.Equal(start):
+		err = tx.Model(&assignment).UpdateColumn("start_date", date.AddDate(0, 0, 1)).Error
+	case date.Equal(end):
+		err = tx.Model(&assignment).UpdateColumn("end_date", date.AddDate(0, 0, -1)).Error
+	default:
+		rest := EmployeeShift{
+			EmployeeID:           assignment.EmployeeID,
+			ShiftID:              assignment.ShiftID,
+			StartDate:            date.AddDate(0, 0, 1),
+			EndDate:              end,
+			Source:               assignment.Source,
+			RotationAssignmentID: assignment.RotationAssignmentID,
+		}
+		if err = tx.Model(&assignment).UpdateColumn("end_date", date.AddDate(0, 0, -1)).Error; err == nil {
+			err = tx.Omit("Shift", "Employee").Create(&rest).Error
+		}
+	}
+	if err != nil {
+		log.Printf("Error cutting shift assignment %d on %s: %v", assignment.ID, date.Format("2006-01-02"), err)
+	}
+	return err
+}
+
+// validateTimezone defaults an empty timezone to UTC and rejects unknown ones
+func validateTimezone(location *Location) error {
+	if location.Timezone == "" {
+		location.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(location.Timezone); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTimezone, location.Timezone)
+	}
+	return nil
+}
+
+func canTransition(from, to string) bool {
+	for _, allowed := range employmentTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func recordEmploymentEvent(tx *gorm.DB, employeeID uint, from, to string, effectiveDate time.Time, reason string) error {
+	event := EmploymentEvent{
+		EmployeeID:    employeeID,
+		FromStatus:    from,
+		ToStatus:      to,
+		EffectiveDate: effectiveDate,
+		Reason:        reason,
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		log.Printf("Error recording employment event: %v", err)
+		return err
+	}
+	return nil
+}
+
+// dayOrToday returns the calendar date of a date, defaulting to today's UTC
+// date when unset
+func dayOrToday(date time.Time) time.Time {
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+	return dateOf(date)
+}
+
+// dateOf returns the calendar date of t, in t's own timezone, as a UTC
+// midnight: the form dates are stored and compared in
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,252 @@
+package employee
+
+import (
+	"clinicplus/internal/shared/testdb"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+func TestCutOccurrence(t *testing.T) {
+	weekdays := Shift{Model: gorm.Model{ID: 3}, StartTime: "09:00", EndTime: "17:00", Timezone: "UTC", DaysOfWeek: "mon,tue,wed,thu,fri"}
+	rotation := uint(12)
+
+	tests := []struct {
+		name       string
+		start, end int
+		date       int
+		want       []string        // Fragments of each statement, in order
+		args       [][]interface{} // Arguments of each statement that must be present
+		wantErr    error
+	}{
+		{
+			name: "only day deletes the assignment for good", start: 4, end: 4, date: 4,
+			want: []string{`DELETE FROM "employee_shifts" WHERE "employee_shifts"."id" = $1`},
+			args: [][]interface{}{{int64(7)}},
+		},
+		{
+			name: "first day moves the start", start: 4, end: 8, date: 4,
+			want: []string{`UPDATE "employee_shifts" SET "start_date" = $1 WHERE`},
+			args: [][]interface{}{{day(5), int64(7)}},
+		},
+		{
+			name: "last day moves the end", start: 4, end: 8, date: 8,
+			want: []string{`UPDATE "employee_shifts" SET "end_date" = $1 WHERE`},
+			args: [][]interface{}{{day(7), int64(7)}},
+		},
+		{
+			name: "a day in the middle splits the assignment", start: 4, end: 15, date: 6,
+			want: []string{
+				`UPDATE "employee_shifts" SET "end_date" = $1 WHERE`,
+				`INSERT INTO "employee_shifts"`,
+			},
+			args: [][]interface{}{{day(5), int64(7)}, {int64(1), int64(3), day(7), day(15), "rotation", int64(rotation)}},
+		},
+		{
+			name: "before the assignment", start: 4, end: 8, date: 1,
+			wantErr: ErrOccurrenceNotFound,
+		},
+		{
+			name: "after the assignment", start: 4, end: 8, date: 11,
+			wantErr: ErrOccurrenceNotFound,
+		},
+		{
+			name: "a day the shift does not run", start: 4, end: 15, date: 9,
+			wantErr: ErrOccurrenceNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			assignment := EmployeeShift{
+				Model:                gorm.Model{ID: 7},
+				EmployeeID:           1,
+				ShiftID:              weekdays.ID,
+				StartDate:            day(tt.start),
+				EndDate:              day(tt.end),
+				Source:               "rotation",
+				RotationAssignmentID: &rotation,
+				Shift:                weekdays,
+			}
+
+			err := cutOccurrence(db, assignment, day(tt.date))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("cutOccurrence error %v, want %v", err, tt.wantErr)
+			}
+
+			statements := fake.Statements()
+			if len(statements) != len(tt.want) {
+				var queries []string
+				for _, s := range statements {
+					queries = append(queries, s.Query)
+				}
+				t.Fatalf("ran %d statements, want %d:\n%s", len(statements), len(tt.want), strings.Join(queries, "\n"))
+			}
+			for i, fragment := range tt.want {
+				got := statements[i]
+				if !strings.Contains(got.Query, fragment) {
+					t.Errorf("statement %d is %q, want it to contain %q", i, got.Query, fragment)
+				}
+				for _, arg := range tt.args[i] {
+					if !got.Has(arg) {
+						t.Errorf("statement %d %q lacks argument %v (has %v)", i, got.Query, arg, got.Args)
+					}
+				}
+			}
+		})
+	}
+}
+
+// lifecycle records the employees its LifecycleListener methods are called with
+type lifecycle struct {
+	terminated []Employee
+}
+
+func (l *lifecycle) EmployeeHired(tx *gorm.DB, employee Employee) error { return nil }
+
+func (l *lifecycle) EmployeeTerminated(tx *gorm.DB, employee Employee) error {
+	l.terminated = append(l.terminated, employee)
+	return nil
+}
+
+var employeeColumns = []string{"id", "employment_status", "termination_date", "termination_reason"}
+
+func TestTerminateEmployee(t *testing.T) {
+	today := dayOrToday(time.Time{})
+
+	tests := []struct {
+		name string
+		date time.Time
+		now  bool // Whether the termination takes effect at once
+	}{
+		{"today", today, true},
+		{"a past date", today.AddDate(0, 0, -3), true},
+		{"a future date is only recorded", today.AddDate(0, 0, 14), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "employees"`, employeeColumns, []interface{}{5, EmploymentStatusActive, nil, ""})
+			listener := &lifecycle{}
+			service := NewEmployeeService(db, AttendancePolicy{})
+			service.AddLifecycleListener(listener)
+
+			employee, err := service.TerminateEmployee(5, tt.date, "Resigned")
+			if err != nil {
+				t.Fatalf("TerminateEmployee: %v", err)
+			}
+			if !employee.TerminationDate.Valid || !employee.TerminationDate.Time.Equal(tt.date) || employee.TerminationReason != "Resigned" {
+				t.Errorf("termination recorded as %v %q", employee.TerminationDate, employee.TerminationReason)
+			}
+			if !fake.Ran(`UPDATE "employees"`) {
+				t.Error("termination date was not saved")
+			}
+
+			wantStatus := EmploymentStatusActive
+			if tt.now {
+				wantStatus = EmploymentStatusTerminated
+			}
+			if employee.EmploymentStatus != wantStatus {
+				t.Errorf("status %q, want %q", employee.EmploymentStatus, wantStatus)
+			}
+			checks := []struct {
+				what string
+				done bool
+			}{
+				{"status changed", fake.Ran(`"employment_status" =`)},
+				{"assignments cut", fake.Ran(`"employee_shifts"`)},
+				{"employment event recorded", fake.Ran(`INSERT INTO "employment_events"`)},
+				{"listeners run", len(listener.terminated) > 0},
+			}
+			for _, check := range checks {
+				if check.done != tt.now {
+					t.Errorf("%s: %v, want %v", check.what, check.done, tt.now)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyScheduledTerminations(t *testing.T) {
+	db, fake := testdb.New(t)
+	now := time.Date(2024, time.March, 4, 0, 5, 0, 0, time.UTC)
+	fake.Returns(`FROM "employees"`, employeeColumns,
+		[]interface{}{5, EmploymentStatusActive, now.AddDate(0, 0, -1), "Resigned"},
+		[]interface{}{6, EmploymentStatusSuspended, now, "Dismissed"},
+	)
+	listener := &lifecycle{}
+	service := NewEmployeeService(db, AttendancePolicy{})
+	service.AddLifecycleListener(listener)
+
+	terminated, err := service.ApplyScheduledTerminations(now)
+	if err != nil {
+		t.Fatalf("ApplyScheduledTerminations: %v", err)
+	}
+	if terminated != 2 {
+		t.Errorf("terminated %d employees, want 2", terminated)
+	}
+
+	selects := fake.Find(`FROM "employees"`)
+	if len(selects) != 1 || !selects[0].Has(day(4)) || !selects[0].Has(EmploymentStatusTerminated) {
+		t.Errorf("due employees fetched with %+v", selects)
+	}
+	if len(listener.terminated) != 2 {
+		t.Fatalf("listeners ran for %d employees, want 2", len(listener.terminated))
+	}
+	for _, employee := range listener.terminated {
+		if employee.EmploymentStatus != EmploymentStatusTerminated {
+			t.Errorf("employee %d passed to listeners as %q", employee.ID, employee.EmploymentStatus)
+		}
+	}
+	if events := fake.Find(`INSERT INTO "employment_events"`); len(events) != 2 || !events[0].Has(day(3)) || !events[0].Has("Resigned") {
+		t.Errorf("employment events %+v, want one per employee effective on the scheduled date", events)
+	}
+}
+
+func TestOverrideClockIn(t *testing.T) {
+	tests := []struct {
+		status  string
+		allowed bool
+	}{
+		{EmploymentStatusActive, true},
+		{EmploymentStatusProbation, true},
+		{EmploymentStatusSuspended, false},
+		{EmploymentStatusOnLeave, false},
+		{EmploymentStatusTerminated, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "employees"`, []string{"id", "name", "employment_status"}, []interface{}{5, "Ana", tt.status})
+			fake.Returns(`FROM "shifts"`, []string{"id", "name", "start_time", "end_time", "days_of_week"},
+				[]interface{}{2, "Day", "08:00", "16:00", "mon,tue,wed,thu,fri,sat,sun"})
+			service := NewEmployeeService(db, AttendancePolicy{})
+
+			attendance, err := service.OverrideClockIn(5, 2, "Covering for a colleague", 30)
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("OverrideClockIn: %v", err)
+				}
+				if attendance.OverrideReason != "Covering for a colleague" || attendance.OverriddenBy == nil || *attendance.OverriddenBy != 30 {
+					t.Errorf("override recorded as %q by %v", attendance.OverrideReason, attendance.OverriddenBy)
+				}
+			} else if !errors.Is(err, ErrClockInRejected) || !strings.Contains(err.Error(), tt.status) {
+				t.Fatalf("OverrideClockIn error %v, want a rejection naming the status", err)
+			}
+			if recorded := fake.Ran(`INSERT INTO "attendances"`); recorded != tt.allowed {
+				t.Errorf("attendance recorded: %v, want %v", recorded, tt.allowed)
+			}
+		})
+	}
+}
+
+// day returns a date in March 2024; the 4th is a Monday
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+}
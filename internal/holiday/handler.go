@@ -0,0 +1,183 @@
+// internal/holiday/handler.go
+package holiday
+
+import (
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type HolidayHandler struct {
+	service HolidayService
+}
+
+func NewHolidayHandler(service HolidayService) *HolidayHandler {
+	return &HolidayHandler{service: service}
+}
+
+func (h *HolidayHandler) GetHolidays(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := Filter{
+		Country: query.Get("country"),
+		State:   query.Get("state"),
+	}
+
+	if locationStr := query.Get("location_id"); locationStr != "" {
+		locationID, err := strconv.ParseUint(locationStr, 10, 32)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid location ID", nil)
+			return
+		}
+		filter.LocationID = uint(locationID)
+	}
+	if yearStr := query.Get("year"); yearStr != "" {
+		year, err := strconv.Atoi(yearStr)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid year", nil)
+			return
+		}
+		filter.Year = year
+	}
+
+	holidays, err := h.service.GetHolidays(filter)
+	if err != nil {
+		log.Printf("Error fetching holidays: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve holidays", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, holidays, nil, nil)
+}
+
+func (h *HolidayHandler) CreateHoliday(w http.ResponseWriter, r *http.Request) {
+	var holiday Holiday
+	if err := json.NewDecoder(r.Body).Decode(&holiday); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreateHoliday(holiday)
+	if err != nil {
+		sendHolidayError(w, err, "Failed to create holiday")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *HolidayHandler) UpdateHoliday(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid holiday ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid holiday ID", nil)
+		return
+	}
+
+	var holiday Holiday
+	if err := json.NewDecoder(r.Body).Decode(&holiday); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateHoliday(uint(id), holiday)
+	if err != nil {
+		sendHolidayError(w, err, "Failed to update holiday")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
+
+func (h *HolidayHandler) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid holiday ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid holiday ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteHoliday(uint(id)); err != nil {
+		sendHolidayError(w, err, "Failed to delete holiday")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Holiday deleted successfully",
+	})
+}
+
+// ImportICalendar accepts an .ics file, either as the "file" field of a
+// multipart form or as a raw text/calendar body. The scope comes from the
+// country, state, location_id, pay_multiplier and working_day query
+// parameters.
+func (h *HolidayHandler) ImportICalendar(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	scope := Holiday{
+		Country: query.Get("country"),
+		State:   query.Get("state"),
+	}
+	if locationStr := query.Get("location_id"); locationStr != "" {
+		locationID, err := strconv.ParseUint(locationStr, 10, 32)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid location ID", nil)
+			return
+		}
+		id := uint(locationID)
+		scope.LocationID = &id
+	}
+	if multiplierStr := query.Get("pay_multiplier"); multiplierStr != "" {
+		multiplier, err := strconv.ParseFloat(multiplierStr, 64)
+		if err != nil || multiplier <= 0 {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid pay multiplier", nil)
+			return
+		}
+		scope.PayMultiplier = multiplier
+	}
+	if workingDayStr := query.Get("working_day"); workingDayStr != "" {
+		workingDay, err := strconv.ParseBool(workingDayStr)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid working day flag", nil)
+			return
+		}
+		scope.WorkingDay = workingDay
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 5<<20)
+	var body io.Reader = r.Body
+	if file, _, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		body = file
+	}
+
+	result, err := h.service.ImportICalendar(body, scope)
+	if err != nil {
+		if err == ErrMissingScope {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+		} else {
+			log.Printf("Error importing holidays: %v", err)
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Failed to import calendar: "+err.Error(), nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, result, nil, nil)
+}
+
+func sendHolidayError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case ErrHolidayNotFound:
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, "Holiday not found", nil)
+	case ErrMissingScope, ErrInvalidHoliday:
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
@@ -0,0 +1,28 @@
+package holiday
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	SourceManual    = "manual"
+	SourceICalendar = "ical"
+)
+
+// Holiday is a public holiday observed in a country, optionally narrowed to a
+// state or a single location. A holiday with only a location applies to that
+// location regardless of country.
+type Holiday struct {
+	gorm.Model
+	Name          string    `json:"name" gorm:"not null"`
+	Date          time.Time `json:"date" gorm:"type:date;not null;index"`
+	Country       string    `json:"country"`
+	State         string    `json:"state"`                                    // Empty for nationwide holidays
+	LocationID    *uint     `json:"location_id"`                              // Set for site-specific closures
+	PayMultiplier float64   `json:"pay_multiplier" gorm:"not null;default:1"` // Premium applied to hours worked on the holiday
+	WorkingDay    bool      `json:"working_day"`                              // Staff scheduled on the day are still expected to attend
+	Source        string    `json:"source" gorm:"not null;default:'manual'"`
+	ExternalUID   string    `json:"-" gorm:"index"` // iCalendar UID, used to update re-imported events
+}
@@ -0,0 +1,285 @@
+// internal/holiday/service.go
+package holiday
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/pkg/ical"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrHolidayNotFound = errors.New("holiday not found")
+	ErrMissingScope    = errors.New("holiday needs a country or a location")
+	ErrInvalidHoliday  = errors.New("holiday name and date are required")
+)
+
+// Filter narrows holiday listings; zero values match everything
+type Filter struct {
+	Country    string
+	State      string
+	LocationID uint
+	Year       int
+}
+
+// ImportResult summarizes an iCalendar import
+type ImportResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"` // Cancelled events
+	Removed int `json:"removed"` // Holidays of earlier imports whose event was cancelled
+}
+
+type HolidayService interface {
+	CreateHoliday(holiday Holiday) (*Holiday, error)
+	GetHolidays(filter Filter) ([]Holiday, error)
+	UpdateHoliday(id uint, holiday Holiday) (*Holiday, error)
+	DeleteHoliday(id uint) error
+	ImportICalendar(r io.Reader, scope Holiday) (*ImportResult, error)
+
+	HolidaysFor(emp employee.Employee, from, to time.Time) ([]Holiday, error)
+	HolidayOn(emp employee.Employee, date time.Time) (*Holiday, error)
+}
+
+type holidayService struct {
+	db *gorm.DB
+}
+
+func NewHolidayService(db *gorm.DB) HolidayService {
+	return &holidayService{db: db}
+}
+
+func (s *holidayService) CreateHoliday(holiday Holiday) (*Holiday, error) {
+	if err := normalize(&holiday); err != nil {
+		return nil, err
+	}
+	holiday.Source = SourceManual
+
+	if err := s.db.Create(&holiday).Error; err != nil {
+		log.Printf("Error creating holiday: %v", err)
+		return nil, err
+	}
+	return &holiday, nil
+}
+
+func (s *holidayService) GetHolidays(filter Filter) ([]Holiday, error) {
+	var holidays []Holiday
+	query := s.db
+	if filter.Country != "" {
+		query = query.Where("LOWER(country) = ?", strings.ToLower(filter.Country))
+	}
+	if filter.State != "" {
+		query = query.Where("state = '' OR LOWER(state) = ?", strings.ToLower(filter.State))
+	}
+	if filter.LocationID != 0 {
+		query = query.Where("location_id IS NULL OR location_id = ?", filter.LocationID)
+	}
+	if filter.Year != 0 {
+		start := time.Date(filter.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		query = query.Where("date >= ? AND date < ?", start, start.AddDate(1, 0, 0))
+	}
+
+	if err := query.Order("date").Find(&holidays).Error; err != nil {
+		log.Printf("Error fetching holidays: %v", err)
+		return nil, err
+	}
+	return holidays, nil
+}
+
+func (s *holidayService) UpdateHoliday(id uint, holiday Holiday) (*Holiday, error) {
+	var existing Holiday
+	if err := s.db.First(&existing, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrHolidayNotFound
+		}
+		log.Printf("Error fetching holiday: %v", err)
+		return nil, err
+	}
+
+	if err := normalize(&holiday); err != nil {
+		return nil, err
+	}
+	existing.Name = holiday.Name
+	existing.Date = holiday.Date
+	existing.Country = holiday.Country
+	existing.State = holiday.State
+	existing.LocationID = holiday.LocationID
+	existing.PayMultiplier = holiday.PayMultiplier
+	existing.WorkingDay = holiday.WorkingDay
+
+	if err := s.db.Save(&existing).Error; err != nil {
+		log.Printf("Error updating holiday: %v", err)
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (s *holidayService) DeleteHoliday(id uint) error {
+	result := s.db.Delete(&Holiday{}, id)
+	if result.Error != nil {
+		log.Printf("Error deleting holiday: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrHolidayNotFound
+	}
+	return nil
+}
+
+// ImportICalendar loads the events of an iCalendar file as holidays in the
+// given scope. Multi-day events become one holiday per day. Events already
+// imported into the same scope (matched by UID and date) are updated instead
+// of duplicated, so a calendar can be re-imported after it changes. Cancelled
+// events are skipped, and the holidays imported from them before removed.
+func (s *holidayService) ImportICalendar(r io.Reader, scope Holiday) (*ImportResult, error) {
+	scope.Name = "import"
+	scope.Date = time.Now()
+	if err := normalize(&scope); err != nil {
+		return nil, err
+	}
+
+	events, err := ical.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	result := &ImportResult{}
+	for _, event := range events {
+		if event.Status == ical.StatusCancelled {
+			result.Skipped++
+			if event.UID == "" {
+				continue
+			}
+			query := tx.Unscoped().Where("external_uid = ? AND country = ? AND state = ?", event.UID, scope.Country, scope.State)
+			if scope.LocationID != nil {
+				query = query.Where("location_id = ?", *scope.LocationID)
+			} else {
+				query = query.Where("location_id IS NULL")
+			}
+			removed := query.Delete(&Holiday{})
+			if removed.Error != nil {
+				tx.Rollback()
+				log.Printf("Error removing cancelled holiday %q: %v", event.Summary, removed.Error)
+				return nil, removed.Error
+			}
+			result.Removed += int(removed.RowsAffected)
+			continue
+		}
+		start := time.Date(event.Start.Year(), event.Start.Month(), event.Start.Day(), 0, 0, 0, 0, time.UTC)
+		end := time.Date(event.End.Year(), event.End.Month(), event.End.Day(), 0, 0, 0, 0, time.UTC)
+		if !end.After(start) {
+			end = start.AddDate(0, 0, 1)
+		}
+
+		for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+			holiday := Holiday{
+				Name:          event.Summary,
+				Date:          day,
+				Country:       scope.Country,
+				State:         scope.State,
+				LocationID:    scope.LocationID,
+				PayMultiplier: scope.PayMultiplier,
+				WorkingDay:    scope.WorkingDay,
+				Source:        SourceICalendar,
+				ExternalUID:   event.UID,
+			}
+
+			var existing Holiday
+			query := tx.Where("external_uid = ? AND date = ? AND country = ? AND state = ?", event.UID, day, scope.Country, scope.State)
+			if scope.LocationID != nil {
+				query = query.Where("location_id = ?", *scope.LocationID)
+			} else {
+				query = query.Where("location_id IS NULL")
+			}
+
+			err := query.First(&existing).Error
+			switch {
+			case event.UID != "" && err == nil:
+				existing.Name = holiday.Name
+				existing.PayMultiplier = holiday.PayMultiplier
+				existing.WorkingDay = holiday.WorkingDay
+				err = tx.Save(&existing).Error
+				result.Updated++
+			case event.UID == "" || gorm.IsRecordNotFoundError(err):
+				err = tx.Create(&holiday).Error
+				result.Created++
+			}
+			if err != nil {
+				tx.Rollback()
+				log.Printf("Error importing holiday %q: %v", event.Summary, err)
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// HolidaysFor returns the holidays observed by an employee between from and to
+// (inclusive): those of their country and state, and of their location
+func (s *holidayService) HolidaysFor(emp employee.Employee, from, to time.Time) ([]Holiday, error) {
+	country, state := emp.Country, emp.State
+	if emp.LocationID != nil && country == "" {
+		var location employee.Location
+		if err := s.db.First(&location, *emp.LocationID).Error; err == nil {
+			country, state = location.Country, location.State
+		}
+	}
+
+	var locationID uint
+	if emp.LocationID != nil {
+		locationID = *emp.LocationID
+	}
+
+	var holidays []Holiday
+	err := s.db.Where("date BETWEEN ? AND ?", from, to).
+		Where("(location_id = ?) OR (location_id IS NULL AND country <> '' AND LOWER(country) = ? AND (state = '' OR LOWER(state) = ?))",
+			locationID, strings.ToLower(country), strings.ToLower(state)).
+		Order("date").
+		Find(&holidays).Error
+	if err != nil {
+		log.Printf("Error fetching holidays for employee %d: %v", emp.ID, err)
+		return nil, err
+	}
+	return holidays, nil
+}
+
+// HolidayOn returns the holiday an employee observes on the given date, or nil
+func (s *holidayService) HolidayOn(emp employee.Employee, date time.Time) (*Holiday, error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	holidays, err := s.HolidaysFor(emp, day, day)
+	if err != nil || len(holidays) == 0 {
+		return nil, err
+	}
+	return &holidays[0], nil
+}
+
+// normalize validates a holiday's scope and fills defaults
+func normalize(holiday *Holiday) error {
+	if holiday.Country == "" && holiday.LocationID == nil {
+		return ErrMissingScope
+	}
+	if holiday.Name == "" || holiday.Date.IsZero() {
+		return ErrInvalidHoliday
+	}
+	if holiday.PayMultiplier == 0 {
+		holiday.PayMultiplier = 1
+	}
+	holiday.Date = time.Date(holiday.Date.Year(), holiday.Date.Month(), holiday.Date.Day(), 0, 0, 0, 0, time.UTC)
+	return nil
+}
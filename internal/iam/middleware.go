@@ -0,0 +1,64 @@
+// internal/iam/middleware.go
+package iam
+
+import (
+	"clinicplus/internal/shared/utils"
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type contextKey string
+
+const userContextKey contextKey = "iam.user"
+
+// RequireAuth rejects requests without a valid bearer token and stores the
+// authenticated user in the request context
+func RequireAuth(service AuthService) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := service.Authenticate(r.Header.Get("Authorization"))
+			if err != nil {
+				utils.SendJSONResponse(w, http.StatusUnauthorized, nil, "Authentication required", nil)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userContextKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// UserFromContext returns the user stored by RequireAuth
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userContextKey).(*User)
+	return user, ok
+}
+
+// CanAccessEmployee reports whether the user may read or change records owned by the given employee
+func CanAccessEmployee(user *User, employeeID uint) bool {
+	return user != nil && (user.IsPrivileged() || user.EmployeeID == employeeID)
+}
+
+// RequireRole rejects requests from users without one of the given roles.
+// It must run after RequireAuth.
+func RequireRole(roles ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				utils.SendJSONResponse(w, http.StatusUnauthorized, nil, "Authentication required", nil)
+				return
+			}
+
+			for _, role := range roles {
+				if user.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			utils.SendJSONResponse(w, http.StatusForbidden, nil, "Insufficient permissions", nil)
+		})
+	}
+}
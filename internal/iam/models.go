@@ -4,10 +4,22 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+const (
+	RoleAdmin    = "Admin"
+	RoleManager  = "Manager"
+	RoleEmployee = "Employee"
+)
+
 type User struct {
 	gorm.Model
 	EmployeeID   uint   `json:"employee_id"`
 	Username     string `json:"username" gorm:"unique"`
 	PasswordHash string `json:"password_hash"`
 	Role         string `json:"role"` // e.g., Admin, Manager, Employee
+	Disabled     bool   `json:"disabled"`
+}
+
+// IsPrivileged reports whether the user may act on other employees' records
+func (u User) IsPrivileged() bool {
+	return u.Role == RoleAdmin || u.Role == RoleManager
 }
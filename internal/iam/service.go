@@ -3,7 +3,9 @@ package iam
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -14,6 +16,7 @@ import (
 type AuthService interface {
 	Login(username, password string) (string, string, time.Time, error)
 	Logout(token string) error
+	Authenticate(token string) (*User, error)
 }
 
 type authService struct {
@@ -39,6 +42,11 @@ func (s *authService) Login(username, password string) (string, string, time.Tim
 		return "", "", time.Time{}, errors.New("invalid username or password")
 	}
 
+	if user.Disabled {
+		log.Printf("Login attempt for disabled user: %s\n", username)
+		return "", "", time.Time{}, errors.New("invalid username or password")
+	}
+
 	// Compare password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		log.Printf("Invalid password for user: %s\n", username)
@@ -71,3 +79,30 @@ func (s *authService) Logout(token string) error {
 	log.Printf("Logout attempt with token: %s\n", token)
 	return nil
 }
+
+// Authenticate validates a bearer token and returns the user it was issued to
+func (s *authService) Authenticate(token string) (*User, error) {
+	tokenString := strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+	if tokenString == "" {
+		return nil, errors.New("missing authentication token")
+	}
+
+	claims := &Claims{}
+	parsed, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return s.jwtKey, nil
+	})
+	if err != nil || !parsed.Valid {
+		return nil, errors.New("invalid authentication token")
+	}
+
+	var user User
+	if err := s.db.Where("username = ? AND disabled = ?", claims.Username, false).First(&user).Error; err != nil {
+		log.Printf("Token presented for unknown or disabled user: %s\n", claims.Username)
+		return nil, errors.New("invalid authentication token")
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,311 @@
+// internal/leave/handler.go
+package leave
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type LeaveHandler struct {
+	service LeaveService
+}
+
+func NewLeaveHandler(service LeaveService) *LeaveHandler {
+	return &LeaveHandler{service: service}
+}
+
+func (h *LeaveHandler) CreateLeaveType(w http.ResponseWriter, r *http.Request) {
+	var leaveType LeaveType
+	if err := json.NewDecoder(r.Body).Decode(&leaveType); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreateLeaveType(leaveType)
+	if err != nil {
+		log.Printf("Error creating leave type: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to create leave type", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *LeaveHandler) GetLeaveTypes(w http.ResponseWriter, r *http.Request) {
+	leaveTypes, err := h.service.GetLeaveTypes()
+	if err != nil {
+		log.Printf("Error fetching leave types: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve leave types", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, leaveTypes, nil, nil)
+}
+
+func (h *LeaveHandler) UpdateLeaveType(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid leave type ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid leave type ID", nil)
+		return
+	}
+
+	var leaveType LeaveType
+	if err := json.NewDecoder(r.Body).Decode(&leaveType); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateLeaveType(uint(id), leaveType)
+	if err != nil {
+		if err == ErrLeaveTypeNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Leave type not found", nil)
+		} else {
+			log.Printf("Error updating leave type: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to update leave type", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
+
+func (h *LeaveHandler) DeleteLeaveType(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid leave type ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid leave type ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteLeaveType(uint(id)); err != nil {
+		if err == ErrLeaveTypeNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Leave type not found", nil)
+		} else {
+			log.Printf("Error deleting leave type: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to delete leave type", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Leave type deleted successfully",
+	})
+}
+
+func (h *LeaveHandler) GetBalances(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	balances, err := h.service.GetBalances(employeeID)
+	if err != nil {
+		log.Printf("Error fetching leave balances: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve leave balances", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, balances, nil, nil)
+}
+
+func (h *LeaveHandler) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	employeeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	var request struct {
+		LeaveTypeID uint    `json:"leave_type_id"`
+		Days        float64 `json:"days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	balance, err := h.service.AdjustBalance(uint(employeeID), request.LeaveTypeID, request.Days)
+	if err != nil {
+		if err == employee.ErrEmployeeNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Employee not found", nil)
+		} else {
+			log.Printf("Error adjusting leave balance: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to adjust leave balance", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, balance, nil, nil)
+}
+
+func (h *LeaveHandler) RequestLeave(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	var request LeaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, conflicts, err := h.service.RequestLeave(employeeID, request)
+	if err != nil {
+		sendLeaveError(w, err, "Failed to request leave")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, map[string]interface{}{
+		"conflicting_shifts": conflicts,
+	})
+}
+
+func (h *LeaveHandler) GetLeaveRequests(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := authorizeEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	requests, err := h.service.GetLeaveRequests(employeeID)
+	if err != nil {
+		log.Printf("Error fetching leave requests: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve leave requests", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, requests, nil, nil)
+}
+
+func (h *LeaveHandler) GetPendingRequests(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+
+	requests, err := h.service.GetPendingRequests(user)
+	if err != nil {
+		log.Printf("Error fetching pending leave requests: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve pending leave requests", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, requests, nil, nil)
+}
+
+func (h *LeaveHandler) ApproveRequest(w http.ResponseWriter, r *http.Request) {
+	id, note, ok := decisionRequest(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, conflicts, err := h.service.ApproveRequest(id, user, note)
+	if err != nil {
+		sendLeaveError(w, err, "Failed to approve leave request")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, map[string]interface{}{
+		"conflicting_shifts": conflicts,
+	})
+}
+
+func (h *LeaveHandler) RejectRequest(w http.ResponseWriter, r *http.Request) {
+	id, note, ok := decisionRequest(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, err := h.service.RejectRequest(id, user, note)
+	if err != nil {
+		sendLeaveError(w, err, "Failed to reject leave request")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, nil)
+}
+
+func (h *LeaveHandler) CancelRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid leave request ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid leave request ID", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, err := h.service.CancelRequest(uint(id), user)
+	if err != nil {
+		sendLeaveError(w, err, "Failed to cancel leave request")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, nil)
+}
+
+// decisionRequest parses the request ID and optional note for approve/reject
+func decisionRequest(w http.ResponseWriter, r *http.Request) (uint, string, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid leave request ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid leave request ID", nil)
+		return 0, "", false
+	}
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+			return 0, "", false
+		}
+	}
+	return uint(id), body.Note, true
+}
+
+// authorizeEmployee parses the employee ID from the path and checks the
+// authenticated user may act for that employee
+func authorizeEmployee(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	employeeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return 0, false
+	}
+
+	user, _ := iam.UserFromContext(r.Context())
+	if !iam.CanAccessEmployee(user, uint(employeeID)) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's leave", nil)
+		return 0, false
+	}
+	return uint(employeeID), true
+}
+
+func sendLeaveError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case employee.ErrEmployeeNotFound, ErrLeaveRequestNotFound:
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case ErrLeaveTypeNotFound, ErrInvalidLeavePeriod, ErrNoWorkingDays:
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case ErrInsufficientBalance, ErrOverlappingLeave, ErrInvalidStatus, ErrTimesheetSignedOff:
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	case ErrNotApprover:
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
@@ -0,0 +1,64 @@
+package leave
+
+import (
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	AccrualNone    = "none"    // Balance is only changed by manual adjustments
+	AccrualMonthly = "monthly" // AccrualDays are credited at the start of each month
+	AccrualAnnual  = "annual"  // AccrualDays are credited at the start of each year
+)
+
+const (
+	StatusPending   = "Pending"
+	StatusApproved  = "Approved"
+	StatusRejected  = "Rejected"
+	StatusCancelled = "Cancelled"
+)
+
+type LeaveType struct {
+	gorm.Model
+	Name          string  `json:"name" gorm:"unique;not null"` // e.g., Vacation, Sick, Parental
+	Paid          bool    `json:"paid"`
+	AccrualPolicy string  `json:"accrual_policy" gorm:"not null;default:'none'"`
+	AccrualDays   float64 `json:"accrual_days"`   // Days credited per accrual period
+	MaxBalance    float64 `json:"max_balance"`    // Cap on accrued days, 0 for no cap
+	AllowNegative bool    `json:"allow_negative"` // Allow requests beyond the available balance
+}
+
+type LeaveBalance struct {
+	gorm.Model
+	EmployeeID        uint    `gorm:"not null;unique_index:idx_leave_balance" json:"employee_id"`
+	LeaveTypeID       uint    `gorm:"not null;unique_index:idx_leave_balance" json:"leave_type_id"`
+	Accrued           float64 `json:"accrued"` // Days credited by accrual and adjustments
+	Used              float64 `json:"used"`    // Days taken by approved requests
+	LastAccrualPeriod string  `json:"-"`       // e.g., 2025-03 or 2025; guards against double accrual
+
+	LeaveType LeaveType `gorm:"foreignkey:LeaveTypeID" json:"leave_type"`
+}
+
+// Available returns the days that can still be requested
+func (b LeaveBalance) Available() float64 {
+	return b.Accrued - b.Used
+}
+
+type LeaveRequest struct {
+	gorm.Model
+	EmployeeID   uint           `gorm:"not null;index" json:"employee_id"`
+	LeaveTypeID  uint           `gorm:"not null" json:"leave_type_id"`
+	StartDate    time.Time      `gorm:"type:date;not null" json:"start_date"`
+	EndDate      time.Time      `gorm:"type:date;not null" json:"end_date"`
+	Days         float64        `json:"days"` // Working days covered by the request
+	Reason       string         `json:"reason"`
+	Status       string         `gorm:"not null;index" json:"status"`
+	ApproverID   *uint          `json:"approver_id"` // Employee expected to decide; nil means any admin
+	DecidedBy    *uint          `json:"decided_by"`  // iam.User who approved or rejected
+	DecidedAt    utils.NullTime `json:"decided_at"`
+	DecisionNote string         `json:"decision_note"`
+
+	LeaveType LeaveType `gorm:"foreignkey:LeaveTypeID" json:"leave_type"`
+}
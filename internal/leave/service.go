@@ -0,0 +1,636 @@
+// internal/leave/service.go
+package leave
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/holiday"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/utils"
+	"clinicplus/internal/timesheet"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrLeaveTypeNotFound    = errors.New("leave type not found")
+	ErrLeaveRequestNotFound = errors.New("leave request not found")
+	ErrInvalidLeavePeriod   = errors.New("leave end date must not be before start date")
+	ErrNoWorkingDays        = errors.New("leave period contains no working days")
+	ErrInsufficientBalance  = errors.New("insufficient leave balance")
+	ErrOverlappingLeave     = errors.New("leave overlaps with an existing request")
+	ErrNotApprover          = errors.New("not allowed to decide on this leave request")
+	ErrInvalidStatus        = errors.New("leave request can no longer be changed")
+	ErrTimesheetSignedOff   = errors.New("leave falls in a signed-off timesheet")
+)
+
+type LeaveService interface {
+	CreateLeaveType(leaveType LeaveType) (*LeaveType, error)
+	GetLeaveTypes() ([]LeaveType, error)
+	UpdateLeaveType(id uint, leaveType LeaveType) (*LeaveType, error)
+	DeleteLeaveType(id uint) error
+
+	GetBalances(employeeID uint) ([]LeaveBalance, error)
+	AdjustBalance(employeeID, leaveTypeID uint, days float64) (*LeaveBalance, error)
+	AccrueBalances(now time.Time) (int, error)
+
+	RequestLeave(employeeID uint, request LeaveRequest) (*LeaveRequest, []employee.EmployeeShift, error)
+	GetLeaveRequests(employeeID uint) ([]LeaveRequest, error)
+	GetPendingRequests(approver *iam.User) ([]LeaveRequest, error)
+	ApproveRequest(id uint, approver *iam.User, note string) (*LeaveRequest, []employee.EmployeeShift, error)
+	RejectRequest(id uint, approver *iam.User, note string) (*LeaveRequest, error)
+	CancelRequest(id uint, user *iam.User) (*LeaveRequest, error)
+}
+
+type leaveService struct {
+	db            *gorm.DB
+	notifications notification.NotificationService
+	holidays      holiday.HolidayService
+	workWeek      []time.Weekday
+}
+
+// NewLeaveService creates a leave service. Days without an assigned shift
+// count against leave when they fall in workWeek.
+func NewLeaveService(db *gorm.DB, notifications notification.NotificationService, holidays holiday.HolidayService, workWeek []time.Weekday) LeaveService {
+	return &leaveService{db: db, notifications: notifications, holidays: holidays, workWeek: workWeek}
+}
+
+func (s *leaveService) CreateLeaveType(leaveType LeaveType) (*LeaveType, error) {
+	if leaveType.AccrualPolicy == "" {
+		leaveType.AccrualPolicy = AccrualNone
+	}
+	if err := s.db.Create(&leaveType).Error; err != nil {
+		log.Printf("Error creating leave type: %v", err)
+		return nil, err
+	}
+	return &leaveType, nil
+}
+
+func (s *leaveService) GetLeaveTypes() ([]LeaveType, error) {
+	var leaveTypes []LeaveType
+	if err := s.db.Find(&leaveTypes).Error; err != nil {
+		log.Printf("Error fetching leave types: %v", err)
+		return nil, err
+	}
+	return leaveTypes, nil
+}
+
+func (s *leaveService) UpdateLeaveType(id uint, leaveType LeaveType) (*LeaveType, error) {
+	var existing LeaveType
+	if err := s.db.First(&existing, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrLeaveTypeNotFound
+		}
+		log.Printf("Error fetching leave type: %v", err)
+		return nil, err
+	}
+
+	leaveType.ID = existing.ID
+	leaveType.CreatedAt = existing.CreatedAt
+	if leaveType.AccrualPolicy == "" {
+		leaveType.AccrualPolicy = AccrualNone
+	}
+	if err := s.db.Save(&leaveType).Error; err != nil {
+		log.Printf("Error updating leave type: %v", err)
+		return nil, err
+	}
+	return &leaveType, nil
+}
+
+func (s *leaveService) DeleteLeaveType(id uint) error {
+	result := s.db.Delete(&LeaveType{}, id)
+	if result.Error != nil {
+		log.Printf("Error deleting leave type: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaveTypeNotFound
+	}
+	return nil
+}
+
+func (s *leaveService) GetBalances(employeeID uint) ([]LeaveBalance, error) {
+	var balances []LeaveBalance
+	if err := s.db.Preload("LeaveType").Where("employee_id = ?", employeeID).Find(&balances).Error; err != nil {
+		log.Printf("Error fetching leave balances: %v", err)
+		return nil, err
+	}
+	return balances, nil
+}
+
+// AdjustBalance credits (or debits, for negative days) an employee's balance manually
+func (s *leaveService) AdjustBalance(employeeID, leaveTypeID uint, days float64) (*LeaveBalance, error) {
+	if err := s.db.First(&employee.Employee{}, employeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+
+	balance, err := s.balance(s.db, employeeID, leaveTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance.Accrued += days
+	if err := s.db.Save(balance).Error; err != nil {
+		log.Printf("Error adjusting leave balance: %v", err)
+		return nil, err
+	}
+	return balance, nil
+}
+
+// AccrueBalances credits accruing leave types for the period containing now.
+// Each balance records the last period it was credited for, so running the
+// job more than once per period is harmless. It returns the number of
+// balances credited.
+func (s *leaveService) AccrueBalances(now time.Time) (int, error) {
+	var leaveTypes []LeaveType
+	if err := s.db.Where("accrual_policy IN (?)", []string{AccrualMonthly, AccrualAnnual}).Find(&leaveTypes).Error; err != nil {
+		log.Printf("Error fetching accruing leave types: %v", err)
+		return 0, err
+	}
+
+	var employees []employee.Employee
+	err := s.db.Where("employment_status <> ? AND (hire_date IS NULL OR hire_date <= ?)", employee.EmploymentStatusTerminated, now).
+		Find(&employees).Error
+	if err != nil {
+		log.Printf("Error fetching employees for accrual: %v", err)
+		return 0, err
+	}
+
+	credited := 0
+	for _, leaveType := range leaveTypes {
+		period := now.Format("2006-01")
+		if leaveType.AccrualPolicy == AccrualAnnual {
+			period = now.Format("2006")
+		}
+
+		for _, emp := range employees {
+			balance, err := s.balance(s.db, emp.ID, leaveType.ID)
+			if err != nil {
+				return credited, err
+			}
+			if balance.LastAccrualPeriod == period {
+				continue
+			}
+
+			balance.Accrued += leaveType.AccrualDays
+			if leaveType.MaxBalance > 0 && balance.Available() > leaveType.MaxBalance {
+				balance.Accrued = balance.Used + leaveType.MaxBalance
+			}
+			balance.LastAccrualPeriod = period
+
+			if err := s.db.Save(balance).Error; err != nil {
+				log.Printf("Error accruing leave balance: %v", err)
+				return credited, err
+			}
+			credited++
+		}
+	}
+
+	return credited, nil
+}
+
+// RequestLeave submits a leave request to the employee's manager. The shift
+// assignments overlapping the requested period are returned so that cover
+// can be arranged.
+func (s *leaveService) RequestLeave(employeeID uint, request LeaveRequest) (*LeaveRequest, []employee.EmployeeShift, error) {
+	var emp employee.Employee
+	if err := s.db.First(&emp, employeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, nil, err
+	}
+
+	var leaveType LeaveType
+	if err := s.db.First(&leaveType, request.LeaveTypeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil, ErrLeaveTypeNotFound
+		}
+		log.Printf("Error fetching leave type: %v", err)
+		return nil, nil, err
+	}
+
+	request.StartDate = request.StartDate.UTC().Truncate(24 * time.Hour)
+	request.EndDate = request.EndDate.UTC().Truncate(24 * time.Hour)
+	if request.EndDate.Before(request.StartDate) {
+		return nil, nil, ErrInvalidLeavePeriod
+	}
+
+	conflicts, err := s.conflictingShifts(employeeID, request.StartDate, request.EndDate)
+	if err != nil {
+		return nil, nil, err
+	}
+	days, err := s.workingDays(emp, request.StartDate, request.EndDate, conflicts)
+	if err != nil {
+		return nil, nil, err
+	}
+	request.Days = float64(len(days))
+	if request.Days == 0 {
+		return nil, nil, ErrNoWorkingDays
+	}
+
+	var overlapping int
+	s.db.Model(&LeaveRequest{}).
+		Where("employee_id = ? AND status IN (?) AND start_date <= ? AND end_date >= ?",
+			employeeID, []string{StatusPending, StatusApproved}, request.EndDate, request.StartDate).
+		Count(&overlapping)
+	if overlapping > 0 {
+		return nil, nil, ErrOverlappingLeave
+	}
+
+	if !leaveType.AllowNegative {
+		balance, err := s.balance(s.db, employeeID, leaveType.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var pending struct{ Days float64 }
+		s.db.Model(&LeaveRequest{}).Select("COALESCE(SUM(days), 0) AS days").
+			Where("employee_id = ? AND leave_type_id = ? AND status = ?", employeeID, leaveType.ID, StatusPending).
+			Scan(&pending)
+		if balance.Available()-pending.Days < request.Days {
+			return nil, nil, ErrInsufficientBalance
+		}
+	}
+
+	request.ID = 0
+	request.EmployeeID = employeeID
+	request.Status = StatusPending
+	request.ApproverID = emp.ManagerID
+	request.DecidedBy = nil
+	request.DecidedAt = utils.NullTime{}
+	request.DecisionNote = ""
+
+	if err := s.db.Create(&request).Error; err != nil {
+		log.Printf("Error creating leave request: %v", err)
+		return nil, nil, err
+	}
+	request.LeaveType = leaveType
+
+	if request.ApproverID != nil {
+		err := s.notifications.Notify(*request.ApproverID, "leave_request",
+			fmt.Sprintf("%s requested %s leave", emp.Name, leaveType.Name),
+			fmt.Sprintf("%s to %s (%.1f days): %s", request.StartDate.Format("2006-01-02"), request.EndDate.Format("2006-01-02"), request.Days, request.Reason))
+		if err != nil {
+			log.Printf("Error notifying approver of leave request %d: %v", request.ID, err)
+		}
+	}
+
+	return &request, conflicts, nil
+}
+
+func (s *leaveService) GetLeaveRequests(employeeID uint) ([]LeaveRequest, error) {
+	var requests []LeaveRequest
+	if err := s.db.Preload("LeaveType").Where("employee_id = ?", employeeID).Order("start_date DESC").Find(&requests).Error; err != nil {
+		log.Printf("Error fetching leave requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// GetPendingRequests returns the pending requests the user is expected to
+// decide on. Admins see every pending request.
+func (s *leaveService) GetPendingRequests(approver *iam.User) ([]LeaveRequest, error) {
+	var requests []LeaveRequest
+	query := s.db.Preload("LeaveType").Where("status = ?", StatusPending)
+	if approver.Role != iam.RoleAdmin {
+		query = query.Where("approver_id = ?", approver.EmployeeID)
+	}
+
+	if err := query.Order("start_date").Find(&requests).Error; err != nil {
+		log.Printf("Error fetching pending leave requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ApproveRequest approves a pending request, deducts the balance and records
+// "On Leave" attendance for each working day of the leave
+func (s *leaveService) ApproveRequest(id uint, approver *iam.User, note string) (*LeaveRequest, []employee.EmployeeShift, error) {
+	request, err := s.pendingRequestFor(id, approver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var emp employee.Employee
+	if err := s.db.First(&emp, request.EmployeeID).Error; err != nil {
+		log.Printf("Error fetching employee: %v", err)
+		return nil, nil, err
+	}
+	if err := s.checkNotSignedOff(request); err != nil {
+		return nil, nil, err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, nil, tx.Error
+	}
+
+	balance, err := s.balance(tx, request.EmployeeID, request.LeaveTypeID)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if !request.LeaveType.AllowNegative && balance.Available() < request.Days {
+		tx.Rollback()
+		return nil, nil, ErrInsufficientBalance
+	}
+	balance.Used += request.Days
+	if err := tx.Save(balance).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error updating leave balance: %v", err)
+		return nil, nil, err
+	}
+
+	conflicts, err := s.conflictingShifts(request.EmployeeID, request.StartDate, request.EndDate)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if err := s.recordLeaveAttendance(tx, emp, request, conflicts); err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	s.decide(request, approver, StatusApproved, note)
+	if err := tx.Save(request).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error approving leave request: %v", err)
+		return nil, nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, nil, err
+	}
+
+	err = s.notifications.Notify(request.EmployeeID, "leave_decision",
+		fmt.Sprintf("Your %s leave was approved", request.LeaveType.Name),
+		fmt.Sprintf("%s to %s. %s", request.StartDate.Format("2006-01-02"), request.EndDate.Format("2006-01-02"), note))
+	if err != nil {
+		log.Printf("Error notifying employee of leave approval %d: %v", request.ID, err)
+	}
+
+	return request, conflicts, nil
+}
+
+func (s *leaveService) RejectRequest(id uint, approver *iam.User, note string) (*LeaveRequest, error) {
+	request, err := s.pendingRequestFor(id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	s.decide(request, approver, StatusRejected, note)
+	if err := s.db.Save(request).Error; err != nil {
+		log.Printf("Error rejecting leave request: %v", err)
+		return nil, err
+	}
+
+	err = s.notifications.Notify(request.EmployeeID, "leave_decision",
+		fmt.Sprintf("Your %s leave was rejected", request.LeaveType.Name),
+		fmt.Sprintf("%s to %s. %s", request.StartDate.Format("2006-01-02"), request.EndDate.Format("2006-01-02"), note))
+	if err != nil {
+		log.Printf("Error notifying employee of leave rejection %d: %v", request.ID, err)
+	}
+
+	return request, nil
+}
+
+// CancelRequest withdraws a pending or approved request. Cancelling approved
+// leave restores the balance and removes its "On Leave" attendance.
+func (s *leaveService) CancelRequest(id uint, user *iam.User) (*LeaveRequest, error) {
+	request, err := s.request(id)
+	if err != nil {
+		return nil, err
+	}
+	if !iam.CanAccessEmployee(user, request.EmployeeID) {
+		return nil, ErrNotApprover
+	}
+	if request.Status != StatusPending && request.Status != StatusApproved {
+		return nil, ErrInvalidStatus
+	}
+	if request.Status == StatusApproved {
+		if err := s.checkNotSignedOff(request); err != nil {
+			return nil, err
+		}
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	if request.Status == StatusApproved {
+		balance, err := s.balance(tx, request.EmployeeID, request.LeaveTypeID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		balance.Used -= request.Days
+		if err := tx.Save(balance).Error; err != nil {
+			tx.Rollback()
+			log.Printf("Error restoring leave balance: %v", err)
+			return nil, err
+		}
+
+		err = tx.Where("employee_id = ? AND status = ? AND date BETWEEN ? AND ? AND locked = ?",
+			request.EmployeeID, employee.AttendanceStatusOnLeave, request.StartDate, request.EndDate, false).
+			Delete(&employee.Attendance{}).Error
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error removing leave attendance: %v", err)
+			return nil, err
+		}
+	}
+
+	request.Status = StatusCancelled
+	if err := tx.Save(request).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error cancelling leave request: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *leaveService) request(id uint) (*LeaveRequest, error) {
+	var request LeaveRequest
+	if err := s.db.Preload("LeaveType").First(&request, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrLeaveRequestNotFound
+		}
+		log.Printf("Error fetching leave request: %v", err)
+		return nil, err
+	}
+	return &request, nil
+}
+
+// pendingRequestFor loads a pending request and checks the user may decide on it:
+// the employee's manager, or any admin. Requests without a manager can be
+// decided by any manager.
+func (s *leaveService) pendingRequestFor(id uint, approver *iam.User) (*LeaveRequest, error) {
+	request, err := s.request(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusPending {
+		return nil, ErrInvalidStatus
+	}
+
+	if approver.Role == iam.RoleAdmin {
+		return request, nil
+	}
+	isManager := request.ApproverID != nil && *request.ApproverID == approver.EmployeeID
+	isFallback := request.ApproverID == nil && approver.IsPrivileged()
+	if (!isManager && !isFallback) || approver.EmployeeID == request.EmployeeID {
+		return nil, ErrNotApprover
+	}
+	return request, nil
+}
+
+func (s *leaveService) decide(request *LeaveRequest, approver *iam.User, status, note string) {
+	request.Status = status
+	request.DecidedBy = &approver.ID
+	request.DecidedAt = utils.NullTime{NullTime: sql.NullTime{Time: time.Now().UTC(), Valid: true}}
+	request.DecisionNote = note
+}
+
+// balance returns the employee's balance for a leave type, creating an empty one if needed
+func (s *leaveService) balance(db *gorm.DB, employeeID, leaveTypeID uint) (*LeaveBalance, error) {
+	var balance LeaveBalance
+	err := db.Where(LeaveBalance{EmployeeID: employeeID, LeaveTypeID: leaveTypeID}).FirstOrCreate(&balance).Error
+	if err != nil {
+		log.Printf("Error fetching leave balance: %v", err)
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// conflictingShifts returns the employee's shift assignments overlapping the period
+func (s *leaveService) conflictingShifts(employeeID uint, start, end time.Time) ([]employee.EmployeeShift, error) {
+	var shifts []employee.EmployeeShift
+	err := s.db.Preload("Shift").
+		Where("employee_id = ? AND start_date <= ? AND end_date >= ?", employeeID, end, start).
+		Find(&shifts).Error
+	if err != nil {
+		log.Printf("Error checking shift conflicts: %v", err)
+		return nil, err
+	}
+	return shifts, nil
+}
+
+// checkNotSignedOff refuses to change the attendance of leave that a
+// signed-off timesheet already covers
+func (s *leaveService) checkNotSignedOff(request *LeaveRequest) error {
+	locked, err := timesheet.SignedOff(s.db, request.EmployeeID, request.StartDate, request.EndDate)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrTimesheetSignedOff
+	}
+	return nil
+}
+
+// recordLeaveAttendance creates an "On Leave" attendance record for every
+// occurrence of the employee's assigned shifts during the leave, including
+// weekends and several shifts on one day. Occurrences already recorded, e.g.
+// clocked in to, are left alone.
+func (s *leaveService) recordLeaveAttendance(tx *gorm.DB, emp employee.Employee, request *LeaveRequest, assignments []employee.EmployeeShift) error {
+	start, end := dateOf(request.StartDate), dateOf(request.EndDate)
+	for _, assignment := range assignments {
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			if day.Before(dateOf(assignment.StartDate)) || day.After(dateOf(assignment.EndDate)) {
+				continue
+			}
+			if _, ok := assignment.Shift.OccurrenceOn(day); !ok {
+				continue
+			}
+
+			var existing int
+			err := tx.Model(&employee.Attendance{}).
+				Where("employee_id = ? AND shift_id = ? AND date = ?", emp.ID, assignment.ShiftID, day).
+				Count(&existing).Error
+			if err != nil {
+				log.Printf("Error checking attendance records: %v", err)
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+
+			attendance := employee.Attendance{
+				EmployeeID: emp.ID,
+				ShiftID:    assignment.ShiftID,
+				Date:       day,
+				Status:     employee.AttendanceStatusOnLeave,
+			}
+			if err := tx.Omit("Employee", "Shift", "Breaks").Create(&attendance).Error; err != nil {
+				log.Printf("Error recording leave attendance: %v", err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// workingDays lists the days between start and end (inclusive) that count
+// against leave. On a day covered by one of the employee's shift assignments,
+// that is a day on which an assigned shift starts, holidays included as the
+// employee is rostered to work them. Other days fall back to the configured
+// work week, less the employee's public holidays.
+func (s *leaveService) workingDays(emp employee.Employee, start, end time.Time, assignments []employee.EmployeeShift) ([]time.Time, error) {
+	holidays, err := s.holidays.HolidaysFor(emp, start, end)
+	if err != nil {
+		return nil, err
+	}
+	observed := make(map[string]bool, len(holidays))
+	for _, h := range holidays {
+		observed[h.Date.Format("2006-01-02")] = true
+	}
+	workWeek := make(map[time.Weekday]bool, len(s.workWeek))
+	for _, weekday := range s.workWeek {
+		workWeek[weekday] = true
+	}
+
+	var days []time.Time
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		assigned, scheduled := false, false
+		for _, assignment := range assignments {
+			if day.Before(dateOf(assignment.StartDate)) || day.After(dateOf(assignment.EndDate)) {
+				continue
+			}
+			assigned = true
+			if _, ok := assignment.Shift.OccurrenceOn(day); ok {
+				scheduled = true
+				break
+			}
+		}
+
+		if scheduled || !assigned && workWeek[day.Weekday()] && !observed[day.Format("2006-01-02")] {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// dateOf truncates a time to its UTC calendar day
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
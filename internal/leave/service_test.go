@@ -0,0 +1,141 @@
+package leave
+
+import (
+	"clinicplus/internal/holiday"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/testdb"
+	"errors"
+	"testing"
+	"time"
+)
+
+// day returns a date in March 2024; the 4th is a Monday
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+var weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+// failingNotifications fails every notification
+type failingNotifications struct{}
+
+func (failingNotifications) Notify(uint, string, string, string) error {
+	return errors.New("mail server down")
+}
+
+func (failingNotifications) GetNotifications(uint, bool) ([]notification.Notification, error) {
+	return nil, nil
+}
+
+func (failingNotifications) MarkRead(uint, *iam.User) (*notification.Notification, error) {
+	return nil, nil
+}
+
+// scriptRequest scripts the employee (managed by employee 8) and a leave type
+// that may go negative, so requests skip the balance check
+func scriptRequest(fake *testdb.DB) {
+	fake.Returns(`FROM "employees"`, []string{"id", "name", "manager_id"}, []interface{}{5, "Ana", 8})
+	fake.Returns(`FROM "leave_types"`, []string{"id", "name", "allow_negative"}, []interface{}{1, "Vacation", true})
+}
+
+func TestRequestLeaveDays(t *testing.T) {
+	type assignment struct {
+		start, end int
+		days       string // Weekdays the assigned shift runs on
+	}
+
+	tests := []struct {
+		name        string
+		start, end  int
+		workWeek    []time.Weekday
+		holidays    []int
+		assignments []assignment
+		want        float64
+		wantErr     error
+	}{
+		{name: "work week without shifts", start: 4, end: 10, workWeek: weekdays, want: 5},
+		{name: "holidays are not counted", start: 4, end: 10, workWeek: weekdays, holidays: []int{6}, want: 4},
+		{name: "configured work week", start: 4, end: 10, workWeek: []time.Weekday{time.Saturday}, want: 1},
+		{
+			name: "weekend shifts", start: 4, end: 10, workWeek: weekdays,
+			assignments: []assignment{{1, 31, "sat,sun"}},
+			want:        2,
+		},
+		{
+			name: "rostered holidays are counted", start: 4, end: 10, workWeek: weekdays, holidays: []int{6},
+			assignments: []assignment{{1, 31, "mon,wed,fri"}},
+			want:        3,
+		},
+		{
+			name: "several shifts on one day count once", start: 4, end: 10, workWeek: weekdays,
+			assignments: []assignment{{1, 31, "mon"}, {1, 31, "mon,tue"}},
+			want:        2,
+		},
+		{
+			name: "work week after the assignment ends", start: 4, end: 10, workWeek: weekdays,
+			assignments: []assignment{{1, 6, "mon,tue"}},
+			want:        4,
+		},
+		{name: "weekend without shifts", start: 9, end: 10, workWeek: weekdays, wantErr: ErrNoWorkingDays},
+		{
+			name: "days off between shifts", start: 5, end: 7, workWeek: weekdays,
+			assignments: []assignment{{1, 31, "mon,fri"}},
+			wantErr:     ErrNoWorkingDays,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			scriptRequest(fake)
+			var assignments, shifts [][]interface{}
+			for i, a := range tt.assignments {
+				assignments = append(assignments, []interface{}{i + 1, 5, i + 1, day(a.start), day(a.end)})
+				shifts = append(shifts, []interface{}{i + 1, "08:00", "16:00", a.days})
+			}
+			fake.Returns(`FROM "employee_shifts"`, []string{"id", "employee_id", "shift_id", "start_date", "end_date"}, assignments...)
+			fake.Returns(`FROM "shifts"`, []string{"id", "start_time", "end_time", "days_of_week"}, shifts...)
+			var holidays [][]interface{}
+			for i, d := range tt.holidays {
+				holidays = append(holidays, []interface{}{i + 1, day(d)})
+			}
+			fake.Returns(`FROM "holidays"`, []string{"id", "date"}, holidays...)
+			service := NewLeaveService(db, notification.NewNotificationService(db), holiday.NewHolidayService(db), tt.workWeek)
+
+			request, _, err := service.RequestLeave(5, LeaveRequest{LeaveTypeID: 1, StartDate: day(tt.start), EndDate: day(tt.end)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequestLeave error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if fake.Ran(`INSERT INTO "leave_requests"`) {
+					t.Error("rejected request was saved")
+				}
+				return
+			}
+			if request.Days != tt.want {
+				t.Errorf("request covers %v days, want %v", request.Days, tt.want)
+			}
+			if inserts := fake.Find(`INSERT INTO "leave_requests"`); len(inserts) != 1 || !inserts[0].Has(tt.want) {
+				t.Errorf("request saved with %+v, want %v days", inserts, tt.want)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 1 || !notifications[0].Has(8) {
+				t.Errorf("approver notified with %+v", notifications)
+			}
+		})
+	}
+}
+
+func TestRequestLeaveNotificationFailure(t *testing.T) {
+	db, fake := testdb.New(t)
+	scriptRequest(fake)
+	service := NewLeaveService(db, failingNotifications{}, holiday.NewHolidayService(db), weekdays)
+
+	request, _, err := service.RequestLeave(5, LeaveRequest{LeaveTypeID: 1, StartDate: day(4), EndDate: day(5)})
+	if err != nil {
+		t.Fatalf("RequestLeave: %v", err)
+	}
+	if request.Status != StatusPending || !fake.Ran(`INSERT INTO "leave_requests"`) {
+		t.Errorf("request not saved when the approver could not be notified: %+v", request)
+	}
+}
@@ -0,0 +1,73 @@
+// internal/notification/handler.go
+package notification
+
+import (
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type NotificationHandler struct {
+	service NotificationService
+}
+
+func NewNotificationHandler(service NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+func (h *NotificationHandler) GetEmployeeNotifications(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	employeeID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	user, _ := iam.UserFromContext(r.Context())
+	if !iam.CanAccessEmployee(user, uint(employeeID)) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's notifications", nil)
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := h.service.GetNotifications(uint(employeeID), unreadOnly)
+	if err != nil {
+		log.Printf("Error fetching notifications: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve notifications", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, notifications, nil, nil)
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid notification ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid notification ID", nil)
+		return
+	}
+
+	user, _ := iam.UserFromContext(r.Context())
+	notification, err := h.service.MarkRead(uint(id), user)
+	if err != nil {
+		if err == ErrNotificationNotFound {
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, "Notification not found", nil)
+		} else if err == ErrNotAllowed {
+			utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this notification", nil)
+		} else {
+			log.Printf("Error marking notification as read: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to update notification", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, notification, nil, nil)
+}
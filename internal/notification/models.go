@@ -0,0 +1,16 @@
+package notification
+
+import (
+	"clinicplus/internal/shared/utils"
+
+	"github.com/jinzhu/gorm"
+)
+
+type Notification struct {
+	gorm.Model
+	EmployeeID uint           `gorm:"not null;index" json:"employee_id"` // Recipient
+	Kind       string         `gorm:"not null" json:"kind"`              // e.g., credential_expiry
+	Subject    string         `json:"subject"`
+	Body       string         `json:"body"`
+	ReadAt     utils.NullTime `json:"read_at"`
+}
@@ -0,0 +1,89 @@
+// internal/notification/service.go
+package notification
+
+import (
+	"clinicplus/internal/iam"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrNotAllowed           = errors.New("not allowed to access this notification")
+)
+
+type NotificationService interface {
+	Notify(employeeID uint, kind, subject, body string) error
+	GetNotifications(employeeID uint, unreadOnly bool) ([]Notification, error)
+	MarkRead(id uint, user *iam.User) (*Notification, error)
+}
+
+type notificationService struct {
+	db *gorm.DB
+}
+
+func NewNotificationService(db *gorm.DB) NotificationService {
+	return &notificationService{db: db}
+}
+
+// Notify stores a notification for an employee. Delivery channels (email, SMS)
+// can hook in here later; for now the inbox is read through the API.
+func (s *notificationService) Notify(employeeID uint, kind, subject, body string) error {
+	notification := Notification{
+		EmployeeID: employeeID,
+		Kind:       kind,
+		Subject:    subject,
+		Body:       body,
+	}
+
+	if err := s.db.Create(&notification).Error; err != nil {
+		log.Printf("Error creating notification: %v", err)
+		return err
+	}
+
+	log.Printf("Notified employee %d: %s", employeeID, subject)
+	return nil
+}
+
+func (s *notificationService) GetNotifications(employeeID uint, unreadOnly bool) ([]Notification, error) {
+	var notifications []Notification
+	query := s.db.Where("employee_id = ?", employeeID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if err := query.Order("created_at DESC").Find(&notifications).Error; err != nil {
+		log.Printf("Error fetching notifications: %v", err)
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkRead marks a notification read on behalf of its recipient or a manager
+func (s *notificationService) MarkRead(id uint, user *iam.User) (*Notification, error) {
+	var notification Notification
+	if err := s.db.First(&notification, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrNotificationNotFound
+		}
+		log.Printf("Error fetching notification: %v", err)
+		return nil, err
+	}
+	if !iam.CanAccessEmployee(user, notification.EmployeeID) {
+		return nil, ErrNotAllowed
+	}
+
+	if !notification.ReadAt.Valid {
+		notification.ReadAt.Time = time.Now().UTC()
+		notification.ReadAt.Valid = true
+		if err := s.db.Save(&notification).Error; err != nil {
+			log.Printf("Error marking notification as read: %v", err)
+			return nil, err
+		}
+	}
+
+	return &notification, nil
+}
@@ -0,0 +1,92 @@
+package notification
+
+import (
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/testdb"
+	"errors"
+	"testing"
+	"time"
+)
+
+var notificationColumns = []string{"id", "employee_id", "kind", "subject", "read_at"}
+
+func TestNotify(t *testing.T) {
+	db, fake := testdb.New(t)
+	service := NewNotificationService(db)
+
+	if err := service.Notify(5, "leave_request", "Leave approved", "Enjoy"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	inserts := fake.Find(`INSERT INTO "notifications"`)
+	if len(inserts) != 1 {
+		t.Fatalf("ran %d inserts, want 1", len(inserts))
+	}
+	for _, want := range []interface{}{5, "leave_request", "Leave approved", "Enjoy"} {
+		if !inserts[0].Has(want) {
+			t.Errorf("insert lacks %v (has %v)", want, inserts[0].Args)
+		}
+	}
+}
+
+func TestGetNotifications(t *testing.T) {
+	for _, unreadOnly := range []bool{false, true} {
+		db, fake := testdb.New(t)
+		fake.Returns(`FROM "notifications"`, notificationColumns, []interface{}{1, 5, "k", "s", nil})
+		service := NewNotificationService(db)
+
+		notifications, err := service.GetNotifications(5, unreadOnly)
+		if err != nil {
+			t.Fatalf("GetNotifications: %v", err)
+		}
+		if len(notifications) != 1 {
+			t.Errorf("got %d notifications, want 1", len(notifications))
+		}
+		if got := fake.Ran("read_at IS NULL"); got != unreadOnly {
+			t.Errorf("unread filter applied: %v, want %v", got, unreadOnly)
+		}
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	readAt := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		user    *iam.User
+		readAt  interface{}
+		wantErr error
+		saved   bool
+	}{
+		{name: "recipient", user: &iam.User{EmployeeID: 5, Role: iam.RoleEmployee}, saved: true},
+		{name: "manager", user: &iam.User{EmployeeID: 9, Role: iam.RoleManager}, saved: true},
+		{name: "someone else", user: &iam.User{EmployeeID: 6, Role: iam.RoleEmployee}, wantErr: ErrNotAllowed},
+		{name: "no user", wantErr: ErrNotAllowed},
+		{name: "already read", user: &iam.User{EmployeeID: 5, Role: iam.RoleEmployee}, readAt: readAt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "notifications"`, notificationColumns, []interface{}{1, 5, "k", "s", tt.readAt})
+			service := NewNotificationService(db)
+
+			notification, err := service.MarkRead(1, tt.user)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MarkRead error %v, want %v", err, tt.wantErr)
+			}
+			if got := fake.Ran(`UPDATE "notifications"`); got != tt.saved {
+				t.Errorf("saved: %v, want %v", got, tt.saved)
+			}
+			if err == nil && !notification.ReadAt.Valid {
+				t.Error("notification not marked read")
+			}
+		})
+	}
+}
+
+func TestMarkReadNotFound(t *testing.T) {
+	db, _ := testdb.New(t)
+	if _, err := NewNotificationService(db).MarkRead(1, &iam.User{Role: iam.RoleAdmin}); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("MarkRead error %v, want %v", err, ErrNotificationNotFound)
+	}
+}
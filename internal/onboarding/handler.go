@@ -0,0 +1,182 @@
+// internal/onboarding/handler.go
+package onboarding
+
+import (
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type OnboardingHandler struct {
+	service OnboardingService
+}
+
+func NewOnboardingHandler(service OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{service: service}
+}
+
+func (h *OnboardingHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var template ChecklistTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreateTemplate(template)
+	if err != nil {
+		sendOnboardingError(w, err, "Failed to create checklist template")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *OnboardingHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.GetTemplates(r.URL.Query().Get("kind"))
+	if err != nil {
+		log.Printf("Error fetching checklist templates: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve checklist templates", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, templates, nil, nil)
+}
+
+func (h *OnboardingHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid template ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid template ID", nil)
+		return
+	}
+
+	var template ChecklistTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateTemplate(uint(id), template)
+	if err != nil {
+		sendOnboardingError(w, err, "Failed to update checklist template")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
+
+func (h *OnboardingHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid template ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid template ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteTemplate(uint(id)); err != nil {
+		sendOnboardingError(w, err, "Failed to delete checklist template")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Checklist template deleted successfully",
+	})
+}
+
+func (h *OnboardingHandler) GetChecklists(w http.ResponseWriter, r *http.Request) {
+	employeeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+
+	user, _ := iam.UserFromContext(r.Context())
+	if !iam.CanAccessEmployee(user, uint(employeeID)) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to view this employee's checklists", nil)
+		return
+	}
+
+	checklists, err := h.service.GetChecklists(uint(employeeID))
+	if err != nil {
+		log.Printf("Error fetching checklists: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve checklists", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, checklists, nil, nil)
+}
+
+// GetMyTasks lists the checklist tasks assigned to the authenticated user
+func (h *OnboardingHandler) GetMyTasks(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+	openOnly := r.URL.Query().Get("all") != "true"
+
+	tasks, err := h.service.GetAssignedTasks(user.EmployeeID, openOnly)
+	if err != nil {
+		log.Printf("Error fetching assigned tasks: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve tasks", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, tasks, nil, nil)
+}
+
+func (h *OnboardingHandler) GetOverdueTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := h.service.GetOverdueTasks(time.Now())
+	if err != nil {
+		log.Printf("Error fetching overdue tasks: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve overdue tasks", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, tasks, nil, nil)
+}
+
+func (h *OnboardingHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid task ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid task ID", nil)
+		return
+	}
+
+	var update TaskUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	task, err := h.service.UpdateTask(uint(id), update, user)
+	if err != nil {
+		sendOnboardingError(w, err, "Failed to update task")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, task, nil, nil)
+}
+
+func sendOnboardingError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTemplateNotFound), errors.Is(err, ErrTaskNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case errors.Is(err, ErrInvalidTemplate), errors.Is(err, ErrInvalidStatus):
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case errors.Is(err, ErrForbidden):
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
@@ -0,0 +1,76 @@
+package onboarding
+
+import (
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	KindOnboarding  = "onboarding"
+	KindOffboarding = "offboarding"
+)
+
+// Assignee types say who a template task is given to when instantiated
+const (
+	AssigneeEmployee = "employee" // The employee being hired or leaving
+	AssigneeManager  = "manager"  // Their manager
+	AssigneeSpecific = "specific" // A fixed employee, e.g., the IT administrator
+)
+
+const (
+	TaskStatusPending    = "Pending"
+	TaskStatusInProgress = "In Progress"
+	TaskStatusDone       = "Done"
+	TaskStatusSkipped    = "Skipped"
+)
+
+type ChecklistTemplate struct {
+	gorm.Model
+	Name        string                  `json:"name" gorm:"not null"`
+	Kind        string                  `json:"kind" gorm:"not null"` // onboarding or offboarding
+	Designation string                  `json:"designation"`          // Empty applies to every designation
+	Items       []ChecklistTemplateItem `gorm:"foreignkey:TemplateID" json:"items"`
+}
+
+type ChecklistTemplateItem struct {
+	gorm.Model
+	TemplateID         uint   `gorm:"not null;index" json:"template_id"`
+	Title              string `json:"title" gorm:"not null"` // e.g., Issue badge, HIPAA training
+	Description        string `json:"description"`
+	AssigneeType       string `json:"assignee_type" gorm:"not null;default:'manager'"`
+	AssigneeEmployeeID *uint  `json:"assignee_employee_id"` // Used with AssigneeSpecific
+	DueDays            int    `json:"due_days"`             // Days after the hire or termination date
+	Position           int    `json:"position"`
+}
+
+// Checklist is a template instantiated for one employee's hire or termination
+type Checklist struct {
+	gorm.Model
+	EmployeeID uint            `gorm:"not null;index" json:"employee_id"`
+	TemplateID uint            `json:"template_id"`
+	Name       string          `json:"name"`
+	Kind       string          `gorm:"not null" json:"kind"`
+	Tasks      []ChecklistTask `gorm:"foreignkey:ChecklistID" json:"tasks"`
+}
+
+type ChecklistTask struct {
+	gorm.Model
+	ChecklistID    uint           `gorm:"not null;index" json:"checklist_id"`
+	Title          string         `json:"title"`
+	Description    string         `json:"description"`
+	AssigneeID     *uint          `json:"assignee_id"` // Employee responsible for the task
+	DueDate        time.Time      `gorm:"type:date" json:"due_date"`
+	Position       int            `json:"position"`
+	Status         string         `gorm:"not null;index" json:"status"`
+	CompletedAt    utils.NullTime `json:"completed_at"`
+	CompletedBy    *uint          `json:"completed_by"` // iam.User who closed the task
+	Notes          string         `json:"notes"`
+	LastRemindedAt utils.NullTime `json:"-"`
+}
+
+// Open reports whether the task still needs work
+func (t ChecklistTask) Open() bool {
+	return t.Status == TaskStatusPending || t.Status == TaskStatusInProgress
+}
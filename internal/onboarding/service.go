@@ -0,0 +1,395 @@
+// internal/onboarding/service.go
+package onboarding
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("checklist template not found")
+	ErrTaskNotFound     = errors.New("checklist task not found")
+	ErrInvalidTemplate  = errors.New("invalid checklist template")
+	ErrInvalidStatus    = errors.New("invalid task status")
+	ErrForbidden        = errors.New("only the assignee or a manager can update this task")
+)
+
+// TaskUpdate carries the changes to a checklist task; nil fields are left alone
+type TaskUpdate struct {
+	Status     *string    `json:"status"`
+	AssigneeID *uint      `json:"assignee_id"`
+	DueDate    *time.Time `json:"due_date"`
+	Notes      *string    `json:"notes"`
+}
+
+type OnboardingService interface {
+	CreateTemplate(template ChecklistTemplate) (*ChecklistTemplate, error)
+	GetTemplates(kind string) ([]ChecklistTemplate, error)
+	UpdateTemplate(id uint, template ChecklistTemplate) (*ChecklistTemplate, error)
+	DeleteTemplate(id uint) error
+
+	GetChecklists(employeeID uint) ([]Checklist, error)
+	GetAssignedTasks(assigneeID uint, openOnly bool) ([]ChecklistTask, error)
+	GetOverdueTasks(now time.Time) ([]ChecklistTask, error)
+	UpdateTask(id uint, update TaskUpdate, user *iam.User) (*ChecklistTask, error)
+	SendOverdueReminders(now time.Time) (int, error)
+
+	EmployeeHired(tx *gorm.DB, emp employee.Employee) error
+	EmployeeTerminated(tx *gorm.DB, emp employee.Employee) error
+}
+
+type onboardingService struct {
+	db            *gorm.DB
+	notifications notification.NotificationService
+}
+
+func NewOnboardingService(db *gorm.DB, notifications notification.NotificationService) OnboardingService {
+	return &onboardingService{db: db, notifications: notifications}
+}
+
+func (s *onboardingService) CreateTemplate(template ChecklistTemplate) (*ChecklistTemplate, error) {
+	if err := validateTemplate(template); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&template).Error; err != nil {
+		log.Printf("Error creating checklist template: %v", err)
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (s *onboardingService) GetTemplates(kind string) ([]ChecklistTemplate, error) {
+	var templates []ChecklistTemplate
+	query := s.db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	})
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	if err := query.Find(&templates).Error; err != nil {
+		log.Printf("Error fetching checklist templates: %v", err)
+		return nil, err
+	}
+	return templates, nil
+}
+
+// UpdateTemplate replaces a template and its items. Checklists already
+// instantiated from it are not changed.
+func (s *onboardingService) UpdateTemplate(id uint, template ChecklistTemplate) (*ChecklistTemplate, error) {
+	var existing ChecklistTemplate
+	if err := s.db.First(&existing, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrTemplateNotFound
+		}
+		log.Printf("Error fetching checklist template: %v", err)
+		return nil, err
+	}
+	if err := validateTemplate(template); err != nil {
+		return nil, err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	if err := tx.Where("template_id = ?", id).Delete(&ChecklistTemplateItem{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error removing checklist template items: %v", err)
+		return nil, err
+	}
+
+	template.ID = existing.ID
+	template.CreatedAt = existing.CreatedAt
+	for i := range template.Items {
+		template.Items[i].ID = 0
+		template.Items[i].TemplateID = existing.ID
+	}
+	if err := tx.Save(&template).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error updating checklist template: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (s *onboardingService) DeleteTemplate(id uint) error {
+	result := s.db.Delete(&ChecklistTemplate{}, id)
+	if result.Error != nil {
+		log.Printf("Error deleting checklist template: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+
+	if err := s.db.Where("template_id = ?", id).Delete(&ChecklistTemplateItem{}).Error; err != nil {
+		log.Printf("Error deleting checklist template items: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *onboardingService) GetChecklists(employeeID uint) ([]Checklist, error) {
+	var checklists []Checklist
+	err := s.db.Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("employee_id = ?", employeeID).Order("created_at").Find(&checklists).Error
+	if err != nil {
+		log.Printf("Error fetching checklists: %v", err)
+		return nil, err
+	}
+	return checklists, nil
+}
+
+func (s *onboardingService) GetAssignedTasks(assigneeID uint, openOnly bool) ([]ChecklistTask, error) {
+	var tasks []ChecklistTask
+	query := s.db.Where("assignee_id = ?", assigneeID)
+	if openOnly {
+		query = query.Where("status IN (?)", []string{TaskStatusPending, TaskStatusInProgress})
+	}
+
+	if err := query.Order("due_date").Find(&tasks).Error; err != nil {
+		log.Printf("Error fetching assigned tasks: %v", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s *onboardingService) GetOverdueTasks(now time.Time) ([]ChecklistTask, error) {
+	var tasks []ChecklistTask
+	err := s.db.Where("status IN (?) AND due_date < ?", []string{TaskStatusPending, TaskStatusInProgress}, startOfDay(now)).
+		Order("due_date").
+		Find(&tasks).Error
+	if err != nil {
+		log.Printf("Error fetching overdue tasks: %v", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// UpdateTask changes a task on behalf of its assignee or a manager
+func (s *onboardingService) UpdateTask(id uint, update TaskUpdate, user *iam.User) (*ChecklistTask, error) {
+	var task ChecklistTask
+	if err := s.db.First(&task, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrTaskNotFound
+		}
+		log.Printf("Error fetching checklist task: %v", err)
+		return nil, err
+	}
+	if user == nil || !(user.IsPrivileged() || task.AssigneeID != nil && *task.AssigneeID == user.EmployeeID) {
+		return nil, ErrForbidden
+	}
+
+	if update.Status != nil {
+		switch *update.Status {
+		case TaskStatusPending, TaskStatusInProgress:
+			task.CompletedAt = utils.NullTime{}
+			task.CompletedBy = nil
+		case TaskStatusDone, TaskStatusSkipped:
+			if task.Open() {
+				task.CompletedAt = utils.NullTime{NullTime: sql.NullTime{Time: time.Now().UTC(), Valid: true}}
+				task.CompletedBy = &user.ID
+			}
+		default:
+			return nil, ErrInvalidStatus
+		}
+		task.Status = *update.Status
+	}
+	if update.AssigneeID != nil {
+		task.AssigneeID = update.AssigneeID
+	}
+	if update.DueDate != nil {
+		task.DueDate = startOfDay(*update.DueDate)
+		task.LastRemindedAt = utils.NullTime{}
+	}
+	if update.Notes != nil {
+		task.Notes = *update.Notes
+	}
+
+	if err := s.db.Save(&task).Error; err != nil {
+		log.Printf("Error updating checklist task: %v", err)
+		return nil, err
+	}
+	return &task, nil
+}
+
+// SendOverdueReminders notifies the assignees of overdue tasks, at most once
+// a day per task. Unassigned tasks are escalated to the employee's manager.
+// It returns the number of reminders sent.
+func (s *onboardingService) SendOverdueReminders(now time.Time) (int, error) {
+	tasks, err := s.GetOverdueTasks(now)
+	if err != nil {
+		return 0, err
+	}
+
+	today := startOfDay(now)
+	sent := 0
+	for _, task := range tasks {
+		if task.LastRemindedAt.Valid && !task.LastRemindedAt.Time.Before(today) {
+			continue
+		}
+
+		var checklist Checklist
+		if err := s.db.First(&checklist, task.ChecklistID).Error; err != nil {
+			log.Printf("Error fetching checklist %d: %v", task.ChecklistID, err)
+			continue
+		}
+
+		recipient := task.AssigneeID
+		if recipient == nil {
+			var emp employee.Employee
+			if err := s.db.Unscoped().First(&emp, checklist.EmployeeID).Error; err == nil {
+				recipient = emp.ManagerID
+			}
+		}
+		if recipient == nil {
+			log.Printf("Overdue task %d has no assignee or manager to remind", task.ID)
+			continue
+		}
+
+		subject := fmt.Sprintf("Overdue %s task: %s", checklist.Kind, task.Title)
+		body := fmt.Sprintf("%s was due on %s.", task.Title, task.DueDate.Format("2006-01-02"))
+		if err := s.notifications.Notify(*recipient, "checklist_overdue", subject, body); err != nil {
+			return sent, err
+		}
+
+		task.LastRemindedAt = utils.NullTime{NullTime: sql.NullTime{Time: now.UTC(), Valid: true}}
+		if err := s.db.Model(&task).Update("last_reminded_at", task.LastRemindedAt).Error; err != nil {
+			log.Printf("Error recording reminder for task %d: %v", task.ID, err)
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// EmployeeHired implements employee.LifecycleListener by instantiating the
+// onboarding checklists for the employee's designation. A rehired employee's
+// user account is enabled again.
+func (s *onboardingService) EmployeeHired(tx *gorm.DB, emp employee.Employee) error {
+	start := emp.HireDate
+	if start.IsZero() {
+		start = time.Now().UTC()
+	}
+	if err := s.instantiate(tx, emp, KindOnboarding, start); err != nil {
+		return err
+	}
+
+	if err := tx.Model(&iam.User{}).Where("employee_id = ?", emp.ID).Update("disabled", false).Error; err != nil {
+		log.Printf("Error enabling user for employee %d: %v", emp.ID, err)
+		return err
+	}
+	return nil
+}
+
+// EmployeeTerminated implements employee.LifecycleListener by instantiating
+// the offboarding checklists and disabling the employee's user account
+func (s *onboardingService) EmployeeTerminated(tx *gorm.DB, emp employee.Employee) error {
+	end := time.Now().UTC()
+	if emp.TerminationDate.Valid {
+		end = emp.TerminationDate.Time
+	}
+	if err := s.instantiate(tx, emp, KindOffboarding, end); err != nil {
+		return err
+	}
+
+	if err := tx.Model(&iam.User{}).Where("employee_id = ?", emp.ID).Update("disabled", true).Error; err != nil {
+		log.Printf("Error disabling user for employee %d: %v", emp.ID, err)
+		return err
+	}
+	return nil
+}
+
+// instantiate creates a checklist from every matching template of the given kind
+func (s *onboardingService) instantiate(tx *gorm.DB, emp employee.Employee, kind string, start time.Time) error {
+	var templates []ChecklistTemplate
+	err := tx.Preload("Items").
+		Where("kind = ? AND (designation = '' OR LOWER(designation) = ?)", kind, strings.ToLower(emp.Designation)).
+		Find(&templates).Error
+	if err != nil {
+		log.Printf("Error fetching %s templates: %v", kind, err)
+		return err
+	}
+
+	for _, template := range templates {
+		checklist := Checklist{
+			EmployeeID: emp.ID,
+			TemplateID: template.ID,
+			Name:       template.Name,
+			Kind:       kind,
+		}
+		for _, item := range template.Items {
+			checklist.Tasks = append(checklist.Tasks, ChecklistTask{
+				Title:       item.Title,
+				Description: item.Description,
+				AssigneeID:  assigneeFor(item, emp),
+				DueDate:     startOfDay(start).AddDate(0, 0, item.DueDays),
+				Position:    item.Position,
+				Status:      TaskStatusPending,
+			})
+		}
+
+		if err := tx.Create(&checklist).Error; err != nil {
+			log.Printf("Error creating %s checklist: %v", kind, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func assigneeFor(item ChecklistTemplateItem, emp employee.Employee) *uint {
+	switch item.AssigneeType {
+	case AssigneeEmployee:
+		id := emp.ID
+		return &id
+	case AssigneeSpecific:
+		return item.AssigneeEmployeeID
+	default:
+		return emp.ManagerID
+	}
+}
+
+func validateTemplate(template ChecklistTemplate) error {
+	if template.Name == "" || (template.Kind != KindOnboarding && template.Kind != KindOffboarding) {
+		return fmt.Errorf("%w: name and a kind of %q or %q are required", ErrInvalidTemplate, KindOnboarding, KindOffboarding)
+	}
+	for _, item := range template.Items {
+		if item.Title == "" {
+			return fmt.Errorf("%w: every item needs a title", ErrInvalidTemplate)
+		}
+		switch item.AssigneeType {
+		case "", AssigneeEmployee, AssigneeManager:
+		case AssigneeSpecific:
+			if item.AssigneeEmployeeID == nil {
+				return fmt.Errorf("%w: item %q needs an assignee_employee_id", ErrInvalidTemplate, item.Title)
+			}
+		default:
+			return fmt.Errorf("%w: unknown assignee type %q", ErrInvalidTemplate, item.AssigneeType)
+		}
+	}
+	return nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
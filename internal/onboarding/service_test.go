@@ -0,0 +1,230 @@
+package onboarding
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/testdb"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// day returns a date in March 2024
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func uintPtr(n uint) *uint { return &n }
+
+func stringPtr(s string) *string { return &s }
+
+func newService(t *testing.T) (OnboardingService, *testdb.DB) {
+	db, fake := testdb.New(t)
+	return NewOnboardingService(db, notification.NewNotificationService(db)), fake
+}
+
+var taskColumns = []string{"id", "checklist_id", "title", "assignee_id", "due_date", "status", "completed_at", "completed_by", "last_reminded_at"}
+
+func TestUpdateTask(t *testing.T) {
+	assignee := &iam.User{Model: gorm.Model{ID: 30}, EmployeeID: 7, Role: iam.RoleEmployee}
+	manager := &iam.User{Model: gorm.Model{ID: 31}, EmployeeID: 8, Role: iam.RoleManager}
+	completedAt := time.Date(2024, time.March, 4, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		status        string // Current status of the task
+		assigneeID    interface{}
+		user          *iam.User
+		update        TaskUpdate
+		wantErr       error
+		wantStatus    string
+		wantCompleted *uint // User recorded as completing the task; nil for an open task
+	}{
+		{
+			name: "assignee completes the task", status: TaskStatusPending, assigneeID: 7, user: assignee,
+			update:     TaskUpdate{Status: stringPtr(TaskStatusDone)},
+			wantStatus: TaskStatusDone, wantCompleted: uintPtr(30),
+		},
+		{
+			name: "manager skips someone else's task", status: TaskStatusInProgress, assigneeID: 7, user: manager,
+			update:     TaskUpdate{Status: stringPtr(TaskStatusSkipped)},
+			wantStatus: TaskStatusSkipped, wantCompleted: uintPtr(31),
+		},
+		{
+			name: "manager reassigns the task", status: TaskStatusPending, assigneeID: 7, user: manager,
+			update:     TaskUpdate{AssigneeID: uintPtr(9)},
+			wantStatus: TaskStatusPending,
+		},
+		{
+			name: "manager updates an unassigned task", status: TaskStatusPending, user: manager,
+			update:     TaskUpdate{Notes: stringPtr("Badge ordered")},
+			wantStatus: TaskStatusPending,
+		},
+		{
+			name: "reopening clears the completion", status: TaskStatusDone, assigneeID: 7, user: assignee,
+			update:     TaskUpdate{Status: stringPtr(TaskStatusInProgress)},
+			wantStatus: TaskStatusInProgress,
+		},
+		{
+			name: "another employee", status: TaskStatusPending, assigneeID: 7,
+			user:    &iam.User{Model: gorm.Model{ID: 32}, EmployeeID: 6, Role: iam.RoleEmployee},
+			update:  TaskUpdate{Status: stringPtr(TaskStatusDone)},
+			wantErr: ErrForbidden,
+		},
+		{
+			name: "an employee on an unassigned task", status: TaskStatusPending, user: assignee,
+			update:  TaskUpdate{AssigneeID: uintPtr(7)},
+			wantErr: ErrForbidden,
+		},
+		{
+			name: "no user", status: TaskStatusPending, assigneeID: 7,
+			update:  TaskUpdate{Status: stringPtr(TaskStatusDone)},
+			wantErr: ErrForbidden,
+		},
+		{
+			name: "unknown status", status: TaskStatusPending, assigneeID: 7, user: assignee,
+			update:  TaskUpdate{Status: stringPtr("Finished")},
+			wantErr: ErrInvalidStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, fake := newService(t)
+			var completed, completedBy interface{}
+			if tt.status == TaskStatusDone {
+				completed, completedBy = completedAt, 30
+			}
+			fake.Returns(`FROM "checklist_tasks"`, taskColumns, []interface{}{1, 2, "Issue badge", tt.assigneeID, day(10), tt.status, completed, completedBy, nil})
+
+			task, err := service.UpdateTask(1, tt.update, tt.user)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateTask error %v, want %v", err, tt.wantErr)
+			}
+			if saved := fake.Ran(`UPDATE "checklist_tasks"`); saved != (tt.wantErr == nil) {
+				t.Errorf("task saved: %v", saved)
+			}
+			if err != nil {
+				return
+			}
+
+			if task.Status != tt.wantStatus {
+				t.Errorf("status %q, want %q", task.Status, tt.wantStatus)
+			}
+			if tt.wantCompleted == nil {
+				if task.CompletedAt.Valid || task.CompletedBy != nil {
+					t.Errorf("open task has completion %v by %v", task.CompletedAt, task.CompletedBy)
+				}
+			} else if !task.CompletedAt.Valid || task.CompletedBy == nil || *task.CompletedBy != *tt.wantCompleted {
+				t.Errorf("completion %v by %v, want by %d", task.CompletedAt, task.CompletedBy, *tt.wantCompleted)
+			}
+			if tt.update.AssigneeID != nil && (task.AssigneeID == nil || *task.AssigneeID != *tt.update.AssigneeID) {
+				t.Errorf("assignee %v, want %d", task.AssigneeID, *tt.update.AssigneeID)
+			}
+		})
+	}
+}
+
+func TestLifecycleChecklists(t *testing.T) {
+	emp := employee.Employee{Designation: "Nurse", ManagerID: uintPtr(8), HireDate: day(4)}
+	emp.ID = 7
+	emp.TerminationDate = utils.NullTime{NullTime: sql.NullTime{Time: day(20), Valid: true}}
+
+	tests := []struct {
+		name     string
+		kind     string
+		run      func(OnboardingService, *gorm.DB) error
+		start    time.Time
+		disabled bool
+	}{
+		{"hire", KindOnboarding, func(s OnboardingService, tx *gorm.DB) error { return s.EmployeeHired(tx, emp) }, day(4), false},
+		{"termination", KindOffboarding, func(s OnboardingService, tx *gorm.DB) error { return s.EmployeeTerminated(tx, emp) }, day(20), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			service := NewOnboardingService(db, notification.NewNotificationService(db))
+			fake.Returns(`FROM "checklist_templates"`, []string{"id", "name", "kind"}, []interface{}{1, "Ward checklist", tt.kind})
+			fake.Returns(`FROM "checklist_template_items"`, []string{"id", "template_id", "title", "assignee_type", "assignee_employee_id", "due_days"},
+				[]interface{}{1, 1, "Read handbook", AssigneeEmployee, nil, 0},
+				[]interface{}{2, 1, "Buddy up", AssigneeManager, nil, 3},
+				[]interface{}{3, 1, "Issue badge", AssigneeSpecific, 42, 1},
+			)
+
+			if err := tt.run(service, db); err != nil {
+				t.Fatalf("lifecycle hook: %v", err)
+			}
+
+			templates := fake.Find(`FROM "checklist_templates"`)
+			if len(templates) != 1 || !templates[0].Has(tt.kind) || !templates[0].Has("nurse") {
+				t.Errorf("templates fetched with %+v", templates)
+			}
+			if checklists := fake.Find(`INSERT INTO "checklists"`); len(checklists) != 1 || !checklists[0].Has(7) || !checklists[0].Has(tt.kind) {
+				t.Errorf("checklists created: %+v", checklists)
+			}
+			tasks := fake.Find(`INSERT INTO "checklist_tasks"`)
+			want := []struct {
+				assignee int
+				due      time.Time
+			}{{7, tt.start}, {8, tt.start.AddDate(0, 0, 3)}, {42, tt.start.AddDate(0, 0, 1)}}
+			if len(tasks) != len(want) {
+				t.Fatalf("created %d tasks, want %d", len(tasks), len(want))
+			}
+			for i, w := range want {
+				if !tasks[i].Has(w.assignee) || !tasks[i].Has(w.due) || !tasks[i].Has(TaskStatusPending) {
+					t.Errorf("task %d created with %v, want assignee %d due %s", i, tasks[i].Args, w.assignee, w.due.Format("2006-01-02"))
+				}
+			}
+
+			users := fake.Find(`UPDATE "users" SET "disabled"`)
+			if len(users) != 1 || !users[0].Has(tt.disabled) || !users[0].Has(7) {
+				t.Errorf("user account updated with %+v, want disabled %v", users, tt.disabled)
+			}
+		})
+	}
+}
+
+func TestSendOverdueReminders(t *testing.T) {
+	now := time.Date(2024, time.March, 12, 9, 0, 0, 0, time.UTC)
+	service, fake := newService(t)
+	fake.Returns(`FROM "checklist_tasks"`, taskColumns,
+		[]interface{}{1, 2, "Issue badge", 42, day(10), TaskStatusPending, nil, nil, nil},
+		[]interface{}{2, 2, "Buddy up", nil, day(11), TaskStatusPending, nil, nil, nil},
+		[]interface{}{3, 2, "Read handbook", 7, day(9), TaskStatusInProgress, nil, nil, now.Add(-time.Hour)},
+		[]interface{}{4, 2, "Collect keys", 7, day(9), TaskStatusPending, nil, nil, now.AddDate(0, 0, -1)},
+	)
+	fake.Returns(`FROM "checklists"`, []string{"id", "employee_id", "kind"}, []interface{}{2, 7, KindOnboarding})
+	fake.Returns(`FROM "employees"`, []string{"id", "manager_id"}, []interface{}{7, 8})
+
+	sent, err := service.SendOverdueReminders(now)
+	if err != nil {
+		t.Fatalf("SendOverdueReminders: %v", err)
+	}
+	if sent != 3 {
+		t.Errorf("sent %d reminders, want 3", sent)
+	}
+
+	overdue := fake.Find(`FROM "checklist_tasks"`)
+	if len(overdue) != 1 || !overdue[0].Has(day(12)) {
+		t.Errorf("overdue tasks fetched with %+v", overdue)
+	}
+	notifications := fake.Find(`INSERT INTO "notifications"`)
+	recipients := []int{42, 8, 7}
+	if len(notifications) != len(recipients) {
+		t.Fatalf("sent %d notifications, want %d", len(notifications), len(recipients))
+	}
+	for i, recipient := range recipients {
+		if !notifications[i].Has(recipient) || !notifications[i].Has("checklist_overdue") {
+			t.Errorf("notification %d sent with %v, want recipient %d", i, notifications[i].Args, recipient)
+		}
+	}
+	if reminded := fake.Find(`"last_reminded_at"`); len(reminded) != 3 {
+		t.Errorf("recorded %d reminders, want 3", len(reminded))
+	}
+}
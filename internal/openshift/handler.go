@@ -0,0 +1,204 @@
+// internal/openshift/handler.go
+package openshift
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type OpenShiftHandler struct {
+	service OpenShiftService
+}
+
+func NewOpenShiftHandler(service OpenShiftService) *OpenShiftHandler {
+	return &OpenShiftHandler{service: service}
+}
+
+func (h *OpenShiftHandler) CreateOpenShift(w http.ResponseWriter, r *http.Request) {
+	var openShift OpenShift
+	if err := json.NewDecoder(r.Body).Decode(&openShift); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	created, err := h.service.CreateOpenShift(user, openShift)
+	if err != nil {
+		sendOpenShiftError(w, err, "Failed to create open shift")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+// GetOpenShifts lists open shifts, optionally by status and between from and
+// to (YYYY-MM-DD)
+func (h *OpenShiftHandler) GetOpenShifts(w http.ResponseWriter, r *http.Request) {
+	filter := Filter{Status: r.URL.Query().Get("status")}
+	for key, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := r.URL.Query().Get(key); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid "+key+" date, expected YYYY-MM-DD", nil)
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	openShifts, err := h.service.GetOpenShifts(filter)
+	if err != nil {
+		sendOpenShiftError(w, err, "Failed to retrieve open shifts")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, openShifts, nil, nil)
+}
+
+func (h *OpenShiftHandler) GetAvailableOpenShifts(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+
+	openShifts, err := h.service.GetAvailableOpenShifts(user.EmployeeID)
+	if err != nil {
+		sendOpenShiftError(w, err, "Failed to retrieve open shifts")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, openShifts, nil, nil)
+}
+
+// GetOpenShift returns an open shift with its bids. Employees only see their
+// own bid.
+func (h *OpenShiftHandler) GetOpenShift(w http.ResponseWriter, r *http.Request) {
+	id, ok := openShiftID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	openShift, err := h.service.GetOpenShift(id)
+	if err != nil {
+		sendOpenShiftError(w, err, "Failed to retrieve open shift")
+		return
+	}
+
+	if !user.IsPrivileged() {
+		var own []ShiftBid
+		for _, bid := range openShift.Bids {
+			if bid.EmployeeID == user.EmployeeID {
+				own = append(own, bid)
+			}
+		}
+		openShift.Bids = own
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, openShift, nil, nil)
+}
+
+func (h *OpenShiftHandler) CancelOpenShift(w http.ResponseWriter, r *http.Request) {
+	id, ok := openShiftID(w, r)
+	if !ok {
+		return
+	}
+
+	openShift, err := h.service.CancelOpenShift(id)
+	if err != nil {
+		sendOpenShiftError(w, err, "Failed to cancel open shift")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, openShift, nil, nil)
+}
+
+func (h *OpenShiftHandler) Award(w http.ResponseWriter, r *http.Request) {
+	id, ok := openShiftID(w, r)
+	if !ok {
+		return
+	}
+
+	openShift, err := h.service.Award(id)
+	if err != nil {
+		sendOpenShiftError(w, err, "Failed to award open shift")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, openShift, nil, nil)
+}
+
+// Bid claims or bids on an open shift for the user's employee
+func (h *OpenShiftHandler) Bid(w http.ResponseWriter, r *http.Request) {
+	id, ok := openShiftID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("Error decoding request body: %v", err)
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+			return
+		}
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	bid, err := h.service.Bid(id, user.EmployeeID, body.Note)
+	if err != nil {
+		sendOpenShiftError(w, err, "Failed to bid on open shift")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, bid, nil, nil)
+}
+
+func (h *OpenShiftHandler) WithdrawBid(w http.ResponseWriter, r *http.Request) {
+	id, ok := openShiftID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	if err := h.service.WithdrawBid(id, user.EmployeeID); err != nil {
+		sendOpenShiftError(w, err, "Failed to withdraw bid")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, map[string]string{"message": "Bid withdrawn"}, nil, nil)
+}
+
+func openShiftID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid open shift ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid open shift ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func sendOpenShiftError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrOpenShiftNotFound), errors.Is(err, ErrBidNotFound),
+		errors.Is(err, employee.ErrShiftNotFound), errors.Is(err, employee.ErrEmployeeNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case errors.Is(err, ErrInvalidOpenShift), errors.Is(err, ErrInvalidAwardRule):
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case errors.Is(err, ErrBiddingClosed), errors.Is(err, ErrNotEligible), errors.Is(err, ErrAlreadyBid):
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
@@ -0,0 +1,58 @@
+package openshift
+
+import (
+	"clinicplus/internal/employee"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Award rules decide which bidders win an open shift
+const (
+	AwardFirstCome   = "first_come"   // Claims are granted on the spot until the slots are filled
+	AwardSeniority   = "seniority"    // Earliest HireDate wins when bidding closes
+	AwardFewestHours = "fewest_hours" // Fewest hours assigned in the shift's week wins when bidding closes
+)
+
+const (
+	StatusOpen      = "open"
+	StatusAwarded   = "awarded"  // Every slot was filled
+	StatusUnfilled  = "unfilled" // Bidding closed with slots left
+	StatusCancelled = "cancelled"
+)
+
+const (
+	BidStatusPending   = "pending"
+	BidStatusWon       = "won"
+	BidStatusLost      = "lost"
+	BidStatusWithdrawn = "withdrawn"
+)
+
+// OpenShift publishes an occurrence of a shift that still needs staff, for
+// eligible employees to claim or bid on until the deadline
+type OpenShift struct {
+	gorm.Model
+	ShiftID     uint      `gorm:"not null;index" json:"shift_id"`
+	Date        time.Time `gorm:"type:date;not null;index" json:"date"`
+	Slots       int       `gorm:"not null;default:1" json:"slots"` // Employees needed
+	Designation string    `json:"designation"`                     // Who may bid; empty means anyone
+	AwardRule   string    `gorm:"not null" json:"award_rule"`      // first_come, seniority or fewest_hours
+	Deadline    time.Time `gorm:"not null;index" json:"deadline"`  // Bidding closes; defaults to the start of the shift
+	Status      string    `gorm:"not null;index" json:"status"`
+	Note        string    `json:"note"`
+	CreatedBy   uint      `json:"created_by"` // iam.User who published the open shift
+
+	Shift employee.Shift `gorm:"foreignkey:ShiftID" json:"shift"`
+	Bids  []ShiftBid     `gorm:"foreignkey:OpenShiftID" json:"bids,omitempty"`
+}
+
+// ShiftBid is an employee's claim on an open shift
+type ShiftBid struct {
+	gorm.Model
+	OpenShiftID     uint   `gorm:"not null;unique_index:idx_shift_bid" json:"open_shift_id"`
+	EmployeeID      uint   `gorm:"not null;unique_index:idx_shift_bid" json:"employee_id"`
+	Status          string `gorm:"not null" json:"status"`
+	Note            string `json:"note"`
+	Outcome         string `json:"outcome"`           // Why a bid lost, e.g. a rejected assignment
+	EmployeeShiftID *uint  `json:"employee_shift_id"` // Assignment created for a winning bid
+}
@@ -0,0 +1,566 @@
+// internal/openshift/service.go
+package openshift
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/leave"
+	"clinicplus/internal/notification"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrOpenShiftNotFound = errors.New("open shift not found")
+	ErrInvalidOpenShift  = errors.New("open shift needs a shift running on a future date, at least one slot and a deadline before the shift starts")
+	ErrInvalidAwardRule  = errors.New("award rule must be first_come, seniority or fewest_hours")
+	ErrBiddingClosed     = errors.New("open shift is no longer taking bids")
+	ErrNotEligible       = errors.New("not eligible for this open shift")
+	ErrAlreadyBid        = errors.New("already bid on this open shift")
+	ErrBidNotFound       = errors.New("bid not found")
+)
+
+// Filter narrows open shift listings; zero values match everything
+type Filter struct {
+	Status string
+	From   time.Time
+	To     time.Time
+}
+
+type OpenShiftService interface {
+	CreateOpenShift(user *iam.User, openShift OpenShift) (*OpenShift, error)
+	GetOpenShifts(filter Filter) ([]OpenShift, error)
+	GetAvailableOpenShifts(employeeID uint) ([]OpenShift, error)
+	GetOpenShift(id uint) (*OpenShift, error)
+	CancelOpenShift(id uint) (*OpenShift, error)
+
+	Bid(id, employeeID uint, note string) (*ShiftBid, error)
+	WithdrawBid(id, employeeID uint) error
+	Award(id uint) (*OpenShift, error)
+	AwardDue(now time.Time) (int, error)
+}
+
+type openShiftService struct {
+	db            *gorm.DB
+	employees     employee.EmployeeService
+	notifications notification.NotificationService
+}
+
+func NewOpenShiftService(db *gorm.DB, employees employee.EmployeeService, notifications notification.NotificationService) OpenShiftService {
+	return &openShiftService{db: db, employees: employees, notifications: notifications}
+}
+
+// CreateOpenShift publishes a shift occurrence for bidding and lets the
+// employees who could take it know
+func (s *openShiftService) CreateOpenShift(user *iam.User, openShift OpenShift) (*OpenShift, error) {
+	if openShift.AwardRule == "" {
+		openShift.AwardRule = AwardFirstCome
+	}
+	if openShift.AwardRule != AwardFirstCome && openShift.AwardRule != AwardSeniority && openShift.AwardRule != AwardFewestHours {
+		return nil, ErrInvalidAwardRule
+	}
+	if openShift.Slots == 0 {
+		openShift.Slots = 1
+	}
+
+	var shift employee.Shift
+	if err := s.db.First(&shift, openShift.ShiftID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrShiftNotFound
+		}
+		log.Printf("Error fetching shift: %v", err)
+		return nil, err
+	}
+
+	openShift.Date = dateOf(openShift.Date)
+	window, ok := shift.OccurrenceOn(openShift.Date)
+	if openShift.Deadline.IsZero() {
+		openShift.Deadline = window.Start
+	}
+	now := time.Now()
+	if !ok || openShift.Slots < 0 || !window.Start.After(now) ||
+		!openShift.Deadline.After(now) || openShift.Deadline.After(window.Start) {
+		return nil, ErrInvalidOpenShift
+	}
+
+	openShift.ID = 0
+	openShift.Status = StatusOpen
+	openShift.CreatedBy = user.ID
+	openShift.Bids = nil
+	if err := s.db.Omit("Shift", "Bids").Create(&openShift).Error; err != nil {
+		log.Printf("Error creating open shift: %v", err)
+		return nil, err
+	}
+	openShift.Shift = shift
+
+	s.announce(&openShift)
+	return &openShift, nil
+}
+
+func (s *openShiftService) GetOpenShifts(filter Filter) ([]OpenShift, error) {
+	var openShifts []OpenShift
+	query := s.db.Preload("Shift")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("date >= ?", dateOf(filter.From))
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("date <= ?", dateOf(filter.To))
+	}
+
+	if err := query.Order("date").Find(&openShifts).Error; err != nil {
+		log.Printf("Error fetching open shifts: %v", err)
+		return nil, err
+	}
+	return openShifts, nil
+}
+
+// GetAvailableOpenShifts returns the open shifts still taking bids whose
+// designation and location suit the employee
+func (s *openShiftService) GetAvailableOpenShifts(employeeID uint) ([]OpenShift, error) {
+	emp, err := s.employee(employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Preload("Shift").
+		Joins("JOIN shifts ON shifts.id = open_shifts.shift_id").
+		Where("open_shifts.status = ? AND open_shifts.deadline > ?", StatusOpen, time.Now()).
+		Where("open_shifts.designation = '' OR LOWER(open_shifts.designation) = ?", strings.ToLower(emp.Designation))
+	if emp.LocationID != nil {
+		query = query.Where("shifts.location_id IS NULL OR shifts.location_id = ?", *emp.LocationID)
+	}
+
+	var openShifts []OpenShift
+	if err := query.Order("open_shifts.date").Find(&openShifts).Error; err != nil {
+		log.Printf("Error fetching available open shifts: %v", err)
+		return nil, err
+	}
+	return openShifts, nil
+}
+
+func (s *openShiftService) GetOpenShift(id uint) (*OpenShift, error) {
+	return s.openShift(s.db.Preload("Shift").Preload("Bids", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}), id)
+}
+
+// CancelOpenShift stops bidding on an open shift. Claims already granted keep
+// their assignments; pending bids lose.
+func (s *openShiftService) CancelOpenShift(id uint) (*OpenShift, error) {
+	openShift, err := s.openShift(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if openShift.Status != StatusOpen {
+		return nil, ErrBiddingClosed
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+	if err := tx.Model(openShift).UpdateColumn("status", StatusCancelled).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error cancelling open shift: %v", err)
+		return nil, err
+	}
+	err = tx.Model(&ShiftBid{}).
+		Where("open_shift_id = ? AND status = ?", id, BidStatusPending).
+		Updates(map[string]interface{}{"status": BidStatusLost, "outcome": "open shift cancelled"}).Error
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error closing bids: %v", err)
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+
+	return s.GetOpenShift(id)
+}
+
+// Bid records an eligible employee's bid. First-come open shifts grant the
+// claim on the spot by assigning the shift; others wait for the deadline.
+// The open shift is locked meanwhile so concurrent claims cannot overfill it.
+func (s *openShiftService) Bid(id, employeeID uint, note string) (*ShiftBid, error) {
+	emp, err := s.employee(employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	openShift, err := s.openShift(tx.Preload("Shift").Set("gorm:query_option", "FOR UPDATE"), id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if openShift.Status != StatusOpen || !time.Now().Before(openShift.Deadline) {
+		tx.Rollback()
+		return nil, ErrBiddingClosed
+	}
+	if err := s.checkEligibility(emp, openShift); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var bid ShiftBid
+	err = tx.Where("open_shift_id = ? AND employee_id = ?", id, employeeID).First(&bid).Error
+	switch {
+	case err == nil && bid.Status != BidStatusWithdrawn:
+		tx.Rollback()
+		return nil, ErrAlreadyBid
+	case err != nil && !gorm.IsRecordNotFoundError(err):
+		tx.Rollback()
+		log.Printf("Error fetching bid: %v", err)
+		return nil, err
+	}
+	bid.OpenShiftID, bid.EmployeeID = id, employeeID
+	bid.Status, bid.Note, bid.Outcome = BidStatusPending, note, ""
+
+	if openShift.AwardRule == AwardFirstCome {
+		assignment, err := s.assign(tx, openShift, employeeID)
+		if err != nil {
+			tx.Rollback()
+			if errors.Is(err, employee.ErrAssignmentRejected) {
+				return nil, fmt.Errorf("%w: %v", ErrNotEligible, err)
+			}
+			return nil, err
+		}
+		bid.Status = BidStatusWon
+		bid.EmployeeShiftID = &assignment.ID
+	}
+
+	if err := tx.Save(&bid).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error saving bid: %v", err)
+		return nil, err
+	}
+
+	if bid.Status == BidStatusWon {
+		var won int
+		tx.Model(&ShiftBid{}).Where("open_shift_id = ? AND status = ?", id, BidStatusWon).Count(&won)
+		if won >= openShift.Slots {
+			if err := tx.Model(openShift).UpdateColumn("status", StatusAwarded).Error; err != nil {
+				tx.Rollback()
+				log.Printf("Error closing open shift: %v", err)
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+
+	if bid.Status == BidStatusWon {
+		s.notifyOutcome(openShift, bid)
+	}
+	return &bid, nil
+}
+
+// WithdrawBid takes back a pending bid while bidding is open
+func (s *openShiftService) WithdrawBid(id, employeeID uint) error {
+	result := s.db.Model(&ShiftBid{}).
+		Where("open_shift_id = ? AND employee_id = ? AND status = ?", id, employeeID, BidStatusPending).
+		UpdateColumn("status", BidStatusWithdrawn)
+	if result.Error != nil {
+		log.Printf("Error withdrawing bid: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBidNotFound
+	}
+	return nil
+}
+
+// Award closes bidding on an open shift and assigns it to the best pending
+// bidders under its award rule. A bidder whose assignment is rejected, e.g.
+// for overlapping another shift by now, loses and the next one is tried.
+func (s *openShiftService) Award(id uint) (*OpenShift, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	openShift, err := s.openShift(tx.Preload("Shift").Set("gorm:query_option", "FOR UPDATE"), id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if openShift.Status != StatusOpen {
+		tx.Rollback()
+		return nil, ErrBiddingClosed
+	}
+
+	var bids []ShiftBid
+	if err := tx.Where("open_shift_id = ? AND status IN (?)", id, []string{BidStatusPending, BidStatusWon}).Order("created_at").Find(&bids).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error fetching bids: %v", err)
+		return nil, err
+	}
+	ranked, err := s.rank(openShift, bids)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	won := 0
+	for _, bid := range bids {
+		if bid.Status == BidStatusWon {
+			won++
+		}
+	}
+
+	var decided []ShiftBid
+	for _, bid := range ranked {
+		if won >= openShift.Slots {
+			bid.Status, bid.Outcome = BidStatusLost, "all slots were filled"
+		} else if assignment, err := s.assign(tx, openShift, bid.EmployeeID); err == nil {
+			bid.Status, bid.EmployeeShiftID = BidStatusWon, &assignment.ID
+			won++
+		} else if errors.Is(err, employee.ErrAssignmentRejected) || errors.Is(err, employee.ErrEmployeeNotFound) {
+			bid.Status, bid.Outcome = BidStatusLost, err.Error()
+		} else {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if err := tx.Save(&bid).Error; err != nil {
+			tx.Rollback()
+			log.Printf("Error saving bid: %v", err)
+			return nil, err
+		}
+		decided = append(decided, bid)
+	}
+
+	openShift.Status = StatusAwarded
+	if won < openShift.Slots {
+		openShift.Status = StatusUnfilled
+	}
+	if err := tx.Model(openShift).UpdateColumn("status", openShift.Status).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error closing open shift: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+
+	for _, bid := range decided {
+		s.notifyOutcome(openShift, bid)
+	}
+	return s.GetOpenShift(id)
+}
+
+// AwardDue awards every open shift whose deadline has passed and returns how
+// many were closed
+func (s *openShiftService) AwardDue(now time.Time) (int, error) {
+	var due []OpenShift
+	if err := s.db.Where("status = ? AND deadline <= ?", StatusOpen, now).Find(&due).Error; err != nil {
+		log.Printf("Error fetching due open shifts: %v", err)
+		return 0, err
+	}
+
+	closed := 0
+	for _, openShift := range due {
+		if _, err := s.Award(openShift.ID); err != nil {
+			if errors.Is(err, ErrBiddingClosed) {
+				continue
+			}
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// rank orders pending bids under the open shift's award rule. Ties, and
+// first-come shifts, go to the earliest bid.
+func (s *openShiftService) rank(openShift *OpenShift, bids []ShiftBid) ([]ShiftBid, error) {
+	var pending []ShiftBid
+	for _, bid := range bids {
+		if bid.Status == BidStatusPending {
+			pending = append(pending, bid)
+		}
+	}
+
+	switch openShift.AwardRule {
+	case AwardSeniority:
+		hired := make(map[uint]time.Time, len(pending))
+		for _, bid := range pending {
+			emp, err := s.employee(bid.EmployeeID)
+			if err != nil && !errors.Is(err, employee.ErrEmployeeNotFound) {
+				return nil, err
+			}
+			if emp != nil && !emp.HireDate.IsZero() {
+				hired[bid.EmployeeID] = emp.HireDate
+			}
+		}
+		sort.SliceStable(pending, func(i, j int) bool {
+			a, aok := hired[pending[i].EmployeeID]
+			b, bok := hired[pending[j].EmployeeID]
+			if aok != bok {
+				return aok
+			}
+			return a.Before(b)
+		})
+	case AwardFewestHours:
+		hours := make(map[uint]time.Duration, len(pending))
+		for _, bid := range pending {
+			worked, err := s.weekHours(bid.EmployeeID, openShift.Date)
+			if err != nil {
+				return nil, err
+			}
+			hours[bid.EmployeeID] = worked
+		}
+		sort.SliceStable(pending, func(i, j int) bool {
+			return hours[pending[i].EmployeeID] < hours[pending[j].EmployeeID]
+		})
+	}
+	return pending, nil
+}
+
+// weekHours returns the paid hours an employee is assigned in the week
+// (Monday to Sunday) containing date
+func (s *openShiftService) weekHours(employeeID uint, date time.Time) (time.Duration, error) {
+	from := date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+	to := from.AddDate(0, 0, 6)
+
+	var assignments []employee.EmployeeShift
+	err := s.db.Preload("Shift").
+		Where("employee_id = ? AND start_date <= ? AND end_date >= ?", employeeID, to, from).
+		Find(&assignments).Error
+	if err != nil {
+		log.Printf("Error fetching shift assignments: %v", err)
+		return 0, err
+	}
+
+	var total time.Duration
+	for _, assignment := range assignments {
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			if day.Before(dateOf(assignment.StartDate)) || day.After(dateOf(assignment.EndDate)) {
+				continue
+			}
+			if _, ok := assignment.Shift.OccurrenceOn(day); ok {
+				total += assignment.Shift.PaidDuration()
+			}
+		}
+	}
+	return total, nil
+}
+
+// checkEligibility rejects employees who may not work the open shift: the
+// wrong designation or location, not currently working, or on leave that day.
+// Double bookings and assignment guards are checked when the shift is assigned.
+func (s *openShiftService) checkEligibility(emp *employee.Employee, openShift *OpenShift) error {
+	if openShift.Designation != "" && !strings.EqualFold(openShift.Designation, emp.Designation) {
+		return fmt.Errorf("%w: open to %s only", ErrNotEligible, openShift.Designation)
+	}
+	if location := openShift.Shift.LocationID; location != nil && emp.LocationID != nil && *location != *emp.LocationID {
+		return fmt.Errorf("%w: shift is at another location", ErrNotEligible)
+	}
+	if !emp.CanWork() {
+		return fmt.Errorf("%w: employee is %s", ErrNotEligible, emp.EmploymentStatus)
+	}
+
+	var onLeave int
+	s.db.Model(&leave.LeaveRequest{}).
+		Where("employee_id = ? AND status = ? AND start_date <= ? AND end_date >= ?", emp.ID, leave.StatusApproved, openShift.Date, openShift.Date).
+		Count(&onLeave)
+	if onLeave > 0 {
+		return fmt.Errorf("%w: on leave on %s", ErrNotEligible, openShift.Date.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// assign gives the open shift's occurrence to an employee through the usual
+// assignment checks, within the transaction deciding the bids
+func (s *openShiftService) assign(tx *gorm.DB, openShift *OpenShift, employeeID uint) (*employee.EmployeeShift, error) {
+	return s.employees.CreateAssignmentIn(tx, employee.EmployeeShift{
+		EmployeeID: employeeID,
+		ShiftID:    openShift.ShiftID,
+		StartDate:  openShift.Date,
+		EndDate:    openShift.Date,
+		Source:     employee.AssignmentSourceOpenShift,
+	})
+}
+
+// announce notifies the current employees who could bid on a new open shift
+func (s *openShiftService) announce(openShift *OpenShift) {
+	query := s.db.Model(&employee.Employee{}).
+		Where("employment_status IN (?)", []string{employee.EmploymentStatusActive, employee.EmploymentStatusProbation})
+	if openShift.Designation != "" {
+		query = query.Where("LOWER(designation) = ?", strings.ToLower(openShift.Designation))
+	}
+	if openShift.Shift.LocationID != nil {
+		query = query.Where("location_id IS NULL OR location_id = ?", *openShift.Shift.LocationID)
+	}
+
+	var employeeIDs []uint
+	if err := query.Pluck("id", &employeeIDs).Error; err != nil {
+		log.Printf("Error fetching employees for open shift %d: %v", openShift.ID, err)
+		return
+	}
+
+	subject := fmt.Sprintf("Open shift: %s on %s", openShift.Shift.Name, openShift.Date.Format("2006-01-02"))
+	body := fmt.Sprintf("%d slot(s), bidding closes %s. %s", openShift.Slots, openShift.Deadline.Format("2006-01-02 15:04 MST"), openShift.Note)
+	for _, employeeID := range employeeIDs {
+		s.notifications.Notify(employeeID, "open_shift", subject, body)
+	}
+}
+
+func (s *openShiftService) notifyOutcome(openShift *OpenShift, bid ShiftBid) {
+	subject := fmt.Sprintf("You did not get the %s shift on %s", openShift.Shift.Name, openShift.Date.Format("2006-01-02"))
+	if bid.Status == BidStatusWon {
+		subject = fmt.Sprintf("You got the %s shift on %s", openShift.Shift.Name, openShift.Date.Format("2006-01-02"))
+	}
+	s.notifications.Notify(bid.EmployeeID, "open_shift_award", subject, bid.Outcome)
+}
+
+func (s *openShiftService) openShift(db *gorm.DB, id uint) (*OpenShift, error) {
+	var openShift OpenShift
+	if err := db.First(&openShift, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrOpenShiftNotFound
+		}
+		log.Printf("Error fetching open shift: %v", err)
+		return nil, err
+	}
+	return &openShift, nil
+}
+
+func (s *openShiftService) employee(id uint) (*employee.Employee, error) {
+	var emp employee.Employee
+	if err := s.db.First(&emp, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+	return &emp, nil
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
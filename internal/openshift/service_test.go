@@ -0,0 +1,243 @@
+package openshift
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/testdb"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// assigner stands in for the employee service, recording the employees open
+// shifts are assigned to
+type assigner struct {
+	employee.EmployeeService
+	assigned []uint
+	reject   map[uint]bool // Employees the assignment guards refuse
+}
+
+func (a *assigner) CreateAssignmentIn(tx *gorm.DB, assignment employee.EmployeeShift) (*employee.EmployeeShift, error) {
+	if a.reject[assignment.EmployeeID] {
+		return nil, fmt.Errorf("%w: overlaps another shift", employee.ErrAssignmentRejected)
+	}
+	if assignment.Source != employee.AssignmentSourceOpenShift || !assignment.StartDate.Equal(assignment.EndDate) {
+		return nil, fmt.Errorf("unexpected assignment %+v", assignment)
+	}
+	a.assigned = append(a.assigned, assignment.EmployeeID)
+	assignment.ID = uint(300 + len(a.assigned))
+	return &assignment, nil
+}
+
+var (
+	openShiftColumns = []string{"id", "shift_id", "date", "slots", "designation", "award_rule", "deadline", "status"}
+	employeeColumns  = []string{"id", "designation", "employment_status", "location_id", "hire_date"}
+)
+
+// scriptShift scripts the Day shift at location 1, running every day
+func scriptShift(fake *testdb.DB) {
+	fake.Returns(`FROM "shifts"`, []string{"id", "name", "start_time", "end_time", "days_of_week", "location_id"},
+		[]interface{}{1, "Day", "08:00", "16:00", "mon,tue,wed,thu,fri,sat,sun", 1})
+}
+
+func TestBid(t *testing.T) {
+	date := dateOf(time.Now()).AddDate(0, 0, 7)
+	deadline := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name       string
+		rule       string
+		status     string
+		deadline   time.Time
+		employee   []interface{}
+		previous   string // Status of an earlier bid by the employee, if any
+		onLeave    bool
+		reject     bool
+		filled     bool // The claim fills the last slot
+		wantErr    error
+		wantStatus string
+	}{
+		{
+			name: "first come claim", rule: AwardFirstCome,
+			employee:   []interface{}{5, "Nurse", employee.EmploymentStatusActive, 1, nil},
+			wantStatus: BidStatusWon,
+		},
+		{
+			name: "first come claim filling the last slot", rule: AwardFirstCome, filled: true,
+			employee:   []interface{}{5, "Nurse", employee.EmploymentStatusActive, 1, nil},
+			wantStatus: BidStatusWon,
+		},
+		{
+			name: "seniority bid waits for the deadline", rule: AwardSeniority,
+			employee:   []interface{}{5, "nurse", employee.EmploymentStatusProbation, nil, nil},
+			wantStatus: BidStatusPending,
+		},
+		{
+			name: "bid again after withdrawing", rule: AwardSeniority, previous: BidStatusWithdrawn,
+			employee:   []interface{}{5, "Nurse", employee.EmploymentStatusActive, 1, nil},
+			wantStatus: BidStatusPending,
+		},
+		{
+			name: "second bid", rule: AwardSeniority, previous: BidStatusPending,
+			employee: []interface{}{5, "Nurse", employee.EmploymentStatusActive, 1, nil},
+			wantErr:  ErrAlreadyBid,
+		},
+		{
+			name: "another designation", rule: AwardFirstCome,
+			employee: []interface{}{5, "Doctor", employee.EmploymentStatusActive, 1, nil},
+			wantErr:  ErrNotEligible,
+		},
+		{
+			name: "another location", rule: AwardFirstCome,
+			employee: []interface{}{5, "Nurse", employee.EmploymentStatusActive, 2, nil},
+			wantErr:  ErrNotEligible,
+		},
+		{
+			name: "suspended", rule: AwardFirstCome,
+			employee: []interface{}{5, "Nurse", employee.EmploymentStatusSuspended, 1, nil},
+			wantErr:  ErrNotEligible,
+		},
+		{
+			name: "on leave", rule: AwardFirstCome, onLeave: true,
+			employee: []interface{}{5, "Nurse", employee.EmploymentStatusActive, 1, nil},
+			wantErr:  ErrNotEligible,
+		},
+		{
+			name: "refused by the assignment guards", rule: AwardFirstCome, reject: true,
+			employee: []interface{}{5, "Nurse", employee.EmploymentStatusActive, 1, nil},
+			wantErr:  ErrNotEligible,
+		},
+		{
+			name: "after the deadline", rule: AwardFirstCome, deadline: time.Now().Add(-time.Minute),
+			employee: []interface{}{5, "Nurse", employee.EmploymentStatusActive, 1, nil},
+			wantErr:  ErrBiddingClosed,
+		},
+		{
+			name: "cancelled", rule: AwardFirstCome, status: StatusCancelled,
+			employee: []interface{}{5, "Nurse", employee.EmploymentStatusActive, 1, nil},
+			wantErr:  ErrBiddingClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			scriptShift(fake)
+			status, closes := tt.status, tt.deadline
+			if status == "" {
+				status = StatusOpen
+			}
+			if closes.IsZero() {
+				closes = deadline
+			}
+			fake.Returns(`FROM "open_shifts"`, openShiftColumns, []interface{}{1, 1, date, 1, "Nurse", tt.rule, closes, status})
+			fake.Returns(`FROM "employees"`, employeeColumns, tt.employee)
+			if tt.filled {
+				fake.Returns(`count(*) FROM "shift_bids"`, []string{"count"}, []interface{}{1})
+			}
+			if tt.previous != "" {
+				fake.Returns(`FROM "shift_bids"`, []string{"id", "open_shift_id", "employee_id", "status"}, []interface{}{9, 1, 5, tt.previous})
+			}
+			if tt.onLeave {
+				fake.Returns(`FROM "leave_requests"`, []string{"count"}, []interface{}{1})
+			}
+			employees := &assigner{reject: map[uint]bool{5: tt.reject}}
+			service := NewOpenShiftService(db, employees, notification.NewNotificationService(db))
+
+			bid, err := service.Bid(1, 5, "Available")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Bid error %v, want %v", err, tt.wantErr)
+			}
+			if saved := fake.Ran(`INSERT INTO "shift_bids"`) || fake.Ran(`UPDATE "shift_bids"`); saved != (tt.wantErr == nil) {
+				t.Errorf("bid saved: %v", saved)
+			}
+			if err != nil {
+				return
+			}
+
+			if bid.Status != tt.wantStatus {
+				t.Errorf("bid %s, want %s", bid.Status, tt.wantStatus)
+			}
+			won := tt.wantStatus == BidStatusWon
+			var wantAssigned []uint
+			if won {
+				wantAssigned = []uint{5}
+			}
+			if !reflect.DeepEqual(employees.assigned, wantAssigned) || (bid.EmployeeShiftID != nil) != won {
+				t.Errorf("assigned %v with assignment %v", employees.assigned, bid.EmployeeShiftID)
+			}
+			closed := fake.Find(`UPDATE "open_shifts" SET "status"`)
+			if awarded := len(closed) == 1 && closed[0].Has(StatusAwarded); awarded != tt.filled {
+				t.Errorf("open shift closed with %+v, want awarded: %v", closed, tt.filled)
+			}
+			if notified := fake.Ran(`INSERT INTO "notifications"`); notified != won {
+				t.Errorf("bidder notified: %v, want %v", notified, won)
+			}
+		})
+	}
+}
+
+func TestAward(t *testing.T) {
+	date := dateOf(time.Now()).AddDate(0, 0, 7)
+	hired := func(year int) time.Time { return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		rule       string
+		slots      int
+		reject     map[uint]bool
+		want       []uint // Employees assigned, in order
+		wantStatus string
+	}{
+		{name: "first come", rule: AwardFirstCome, slots: 2, want: []uint{5, 6}, wantStatus: StatusAwarded},
+		{name: "seniority", rule: AwardSeniority, slots: 2, want: []uint{6, 7}, wantStatus: StatusAwarded},
+		{name: "rejected bidders make way", rule: AwardSeniority, slots: 2, reject: map[uint]bool{7: true}, want: []uint{6, 5}, wantStatus: StatusAwarded},
+		{name: "unfilled", rule: AwardSeniority, slots: 3, reject: map[uint]bool{7: true}, want: []uint{6, 5}, wantStatus: StatusUnfilled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			scriptShift(fake)
+			fake.Returns(`FROM "open_shifts"`, openShiftColumns, []interface{}{1, 1, date, tt.slots, "Nurse", tt.rule, time.Now(), StatusOpen})
+			fake.Returns(`FROM "shift_bids"`, []string{"id", "open_shift_id", "employee_id", "status"},
+				[]interface{}{21, 1, 5, BidStatusPending}, []interface{}{22, 1, 6, BidStatusPending}, []interface{}{23, 1, 7, BidStatusPending})
+			fake.Returns(`"employees"."id" = 5`, employeeColumns, []interface{}{5, "Nurse", employee.EmploymentStatusActive, nil, hired(2020)})
+			fake.Returns(`"employees"."id" = 6`, employeeColumns, []interface{}{6, "Nurse", employee.EmploymentStatusActive, nil, hired(2015)})
+			fake.Returns(`"employees"."id" = 7`, employeeColumns, []interface{}{7, "Nurse", employee.EmploymentStatusActive, nil, hired(2018)})
+			employees := &assigner{reject: tt.reject}
+			service := NewOpenShiftService(db, employees, notification.NewNotificationService(db))
+
+			if _, err := service.Award(1); err != nil {
+				t.Fatalf("Award: %v", err)
+			}
+			if !reflect.DeepEqual(employees.assigned, tt.want) {
+				t.Errorf("assigned %v, want %v", employees.assigned, tt.want)
+			}
+
+			bids := fake.Find(`UPDATE "shift_bids"`)
+			if len(bids) != 3 {
+				t.Fatalf("saved %d bids, want 3", len(bids))
+			}
+			for _, bid := range bids {
+				winner := false
+				for _, id := range tt.want {
+					winner = winner || bid.Has(int(id))
+				}
+				if won := bid.Has(BidStatusWon); won != winner {
+					t.Errorf("bid saved with %v, want won: %v", bid.Args, winner)
+				}
+			}
+			if closed := fake.Find(`UPDATE "open_shifts" SET "status"`); len(closed) != 1 || !closed[0].Has(tt.wantStatus) {
+				t.Errorf("open shift closed with %+v, want %s", closed, tt.wantStatus)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 3 {
+				t.Errorf("sent %d notifications, want one per bidder", len(notifications))
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+// internal/payroll/calculator.go
+package payroll
+
+import (
+	"clinicplus/internal/compensation"
+	"clinicplus/internal/employee"
+	"clinicplus/internal/holiday"
+	"clinicplus/internal/shared/config"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Policy holds the pay rules applied by the payroll engine
+type Policy struct {
+	StandardWeeklyHours decimal.Decimal
+	OvertimeThreshold   decimal.Decimal // Weekly hours after which overtime is paid
+	OvertimeMultiplier  decimal.Decimal
+	NightStartHour      int
+	NightEndHour        int
+	NightDifferential   decimal.Decimal // Premium as a fraction of the hourly rate
+	WeekendDifferential decimal.Decimal
+}
+
+// PolicyFromConfig builds the payroll policy from the PAYROLL_* environment variables
+func PolicyFromConfig() Policy {
+	nightStart, nightEnd := config.GetPayrollNightWindow()
+	return Policy{
+		StandardWeeklyHours: decimal.NewFromFloat(config.GetPayrollStandardWeeklyHours()),
+		OvertimeThreshold:   decimal.NewFromFloat(config.GetPayrollOvertimeThreshold()),
+		OvertimeMultiplier:  decimal.NewFromFloat(config.GetPayrollOvertimeMultiplier()),
+		NightStartHour:      nightStart,
+		NightEndHour:        nightEnd,
+		NightDifferential:   decimal.NewFromFloat(config.GetPayrollNightDifferential()),
+		WeekendDifferential: decimal.NewFromFloat(config.GetPayrollWeekendDifferential()),
+	}
+}
+
+// employeeData is everything the calculator needs to pay one employee
+type employeeData struct {
+	employee    employee.Employee
+	location    *time.Location
+	records     []compensation.CompensationRecord // Oldest first
+	attendance  []employee.Attendance             // From the start of the period's first week, by clock-in
+	assignments []employee.EmployeeShift
+	holidays    map[string]holiday.Holiday // Keyed by date (YYYY-MM-DD)
+}
+
+type lineKey struct {
+	kind        string
+	description string
+	rate        string
+}
+
+// calculation accumulates the entry for one employee. Amounts are summed
+// unrounded per line and rounded once when the entry is finished.
+type calculation struct {
+	policy   Policy
+	data     employeeData
+	today    time.Time
+	entry    PayrollEntry
+	lines    map[lineKey]*PayrollLine
+	order    []lineKey
+	warnings []string
+	warned   map[string]bool
+}
+
+// calculate computes an employee's payroll entry for the period from start to
+// end (inclusive). Hours are taken from attendance less unpaid breaks:
+// overtime is counted per
+// ISO week, including days of the week that fall before the period; night,
+// weekend and holiday premiums are paid on top of regular and overtime pay.
+// Hourly staff are paid for the hours they work or take as paid leave;
+// salaried staff earn their base pay per calendar day employed, less unpaid
+// absences from assigned shifts.
+func calculate(policy Policy, data employeeData, start, end, today time.Time) PayrollEntry {
+	c := &calculation{
+		policy: policy,
+		data:   data,
+		today:  dateOf(today),
+		entry: PayrollEntry{
+			EmployeeID:   data.employee.ID,
+			EmployeeName: data.employee.Name,
+		},
+		lines:  make(map[lineKey]*PayrollLine),
+		warned: make(map[string]bool),
+	}
+	if c.data.location == nil {
+		c.data.location = time.UTC
+	}
+
+	c.workedTime(start, end)
+	c.unpaidAbsences(start, end)
+	c.salariedPay(start, end)
+	return c.finish()
+}
+
+func (c *calculation) workedTime(start, end time.Time) {
+	weekly := make(map[string]decimal.Decimal)
+
+	for _, attendance := range c.data.attendance {
+		day := dateOf(attendance.Date)
+		inPeriod := !day.Before(start) && !day.After(end)
+
+		switch attendance.Status {
+		case employee.AttendanceStatusOnLeave:
+			if inPeriod {
+				c.paidLeave(day, attendance)
+			}
+			continue
+		case employee.AttendanceStatusAbsent:
+			continue
+		case employee.AttendanceStatusMissingClockOut:
+			if inPeriod && attendance.ClockOutTime.Valid {
+				c.warn(fmt.Sprintf("Clock-out missing on %s, closed at the shift end", day.Format("2006-01-02")))
+			}
+		}
+		if attendance.ManualEntry && inPeriod {
+			c.warn(fmt.Sprintf("Attendance on %s was entered by a correction", day.Format("2006-01-02")))
+		}
+		if !attendance.ClockOutTime.Valid {
+			if inPeriod {
+				c.warn(fmt.Sprintf("Missing clock-out on %s", day.Format("2006-01-02")))
+			}
+			continue
+		}
+
+		// Unpaid breaks are not worked time; a mandatory break that was not
+		// taken but deducted comes off the regular hours
+		windows := attendance.WorkedWindows()
+		if len(windows) == 0 {
+			continue
+		}
+		var clocked time.Duration
+		for _, window := range windows {
+			clocked += window.End.Sub(window.Start)
+		}
+		deducted := time.Duration(attendance.DeductedBreakMinutes) * time.Minute
+		if deducted > clocked {
+			deducted = clocked
+		}
+		worked := hoursOf(clocked - deducted)
+		if attendance.BreakViolation != "" && inPeriod {
+			c.warn(fmt.Sprintf("Mandatory break not taken on %s", day.Format("2006-01-02")))
+		}
+
+		year, week := day.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+		weekly[weekKey] = weekly[weekKey].Add(worked)
+		overtime := decimal.Max(decimal.Zero, decimal.Min(worked, weekly[weekKey].Sub(c.policy.OvertimeThreshold)))
+		if !inPeriod {
+			continue
+		}
+
+		regular := worked.Sub(overtime)
+		var nightWorked, weekendWorked time.Duration
+		var holidays []holidayHours
+		for _, window := range windows {
+			nightWorked += nightTime(window.Start, window.End, c.data.location, c.policy.NightStartHour, c.policy.NightEndHour)
+			weekendWorked += weekendTime(window.Start, window.End, c.data.location)
+			holidays = append(holidays, c.holidayTime(window.Start, window.End)...)
+		}
+		night, weekend := hoursOf(nightWorked), hoursOf(weekendWorked)
+
+		c.entry.RegularHours = c.entry.RegularHours.Add(regular)
+		c.entry.OvertimeHours = c.entry.OvertimeHours.Add(overtime)
+		c.entry.NightHours = c.entry.NightHours.Add(night)
+		c.entry.WeekendHours = c.entry.WeekendHours.Add(weekend)
+		for _, observed := range holidays {
+			c.entry.HolidayHours = c.entry.HolidayHours.Add(observed.hours)
+		}
+
+		record, rate, ok := c.rateOn(day)
+		if !ok {
+			continue
+		}
+		if record.PayFrequency == compensation.FrequencyHourly {
+			c.add(LineRegular, "Regular hours", regular, rate)
+			for _, component := range record.Components {
+				if component.Kind == compensation.ComponentAllowance {
+					c.add(LineAllowance, component.Name, worked, component.Amount)
+				}
+			}
+		}
+		c.add(LineOvertime, "Overtime", overtime, rate.Mul(c.policy.OvertimeMultiplier))
+		c.add(LineNight, "Night differential", night, rate.Mul(c.policy.NightDifferential))
+		c.add(LineWeekend, "Weekend differential", weekend, rate.Mul(c.policy.WeekendDifferential))
+		for _, observed := range holidays {
+			premium := decimal.NewFromFloat(observed.holiday.PayMultiplier).Sub(decimal.NewFromInt(1))
+			if premium.IsPositive() {
+				c.add(LineHoliday, observed.holiday.Name, observed.hours, rate.Mul(premium))
+			}
+		}
+	}
+}
+
+// paidLeave counts a day of approved leave at the paid length of the shift
+// it replaces, or a standard day when no shift was assigned
+func (c *calculation) paidLeave(day time.Time, attendance employee.Attendance) {
+	hours := c.policy.StandardWeeklyHours.Div(decimal.NewFromInt(5))
+	if attendance.ShiftID != 0 && attendance.Shift.ID != 0 {
+		hours = hoursOf(attendance.Shift.PaidDuration())
+	}
+	c.entry.PaidLeaveHours = c.entry.PaidLeaveHours.Add(hours)
+
+	record, rate, ok := c.rateOn(day)
+	if ok && record.PayFrequency == compensation.FrequencyHourly {
+		c.add(LinePaidLeave, "Paid leave", hours, rate)
+	}
+}
+
+// unpaidAbsences counts assigned shifts up to today with no attendance at
+// all, at their length less breaks. Holidays are not absences. Salaried pay is
+// reduced by the absent hours.
+func (c *calculation) unpaidAbsences(start, end time.Time) {
+	attended := make(map[string]bool, len(c.data.attendance))
+	for _, attendance := range c.data.attendance {
+		if attendance.Status != employee.AttendanceStatusAbsent {
+			attended[dateOf(attendance.Date).Format("2006-01-02")] = true
+		}
+	}
+
+	for day := start; !day.After(end) && !day.After(c.today); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		if !c.employed(day) || attended[key] {
+			continue
+		}
+		if _, ok := c.data.holidays[key]; ok {
+			continue
+		}
+
+		for _, assignment := range c.data.assignments {
+			if day.Before(dateOf(assignment.StartDate)) || day.After(dateOf(assignment.EndDate)) || !assignment.Shift.RunsOn(day.Weekday()) {
+				continue
+			}
+			hours := hoursOf(assignment.Shift.PaidDuration())
+			c.entry.UnpaidAbsenceHours = c.entry.UnpaidAbsenceHours.Add(hours)
+
+			record, rate, ok := c.rateOn(day)
+			if ok && record.PayFrequency != compensation.FrequencyHourly {
+				c.add(LineUnpaidAbsence, "Unpaid absence", hours, rate.Neg())
+			}
+		}
+	}
+}
+
+// salariedPay earns base pay and allowances for each calendar day employed
+func (c *calculation) salariedPay(start, end time.Time) {
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if !c.employed(day) {
+			continue
+		}
+		record, _, ok := c.rateOn(day)
+		if !ok || record.PayFrequency == compensation.FrequencyHourly {
+			continue
+		}
+
+		c.addDay(LineBase, "Base pay", record.DailyAmount(record.Base()))
+		for _, component := range record.Components {
+			if component.Kind == compensation.ComponentAllowance {
+				c.addDay(LineAllowance, component.Name, record.DailyAmount(component.Amount))
+			}
+		}
+	}
+}
+
+// rateOn returns the compensation record in effect on day and its hourly
+// rate. Days without a record, or paid in another currency than the rest of
+// the entry, are reported as warnings and not paid.
+func (c *calculation) rateOn(day time.Time) (*compensation.CompensationRecord, decimal.Decimal, bool) {
+	var record *compensation.CompensationRecord
+	for i := len(c.data.records) - 1; i >= 0; i-- {
+		candidate := c.data.records[i]
+		if !dateOf(candidate.EffectiveDate).After(day) && (!candidate.EndDate.Valid || !day.After(dateOf(candidate.EndDate.Time))) {
+			record = &c.data.records[i]
+			break
+		}
+	}
+
+	if record == nil {
+		c.warn("No compensation record for part of the period")
+		return nil, decimal.Zero, false
+	}
+	if c.entry.Currency == "" {
+		c.entry.Currency = record.Currency
+	}
+	if record.Currency != c.entry.Currency {
+		c.warn(fmt.Sprintf("Pay in %s not included, the entry is in %s", record.Currency, c.entry.Currency))
+		return nil, decimal.Zero, false
+	}
+	return record, record.HourlyRate(c.policy.StandardWeeklyHours), true
+}
+
+type holidayHours struct {
+	holiday holiday.Holiday
+	hours   decimal.Decimal
+}
+
+// holidayTime splits a worked interval by the holidays it falls on
+func (c *calculation) holidayTime(start, end time.Time) []holidayHours {
+	var worked []holidayHours
+	localDays(start, end, c.data.location, func(day time.Time) {
+		if observed, ok := c.data.holidays[day.Format("2006-01-02")]; ok {
+			worked = append(worked, holidayHours{holiday: observed, hours: hoursOf(overlap(start, end, day, nextDay(day)))})
+		}
+	})
+	return worked
+}
+
+func (c *calculation) employed(day time.Time) bool {
+	emp := c.data.employee
+	if !emp.HireDate.IsZero() && day.Before(dateOf(emp.HireDate)) {
+		return false
+	}
+	return !emp.TerminationDate.Valid || !day.After(dateOf(emp.TerminationDate.Time))
+}
+
+// add accumulates hours paid at rate
+func (c *calculation) add(kind, description string, hours, rate decimal.Decimal) {
+	if hours.IsZero() || rate.IsZero() {
+		return
+	}
+	line := c.line(kind, description, rate)
+	line.Hours = line.Hours.Add(hours)
+	line.Amount = line.Amount.Add(hours.Mul(rate))
+}
+
+// addDay accumulates one day paid at a daily rate
+func (c *calculation) addDay(kind, description string, rate decimal.Decimal) {
+	if rate.IsZero() {
+		return
+	}
+	line := c.line(kind, description, rate)
+	line.Amount = line.Amount.Add(rate)
+}
+
+func (c *calculation) line(kind, description string, rate decimal.Decimal) *PayrollLine {
+	rate = rate.Round(4)
+	key := lineKey{kind: kind, description: description, rate: rate.String()}
+	line, ok := c.lines[key]
+	if !ok {
+		line = &PayrollLine{Kind: kind, Description: description, Rate: rate}
+		c.lines[key] = line
+		c.order = append(c.order, key)
+	}
+	return line
+}
+
+func (c *calculation) warn(message string) {
+	if !c.warned[message] {
+		c.warned[message] = true
+		c.warnings = append(c.warnings, message)
+	}
+}
+
+func (c *calculation) finish() PayrollEntry {
+	entry := c.entry
+	entry.RegularHours = entry.RegularHours.Round(2)
+	entry.OvertimeHours = entry.OvertimeHours.Round(2)
+	entry.NightHours = entry.NightHours.Round(2)
+	entry.WeekendHours = entry.WeekendHours.Round(2)
+	entry.HolidayHours = entry.HolidayHours.Round(2)
+	entry.PaidLeaveHours = entry.PaidLeaveHours.Round(2)
+	entry.UnpaidAbsenceHours = entry.UnpaidAbsenceHours.Round(2)
+
+	for _, key := range c.order {
+		line := *c.lines[key]
+		line.Hours = line.Hours.Round(2)
+		line.Amount = line.Amount.Round(2)
+		entry.GrossPay = entry.GrossPay.Add(line.Amount)
+		entry.Lines = append(entry.Lines, line)
+	}
+	entry.Warnings = strings.Join(c.warnings, "\n")
+	return entry
+}
+
+func hoursOf(d time.Duration) decimal.Decimal {
+	return decimal.NewFromInt(int64(d / time.Second)).Div(decimal.NewFromInt(3600))
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func nextDay(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+}
+
+// overlap returns how much of [start, end) falls within [from, to)
+func overlap(start, end, from, to time.Time) time.Duration {
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+// localDays calls fn with the local midnight of every day [start, end) touches
+func localDays(start, end time.Time, location *time.Location, fn func(day time.Time)) {
+	local := start.In(location)
+	for day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location); day.Before(end); day = nextDay(day) {
+		fn(day)
+	}
+}
+
+// nightTime returns how much of [start, end) falls in the local night window.
+// The window that began the evening before start is included.
+func nightTime(start, end time.Time, location *time.Location, startHour, endHour int) time.Duration {
+	var total time.Duration
+	local := start.In(location)
+	for day := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, location); day.Before(end); day = nextDay(day) {
+		from := time.Date(day.Year(), day.Month(), day.Day(), startHour, 0, 0, 0, location)
+		to := time.Date(day.Year(), day.Month(), day.Day(), endHour, 0, 0, 0, location)
+		if endHour <= startHour {
+			to = time.Date(day.Year(), day.Month(), day.Day()+1, endHour, 0, 0, 0, location)
+		}
+		total += overlap(start, end, from, to)
+	}
+	return total
+}
+
+// weekendTime returns how much of [start, end) falls on a local Saturday or Sunday
+func weekendTime(start, end time.Time, location *time.Location) time.Duration {
+	var total time.Duration
+	localDays(start, end, location, func(day time.Time) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			total += overlap(start, end, day, nextDay(day))
+		}
+	})
+	return total
+}
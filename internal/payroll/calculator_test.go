@@ -0,0 +1,343 @@
+package payroll
+
+import (
+	"clinicplus/internal/compensation"
+	"clinicplus/internal/employee"
+	"clinicplus/internal/holiday"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+var testPolicy = Policy{
+	StandardWeeklyHours: decimal.NewFromInt(40),
+	OvertimeThreshold:   decimal.NewFromInt(40),
+	OvertimeMultiplier:  decimal.RequireFromString("1.5"),
+	NightStartHour:      22,
+	NightEndHour:        6,
+	NightDifferential:   decimal.RequireFromString("0.1"),
+	WeekendDifferential: decimal.RequireFromString("0.25"),
+}
+
+// date returns midnight UTC of a day in March 2024; the 4th is a Monday
+func date(day int) time.Time {
+	return time.Date(2024, time.March, day, 0, 0, 0, 0, time.UTC)
+}
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+}
+
+func nullTime(t time.Time) utils.NullTime {
+	return utils.NullTime{NullTime: sql.NullTime{Time: t, Valid: true}}
+}
+
+// worked is a closed attendance record clocked from start to end on day
+func worked(day int, start, end time.Time, breaks ...employee.AttendanceBreak) employee.Attendance {
+	return employee.Attendance{
+		Date:         date(day),
+		ClockInTime:  start,
+		ClockOutTime: nullTime(end),
+		Status:       "Present",
+		Breaks:       breaks,
+	}
+}
+
+func pay(frequency string, base string, allowances ...compensation.PayComponent) []compensation.CompensationRecord {
+	components := []compensation.CompensationComponent{
+		{PayComponent: compensation.PayComponent{Kind: compensation.ComponentBase, Name: "Base", Amount: decimal.RequireFromString(base)}},
+	}
+	for _, allowance := range allowances {
+		components = append(components, compensation.CompensationComponent{PayComponent: allowance})
+	}
+	return []compensation.CompensationRecord{{
+		EffectiveDate: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Currency:      "USD",
+		PayFrequency:  frequency,
+		Components:    components,
+	}}
+}
+
+var dayShift = employee.Shift{
+	Model:      gorm.Model{ID: 1},
+	Name:       "Day",
+	StartTime:  "09:00",
+	EndTime:    "17:00",
+	Timezone:   "UTC",
+	DaysOfWeek: "mon,tue,wed,thu,fri",
+}
+
+func TestCalculate(t *testing.T) {
+	hourly := pay(compensation.FrequencyHourly, "20")
+	tenHourDays := func(from, to int) []employee.Attendance {
+		var records []employee.Attendance
+		for day := from; day <= to; day++ {
+			records = append(records, worked(day, at(day, 8, 0), at(day, 18, 0)))
+		}
+		return records
+	}
+	withBreak := dayShift
+	withBreak.BreakMinutes = 30
+
+	tests := []struct {
+		name       string
+		data       employeeData
+		start, end time.Time
+
+		regular, overtime, night, weekend, holiday, leave, absent string // Hours
+		gross                                                     string
+		lines                                                     map[string]string // Amount by line kind
+		warning                                                   string
+	}{
+		{
+			name:    "regular day",
+			data:    employeeData{records: hourly, attendance: []employee.Attendance{worked(4, at(4, 9, 0), at(4, 17, 0))}},
+			regular: "8", gross: "160",
+			lines: map[string]string{LineRegular: "160"},
+		},
+		{
+			name: "unpaid breaks are not worked, paid ones are",
+			data: employeeData{records: hourly, attendance: []employee.Attendance{worked(4, at(4, 9, 0), at(4, 17, 0),
+				employee.AttendanceBreak{StartTime: at(4, 12, 0), EndTime: nullTime(at(4, 12, 30))},
+				employee.AttendanceBreak{Paid: true, StartTime: at(4, 15, 0), EndTime: nullTime(at(4, 15, 15))},
+			)}},
+			regular: "7.5", gross: "150",
+		},
+		{
+			name: "a mandatory break not taken is deducted",
+			data: employeeData{records: hourly, attendance: []employee.Attendance{func() employee.Attendance {
+				attendance := worked(4, at(4, 9, 0), at(4, 17, 0))
+				attendance.DeductedBreakMinutes = 30
+				attendance.BreakViolation = "No break taken"
+				return attendance
+			}()}},
+			regular: "7.5", gross: "150",
+			warning: "Mandatory break not taken on 2024-03-04",
+		},
+		{
+			name:    "overtime past the weekly threshold",
+			data:    employeeData{records: hourly, attendance: tenHourDays(4, 8)},
+			regular: "40", overtime: "10", gross: "1100",
+			lines: map[string]string{LineRegular: "800", LineOvertime: "300"},
+		},
+		{
+			name:    "days of the week before the period count towards overtime",
+			data:    employeeData{records: hourly, attendance: tenHourDays(4, 8)},
+			start:   date(6),
+			regular: "20", overtime: "10", gross: "700",
+		},
+		{
+			name:    "night and weekend differentials",
+			data:    employeeData{records: hourly, attendance: []employee.Attendance{worked(9, at(9, 22, 0), at(10, 6, 0))}},
+			regular: "8", night: "8", weekend: "8", gross: "216",
+			lines: map[string]string{LineRegular: "160", LineNight: "16", LineWeekend: "40"},
+		},
+		{
+			name: "holiday premium",
+			data: employeeData{
+				records:    hourly,
+				attendance: []employee.Attendance{worked(4, at(4, 9, 0), at(4, 17, 0))},
+				holidays:   map[string]holiday.Holiday{"2024-03-04": {Name: "Founders' Day", PayMultiplier: 2}},
+			},
+			regular: "8", holiday: "8", gross: "320",
+			lines: map[string]string{LineHoliday: "160"},
+		},
+		{
+			name: "paid leave at the length of the replaced shift",
+			data: employeeData{records: hourly, attendance: []employee.Attendance{
+				{Date: date(4), ShiftID: 1, Shift: withBreak, Status: employee.AttendanceStatusOnLeave},
+			}},
+			leave: "7.5", gross: "150",
+			lines: map[string]string{LinePaidLeave: "150"},
+		},
+		{
+			name: "paid leave without a shift is a standard day",
+			data: employeeData{records: hourly, attendance: []employee.Attendance{
+				{Date: date(4), Status: employee.AttendanceStatusOnLeave},
+			}},
+			leave: "8", gross: "160",
+		},
+		{
+			name: "missing clock-out is not paid",
+			data: employeeData{records: hourly, attendance: []employee.Attendance{
+				{Date: date(4), ClockInTime: at(4, 9, 0), Status: "Present"},
+			}},
+			gross:   "0",
+			warning: "Missing clock-out on 2024-03-04",
+		},
+		{
+			name: "hourly allowance per hour worked",
+			data: employeeData{
+				records: pay(compensation.FrequencyHourly, "20",
+					compensation.PayComponent{Kind: compensation.ComponentAllowance, Name: "Night duty", Amount: decimal.NewFromInt(2)}),
+				attendance: []employee.Attendance{worked(4, at(4, 9, 0), at(4, 17, 0))},
+			},
+			regular: "8", gross: "176",
+			lines: map[string]string{LineAllowance: "16"},
+		},
+		{
+			name:  "salaried base per calendar day",
+			data:  employeeData{records: pay(compensation.FrequencyAnnual, "36500")},
+			gross: "700",
+			lines: map[string]string{LineBase: "700"},
+		},
+		{
+			name: "salaried pay less unpaid absences",
+			data: employeeData{
+				records:     pay(compensation.FrequencyAnnual, "36500"),
+				assignments: []employee.EmployeeShift{{ShiftID: 1, Shift: dayShift, StartDate: date(4), EndDate: date(4)}},
+			},
+			absent: "8", gross: "559.62",
+			lines: map[string]string{LineBase: "700", LineUnpaidAbsence: "-140.38"},
+		},
+		{
+			name: "holidays are not absences",
+			data: employeeData{
+				records:     pay(compensation.FrequencyAnnual, "36500"),
+				assignments: []employee.EmployeeShift{{ShiftID: 1, Shift: dayShift, StartDate: date(4), EndDate: date(4)}},
+				holidays:    map[string]holiday.Holiday{"2024-03-04": {Name: "Founders' Day", PayMultiplier: 1}},
+			},
+			gross: "700",
+		},
+		{
+			name: "a clock-out closed at the shift end is paid with a warning",
+			data: employeeData{records: hourly, attendance: []employee.Attendance{func() employee.Attendance {
+				attendance := worked(4, at(4, 9, 0), at(4, 17, 0))
+				attendance.Status = employee.AttendanceStatusMissingClockOut
+				return attendance
+			}()}},
+			regular: "8", gross: "160",
+			warning: "Clock-out missing on 2024-03-04, closed at the shift end",
+		},
+		{
+			name: "absence records are unpaid absences",
+			data: employeeData{
+				records:     pay(compensation.FrequencyAnnual, "36500"),
+				attendance:  []employee.Attendance{{Date: date(4), ShiftID: 1, Shift: dayShift, Status: employee.AttendanceStatusAbsent}},
+				assignments: []employee.EmployeeShift{{ShiftID: 1, Shift: dayShift, StartDate: date(4), EndDate: date(4)}},
+			},
+			absent: "8", gross: "559.62",
+		},
+		{
+			name: "corrected attendance is paid with a warning",
+			data: employeeData{records: hourly, attendance: []employee.Attendance{func() employee.Attendance {
+				attendance := worked(4, at(4, 9, 0), at(4, 17, 0))
+				attendance.ManualEntry = true
+				return attendance
+			}()}},
+			regular: "8", gross: "160",
+			warning: "Attendance on 2024-03-04 was entered by a correction",
+		},
+		{
+			name:    "no compensation record",
+			data:    employeeData{attendance: []employee.Attendance{worked(4, at(4, 9, 0), at(4, 17, 0))}},
+			regular: "8", gross: "0",
+			warning: "No compensation record for part of the period",
+		},
+		{
+			name: "employment starts mid-period",
+			data: employeeData{
+				employee: employee.Employee{HireDate: date(8)},
+				records:  pay(compensation.FrequencyAnnual, "36500"),
+			},
+			gross: "300",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.start, tt.end
+			if start.IsZero() {
+				start = date(4)
+			}
+			if end.IsZero() {
+				end = date(10)
+			}
+			entry := calculate(testPolicy, tt.data, start, end, date(31))
+
+			hours := []struct {
+				name      string
+				got       decimal.Decimal
+				wantValue string
+			}{
+				{"regular", entry.RegularHours, tt.regular},
+				{"overtime", entry.OvertimeHours, tt.overtime},
+				{"night", entry.NightHours, tt.night},
+				{"weekend", entry.WeekendHours, tt.weekend},
+				{"holiday", entry.HolidayHours, tt.holiday},
+				{"paid leave", entry.PaidLeaveHours, tt.leave},
+				{"unpaid absence", entry.UnpaidAbsenceHours, tt.absent},
+			}
+			for _, h := range hours {
+				want := decimal.Zero
+				if h.wantValue != "" {
+					want = decimal.RequireFromString(h.wantValue)
+				}
+				if !h.got.Equal(want) {
+					t.Errorf("%s hours = %s, want %s", h.name, h.got, want)
+				}
+			}
+
+			if want := decimal.RequireFromString(tt.gross); !entry.GrossPay.Equal(want) {
+				t.Errorf("gross pay = %s, want %s (lines %+v)", entry.GrossPay, want, entry.Lines)
+			}
+			for kind, amount := range tt.lines {
+				total := decimal.Zero
+				for _, line := range entry.Lines {
+					if line.Kind == kind {
+						total = total.Add(line.Amount)
+					}
+				}
+				if want := decimal.RequireFromString(amount); !total.Equal(want) {
+					t.Errorf("%s lines = %s, want %s", kind, total, want)
+				}
+			}
+			if tt.warning != "" && !strings.Contains(entry.Warnings, tt.warning) {
+				t.Errorf("warnings %q do not mention %q", entry.Warnings, tt.warning)
+			}
+			if tt.warning == "" && entry.Warnings != "" {
+				t.Errorf("unexpected warnings %q", entry.Warnings)
+			}
+		})
+	}
+}
+
+func TestNightTime(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       time.Duration
+	}{
+		{"day shift", at(4, 9, 0), at(4, 17, 0), 0},
+		{"evening into the night", at(4, 18, 0), at(4, 23, 30), 90 * time.Minute},
+		{"overnight", at(4, 20, 0), at(5, 8, 0), 8 * time.Hour},
+		{"early morning, window from the evening before", at(5, 4, 0), at(5, 10, 0), 2 * time.Hour},
+		{"two nights", at(4, 21, 0), at(6, 7, 0), 16 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nightTime(tt.start, tt.end, time.UTC, 22, 6); got != tt.want {
+				t.Errorf("nightTime = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeekendTimeInLocalTime(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	// Friday 20:00 to 22:00 UTC is Saturday 01:30 to 03:30 in Kolkata
+	if got := weekendTime(at(8, 20, 0), at(8, 22, 0), kolkata); got != 2*time.Hour {
+		t.Errorf("weekendTime = %s, want 2h", got)
+	}
+	if got := weekendTime(at(8, 20, 0), at(8, 22, 0), time.UTC); got != 0 {
+		t.Errorf("weekendTime in UTC = %s, want 0", got)
+	}
+}
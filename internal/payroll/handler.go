@@ -0,0 +1,188 @@
+// internal/payroll/handler.go
+package payroll
+
+import (
+	"bytes"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type PayrollHandler struct {
+	service PayrollService
+}
+
+func NewPayrollHandler(service PayrollService) *PayrollHandler {
+	return &PayrollHandler{service: service}
+}
+
+func (h *PayrollHandler) CreateRun(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		PeriodStart string `json:"period_start"` // YYYY-MM-DD
+		PeriodEnd   string `json:"period_end"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+	periodStart, err1 := time.Parse("2006-01-02", body.PeriodStart)
+	periodEnd, err2 := time.Parse("2006-01-02", body.PeriodEnd)
+	if err1 != nil || err2 != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid period, expected YYYY-MM-DD dates", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	run, err := h.service.CreateRun(periodStart, periodEnd, user)
+	if err != nil {
+		sendPayrollError(w, err, "Failed to create payroll run")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, run, nil, nil)
+}
+
+func (h *PayrollHandler) GetRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.service.GetRuns()
+	if err != nil {
+		log.Printf("Error fetching payroll runs: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve payroll runs", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, runs, nil, nil)
+}
+
+func (h *PayrollHandler) GetRun(w http.ResponseWriter, r *http.Request) {
+	id, ok := runID(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := h.service.GetRun(id)
+	if err != nil {
+		sendPayrollError(w, err, "Failed to retrieve payroll run")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, run, nil, nil)
+}
+
+func (h *PayrollHandler) RerunPayroll(w http.ResponseWriter, r *http.Request) {
+	id, ok := runID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	run, diff, err := h.service.RerunPayroll(id, user)
+	if err != nil {
+		sendPayrollError(w, err, "Failed to rerun payroll")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, run, nil, map[string]interface{}{
+		"diff": diff,
+	})
+}
+
+func (h *PayrollHandler) DiffRun(w http.ResponseWriter, r *http.Request) {
+	id, ok := runID(w, r)
+	if !ok {
+		return
+	}
+
+	diff, err := h.service.DiffRun(id)
+	if err != nil {
+		sendPayrollError(w, err, "Failed to compare payroll runs")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, diff, nil, nil)
+}
+
+func (h *PayrollHandler) LockRun(w http.ResponseWriter, r *http.Request) {
+	id, ok := runID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	run, err := h.service.LockRun(id, user)
+	if err != nil {
+		sendPayrollError(w, err, "Failed to lock payroll run")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, run, nil, nil)
+}
+
+func (h *PayrollHandler) DeleteRun(w http.ResponseWriter, r *http.Request) {
+	id, ok := runID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRun(id); err != nil {
+		sendPayrollError(w, err, "Failed to delete payroll run")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Payroll run deleted successfully",
+	})
+}
+
+// ExportCSV downloads a locked run in the format expected by the payroll provider
+func (h *PayrollHandler) ExportCSV(w http.ResponseWriter, r *http.Request) {
+	id, ok := runID(w, r)
+	if !ok {
+		return
+	}
+
+	// Buffer the export so errors can still be reported as JSON
+	var buf bytes.Buffer
+	if err := h.service.ExportCSV(id, &buf); err != nil {
+		sendPayrollError(w, err, "Failed to export payroll run")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("payroll-run-%d.csv", id)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Error writing payroll export %d: %v", id, err)
+	}
+}
+
+func runID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid payroll run ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid payroll run ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func sendPayrollError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case ErrRunNotFound:
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case ErrInvalidPeriod:
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case ErrPeriodOverlap, ErrRunLocked, ErrRunNotLocked, ErrNotLatestRun:
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
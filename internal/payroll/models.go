@@ -0,0 +1,73 @@
+package payroll
+
+import (
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	RunStatusDraft      = "draft"
+	RunStatusLocked     = "locked"
+	RunStatusSuperseded = "superseded" // A later version of the run was locked
+)
+
+const (
+	LineBase          = "base"
+	LineAllowance     = "allowance"
+	LineRegular       = "regular"
+	LinePaidLeave     = "paid_leave"
+	LineOvertime      = "overtime"
+	LineNight         = "night_differential"
+	LineWeekend       = "weekend_differential"
+	LineHoliday       = "holiday_differential"
+	LineUnpaidAbsence = "unpaid_absence"
+)
+
+// PayrollRun is the payroll calculated for a pay period. Rerunning a period
+// creates a new version; locking a version supersedes the others.
+type PayrollRun struct {
+	gorm.Model
+	PeriodStart   time.Time      `gorm:"type:date;not null;index" json:"period_start"`
+	PeriodEnd     time.Time      `gorm:"type:date;not null" json:"period_end"`
+	Status        string         `gorm:"not null;default:'draft'" json:"status"`
+	Version       int            `gorm:"not null;default:1" json:"version"`
+	PreviousRunID *uint          `json:"previous_run_id"`
+	CreatedBy     uint           `json:"created_by"` // iam.User
+	LockedBy      *uint          `json:"locked_by"`  // iam.User
+	LockedAt      utils.NullTime `json:"locked_at"`
+	Entries       []PayrollEntry `gorm:"foreignkey:RunID" json:"entries,omitempty"`
+}
+
+// PayrollEntry is one employee's hours and gross pay within a run
+type PayrollEntry struct {
+	gorm.Model
+	RunID              uint            `gorm:"not null;index" json:"run_id"`
+	EmployeeID         uint            `gorm:"not null;index" json:"employee_id"`
+	EmployeeName       string          `json:"employee_name"`
+	Currency           string          `gorm:"type:char(3)" json:"currency"`
+	RegularHours       decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"regular_hours"`
+	OvertimeHours      decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"overtime_hours"`
+	NightHours         decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"night_hours"`
+	WeekendHours       decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"weekend_hours"`
+	HolidayHours       decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"holiday_hours"`
+	PaidLeaveHours     decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"paid_leave_hours"`
+	UnpaidAbsenceHours decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"unpaid_absence_hours"`
+	GrossPay           decimal.Decimal `gorm:"type:numeric(14,2);not null" json:"gross_pay"`
+	Warnings           string          `json:"warnings"` // e.g., missing clock-outs, one per line
+	Lines              []PayrollLine   `gorm:"foreignkey:EntryID" json:"lines"`
+}
+
+// PayrollLine is one earning or deduction of an entry. Hours are zero for
+// day-based lines such as salaried base pay, whose rate is per day.
+type PayrollLine struct {
+	gorm.Model
+	EntryID     uint            `gorm:"not null;index" json:"entry_id"`
+	Kind        string          `gorm:"not null" json:"kind"`
+	Description string          `json:"description"`
+	Hours       decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"hours"`
+	Rate        decimal.Decimal `gorm:"type:numeric(14,4);not null" json:"rate"`
+	Amount      decimal.Decimal `gorm:"type:numeric(14,2);not null" json:"amount"`
+}
@@ -0,0 +1,487 @@
+// internal/payroll/service.go
+package payroll
+
+import (
+	"clinicplus/internal/compensation"
+	"clinicplus/internal/employee"
+	"clinicplus/internal/holiday"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrRunNotFound   = errors.New("payroll run not found")
+	ErrInvalidPeriod = errors.New("pay period must end on or after its start and span at most 31 days")
+	ErrPeriodOverlap = errors.New("a payroll run already covers part of this period, rerun it instead")
+	ErrRunLocked     = errors.New("payroll run is locked")
+	ErrRunNotLocked  = errors.New("only locked payroll runs can be exported")
+	ErrNotLatestRun  = errors.New("only the latest version of a payroll run can be rerun, locked or deleted")
+)
+
+// EntryDiff describes how an employee's entry changed between two versions of a run
+type EntryDiff struct {
+	EmployeeID   uint            `json:"employee_id"`
+	EmployeeName string          `json:"employee_name"`
+	Change       string          `json:"change"` // added, removed or changed
+	GrossBefore  decimal.Decimal `json:"gross_before"`
+	GrossAfter   decimal.Decimal `json:"gross_after"`
+	Lines        []LineDiff      `json:"lines"`
+}
+
+// LineDiff compares the hours and amount of one kind of line
+type LineDiff struct {
+	Kind         string          `json:"kind"`
+	HoursBefore  decimal.Decimal `json:"hours_before"`
+	HoursAfter   decimal.Decimal `json:"hours_after"`
+	AmountBefore decimal.Decimal `json:"amount_before"`
+	AmountAfter  decimal.Decimal `json:"amount_after"`
+}
+
+type PayrollService interface {
+	CreateRun(periodStart, periodEnd time.Time, user *iam.User) (*PayrollRun, error)
+	GetRuns() ([]PayrollRun, error)
+	GetRun(id uint) (*PayrollRun, error)
+	RerunPayroll(id uint, user *iam.User) (*PayrollRun, []EntryDiff, error)
+	DiffRun(id uint) ([]EntryDiff, error)
+	LockRun(id uint, user *iam.User) (*PayrollRun, error)
+	DeleteRun(id uint) error
+	ExportCSV(id uint, w io.Writer) error
+}
+
+type payrollService struct {
+	db       *gorm.DB
+	holidays holiday.HolidayService
+	policy   Policy
+}
+
+func NewPayrollService(db *gorm.DB, holidays holiday.HolidayService, policy Policy) PayrollService {
+	return &payrollService{db: db, holidays: holidays, policy: policy}
+}
+
+// CreateRun calculates a draft payroll run for a new pay period
+func (s *payrollService) CreateRun(periodStart, periodEnd time.Time, user *iam.User) (*PayrollRun, error) {
+	periodStart, periodEnd = dateOf(periodStart), dateOf(periodEnd)
+	if periodEnd.Before(periodStart) || periodEnd.Sub(periodStart) > 30*24*time.Hour {
+		return nil, ErrInvalidPeriod
+	}
+
+	var overlapping int
+	s.db.Model(&PayrollRun{}).Where("period_start <= ? AND period_end >= ?", periodEnd, periodStart).Count(&overlapping)
+	if overlapping > 0 {
+		return nil, ErrPeriodOverlap
+	}
+
+	run := PayrollRun{
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Status:      RunStatusDraft,
+		Version:     1,
+		CreatedBy:   user.ID,
+	}
+	if err := s.calculateRun(&run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (s *payrollService) GetRuns() ([]PayrollRun, error) {
+	var runs []PayrollRun
+	if err := s.db.Order("period_start DESC, version DESC").Find(&runs).Error; err != nil {
+		log.Printf("Error fetching payroll runs: %v", err)
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (s *payrollService) GetRun(id uint) (*PayrollRun, error) {
+	var run PayrollRun
+	if err := s.db.Preload("Entries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("employee_name, employee_id")
+	}).Preload("Entries.Lines").First(&run, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrRunNotFound
+		}
+		log.Printf("Error fetching payroll run: %v", err)
+		return nil, err
+	}
+	return &run, nil
+}
+
+// RerunPayroll recalculates the period of the latest version of a run as a
+// new draft version, and returns how it differs from the run it replaces
+func (s *payrollService) RerunPayroll(id uint, user *iam.User) (*PayrollRun, []EntryDiff, error) {
+	previous, err := s.latestRun(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	run := PayrollRun{
+		PeriodStart:   previous.PeriodStart,
+		PeriodEnd:     previous.PeriodEnd,
+		Status:        RunStatusDraft,
+		Version:       previous.Version + 1,
+		PreviousRunID: &previous.ID,
+		CreatedBy:     user.ID,
+	}
+	if err := s.calculateRun(&run); err != nil {
+		return nil, nil, err
+	}
+	return &run, diffEntries(previous.Entries, run.Entries), nil
+}
+
+// DiffRun compares a run with the version it was rerun from
+func (s *payrollService) DiffRun(id uint) ([]EntryDiff, error) {
+	run, err := s.GetRun(id)
+	if err != nil {
+		return nil, err
+	}
+	if run.PreviousRunID == nil {
+		return diffEntries(nil, run.Entries), nil
+	}
+
+	previous, err := s.GetRun(*run.PreviousRunID)
+	if err != nil {
+		return nil, err
+	}
+	return diffEntries(previous.Entries, run.Entries), nil
+}
+
+// LockRun finalises the latest version of a run. Other versions of the period
+// are marked superseded; a locked run can no longer be deleted.
+func (s *payrollService) LockRun(id uint, user *iam.User) (*PayrollRun, error) {
+	run, err := s.latestRun(id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == RunStatusLocked {
+		return nil, ErrRunLocked
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	err = tx.Model(&PayrollRun{}).
+		Where("period_start = ? AND period_end = ? AND id <> ?", run.PeriodStart, run.PeriodEnd, run.ID).
+		Update("status", RunStatusSuperseded).Error
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error superseding payroll runs: %v", err)
+		return nil, err
+	}
+
+	run.Status = RunStatusLocked
+	run.LockedBy = &user.ID
+	run.LockedAt = utils.NullTime{NullTime: sql.NullTime{Time: time.Now().UTC(), Valid: true}}
+	if err := tx.Omit("Entries").Save(run).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error locking payroll run: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+	return run, nil
+}
+
+// DeleteRun discards the latest version of a run, if still a draft, along
+// with its entries
+func (s *payrollService) DeleteRun(id uint) error {
+	run, err := s.latestRun(id)
+	if err != nil {
+		return err
+	}
+	if run.Status != RunStatusDraft {
+		return ErrRunLocked
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return tx.Error
+	}
+
+	var entryIDs []uint
+	for _, entry := range run.Entries {
+		entryIDs = append(entryIDs, entry.ID)
+	}
+	if len(entryIDs) > 0 {
+		if err := tx.Where("entry_id IN (?)", entryIDs).Delete(&PayrollLine{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("Error deleting payroll lines: %v", err)
+			return err
+		}
+	}
+	if err := tx.Where("run_id = ?", run.ID).Delete(&PayrollEntry{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error deleting payroll entries: %v", err)
+		return err
+	}
+	if err := tx.Delete(run).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error deleting payroll run: %v", err)
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return err
+	}
+	return nil
+}
+
+var csvColumns = []string{LineBase, LineAllowance, LineRegular, LinePaidLeave, LineOvertime, LineNight, LineWeekend, LineHoliday, LineUnpaidAbsence}
+
+// ExportCSV writes a locked run as one row per employee: hours, the amount
+// of each kind of line and the gross pay
+func (s *payrollService) ExportCSV(id uint, w io.Writer) error {
+	run, err := s.GetRun(id)
+	if err != nil {
+		return err
+	}
+	if run.Status != RunStatusLocked {
+		return ErrRunNotLocked
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{"employee_id", "employee_name", "period_start", "period_end", "currency",
+		"regular_hours", "overtime_hours", "night_hours", "weekend_hours", "holiday_hours", "paid_leave_hours", "unpaid_absence_hours"}
+	header = append(header, csvColumns...)
+	header = append(header, "gross_pay")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, entry := range run.Entries {
+		amounts := make(map[string]decimal.Decimal)
+		for _, line := range entry.Lines {
+			amounts[line.Kind] = amounts[line.Kind].Add(line.Amount)
+		}
+
+		row := []string{
+			strconv.FormatUint(uint64(entry.EmployeeID), 10),
+			entry.EmployeeName,
+			run.PeriodStart.Format("2006-01-02"),
+			run.PeriodEnd.Format("2006-01-02"),
+			entry.Currency,
+			entry.RegularHours.StringFixed(2),
+			entry.OvertimeHours.StringFixed(2),
+			entry.NightHours.StringFixed(2),
+			entry.WeekendHours.StringFixed(2),
+			entry.HolidayHours.StringFixed(2),
+			entry.PaidLeaveHours.StringFixed(2),
+			entry.UnpaidAbsenceHours.StringFixed(2),
+		}
+		for _, kind := range csvColumns {
+			row = append(row, amounts[kind].StringFixed(2))
+		}
+		row = append(row, entry.GrossPay.StringFixed(2))
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// latestRun loads a run and checks no later version of its period exists
+func (s *payrollService) latestRun(id uint) (*PayrollRun, error) {
+	run, err := s.GetRun(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var later int
+	s.db.Model(&PayrollRun{}).
+		Where("period_start = ? AND period_end = ? AND version > ?", run.PeriodStart, run.PeriodEnd, run.Version).
+		Count(&later)
+	if later > 0 {
+		return nil, ErrNotLatestRun
+	}
+	return run, nil
+}
+
+// calculateRun computes an entry for every employee employed during the
+// period and saves the run with its entries
+func (s *payrollService) calculateRun(run *PayrollRun) error {
+	var employees []employee.Employee
+	err := s.db.Where("(hire_date IS NULL OR hire_date <= ?) AND (termination_date IS NULL OR termination_date >= ?)", run.PeriodEnd, run.PeriodStart).
+		Order("name, id").
+		Find(&employees).Error
+	if err != nil {
+		log.Printf("Error fetching employees for payroll: %v", err)
+		return err
+	}
+
+	// Overtime is counted per ISO week, so attendance is loaded from the
+	// Monday of the week the period starts in
+	weekStart := run.PeriodStart.AddDate(0, 0, -((int(run.PeriodStart.Weekday()) + 6) % 7))
+
+	var attendance []employee.Attendance
+	err = s.db.Preload("Shift").Preload("Breaks").Where("date BETWEEN ? AND ?", weekStart, run.PeriodEnd).Order("clock_in_time").Find(&attendance).Error
+	if err != nil {
+		log.Printf("Error fetching attendance for payroll: %v", err)
+		return err
+	}
+
+	var assignments []employee.EmployeeShift
+	err = s.db.Preload("Shift").Where("start_date <= ? AND end_date >= ?", run.PeriodEnd, run.PeriodStart).Find(&assignments).Error
+	if err != nil {
+		log.Printf("Error fetching shift assignments for payroll: %v", err)
+		return err
+	}
+
+	var records []compensation.CompensationRecord
+	err = s.db.Preload("Components").
+		Where("effective_date <= ? AND (end_date IS NULL OR end_date >= ?)", run.PeriodEnd, run.PeriodStart).
+		Order("effective_date").
+		Find(&records).Error
+	if err != nil {
+		log.Printf("Error fetching compensation records for payroll: %v", err)
+		return err
+	}
+
+	var locations []employee.Location
+	if err := s.db.Find(&locations).Error; err != nil {
+		log.Printf("Error fetching locations for payroll: %v", err)
+		return err
+	}
+	timezones := make(map[uint]*time.Location, len(locations))
+	for _, location := range locations {
+		if tz, err := time.LoadLocation(location.Timezone); err == nil {
+			timezones[location.ID] = tz
+		}
+	}
+
+	data := make(map[uint]*employeeData, len(employees))
+	for _, emp := range employees {
+		holidays, err := s.holidays.HolidaysFor(emp, run.PeriodStart, run.PeriodEnd)
+		if err != nil {
+			return err
+		}
+
+		d := &employeeData{employee: emp, location: time.UTC, holidays: make(map[string]holiday.Holiday, len(holidays))}
+		if emp.LocationID != nil && timezones[*emp.LocationID] != nil {
+			d.location = timezones[*emp.LocationID]
+		}
+		for _, h := range holidays {
+			d.holidays[h.Date.Format("2006-01-02")] = h
+		}
+		data[emp.ID] = d
+	}
+	for _, a := range attendance {
+		if d, ok := data[a.EmployeeID]; ok {
+			d.attendance = append(d.attendance, a)
+		}
+	}
+	for _, a := range assignments {
+		if d, ok := data[a.EmployeeID]; ok {
+			d.assignments = append(d.assignments, a)
+		}
+	}
+	for _, r := range records {
+		if d, ok := data[r.EmployeeID]; ok {
+			d.records = append(d.records, r)
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, emp := range employees {
+		run.Entries = append(run.Entries, calculate(s.policy, *data[emp.ID], run.PeriodStart, run.PeriodEnd, now))
+	}
+
+	if err := s.db.Create(run).Error; err != nil {
+		log.Printf("Error saving payroll run: %v", err)
+		return err
+	}
+	return nil
+}
+
+// diffEntries compares the entries of two versions of a run, per employee and
+// kind of line. Unchanged employees are left out.
+func diffEntries(before, after []PayrollEntry) []EntryDiff {
+	previous := make(map[uint]PayrollEntry, len(before))
+	for _, entry := range before {
+		previous[entry.EmployeeID] = entry
+	}
+
+	diffs := []EntryDiff{}
+	for _, entry := range after {
+		old, existed := previous[entry.EmployeeID]
+		delete(previous, entry.EmployeeID)
+
+		diff := EntryDiff{
+			EmployeeID:   entry.EmployeeID,
+			EmployeeName: entry.EmployeeName,
+			Change:       "changed",
+			GrossBefore:  old.GrossPay,
+			GrossAfter:   entry.GrossPay,
+			Lines:        diffLines(old.Lines, entry.Lines),
+		}
+		if !existed {
+			diff.Change = "added"
+		} else if len(diff.Lines) == 0 && old.GrossPay.Equal(entry.GrossPay) {
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+
+	for _, entry := range before {
+		if _, removed := previous[entry.EmployeeID]; removed {
+			diffs = append(diffs, EntryDiff{
+				EmployeeID:   entry.EmployeeID,
+				EmployeeName: entry.EmployeeName,
+				Change:       "removed",
+				GrossBefore:  entry.GrossPay,
+				Lines:        diffLines(entry.Lines, nil),
+			})
+		}
+	}
+	return diffs
+}
+
+func diffLines(before, after []PayrollLine) []LineDiff {
+	var kinds []string
+	totals := make(map[string]*LineDiff)
+	total := func(kind string) *LineDiff {
+		if _, ok := totals[kind]; !ok {
+			totals[kind] = &LineDiff{Kind: kind}
+			kinds = append(kinds, kind)
+		}
+		return totals[kind]
+	}
+	for _, line := range before {
+		t := total(line.Kind)
+		t.HoursBefore = t.HoursBefore.Add(line.Hours)
+		t.AmountBefore = t.AmountBefore.Add(line.Amount)
+	}
+	for _, line := range after {
+		t := total(line.Kind)
+		t.HoursAfter = t.HoursAfter.Add(line.Hours)
+		t.AmountAfter = t.AmountAfter.Add(line.Amount)
+	}
+
+	var diffs []LineDiff
+	for _, kind := range kinds {
+		t := totals[kind]
+		if !t.HoursBefore.Equal(t.HoursAfter) || !t.AmountBefore.Equal(t.AmountAfter) {
+			diffs = append(diffs, *t)
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,115 @@
+// internal/roster/handler.go
+package roster
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type RosterHandler struct {
+	service RosterService
+}
+
+func NewRosterHandler(service RosterService) *RosterHandler {
+	return &RosterHandler{service: service}
+}
+
+// GetRoster returns the roster for the from and to query parameters
+// (YYYY-MM-DD, defaulting to the coming week), optionally narrowed by
+// location and department
+func (h *RosterHandler) GetRoster(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parseRange(w, r)
+	if !ok {
+		return
+	}
+
+	filter := Filter{From: from, To: to, Department: r.URL.Query().Get("department")}
+	if locationStr := r.URL.Query().Get("location"); locationStr != "" {
+		locationID, err := strconv.ParseUint(locationStr, 10, 32)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid location ID", nil)
+			return
+		}
+		location := uint(locationID)
+		filter.LocationID = &location
+	}
+
+	roster, err := h.service.GetRoster(filter)
+	if err != nil {
+		sendRosterError(w, err, "Failed to retrieve roster")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, roster, nil, nil)
+}
+
+// GetEmployeeSchedule returns an employee's shifts between the from and to
+// query parameters. Employees may view their own schedule.
+func (h *RosterHandler) GetEmployeeSchedule(w http.ResponseWriter, r *http.Request) {
+	employeeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid employee ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+	if !iam.CanAccessEmployee(user, uint(employeeID)) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's schedule", nil)
+		return
+	}
+
+	from, to, ok := parseRange(w, r)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.service.GetEmployeeSchedule(uint(employeeID), from, to)
+	if err != nil {
+		sendRosterError(w, err, "Failed to retrieve schedule")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, schedule, nil, nil)
+}
+
+func parseRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	from := time.Now().UTC()
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		parsed, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid from date, expected YYYY-MM-DD", nil)
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 0, 6)
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		parsed, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid to date, expected YYYY-MM-DD", nil)
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	return from, to, true
+}
+
+func sendRosterError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case employee.ErrEmployeeNotFound:
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case ErrInvalidRange:
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
@@ -0,0 +1,75 @@
+package roster
+
+import (
+	"time"
+)
+
+// Filter narrows a roster to a date range and optionally a location or department
+type Filter struct {
+	From       time.Time
+	To         time.Time
+	LocationID *uint
+	Department string
+}
+
+// RosteredEmployee is an employee scheduled on a slot
+type RosteredEmployee struct {
+	EmployeeID  uint   `json:"employee_id"`
+	Name        string `json:"name"`
+	Designation string `json:"designation"`
+	Department  string `json:"department"`
+	LocationID  *uint  `json:"location_id"`
+	Source      string `json:"source"`   // manual, rotation or scheduler
+	OnLeave     bool   `json:"on_leave"` // Assigned but on approved leave that day
+}
+
+// Slot is one occurrence of a shift on a roster day. A slot without anyone
+// available to work it is a gap.
+type Slot struct {
+	ShiftID    uint               `json:"shift_id"`
+	ShiftName  string             `json:"shift_name"`
+	LocationID *uint              `json:"location_id"`
+	Start      time.Time          `json:"start"`
+	End        time.Time          `json:"end"`
+	Employees  []RosteredEmployee `json:"employees"`
+	Available  int                `json:"available"`
+	Gap        bool               `json:"gap"`
+}
+
+type Day struct {
+	Date  time.Time `json:"date"`
+	Slots []Slot    `json:"slots"`
+	Gaps  int       `json:"gaps"`
+}
+
+// Roster is a day-by-day, shift-by-shift matrix of assigned employees
+type Roster struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	LocationID *uint     `json:"location_id,omitempty"`
+	Department string    `json:"department,omitempty"`
+	Days       []Day     `json:"days"`
+	Gaps       int       `json:"gaps"`
+}
+
+// ScheduleEntry is one shift an employee is scheduled to work
+type ScheduleEntry struct {
+	Date       time.Time `json:"date"`
+	ShiftID    uint      `json:"shift_id"`
+	ShiftName  string    `json:"shift_name"`
+	LocationID *uint     `json:"location_id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Hours      float64   `json:"hours"` // Paid hours, breaks excluded
+	Source     string    `json:"source"`
+	OnLeave    bool      `json:"on_leave"`
+}
+
+// Schedule lists an employee's shifts over a date range
+type Schedule struct {
+	EmployeeID uint            `json:"employee_id"`
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Entries    []ScheduleEntry `json:"entries"`
+	TotalHours float64         `json:"total_hours"` // Paid hours of the entries not on leave
+}
@@ -0,0 +1,244 @@
+// internal/roster/service.go
+package roster
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/leave"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MaxRangeDays bounds the number of days a roster or schedule may cover
+const MaxRangeDays = 62
+
+var ErrInvalidRange = errors.New("invalid date range: to must not be before from, and the range may cover at most 62 days")
+
+type RosterService interface {
+	GetRoster(filter Filter) (*Roster, error)
+	GetEmployeeSchedule(employeeID uint, from, to time.Time) (*Schedule, error)
+}
+
+type rosterService struct {
+	db *gorm.DB
+}
+
+func NewRosterService(db *gorm.DB) RosterService {
+	return &rosterService{db: db}
+}
+
+// GetRoster expands shift assignments into every day of the range. Each day
+// lists the occurrences of the shifts running on it with the employees
+// assigned; occurrences nobody is available to work are flagged as gaps.
+// With a department filter, a gap means nobody from that department.
+func (s *rosterService) GetRoster(filter Filter) (*Roster, error) {
+	from, to, err := dateRange(filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Order("start_time, name")
+	if filter.LocationID != nil {
+		// Shifts without a location run wherever their employees work
+		query = query.Where("location_id = ? OR location_id IS NULL", *filter.LocationID)
+	}
+	var shifts []employee.Shift
+	if err := query.Find(&shifts).Error; err != nil {
+		log.Printf("Error fetching shifts: %v", err)
+		return nil, err
+	}
+
+	var assignments []employee.EmployeeShift
+	if err := s.db.Preload("Employee").
+		Where("start_date <= ? AND end_date >= ?", to, from).
+		Find(&assignments).Error; err != nil {
+		log.Printf("Error fetching shift assignments: %v", err)
+		return nil, err
+	}
+	byShift := make(map[uint][]employee.EmployeeShift)
+	for _, assignment := range assignments {
+		byShift[assignment.ShiftID] = append(byShift[assignment.ShiftID], assignment)
+	}
+
+	leaves, err := s.approvedLeave(nil, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	roster := &Roster{From: from, To: to, LocationID: filter.LocationID, Department: filter.Department, Days: []Day{}}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		rosterDay := Day{Date: day, Slots: []Slot{}}
+		for _, shift := range shifts {
+			window, ok := shift.OccurrenceOn(day)
+			if !ok {
+				continue
+			}
+
+			slot := Slot{
+				ShiftID:    shift.ID,
+				ShiftName:  shift.Name,
+				LocationID: shift.LocationID,
+				Start:      window.Start,
+				End:        window.End,
+				Employees:  []RosteredEmployee{},
+			}
+			for _, assignment := range byShift[shift.ID] {
+				emp := assignment.Employee
+				if !covers(assignment, day) || !emp.CanWork() {
+					continue
+				}
+				if filter.Department != "" && !strings.EqualFold(emp.Department, filter.Department) {
+					continue
+				}
+				if filter.LocationID != nil && shift.LocationID == nil &&
+					(emp.LocationID == nil || *emp.LocationID != *filter.LocationID) {
+					continue
+				}
+
+				onLeave := leaves.on(emp.ID, day)
+				if !onLeave {
+					slot.Available++
+				}
+				slot.Employees = append(slot.Employees, RosteredEmployee{
+					EmployeeID:  emp.ID,
+					Name:        emp.Name,
+					Designation: emp.Designation,
+					Department:  emp.Department,
+					LocationID:  emp.LocationID,
+					Source:      assignment.Source,
+					OnLeave:     onLeave,
+				})
+			}
+			sort.Slice(slot.Employees, func(i, j int) bool {
+				return slot.Employees[i].Name < slot.Employees[j].Name
+			})
+
+			slot.Gap = slot.Available == 0
+			if slot.Gap {
+				rosterDay.Gaps++
+			}
+			rosterDay.Slots = append(rosterDay.Slots, slot)
+		}
+		sort.SliceStable(rosterDay.Slots, func(i, j int) bool {
+			return rosterDay.Slots[i].Start.Before(rosterDay.Slots[j].Start)
+		})
+
+		roster.Gaps += rosterDay.Gaps
+		roster.Days = append(roster.Days, rosterDay)
+	}
+	return roster, nil
+}
+
+// GetEmployeeSchedule lists every shift occurrence the employee is assigned
+// to in the range, in chronological order
+func (s *rosterService) GetEmployeeSchedule(employeeID uint, from, to time.Time) (*Schedule, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&employee.Employee{}, employeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+
+	var assignments []employee.EmployeeShift
+	if err := s.db.Preload("Shift").
+		Where("employee_id = ? AND start_date <= ? AND end_date >= ?", employeeID, to, from).
+		Find(&assignments).Error; err != nil {
+		log.Printf("Error fetching shift assignments: %v", err)
+		return nil, err
+	}
+
+	leaves, err := s.approvedLeave(&employeeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &Schedule{EmployeeID: employeeID, From: from, To: to, Entries: []ScheduleEntry{}}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, assignment := range assignments {
+			if !covers(assignment, day) {
+				continue
+			}
+			window, ok := assignment.Shift.OccurrenceOn(day)
+			if !ok {
+				continue
+			}
+
+			entry := ScheduleEntry{
+				Date:       day,
+				ShiftID:    assignment.ShiftID,
+				ShiftName:  assignment.Shift.Name,
+				LocationID: assignment.Shift.LocationID,
+				Start:      window.Start,
+				End:        window.End,
+				Hours:      assignment.Shift.PaidDuration().Hours(),
+				Source:     assignment.Source,
+				OnLeave:    leaves.on(employeeID, day),
+			}
+			if !entry.OnLeave {
+				schedule.TotalHours += entry.Hours
+			}
+			schedule.Entries = append(schedule.Entries, entry)
+		}
+	}
+	sort.SliceStable(schedule.Entries, func(i, j int) bool {
+		return schedule.Entries[i].Start.Before(schedule.Entries[j].Start)
+	})
+	return schedule, nil
+}
+
+// leaveDays holds approved leave ranges by employee
+type leaveDays map[uint][]leave.LeaveRequest
+
+func (l leaveDays) on(employeeID uint, day time.Time) bool {
+	for _, request := range l[employeeID] {
+		if !day.Before(dateOf(request.StartDate)) && !day.After(dateOf(request.EndDate)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *rosterService) approvedLeave(employeeID *uint, from, to time.Time) (leaveDays, error) {
+	query := s.db.Where("status = ? AND start_date <= ? AND end_date >= ?", leave.StatusApproved, to, from)
+	if employeeID != nil {
+		query = query.Where("employee_id = ?", *employeeID)
+	}
+
+	var requests []leave.LeaveRequest
+	if err := query.Find(&requests).Error; err != nil {
+		log.Printf("Error fetching approved leave: %v", err)
+		return nil, err
+	}
+
+	leaves := make(leaveDays)
+	for _, request := range requests {
+		leaves[request.EmployeeID] = append(leaves[request.EmployeeID], request)
+	}
+	return leaves, nil
+}
+
+// covers reports whether an assignment includes the calendar day
+func covers(assignment employee.EmployeeShift, day time.Time) bool {
+	return !day.Before(dateOf(assignment.StartDate)) && !day.After(dateOf(assignment.EndDate))
+}
+
+func dateRange(from, to time.Time) (time.Time, time.Time, error) {
+	from, to = dateOf(from), dateOf(to)
+	if to.Before(from) || to.Sub(from) >= MaxRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, ErrInvalidRange
+	}
+	return from, to, nil
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
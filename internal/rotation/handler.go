@@ -0,0 +1,365 @@
+// internal/rotation/handler.go
+package rotation
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type RotationHandler struct {
+	service RotationService
+}
+
+func NewRotationHandler(service RotationService) *RotationHandler {
+	return &RotationHandler{service: service}
+}
+
+func (h *RotationHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var team Team
+	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreateTeam(team)
+	if err != nil {
+		sendRotationError(w, err, "Failed to create team")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *RotationHandler) GetTeams(w http.ResponseWriter, r *http.Request) {
+	teams, err := h.service.GetTeams()
+	if err != nil {
+		log.Printf("Error fetching teams: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve teams", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, teams, nil, nil)
+}
+
+func (h *RotationHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "team")
+	if !ok {
+		return
+	}
+
+	team, err := h.service.GetTeam(id)
+	if err != nil {
+		sendRotationError(w, err, "Failed to retrieve team")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, team, nil, nil)
+}
+
+func (h *RotationHandler) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "team")
+	if !ok {
+		return
+	}
+
+	var team Team
+	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateTeam(id, team)
+	if err != nil {
+		sendRotationError(w, err, "Failed to update team")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
+
+func (h *RotationHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "team")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTeam(id); err != nil {
+		sendRotationError(w, err, "Failed to delete team")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Team deleted successfully",
+	})
+}
+
+func (h *RotationHandler) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "team")
+	if !ok {
+		return
+	}
+
+	var member TeamMember
+	if err := json.NewDecoder(r.Body).Decode(&member); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, result, err := h.service.AddTeamMember(id, member)
+	if err != nil {
+		sendRotationError(w, err, "Failed to add team member")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, map[string]interface{}{
+		"generation": result,
+	})
+}
+
+func (h *RotationHandler) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "team")
+	if !ok {
+		return
+	}
+	employeeID, ok := pathID(w, r, "employee_id", "employee")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.RemoveTeamMember(id, employeeID)
+	if err != nil {
+		sendRotationError(w, err, "Failed to remove team member")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, result, nil, nil)
+}
+
+func (h *RotationHandler) CreatePattern(w http.ResponseWriter, r *http.Request) {
+	var pattern RotationPattern
+	if err := json.NewDecoder(r.Body).Decode(&pattern); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.CreatePattern(pattern)
+	if err != nil {
+		sendRotationError(w, err, "Failed to create rotation pattern")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *RotationHandler) GetPatterns(w http.ResponseWriter, r *http.Request) {
+	patterns, err := h.service.GetPatterns()
+	if err != nil {
+		log.Printf("Error fetching rotation patterns: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve rotation patterns", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, patterns, nil, nil)
+}
+
+func (h *RotationHandler) GetPattern(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "rotation pattern")
+	if !ok {
+		return
+	}
+
+	pattern, err := h.service.GetPattern(id)
+	if err != nil {
+		sendRotationError(w, err, "Failed to retrieve rotation pattern")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, pattern, nil, nil)
+}
+
+// UpdatePattern replaces a pattern and reports how its assignments were regenerated
+func (h *RotationHandler) UpdatePattern(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "rotation pattern")
+	if !ok {
+		return
+	}
+
+	var pattern RotationPattern
+	if err := json.NewDecoder(r.Body).Decode(&pattern); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, result, err := h.service.UpdatePattern(id, pattern)
+	if err != nil {
+		sendRotationError(w, err, "Failed to update rotation pattern")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, map[string]interface{}{
+		"generation": result,
+	})
+}
+
+func (h *RotationHandler) DeletePattern(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "rotation pattern")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePattern(id); err != nil {
+		sendRotationError(w, err, "Failed to delete rotation pattern")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Rotation pattern deleted successfully",
+	})
+}
+
+func (h *RotationHandler) AssignPattern(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "rotation pattern")
+	if !ok {
+		return
+	}
+
+	var assignment RotationAssignment
+	if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, result, err := h.service.AssignPattern(id, assignment)
+	if err != nil {
+		sendRotationError(w, err, "Failed to assign rotation pattern")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, map[string]interface{}{
+		"generation": result,
+	})
+}
+
+func (h *RotationHandler) GetAssignments(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "rotation pattern")
+	if !ok {
+		return
+	}
+
+	assignments, err := h.service.GetAssignments(id)
+	if err != nil {
+		log.Printf("Error fetching rotation assignments: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve rotation assignments", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, assignments, nil, nil)
+}
+
+func (h *RotationHandler) DeleteAssignment(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "rotation assignment")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.DeleteAssignment(id)
+	if err != nil {
+		sendRotationError(w, err, "Failed to delete rotation assignment")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, result, nil, nil)
+}
+
+func (h *RotationHandler) Regenerate(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "rotation assignment")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Regenerate(id)
+	if err != nil {
+		sendRotationError(w, err, "Failed to regenerate rotation assignment")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, result, nil, nil)
+}
+
+func (h *RotationHandler) SetOverride(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "rotation assignment")
+	if !ok {
+		return
+	}
+
+	var override RotationOverride
+	if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	saved, result, err := h.service.SetOverride(id, override)
+	if err != nil {
+		sendRotationError(w, err, "Failed to save rotation override")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, saved, nil, map[string]interface{}{
+		"generation": result,
+	})
+}
+
+func (h *RotationHandler) DeleteOverride(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "rotation override")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.DeleteOverride(id)
+	if err != nil {
+		sendRotationError(w, err, "Failed to delete rotation override")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, result, nil, nil)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, key, name string) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)[key], 10, 32)
+	if err != nil {
+		log.Printf("Invalid %s ID: %v", name, err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid "+name+" ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func sendRotationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTeamNotFound), errors.Is(err, ErrPatternNotFound), errors.Is(err, ErrAssignmentNotFound),
+		errors.Is(err, ErrOverrideNotFound), errors.Is(err, employee.ErrEmployeeNotFound), errors.Is(err, employee.ErrShiftNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case errors.Is(err, ErrInvalidPattern), errors.Is(err, ErrInvalidTarget), errors.Is(err, ErrInvalidDateRange),
+		errors.Is(err, ErrOutsideAssignment), errors.Is(err, ErrNotMember):
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case errors.Is(err, ErrAlreadyMember):
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
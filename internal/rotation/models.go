@@ -0,0 +1,85 @@
+package rotation
+
+import (
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Team is a group of employees that share a rotation, e.g., ICU night crew
+type Team struct {
+	gorm.Model
+	Name       string       `json:"name" gorm:"unique;not null"`
+	LocationID *uint        `json:"location_id"`
+	Members    []TeamMember `gorm:"foreignkey:TeamID" json:"members"`
+}
+
+type TeamMember struct {
+	gorm.Model
+	TeamID     uint `gorm:"not null;unique_index:idx_team_member" json:"team_id"`
+	EmployeeID uint `gorm:"not null;unique_index:idx_team_member" json:"employee_id"`
+	Offset     int  `json:"offset"` // Days this member is shifted within the cycle, to stagger the team
+}
+
+// RotationPattern is a repeating cycle of shifts and off days. Day 0 of the cycle
+// falls on AnchorDate, and on every CycleLength days before and after it.
+type RotationPattern struct {
+	gorm.Model
+	Name        string         `json:"name" gorm:"not null"` // e.g., 4 on 4 off, Alternating day/night
+	CycleLength int            `json:"cycle_length" gorm:"not null"`
+	AnchorDate  time.Time      `json:"anchor_date" gorm:"type:date;not null"`
+	Steps       []RotationStep `gorm:"foreignkey:PatternID" json:"steps"`
+}
+
+// RotationStep is one day of the cycle. Days without a step, or with no shift,
+// are off days.
+type RotationStep struct {
+	gorm.Model
+	PatternID uint  `gorm:"not null;index" json:"pattern_id"`
+	Day       int   `json:"day"`      // 0-based position in the cycle
+	ShiftID   *uint `json:"shift_id"` // Nil for an off day
+}
+
+// RotationAssignment applies a pattern to one employee or to every member of a team
+// from StartDate, until EndDate when set
+type RotationAssignment struct {
+	gorm.Model
+	PatternID        uint           `gorm:"not null;index" json:"pattern_id"`
+	EmployeeID       *uint          `gorm:"index" json:"employee_id"`
+	TeamID           *uint          `gorm:"index" json:"team_id"`
+	Offset           int            `json:"offset"` // Days the whole assignment is shifted within the cycle
+	StartDate        time.Time      `gorm:"type:date;not null" json:"start_date"`
+	EndDate          utils.NullTime `gorm:"type:date" json:"end_date"`
+	GeneratedThrough utils.NullTime `gorm:"type:date" json:"generated_through"` // Last day materialized into shift assignments
+
+	RotationPattern RotationPattern `gorm:"foreignkey:PatternID" json:"pattern,omitempty"`
+}
+
+// RotationOverride replaces the pattern for one employee on one day. It survives
+// regeneration, so manual changes to a rotation are not lost.
+type RotationOverride struct {
+	gorm.Model
+	AssignmentID uint      `gorm:"not null;unique_index:idx_rotation_override" json:"assignment_id"`
+	EmployeeID   uint      `gorm:"not null;unique_index:idx_rotation_override" json:"employee_id"`
+	Date         time.Time `gorm:"type:date;not null;unique_index:idx_rotation_override" json:"date"`
+	ShiftID      *uint     `json:"shift_id"` // Nil to take the day off
+	Reason       string    `json:"reason"`
+}
+
+// ShiftOn returns the shift the pattern schedules on a date, shifted by offset
+// days, or nil on off days
+func (p RotationPattern) ShiftOn(date time.Time, offset int) *uint {
+	if p.CycleLength <= 0 {
+		return nil
+	}
+
+	days := int(date.Sub(p.AnchorDate).Round(24*time.Hour).Hours() / 24)
+	position := ((days+offset)%p.CycleLength + p.CycleLength) % p.CycleLength
+	for _, step := range p.Steps {
+		if step.Day == position {
+			return step.ShiftID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,673 @@
+// internal/rotation/service.go
+package rotation
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrTeamNotFound       = errors.New("team not found")
+	ErrPatternNotFound    = errors.New("rotation pattern not found")
+	ErrAssignmentNotFound = errors.New("rotation assignment not found")
+	ErrOverrideNotFound   = errors.New("rotation override not found")
+	ErrInvalidPattern     = errors.New("cycle length must be positive and every step must fall within the cycle")
+	ErrInvalidTarget      = errors.New("a rotation is assigned to either an employee or a team")
+	ErrInvalidDateRange   = errors.New("end date must not be before start date")
+	ErrAlreadyMember      = errors.New("employee is already a member of this team")
+	ErrNotMember          = errors.New("employee is not a member of this team")
+	ErrOutsideAssignment  = errors.New("override date is outside the rotation assignment")
+)
+
+// SkippedDay is a day the pattern could not be materialized, e.g., because
+// the employee has a manual assignment or lacks a required credential
+type SkippedDay struct {
+	EmployeeID uint      `json:"employee_id"`
+	Date       time.Time `json:"date"`
+	Reason     string    `json:"reason"`
+}
+
+// GenerationResult summarises a materialization of rotation assignments
+type GenerationResult struct {
+	Created int          `json:"created"`
+	Removed int          `json:"removed"`
+	Skipped []SkippedDay `json:"skipped"`
+}
+
+type RotationService interface {
+	CreateTeam(team Team) (*Team, error)
+	GetTeams() ([]Team, error)
+	GetTeam(id uint) (*Team, error)
+	UpdateTeam(id uint, team Team) (*Team, error)
+	DeleteTeam(id uint) error
+	AddTeamMember(teamID uint, member TeamMember) (*TeamMember, *GenerationResult, error)
+	RemoveTeamMember(teamID, employeeID uint) (*GenerationResult, error)
+
+	CreatePattern(pattern RotationPattern) (*RotationPattern, error)
+	GetPatterns() ([]RotationPattern, error)
+	GetPattern(id uint) (*RotationPattern, error)
+	UpdatePattern(id uint, pattern RotationPattern) (*RotationPattern, *GenerationResult, error)
+	DeletePattern(id uint) error
+
+	AssignPattern(patternID uint, assignment RotationAssignment) (*RotationAssignment, *GenerationResult, error)
+	GetAssignments(patternID uint) ([]RotationAssignment, error)
+	DeleteAssignment(id uint) (*GenerationResult, error)
+	Regenerate(id uint) (*GenerationResult, error)
+	SetOverride(assignmentID uint, override RotationOverride) (*RotationOverride, *GenerationResult, error)
+	DeleteOverride(id uint) (*GenerationResult, error)
+	ExtendAll(now time.Time) (*GenerationResult, error)
+}
+
+type rotationService struct {
+	db        *gorm.DB
+	employees employee.EmployeeService
+	horizon   int
+}
+
+// NewRotationService creates the rotation service. Assignments are
+// materialized horizonDays ahead of today.
+func NewRotationService(db *gorm.DB, employees employee.EmployeeService, horizonDays int) RotationService {
+	return &rotationService{db: db, employees: employees, horizon: horizonDays}
+}
+
+func (s *rotationService) CreateTeam(team Team) (*Team, error) {
+	team.Members = nil
+	if err := s.db.Create(&team).Error; err != nil {
+		log.Printf("Error creating team: %v", err)
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (s *rotationService) GetTeams() ([]Team, error) {
+	var teams []Team
+	if err := s.db.Preload("Members").Order("name").Find(&teams).Error; err != nil {
+		log.Printf("Error fetching teams: %v", err)
+		return nil, err
+	}
+	return teams, nil
+}
+
+func (s *rotationService) GetTeam(id uint) (*Team, error) {
+	var team Team
+	if err := s.db.Preload("Members").First(&team, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrTeamNotFound
+		}
+		log.Printf("Error fetching team: %v", err)
+		return nil, err
+	}
+	return &team, nil
+}
+
+// UpdateTeam renames or relocates a team. Members are managed separately.
+func (s *rotationService) UpdateTeam(id uint, team Team) (*Team, error) {
+	existing, err := s.GetTeam(id)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.Name = team.Name
+	existing.LocationID = team.LocationID
+	if err := s.db.Omit("Members").Save(existing).Error; err != nil {
+		log.Printf("Error updating team: %v", err)
+		return nil, err
+	}
+	return existing, nil
+}
+
+// DeleteTeam removes a team, its rotation assignments and their future shifts
+func (s *rotationService) DeleteTeam(id uint) error {
+	if _, err := s.GetTeam(id); err != nil {
+		return err
+	}
+
+	var assignments []RotationAssignment
+	if err := s.db.Where("team_id = ?", id).Find(&assignments).Error; err != nil {
+		log.Printf("Error fetching team rotation assignments: %v", err)
+		return err
+	}
+	for _, assignment := range assignments {
+		if _, err := s.DeleteAssignment(assignment.ID); err != nil {
+			return err
+		}
+	}
+
+	// Hard deletes, as the unique indexes on team names and members would
+	// otherwise refuse creating them again
+	if err := s.db.Unscoped().Where("team_id = ?", id).Delete(&TeamMember{}).Error; err != nil {
+		log.Printf("Error deleting team members: %v", err)
+		return err
+	}
+	if err := s.db.Unscoped().Delete(&Team{}, id).Error; err != nil {
+		log.Printf("Error deleting team: %v", err)
+		return err
+	}
+	return nil
+}
+
+// AddTeamMember adds an employee to a team and schedules them on the team's rotations
+func (s *rotationService) AddTeamMember(teamID uint, member TeamMember) (*TeamMember, *GenerationResult, error) {
+	if _, err := s.GetTeam(teamID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.db.First(&employee.Employee{}, member.EmployeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, nil, err
+	}
+
+	var existing int
+	if err := s.db.Model(&TeamMember{}).Where("team_id = ? AND employee_id = ?", teamID, member.EmployeeID).Count(&existing).Error; err != nil {
+		log.Printf("Error checking team membership: %v", err)
+		return nil, nil, err
+	}
+	if existing > 0 {
+		return nil, nil, ErrAlreadyMember
+	}
+
+	member.ID = 0
+	member.TeamID = teamID
+	if err := s.db.Create(&member).Error; err != nil {
+		log.Printf("Error adding team member: %v", err)
+		return nil, nil, err
+	}
+
+	result, err := s.regenerateWhere("team_id = ?", teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &member, result, nil
+}
+
+// RemoveTeamMember takes an employee off a team and off its future rotation shifts
+func (s *rotationService) RemoveTeamMember(teamID, employeeID uint) (*GenerationResult, error) {
+	result := s.db.Unscoped().Where("team_id = ? AND employee_id = ?", teamID, employeeID).Delete(&TeamMember{})
+	if result.Error != nil {
+		log.Printf("Error removing team member: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotMember
+	}
+
+	removed := s.db.Unscoped().
+		Where("employee_id = ? AND start_date >= ? AND rotation_assignment_id IN (?)",
+			employeeID, today(), s.db.Model(&RotationAssignment{}).Select("id").Where("team_id = ?", teamID).QueryExpr()).
+		Delete(&employee.EmployeeShift{})
+	if removed.Error != nil {
+		log.Printf("Error removing rotation shifts: %v", removed.Error)
+		return nil, removed.Error
+	}
+	return &GenerationResult{Removed: int(removed.RowsAffected), Skipped: []SkippedDay{}}, nil
+}
+
+func (s *rotationService) CreatePattern(pattern RotationPattern) (*RotationPattern, error) {
+	if err := validatePattern(&pattern); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&pattern).Error; err != nil {
+		log.Printf("Error creating rotation pattern: %v", err)
+		return nil, err
+	}
+	return &pattern, nil
+}
+
+func (s *rotationService) GetPatterns() ([]RotationPattern, error) {
+	var patterns []RotationPattern
+	if err := s.db.Preload("Steps", orderSteps).Order("name").Find(&patterns).Error; err != nil {
+		log.Printf("Error fetching rotation patterns: %v", err)
+		return nil, err
+	}
+	return patterns, nil
+}
+
+func (s *rotationService) GetPattern(id uint) (*RotationPattern, error) {
+	var pattern RotationPattern
+	if err := s.db.Preload("Steps", orderSteps).First(&pattern, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrPatternNotFound
+		}
+		log.Printf("Error fetching rotation pattern: %v", err)
+		return nil, err
+	}
+	return &pattern, nil
+}
+
+// UpdatePattern replaces a pattern and its steps, then regenerates every
+// assignment using it from today onwards
+func (s *rotationService) UpdatePattern(id uint, pattern RotationPattern) (*RotationPattern, *GenerationResult, error) {
+	existing, err := s.GetPattern(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validatePattern(&pattern); err != nil {
+		return nil, nil, err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, nil, tx.Error
+	}
+
+	if err := tx.Unscoped().Where("pattern_id = ?", id).Delete(&RotationStep{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error removing rotation pattern steps: %v", err)
+		return nil, nil, err
+	}
+
+	pattern.ID = existing.ID
+	pattern.CreatedAt = existing.CreatedAt
+	for i := range pattern.Steps {
+		pattern.Steps[i].ID = 0
+		pattern.Steps[i].PatternID = existing.ID
+	}
+	if err := tx.Save(&pattern).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error updating rotation pattern: %v", err)
+		return nil, nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, nil, err
+	}
+
+	result, err := s.regenerateWhere("pattern_id = ?", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &pattern, result, nil
+}
+
+// DeletePattern removes a pattern along with its assignments and their future shifts
+func (s *rotationService) DeletePattern(id uint) error {
+	if _, err := s.GetPattern(id); err != nil {
+		return err
+	}
+
+	assignments, err := s.GetAssignments(id)
+	if err != nil {
+		return err
+	}
+	for _, assignment := range assignments {
+		if _, err := s.DeleteAssignment(assignment.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.db.Where("pattern_id = ?", id).Delete(&RotationStep{}).Error; err != nil {
+		log.Printf("Error deleting rotation pattern steps: %v", err)
+		return err
+	}
+	if err := s.db.Delete(&RotationPattern{}, id).Error; err != nil {
+		log.Printf("Error deleting rotation pattern: %v", err)
+		return err
+	}
+	return nil
+}
+
+// AssignPattern applies a pattern to an employee or a team and materializes
+// it through the horizon
+func (s *rotationService) AssignPattern(patternID uint, assignment RotationAssignment) (*RotationAssignment, *GenerationResult, error) {
+	if _, err := s.GetPattern(patternID); err != nil {
+		return nil, nil, err
+	}
+	if (assignment.EmployeeID == nil) == (assignment.TeamID == nil) {
+		return nil, nil, ErrInvalidTarget
+	}
+	if assignment.EmployeeID != nil {
+		if err := s.db.First(&employee.Employee{}, *assignment.EmployeeID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, nil, employee.ErrEmployeeNotFound
+			}
+			log.Printf("Error fetching employee: %v", err)
+			return nil, nil, err
+		}
+	}
+	if assignment.TeamID != nil {
+		if _, err := s.GetTeam(*assignment.TeamID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	assignment.StartDate = dateOf(assignment.StartDate)
+	if assignment.EndDate.Valid {
+		assignment.EndDate.Time = dateOf(assignment.EndDate.Time)
+		if assignment.EndDate.Time.Before(assignment.StartDate) {
+			return nil, nil, ErrInvalidDateRange
+		}
+	}
+
+	assignment.ID = 0
+	assignment.PatternID = patternID
+	assignment.GeneratedThrough = utils.NullTime{}
+	if err := s.db.Omit("RotationPattern").Create(&assignment).Error; err != nil {
+		log.Printf("Error creating rotation assignment: %v", err)
+		return nil, nil, err
+	}
+
+	result := &GenerationResult{Skipped: []SkippedDay{}}
+	if err := s.generate(&assignment, today(), result); err != nil {
+		return nil, nil, err
+	}
+	return &assignment, result, nil
+}
+
+func (s *rotationService) GetAssignments(patternID uint) ([]RotationAssignment, error) {
+	var assignments []RotationAssignment
+	if err := s.db.Where("pattern_id = ?", patternID).Order("start_date").Find(&assignments).Error; err != nil {
+		log.Printf("Error fetching rotation assignments: %v", err)
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// DeleteAssignment stops a rotation. Shifts it generated from today onwards
+// are removed; past ones stay as history.
+func (s *rotationService) DeleteAssignment(id uint) (*GenerationResult, error) {
+	assignment, err := s.assignment(id)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := s.removeGenerated(assignment, today())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("assignment_id = ?", id).Delete(&RotationOverride{}).Error; err != nil {
+		log.Printf("Error deleting rotation overrides: %v", err)
+		return nil, err
+	}
+	if err := s.db.Delete(assignment).Error; err != nil {
+		log.Printf("Error deleting rotation assignment: %v", err)
+		return nil, err
+	}
+	return &GenerationResult{Removed: removed, Skipped: []SkippedDay{}}, nil
+}
+
+// Regenerate rebuilds an assignment's shifts from today through the horizon
+func (s *rotationService) Regenerate(id uint) (*GenerationResult, error) {
+	if _, err := s.assignment(id); err != nil {
+		return nil, err
+	}
+	return s.regenerateWhere("id = ?", id)
+}
+
+// SetOverride replaces the pattern for one employee on one day, with another
+// shift or a day off, and regenerates the assignment
+func (s *rotationService) SetOverride(assignmentID uint, override RotationOverride) (*RotationOverride, *GenerationResult, error) {
+	assignment, err := s.assignment(assignmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	override.Date = dateOf(override.Date)
+	if override.Date.Before(assignment.StartDate) || (assignment.EndDate.Valid && override.Date.After(dateOf(assignment.EndDate.Time))) {
+		return nil, nil, ErrOutsideAssignment
+	}
+	members, err := s.members(assignment)
+	if err != nil {
+		return nil, nil, err
+	}
+	isMember := false
+	for _, member := range members {
+		isMember = isMember || member.EmployeeID == override.EmployeeID
+	}
+	if !isMember {
+		return nil, nil, ErrNotMember
+	}
+	if override.ShiftID != nil {
+		if err := s.db.First(&employee.Shift{}, *override.ShiftID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, nil, employee.ErrShiftNotFound
+			}
+			log.Printf("Error fetching shift: %v", err)
+			return nil, nil, err
+		}
+	}
+
+	var existing RotationOverride
+	err = s.db.Where("assignment_id = ? AND employee_id = ? AND date = ?", assignmentID, override.EmployeeID, override.Date).First(&existing).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		log.Printf("Error fetching rotation override: %v", err)
+		return nil, nil, err
+	}
+	override.ID = existing.ID
+	override.CreatedAt = existing.CreatedAt
+	override.AssignmentID = assignmentID
+	if err := s.db.Save(&override).Error; err != nil {
+		log.Printf("Error saving rotation override: %v", err)
+		return nil, nil, err
+	}
+
+	result, err := s.regenerateWhere("id = ?", assignmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &override, result, nil
+}
+
+// DeleteOverride returns the day to the pattern
+func (s *rotationService) DeleteOverride(id uint) (*GenerationResult, error) {
+	var override RotationOverride
+	if err := s.db.First(&override, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrOverrideNotFound
+		}
+		log.Printf("Error fetching rotation override: %v", err)
+		return nil, err
+	}
+
+	if err := s.db.Unscoped().Delete(&override).Error; err != nil {
+		log.Printf("Error deleting rotation override: %v", err)
+		return nil, err
+	}
+	return s.regenerateWhere("id = ?", override.AssignmentID)
+}
+
+// ExtendAll materializes every running assignment up to the horizon from
+// where it was last generated. It is run daily so rotations keep rolling forward.
+func (s *rotationService) ExtendAll(now time.Time) (*GenerationResult, error) {
+	var assignments []RotationAssignment
+	if err := s.db.Where("end_date IS NULL OR end_date >= ?", dateOf(now)).Find(&assignments).Error; err != nil {
+		log.Printf("Error fetching rotation assignments: %v", err)
+		return nil, err
+	}
+
+	result := &GenerationResult{Skipped: []SkippedDay{}}
+	for i := range assignments {
+		from := dateOf(now)
+		if assignments[i].GeneratedThrough.Valid && !assignments[i].GeneratedThrough.Time.Before(from) {
+			from = dateOf(assignments[i].GeneratedThrough.Time).AddDate(0, 0, 1)
+		}
+		if err := s.generate(&assignments[i], from, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// regenerateWhere removes and rebuilds the future shifts of the matching assignments
+func (s *rotationService) regenerateWhere(query string, args ...interface{}) (*GenerationResult, error) {
+	var assignments []RotationAssignment
+	if err := s.db.Where(query, args...).Find(&assignments).Error; err != nil {
+		log.Printf("Error fetching rotation assignments: %v", err)
+		return nil, err
+	}
+
+	result := &GenerationResult{Skipped: []SkippedDay{}}
+	from := today()
+	for i := range assignments {
+		removed, err := s.removeGenerated(&assignments[i], from)
+		if err != nil {
+			return nil, err
+		}
+		result.Removed += removed
+
+		if err := s.generate(&assignments[i], from, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *rotationService) removeGenerated(assignment *RotationAssignment, from time.Time) (int, error) {
+	// Generated rows are removed outright, so the same day can be generated
+	// again without hitting the assignment unique index
+	removed := s.db.Unscoped().Where("rotation_assignment_id = ? AND start_date >= ?", assignment.ID, from).Delete(&employee.EmployeeShift{})
+	if removed.Error != nil {
+		log.Printf("Error removing rotation shifts: %v", removed.Error)
+		return 0, removed.Error
+	}
+	return int(removed.RowsAffected), nil
+}
+
+// generate creates one-day shift assignments for every member from `from`
+// through the horizon. Overrides replace the pattern; days where the employee
+// already has a manual assignment are left alone, as are assignments the
+// employee service rejects.
+func (s *rotationService) generate(assignment *RotationAssignment, from time.Time, result *GenerationResult) error {
+	pattern, err := s.GetPattern(assignment.PatternID)
+	if err != nil {
+		return err
+	}
+	members, err := s.members(assignment)
+	if err != nil {
+		return err
+	}
+
+	through := today().AddDate(0, 0, s.horizon)
+	if assignment.EndDate.Valid && assignment.EndDate.Time.Before(through) {
+		through = dateOf(assignment.EndDate.Time)
+	}
+	if from.Before(assignment.StartDate) {
+		from = dateOf(assignment.StartDate)
+	}
+
+	var overrides []RotationOverride
+	if err := s.db.Where("assignment_id = ? AND date BETWEEN ? AND ?", assignment.ID, from, through).Find(&overrides).Error; err != nil {
+		log.Printf("Error fetching rotation overrides: %v", err)
+		return err
+	}
+	overridden := make(map[string]RotationOverride, len(overrides))
+	for _, override := range overrides {
+		overridden[overrideKey(override.EmployeeID, override.Date)] = override
+	}
+
+	for day := from; !day.After(through); day = day.AddDate(0, 0, 1) {
+		for _, member := range members {
+			shiftID := pattern.ShiftOn(day, assignment.Offset+member.Offset)
+			if override, ok := overridden[overrideKey(member.EmployeeID, day)]; ok {
+				shiftID = override.ShiftID
+			}
+			if shiftID == nil {
+				continue
+			}
+
+			var manual int
+			s.db.Model(&employee.EmployeeShift{}).
+				Where("employee_id = ? AND start_date <= ? AND end_date >= ? AND rotation_assignment_id IS NULL", member.EmployeeID, day, day).
+				Count(&manual)
+			if manual > 0 {
+				result.Skipped = append(result.Skipped, SkippedDay{EmployeeID: member.EmployeeID, Date: day, Reason: "manual shift assignment"})
+				continue
+			}
+
+			_, err := s.employees.CreateAssignment(employee.EmployeeShift{
+				EmployeeID:           member.EmployeeID,
+				ShiftID:              *shiftID,
+				StartDate:            day,
+				EndDate:              day,
+				Source:               employee.AssignmentSourceRotation,
+				RotationAssignmentID: &assignment.ID,
+			})
+			if err != nil {
+				if errors.Is(err, employee.ErrAssignmentRejected) || errors.Is(err, employee.ErrShiftNotFound) || errors.Is(err, employee.ErrEmployeeNotFound) {
+					result.Skipped = append(result.Skipped, SkippedDay{EmployeeID: member.EmployeeID, Date: day, Reason: err.Error()})
+					continue
+				}
+				return err
+			}
+			result.Created++
+		}
+	}
+
+	if through.Before(from) {
+		return nil
+	}
+	assignment.GeneratedThrough = utils.NullTime{NullTime: sql.NullTime{Time: through, Valid: true}}
+	if err := s.db.Model(assignment).UpdateColumn("generated_through", assignment.GeneratedThrough).Error; err != nil {
+		log.Printf("Error updating rotation assignment: %v", err)
+		return err
+	}
+	return nil
+}
+
+// members returns who an assignment schedules: the employee, or each team member
+func (s *rotationService) members(assignment *RotationAssignment) ([]TeamMember, error) {
+	if assignment.EmployeeID != nil {
+		return []TeamMember{{EmployeeID: *assignment.EmployeeID}}, nil
+	}
+
+	var members []TeamMember
+	if err := s.db.Where("team_id = ?", assignment.TeamID).Find(&members).Error; err != nil {
+		log.Printf("Error fetching team members: %v", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+func (s *rotationService) assignment(id uint) (*RotationAssignment, error) {
+	var assignment RotationAssignment
+	if err := s.db.First(&assignment, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrAssignmentNotFound
+		}
+		log.Printf("Error fetching rotation assignment: %v", err)
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func validatePattern(pattern *RotationPattern) error {
+	if pattern.CycleLength <= 0 {
+		return ErrInvalidPattern
+	}
+	seen := make(map[int]bool, len(pattern.Steps))
+	for _, step := range pattern.Steps {
+		if step.Day < 0 || step.Day >= pattern.CycleLength || seen[step.Day] {
+			return ErrInvalidPattern
+		}
+		seen[step.Day] = true
+	}
+	pattern.AnchorDate = dateOf(pattern.AnchorDate)
+	return nil
+}
+
+func orderSteps(db *gorm.DB) *gorm.DB {
+	return db.Order("day")
+}
+
+func overrideKey(employeeID uint, date time.Time) string {
+	return fmt.Sprintf("%d/%s", employeeID, date.Format("2006-01-02"))
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func today() time.Time {
+	return dateOf(time.Now().UTC())
+}
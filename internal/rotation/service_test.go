@@ -0,0 +1,196 @@
+package rotation
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/shared/testdb"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// assignments stands in for the employee service, recording the shift
+// assignments rotations create
+type assignments struct {
+	employee.EmployeeService
+	created []string
+	reject  string // Assignment refused by the guards, as formatted by key
+}
+
+func key(employeeID, shiftID uint, day int) string {
+	return fmt.Sprintf("employee %d shift %d day %d", employeeID, shiftID, day)
+}
+
+func (a *assignments) CreateAssignment(assignment employee.EmployeeShift) (*employee.EmployeeShift, error) {
+	k := key(assignment.EmployeeID, assignment.ShiftID, int(assignment.StartDate.Sub(today()).Hours()/24))
+	if k == a.reject {
+		return nil, fmt.Errorf("%w: lacks a credential", employee.ErrAssignmentRejected)
+	}
+	if assignment.Source != employee.AssignmentSourceRotation || assignment.RotationAssignmentID == nil || !assignment.EndDate.Equal(assignment.StartDate) {
+		return nil, fmt.Errorf("unexpected assignment %+v", assignment)
+	}
+	a.created = append(a.created, k)
+	return &assignment, nil
+}
+
+var assignmentColumns = []string{"id", "pattern_id", "employee_id", "team_id", "offset", "start_date", "generated_through"}
+
+func TestExtendAll(t *testing.T) {
+	now := today()
+	in := func(days int) time.Time { return now.AddDate(0, 0, days) }
+
+	tests := []struct {
+		name        string
+		assignment  []interface{}
+		members     [][]interface{} // employee_id, offset
+		overrides   [][]interface{} // employee_id, day, shift_id
+		manual      bool            // Every day already has a manual assignment
+		reject      string
+		want        []string
+		wantSkipped int
+	}{
+		{
+			// The pattern works days 0 and 1 of a 4-day cycle anchored today
+			name:       "employee",
+			assignment: []interface{}{1, 1, 5, nil, 0, now, nil},
+			want:       []string{key(5, 1, 0), key(5, 1, 1), key(5, 1, 4), key(5, 1, 5)},
+		},
+		{
+			name:       "overrides replace the pattern",
+			assignment: []interface{}{1, 1, 5, nil, 0, now, nil},
+			overrides:  [][]interface{}{{5, 2, 3}, {5, 4, nil}},
+			want:       []string{key(5, 1, 0), key(5, 1, 1), key(5, 3, 2), key(5, 1, 5)},
+		},
+		{
+			name:       "team members are staggered by their offsets",
+			assignment: []interface{}{1, 1, nil, 2, 0, now, nil},
+			members:    [][]interface{}{{5, 0}, {6, 2}},
+			want: []string{
+				key(5, 1, 0), key(5, 1, 1), key(6, 1, 2), key(6, 1, 3),
+				key(5, 1, 4), key(5, 1, 5), key(6, 1, 6), key(6, 1, 7),
+			},
+		},
+		{
+			name:       "assignment offset",
+			assignment: []interface{}{1, 1, 5, nil, 1, now, nil},
+			want:       []string{key(5, 1, 0), key(5, 1, 3), key(5, 1, 4), key(5, 1, 7)},
+		},
+		{
+			name:       "continues after the days already generated",
+			assignment: []interface{}{1, 1, 5, nil, 0, now, in(3)},
+			want:       []string{key(5, 1, 4), key(5, 1, 5)},
+		},
+		{
+			name:       "starts on the assignment start date",
+			assignment: []interface{}{1, 1, 5, nil, 0, in(3), nil},
+			want:       []string{key(5, 1, 4), key(5, 1, 5)},
+		},
+		{
+			name:        "manual assignments are left alone",
+			assignment:  []interface{}{1, 1, 5, nil, 0, now, nil},
+			manual:      true,
+			wantSkipped: 4,
+		},
+		{
+			name:        "rejected assignments are skipped",
+			assignment:  []interface{}{1, 1, 5, nil, 0, now, nil},
+			reject:      key(5, 1, 1),
+			want:        []string{key(5, 1, 0), key(5, 1, 4), key(5, 1, 5)},
+			wantSkipped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "rotation_assignments"`, assignmentColumns, tt.assignment)
+			fake.Returns(`FROM "rotation_patterns"`, []string{"id", "name", "cycle_length", "anchor_date"}, []interface{}{1, "2 on 2 off", 4, now})
+			fake.Returns(`FROM "rotation_steps"`, []string{"id", "pattern_id", "day", "shift_id"},
+				[]interface{}{1, 1, 0, 1}, []interface{}{2, 1, 1, 1}, []interface{}{3, 1, 2, nil})
+			var members [][]interface{}
+			for i, m := range tt.members {
+				members = append(members, []interface{}{i + 1, 2, m[0], m[1]})
+			}
+			fake.Returns(`FROM "team_members"`, []string{"id", "team_id", "employee_id", "offset"}, members...)
+			var overrides [][]interface{}
+			for i, o := range tt.overrides {
+				overrides = append(overrides, []interface{}{i + 1, 1, o[0], in(o[1].(int)), o[2]})
+			}
+			fake.Returns(`FROM "rotation_overrides"`, []string{"id", "assignment_id", "employee_id", "date", "shift_id"}, overrides...)
+			if tt.manual {
+				fake.Returns(`rotation_assignment_id IS NULL`, []string{"count"}, []interface{}{1})
+			}
+			employees := &assignments{reject: tt.reject}
+			service := NewRotationService(db, employees, 7)
+
+			result, err := service.ExtendAll(now)
+			if err != nil {
+				t.Fatalf("ExtendAll: %v", err)
+			}
+			if !reflect.DeepEqual(employees.created, tt.want) {
+				t.Errorf("created %v, want %v", employees.created, tt.want)
+			}
+			if result.Created != len(tt.want) || len(result.Skipped) != tt.wantSkipped {
+				t.Errorf("result created %d and skipped %v, want %d and %d skipped", result.Created, result.Skipped, len(tt.want), tt.wantSkipped)
+			}
+			if updates := fake.Find(`SET "generated_through"`); len(updates) != 1 || !updates[0].Has(in(7)) {
+				t.Errorf("generated through recorded with %+v, want the horizon", updates)
+			}
+		})
+	}
+}
+
+func TestCreatePatternValidation(t *testing.T) {
+	shift := uint(1)
+	tests := []struct {
+		name    string
+		pattern RotationPattern
+		wantErr error
+	}{
+		{"valid", RotationPattern{CycleLength: 2, Steps: []RotationStep{{Day: 0, ShiftID: &shift}, {Day: 1}}}, nil},
+		{"no cycle", RotationPattern{}, ErrInvalidPattern},
+		{"step after the cycle", RotationPattern{CycleLength: 2, Steps: []RotationStep{{Day: 2, ShiftID: &shift}}}, ErrInvalidPattern},
+		{"negative step", RotationPattern{CycleLength: 2, Steps: []RotationStep{{Day: -1, ShiftID: &shift}}}, ErrInvalidPattern},
+		{"two steps on one day", RotationPattern{CycleLength: 2, Steps: []RotationStep{{Day: 1}, {Day: 1, ShiftID: &shift}}}, ErrInvalidPattern},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			_, err := NewRotationService(db, nil, 7).CreatePattern(tt.pattern)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreatePattern error %v, want %v", err, tt.wantErr)
+			}
+			if saved := fake.Ran(`INSERT INTO "rotation_patterns"`); saved != (tt.wantErr == nil) {
+				t.Errorf("pattern saved: %v", saved)
+			}
+		})
+	}
+}
+
+func TestAssignPatternTarget(t *testing.T) {
+	employeeID, teamID := uint(5), uint(2)
+	tests := []struct {
+		name       string
+		assignment RotationAssignment
+		wantErr    error
+	}{
+		{"neither employee nor team", RotationAssignment{StartDate: today()}, ErrInvalidTarget},
+		{"both employee and team", RotationAssignment{EmployeeID: &employeeID, TeamID: &teamID, StartDate: today()}, ErrInvalidTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "rotation_patterns"`, []string{"id", "cycle_length"}, []interface{}{1, 4})
+			_, _, err := NewRotationService(db, &assignments{}, 7).AssignPattern(1, tt.assignment)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AssignPattern error %v, want %v", err, tt.wantErr)
+			}
+			if fake.Ran(`INSERT INTO "rotation_assignments"`) {
+				t.Error("assignment saved")
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+// internal/scheduler/handler.go
+package scheduler
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type SchedulerHandler struct {
+	service SchedulerService
+}
+
+func NewSchedulerHandler(service SchedulerService) *SchedulerHandler {
+	return &SchedulerHandler{service: service}
+}
+
+func (h *SchedulerHandler) GenerateDraft(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		From       string `json:"from"` // YYYY-MM-DD
+		To         string `json:"to"`
+		LocationID *uint  `json:"location_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+	from, err1 := time.Parse("2006-01-02", body.From)
+	to, err2 := time.Parse("2006-01-02", body.To)
+	if err1 != nil || err2 != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid period, expected YYYY-MM-DD dates", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	draft, err := h.service.GenerateDraft(from, to, body.LocationID, user)
+	if err != nil {
+		sendSchedulerError(w, err, "Failed to generate schedule draft")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, draft, nil, nil)
+}
+
+func (h *SchedulerHandler) GetDrafts(w http.ResponseWriter, r *http.Request) {
+	drafts, err := h.service.GetDrafts()
+	if err != nil {
+		log.Printf("Error fetching schedule drafts: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve schedule drafts", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, drafts, nil, nil)
+}
+
+func (h *SchedulerHandler) GetDraft(w http.ResponseWriter, r *http.Request) {
+	id, ok := draftID(w, r)
+	if !ok {
+		return
+	}
+
+	draft, err := h.service.GetDraft(id)
+	if err != nil {
+		sendSchedulerError(w, err, "Failed to retrieve schedule draft")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, draft, nil, nil)
+}
+
+func (h *SchedulerHandler) AddAssignment(w http.ResponseWriter, r *http.Request) {
+	id, ok := draftID(w, r)
+	if !ok {
+		return
+	}
+
+	var assignment DraftAssignment
+	if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	created, err := h.service.AddAssignment(id, assignment)
+	if err != nil {
+		sendSchedulerError(w, err, "Failed to add draft assignment")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+func (h *SchedulerHandler) RemoveAssignment(w http.ResponseWriter, r *http.Request) {
+	id, ok := draftID(w, r)
+	if !ok {
+		return
+	}
+	assignmentID, err := strconv.ParseUint(mux.Vars(r)["assignment_id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid draft assignment ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid draft assignment ID", nil)
+		return
+	}
+
+	if err := h.service.RemoveAssignment(id, uint(assignmentID)); err != nil {
+		sendSchedulerError(w, err, "Failed to remove draft assignment")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Draft assignment removed successfully",
+	})
+}
+
+func (h *SchedulerHandler) PublishDraft(w http.ResponseWriter, r *http.Request) {
+	id, ok := draftID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	draft, result, err := h.service.PublishDraft(id, user)
+	if err != nil {
+		sendSchedulerError(w, err, "Failed to publish schedule draft")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, draft, nil, map[string]interface{}{
+		"publication": result,
+	})
+}
+
+func (h *SchedulerHandler) ReviseDraft(w http.ResponseWriter, r *http.Request) {
+	id, ok := draftID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	draft, err := h.service.ReviseDraft(id, user)
+	if err != nil {
+		sendSchedulerError(w, err, "Failed to revise schedule")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, draft, nil, nil)
+}
+
+// DiffDraft compares a schedule version with the one it revises, or with the
+// version given as ?against=<id>
+func (h *SchedulerHandler) DiffDraft(w http.ResponseWriter, r *http.Request) {
+	id, ok := draftID(w, r)
+	if !ok {
+		return
+	}
+	var againstID *uint
+	if value := r.URL.Query().Get("against"); value != "" {
+		against, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid against parameter, expected a schedule draft ID", nil)
+			return
+		}
+		versionID := uint(against)
+		againstID = &versionID
+	}
+
+	changes, err := h.service.DiffDraft(id, againstID)
+	if err != nil {
+		sendSchedulerError(w, err, "Failed to compare schedule versions")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, changes, nil, nil)
+}
+
+func (h *SchedulerHandler) DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	id, ok := draftID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteDraft(id); err != nil {
+		sendSchedulerError(w, err, "Failed to delete schedule draft")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, nil, nil, map[string]interface{}{
+		"message": "Schedule draft deleted successfully",
+	})
+}
+
+func draftID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid schedule draft ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid schedule draft ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func sendSchedulerError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case ErrDraftNotFound, ErrAssignmentNotFound, employee.ErrEmployeeNotFound, employee.ErrShiftNotFound:
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case ErrInvalidRange, ErrOutsideDraft, ErrShiftNotRunning, ErrDifferentPeriod:
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case ErrDraftPublished, ErrDraftNotPublished, ErrDraftRevised:
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
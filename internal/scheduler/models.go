@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DraftStatusDraft      = "draft"
+	DraftStatusPublished  = "published"
+	DraftStatusSuperseded = "superseded" // A later version of the schedule was published
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+)
+
+// ScheduleDraft is one version of the schedule for a period. Managers review
+// and adjust a draft before publishing it as EmployeeShift assignments; a
+// published schedule is changed by revising it into a new draft version.
+type ScheduleDraft struct {
+	gorm.Model
+	From            time.Time      `gorm:"type:date;not null" json:"from"`
+	To              time.Time      `gorm:"type:date;not null" json:"to"`
+	LocationID      *uint          `json:"location_id"` // Nil schedules every location
+	Status          string         `gorm:"not null;default:'draft';index" json:"status"`
+	Version         int            `gorm:"not null;default:1" json:"version"`
+	PreviousDraftID *uint          `gorm:"index" json:"previous_draft_id"` // Published version this one revises
+	TimedOut        bool           `json:"timed_out"`                      // The solver ran out of time before every slot was considered
+	CreatedBy       uint           `json:"created_by"`
+	PublishedBy     *uint          `json:"published_by"`
+	PublishedAt     utils.NullTime `json:"published_at"`
+
+	Assignments []DraftAssignment `gorm:"foreignkey:DraftID" json:"assignments"`
+	Gaps        []DraftGap        `gorm:"foreignkey:DraftID" json:"gaps"`
+}
+
+// DraftAssignment is one proposed shift for one employee on one day
+type DraftAssignment struct {
+	gorm.Model
+	DraftID    uint      `gorm:"not null;index" json:"draft_id"`
+	EmployeeID uint      `gorm:"not null" json:"employee_id"`
+	ShiftID    uint      `gorm:"not null" json:"shift_id"`
+	Date       time.Time `gorm:"type:date;not null" json:"date"`
+	Manual     bool      `json:"manual"`   // Added by a manager rather than the solver
+	Warnings   string    `json:"warnings"` // Constraints a manual assignment breaks, separated by "; "
+}
+
+// DraftGap is a coverage requirement the solver could not meet
+type DraftGap struct {
+	gorm.Model
+	DraftID     uint      `gorm:"not null;index" json:"draft_id"`
+	RuleID      uint      `json:"rule_id"`
+	ShiftID     uint      `json:"shift_id"`
+	Date        time.Time `gorm:"type:date;not null" json:"date"`
+	Designation string    `json:"designation"`
+	Shortfall   int       `json:"shortfall"`
+}
+
+// ScheduleChange is an assignment one version of a schedule has and the
+// other does not
+type ScheduleChange struct {
+	Change     string    `json:"change"` // added or removed
+	EmployeeID uint      `json:"employee_id"`
+	ShiftID    uint      `json:"shift_id"`
+	Date       time.Time `json:"date"`
+}
+
+// PublishResult reports how a draft was turned into shift assignments.
+// Skipped assignments are dropped from the published version.
+type PublishResult struct {
+	Created  int            `json:"created"`
+	Removed  int            `json:"removed"`
+	Skipped  []SkippedShift `json:"skipped"`
+	Notified int            `json:"notified"` // Employees told about their changed assignments
+}
+
+// SkippedShift is a draft assignment that could not be published
+type SkippedShift struct {
+	DraftAssignmentID uint      `json:"draft_assignment_id"`
+	EmployeeID        uint      `json:"employee_id"`
+	Date              time.Time `json:"date"`
+	Reason            string    `json:"reason"`
+}
@@ -0,0 +1,736 @@
+// internal/scheduler/service.go
+package scheduler
+
+import (
+	"clinicplus/internal/availability"
+	"clinicplus/internal/coverage"
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/leave"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MaxRangeDays bounds the period a draft may cover
+const MaxRangeDays = 62
+
+var (
+	ErrDraftNotFound      = errors.New("schedule draft not found")
+	ErrAssignmentNotFound = errors.New("draft assignment not found")
+	ErrDraftPublished     = errors.New("schedule draft is already published")
+	ErrInvalidRange       = errors.New("invalid period: to must not be before from, and the period may cover at most 62 days")
+	ErrOutsideDraft       = errors.New("date is outside the draft period")
+	ErrShiftNotRunning    = errors.New("shift does not run on that day")
+	ErrDraftNotPublished  = errors.New("only the published version of a schedule can be revised")
+	ErrDraftRevised       = errors.New("schedule already has a newer version")
+	ErrDifferentPeriod    = errors.New("schedule versions cover different periods")
+)
+
+type SchedulerService interface {
+	GenerateDraft(from, to time.Time, locationID *uint, user *iam.User) (*ScheduleDraft, error)
+	GetDrafts() ([]ScheduleDraft, error)
+	GetDraft(id uint) (*ScheduleDraft, error)
+	AddAssignment(draftID uint, assignment DraftAssignment) (*DraftAssignment, error)
+	RemoveAssignment(draftID, assignmentID uint) error
+	PublishDraft(id uint, user *iam.User) (*ScheduleDraft, *PublishResult, error)
+	ReviseDraft(id uint, user *iam.User) (*ScheduleDraft, error)
+	DiffDraft(id uint, againstID *uint) ([]ScheduleChange, error)
+	DeleteDraft(id uint) error
+	AddGuard(guard employee.AssignmentGuard)
+}
+
+type schedulerService struct {
+	db            *gorm.DB
+	employees     employee.EmployeeService
+	notifications notification.NotificationService
+	policy        Policy
+	guards        []employee.AssignmentGuard
+}
+
+// NewSchedulerService creates the scheduler. Drafts are published through
+// the employee service, so its guards apply again at publication.
+func NewSchedulerService(db *gorm.DB, employees employee.EmployeeService, notifications notification.NotificationService, policy Policy) SchedulerService {
+	return &schedulerService{db: db, employees: employees, notifications: notifications, policy: policy}
+}
+
+// AddGuard registers an assignment guard the solver consults for each
+// candidate, so that it only proposes assignments that can be published
+func (s *schedulerService) AddGuard(guard employee.AssignmentGuard) {
+	s.guards = append(s.guards, guard)
+}
+
+// GenerateDraft proposes assignments meeting the coverage rules for the
+// period, on top of the shifts already assigned. Requirements that cannot
+// be met within the policy are recorded as gaps.
+func (s *schedulerService) GenerateDraft(from, to time.Time, locationID *uint, user *iam.User) (*ScheduleDraft, error) {
+	from, to = dateOf(from), dateOf(to)
+	if to.Before(from) || to.Sub(from) >= MaxRangeDays*24*time.Hour {
+		return nil, ErrInvalidRange
+	}
+	deadline := time.Now().Add(s.policy.TimeBudget)
+
+	query := s.db.Preload("Shift")
+	if locationID != nil {
+		query = query.Where("location_id = ? OR location_id IS NULL", *locationID)
+	}
+	var rules []coverage.CoverageRule
+	if err := query.Order("id").Find(&rules).Error; err != nil {
+		log.Printf("Error fetching coverage rules: %v", err)
+		return nil, err
+	}
+
+	var employees []employee.Employee
+	if err := s.db.Where("employment_status IN (?)", []string{employee.EmploymentStatusActive, employee.EmploymentStatusProbation}).
+		Order("id").Find(&employees).Error; err != nil {
+		log.Printf("Error fetching employees: %v", err)
+		return nil, err
+	}
+	staffByID, assigned, err := s.loadStaff(employees, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var needs []need
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, rule := range rules {
+			if locationID != nil && rule.Shift.LocationID != nil && *rule.Shift.LocationID != *locationID {
+				continue
+			}
+			window, ok := rule.Shift.OccurrenceOn(day)
+			if !ok {
+				continue
+			}
+
+			missing := rule.MinStaff
+			for _, employeeID := range assigned[slotKey(rule.ShiftID, day)] {
+				candidate, ok := staffByID[employeeID]
+				if ok && eligible(candidate.employee, rule) && !candidate.onLeave(day) {
+					missing--
+				}
+			}
+			if missing > 0 {
+				needs = append(needs, need{rule: rule, date: day, window: window, missing: missing})
+			}
+		}
+	}
+
+	candidates := make([]*staff, 0, len(staffByID))
+	for _, candidate := range staffByID {
+		candidates = append(candidates, candidate)
+	}
+	result := solve(needs, candidates, s.policy, s.checkGuards, deadline)
+
+	var createdBy uint
+	if user != nil {
+		createdBy = user.ID
+	}
+	draft := ScheduleDraft{From: from, To: to, LocationID: locationID, Status: DraftStatusDraft, Version: 1, TimedOut: result.timedOut, CreatedBy: createdBy}
+	for _, p := range result.placements {
+		draft.Assignments = append(draft.Assignments, DraftAssignment{EmployeeID: p.employeeID, ShiftID: p.shiftID, Date: p.date})
+	}
+	draft.Gaps = result.gaps
+
+	if err := s.db.Create(&draft).Error; err != nil {
+		log.Printf("Error creating schedule draft: %v", err)
+		return nil, err
+	}
+	log.Printf("Schedule draft %d proposes %d assignments with %d gaps", draft.ID, len(draft.Assignments), len(draft.Gaps))
+	return &draft, nil
+}
+
+func (s *schedulerService) GetDrafts() ([]ScheduleDraft, error) {
+	var drafts []ScheduleDraft
+	if err := s.db.Order("created_at DESC").Find(&drafts).Error; err != nil {
+		log.Printf("Error fetching schedule drafts: %v", err)
+		return nil, err
+	}
+	return drafts, nil
+}
+
+func (s *schedulerService) GetDraft(id uint) (*ScheduleDraft, error) {
+	return s.draft(s.db, id)
+}
+
+// draft loads a draft with its assignments and gaps through db, which may be
+// a transaction
+func (s *schedulerService) draft(db *gorm.DB, id uint) (*ScheduleDraft, error) {
+	var draft ScheduleDraft
+	err := db.
+		Preload("Assignments", func(db *gorm.DB) *gorm.DB { return db.Order("date, shift_id, employee_id") }).
+		Preload("Gaps", func(db *gorm.DB) *gorm.DB { return db.Order("date, shift_id") }).
+		First(&draft, id).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrDraftNotFound
+		}
+		log.Printf("Error fetching schedule draft: %v", err)
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// AddAssignment lets a manager add a shift to a draft. Limits the solver
+// would respect do not block it but are recorded as warnings.
+func (s *schedulerService) AddAssignment(draftID uint, assignment DraftAssignment) (*DraftAssignment, error) {
+	draft, err := s.GetDraft(draftID)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Status != DraftStatusDraft {
+		return nil, ErrDraftPublished
+	}
+	assignment.Date = dateOf(assignment.Date)
+	if assignment.Date.Before(draft.From) || assignment.Date.After(draft.To) {
+		return nil, ErrOutsideDraft
+	}
+
+	var emp employee.Employee
+	if err := s.db.First(&emp, assignment.EmployeeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+	var shift employee.Shift
+	if err := s.db.First(&shift, assignment.ShiftID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrShiftNotFound
+		}
+		log.Printf("Error fetching shift: %v", err)
+		return nil, err
+	}
+	window, ok := shift.OccurrenceOn(assignment.Date)
+	if !ok {
+		return nil, ErrShiftNotRunning
+	}
+
+	staffByID, _, err := s.loadStaff([]employee.Employee{emp}, draft.From, draft.To)
+	if err != nil {
+		return nil, err
+	}
+	candidate := staffByID[emp.ID]
+	if err := s.placeDraft(candidate, draft.Assignments); err != nil {
+		return nil, err
+	}
+
+	warnings := candidate.violations(shift, assignment.Date, window, s.policy)
+	if err := s.checkGuards(emp, employee.EmployeeShift{
+		EmployeeID: emp.ID, ShiftID: shift.ID, StartDate: assignment.Date, EndDate: assignment.Date, Shift: shift,
+	}); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+
+	assignment.ID = 0
+	assignment.DraftID = draftID
+	assignment.Manual = true
+	assignment.Warnings = strings.Join(warnings, "; ")
+	if err := s.db.Create(&assignment).Error; err != nil {
+		log.Printf("Error adding draft assignment: %v", err)
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (s *schedulerService) RemoveAssignment(draftID, assignmentID uint) error {
+	draft, err := s.GetDraft(draftID)
+	if err != nil {
+		return err
+	}
+	if draft.Status != DraftStatusDraft {
+		return ErrDraftPublished
+	}
+
+	result := s.db.Unscoped().Where("id = ? AND draft_id = ?", assignmentID, draftID).Delete(&DraftAssignment{})
+	if result.Error != nil {
+		log.Printf("Error removing draft assignment: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAssignmentNotFound
+	}
+	return nil
+}
+
+// PublishDraft makes a draft the published schedule of its period. A first
+// version creates a one-day shift assignment for every draft assignment; a
+// revision removes the assignments it dropped, creates the ones it added and
+// supersedes the version it revises. Assignments the employee service
+// rejects, e.g. because the schedule changed since the draft was made, are
+// skipped and reported. Everything is applied in one transaction holding the
+// draft locked, so a failed or concurrent publication changes nothing. Every
+// employee whose assignments changed is notified.
+func (s *schedulerService) PublishDraft(id uint, user *iam.User) (*ScheduleDraft, *PublishResult, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, nil, tx.Error
+	}
+	draft, result, published, err := s.publish(tx, id, user)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, nil, err
+	}
+
+	result.Notified = s.notifyChanges(draft, published)
+	if draft, err = s.GetDraft(id); err != nil {
+		return nil, nil, err
+	}
+	return draft, result, nil
+}
+
+// publish applies a draft within tx, returning the changes made
+func (s *schedulerService) publish(tx *gorm.DB, id uint, user *iam.User) (*ScheduleDraft, *PublishResult, []ScheduleChange, error) {
+	var locked ScheduleDraft
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&locked, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil, nil, ErrDraftNotFound
+		}
+		log.Printf("Error locking schedule draft: %v", err)
+		return nil, nil, nil, err
+	}
+	draft, err := s.draft(tx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if draft.Status != DraftStatusDraft {
+		return nil, nil, nil, ErrDraftPublished
+	}
+
+	var previous *ScheduleDraft
+	if draft.PreviousDraftID != nil {
+		if previous, err = s.draft(tx, *draft.PreviousDraftID); err != nil {
+			return nil, nil, nil, err
+		}
+		if previous.Status != DraftStatusPublished {
+			return nil, nil, nil, ErrDraftRevised
+		}
+	}
+	var before []DraftAssignment
+	if previous != nil {
+		before = previous.Assignments
+	}
+
+	byKey := make(map[string]DraftAssignment, len(draft.Assignments))
+	for _, assignment := range draft.Assignments {
+		byKey[changeKey(assignment.EmployeeID, assignment.ShiftID, assignment.Date)] = assignment
+	}
+
+	result := &PublishResult{Skipped: []SkippedShift{}}
+	var published []ScheduleChange
+	changes := diffAssignments(before, draft.Assignments)
+	for _, change := range changes {
+		if change.Change != ChangeRemoved {
+			continue
+		}
+		// An occurrence already gone, e.g. swapped away, needs no removing
+		err := s.employees.RemoveOccurrence(tx, change.EmployeeID, change.ShiftID, change.Date)
+		if err != nil && !errors.Is(err, employee.ErrOccurrenceNotFound) {
+			return nil, nil, nil, err
+		}
+		if err == nil {
+			result.Removed++
+		}
+		published = append(published, change)
+	}
+
+	for _, change := range changes {
+		if change.Change != ChangeAdded {
+			continue
+		}
+		assignment := byKey[changeKey(change.EmployeeID, change.ShiftID, change.Date)]
+		_, err := s.employees.CreateAssignmentIn(tx, employee.EmployeeShift{
+			EmployeeID: assignment.EmployeeID,
+			ShiftID:    assignment.ShiftID,
+			StartDate:  assignment.Date,
+			EndDate:    assignment.Date,
+			Source:     employee.AssignmentSourceScheduler,
+		})
+		if err != nil {
+			if errors.Is(err, employee.ErrAssignmentRejected) || errors.Is(err, employee.ErrShiftNotFound) || errors.Is(err, employee.ErrEmployeeNotFound) {
+				result.Skipped = append(result.Skipped, SkippedShift{
+					DraftAssignmentID: assignment.ID, EmployeeID: assignment.EmployeeID, Date: assignment.Date, Reason: err.Error(),
+				})
+				// The published version records what was actually assigned
+				if err := tx.Unscoped().Delete(&assignment).Error; err != nil {
+					log.Printf("Error dropping skipped draft assignment: %v", err)
+					return nil, nil, nil, err
+				}
+				continue
+			}
+			return nil, nil, nil, err
+		}
+		result.Created++
+		published = append(published, change)
+	}
+
+	draft.Status = DraftStatusPublished
+	draft.PublishedAt = utils.NullTime{NullTime: sql.NullTime{Time: time.Now(), Valid: true}}
+	if user != nil {
+		draft.PublishedBy = &user.ID
+	}
+	if previous != nil {
+		if err := tx.Model(previous).Update("status", DraftStatusSuperseded).Error; err != nil {
+			log.Printf("Error superseding schedule draft: %v", err)
+			return nil, nil, nil, err
+		}
+	}
+	if err := tx.Model(draft).Updates(map[string]interface{}{
+		"status":       draft.Status,
+		"published_at": draft.PublishedAt,
+		"published_by": draft.PublishedBy,
+	}).Error; err != nil {
+		log.Printf("Error publishing schedule draft: %v", err)
+		return nil, nil, nil, err
+	}
+	return draft, result, published, nil
+}
+
+// ReviseDraft starts a new draft version of a published schedule holding the
+// same assignments, for managers to change and publish in its place
+func (s *schedulerService) ReviseDraft(id uint, user *iam.User) (*ScheduleDraft, error) {
+	previous, err := s.GetDraft(id)
+	if err != nil {
+		return nil, err
+	}
+	if previous.Status != DraftStatusPublished {
+		return nil, ErrDraftNotPublished
+	}
+
+	var revisions int
+	if err := s.db.Model(&ScheduleDraft{}).Where("previous_draft_id = ?", previous.ID).Count(&revisions).Error; err != nil {
+		log.Printf("Error checking schedule revisions: %v", err)
+		return nil, err
+	}
+	if revisions > 0 {
+		return nil, ErrDraftRevised
+	}
+
+	var createdBy uint
+	if user != nil {
+		createdBy = user.ID
+	}
+	draft := ScheduleDraft{
+		From:            previous.From,
+		To:              previous.To,
+		LocationID:      previous.LocationID,
+		Status:          DraftStatusDraft,
+		Version:         previous.Version + 1,
+		PreviousDraftID: &previous.ID,
+		CreatedBy:       createdBy,
+	}
+	for _, assignment := range previous.Assignments {
+		draft.Assignments = append(draft.Assignments, DraftAssignment{
+			EmployeeID: assignment.EmployeeID,
+			ShiftID:    assignment.ShiftID,
+			Date:       assignment.Date,
+			Manual:     assignment.Manual,
+			Warnings:   assignment.Warnings,
+		})
+	}
+	for _, gap := range previous.Gaps {
+		draft.Gaps = append(draft.Gaps, DraftGap{
+			RuleID:      gap.RuleID,
+			ShiftID:     gap.ShiftID,
+			Date:        gap.Date,
+			Designation: gap.Designation,
+			Shortfall:   gap.Shortfall,
+		})
+	}
+
+	if err := s.db.Create(&draft).Error; err != nil {
+		log.Printf("Error creating schedule revision: %v", err)
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// DiffDraft compares a version of a schedule with another version of the same
+// period, by default the version it revises
+func (s *schedulerService) DiffDraft(id uint, againstID *uint) ([]ScheduleChange, error) {
+	draft, err := s.GetDraft(id)
+	if err != nil {
+		return nil, err
+	}
+	if againstID == nil {
+		againstID = draft.PreviousDraftID
+	}
+	if againstID == nil {
+		return diffAssignments(nil, draft.Assignments), nil
+	}
+
+	against, err := s.GetDraft(*againstID)
+	if err != nil {
+		return nil, err
+	}
+	if !samePeriod(*draft, *against) {
+		return nil, ErrDifferentPeriod
+	}
+	return diffAssignments(against.Assignments, draft.Assignments), nil
+}
+
+// DeleteDraft discards a draft. Published drafts are kept as history.
+func (s *schedulerService) DeleteDraft(id uint) error {
+	draft, err := s.GetDraft(id)
+	if err != nil {
+		return err
+	}
+	if draft.Status != DraftStatusDraft {
+		return ErrDraftPublished
+	}
+
+	if err := s.db.Unscoped().Where("draft_id = ?", id).Delete(&DraftAssignment{}).Error; err != nil {
+		log.Printf("Error deleting draft assignments: %v", err)
+		return err
+	}
+	if err := s.db.Unscoped().Where("draft_id = ?", id).Delete(&DraftGap{}).Error; err != nil {
+		log.Printf("Error deleting draft gaps: %v", err)
+		return err
+	}
+	if err := s.db.Unscoped().Delete(draft).Error; err != nil {
+		log.Printf("Error deleting schedule draft: %v", err)
+		return err
+	}
+	return nil
+}
+
+// loadStaff builds the working state of the employees from their assigned
+// shifts, approved leave and shift preferences. Assignments are read from the Monday before
+// the period to the Sunday after it, so weekly hours and rest include
+// shifts just outside it. It also returns who is assigned to each shift on
+// each day of the period.
+func (s *schedulerService) loadStaff(employees []employee.Employee, from, to time.Time) (map[uint]*staff, map[string][]uint, error) {
+	staffByID := make(map[uint]*staff, len(employees))
+	ids := make([]uint, 0, len(employees))
+	for _, emp := range employees {
+		staffByID[emp.ID] = &staff{employee: emp, weekHours: make(map[string]float64), prefer: make(map[uint]int)}
+		ids = append(ids, emp.ID)
+	}
+	assigned := make(map[string][]uint)
+	if len(ids) == 0 {
+		return staffByID, assigned, nil
+	}
+
+	loadFrom := weekStart(from).AddDate(0, 0, -1)
+	loadTo := weekStart(to).AddDate(0, 0, 7)
+	var assignments []employee.EmployeeShift
+	if err := s.db.Preload("Shift").
+		Where("employee_id IN (?) AND start_date <= ? AND end_date >= ?", ids, loadTo, loadFrom).
+		Find(&assignments).Error; err != nil {
+		log.Printf("Error fetching shift assignments: %v", err)
+		return nil, nil, err
+	}
+	for _, assignment := range assignments {
+		candidate := staffByID[assignment.EmployeeID]
+		for day := maxTime(dateOf(assignment.StartDate), loadFrom); !day.After(minTime(dateOf(assignment.EndDate), loadTo)); day = day.AddDate(0, 0, 1) {
+			window, ok := assignment.Shift.OccurrenceOn(day)
+			if !ok {
+				continue
+			}
+			candidate.windows = append(candidate.windows, window)
+			candidate.weekHours[weekOf(window)] += assignment.Shift.PaidDuration().Hours()
+			if !day.Before(from) && !day.After(to) {
+				key := slotKey(assignment.ShiftID, day)
+				assigned[key] = append(assigned[key], assignment.EmployeeID)
+			}
+		}
+	}
+
+	var requests []leave.LeaveRequest
+	if err := s.db.Where("employee_id IN (?) AND status = ? AND start_date <= ? AND end_date >= ?", ids, leave.StatusApproved, to, from).
+		Find(&requests).Error; err != nil {
+		log.Printf("Error fetching approved leave: %v", err)
+		return nil, nil, err
+	}
+	for _, request := range requests {
+		candidate := staffByID[request.EmployeeID]
+		candidate.leave = append(candidate.leave, [2]time.Time{dateOf(request.StartDate), dateOf(request.EndDate)})
+	}
+
+	var preferences []availability.ShiftPreference
+	if err := s.db.Where("employee_id IN (?)", ids).Find(&preferences).Error; err != nil {
+		log.Printf("Error fetching shift preferences: %v", err)
+		return nil, nil, err
+	}
+	for _, preference := range preferences {
+		switch preference.Preference {
+		case availability.PreferencePrefer:
+			staffByID[preference.EmployeeID].prefer[preference.ShiftID] = 1
+		case availability.PreferenceAvoid:
+			staffByID[preference.EmployeeID].prefer[preference.ShiftID] = -1
+		}
+	}
+	return staffByID, assigned, nil
+}
+
+// placeDraft adds the employee's draft assignments to their working state
+func (s *schedulerService) placeDraft(candidate *staff, assignments []DraftAssignment) error {
+	shifts := make(map[uint]employee.Shift)
+	for _, assignment := range assignments {
+		if assignment.EmployeeID != candidate.employee.ID {
+			continue
+		}
+		shift, ok := shifts[assignment.ShiftID]
+		if !ok {
+			if err := s.db.First(&shift, assignment.ShiftID).Error; err != nil {
+				log.Printf("Error fetching shift: %v", err)
+				return err
+			}
+			shifts[assignment.ShiftID] = shift
+		}
+		if window, ok := shift.OccurrenceOn(assignment.Date); ok {
+			candidate.place(shift, window, s.policy)
+		}
+	}
+	return nil
+}
+
+func (s *schedulerService) checkGuards(emp employee.Employee, assignment employee.EmployeeShift) error {
+	for _, guard := range s.guards {
+		if err := guard.CheckAssignment(s.db, emp, assignment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyChanges tells each employee how a published version changed their
+// assignments and returns how many were notified
+func (s *schedulerService) notifyChanges(draft *ScheduleDraft, changes []ScheduleChange) int {
+	if len(changes) == 0 {
+		return 0
+	}
+	shiftIDs := make([]uint, 0, len(changes))
+	for _, change := range changes {
+		shiftIDs = append(shiftIDs, change.ShiftID)
+	}
+	var shifts []employee.Shift
+	if err := s.db.Where("id IN (?)", shiftIDs).Find(&shifts).Error; err != nil {
+		log.Printf("Error fetching shifts: %v", err)
+	}
+	names := make(map[uint]string, len(shifts))
+	for _, shift := range shifts {
+		names[shift.ID] = shift.Name
+	}
+
+	var employeeIDs []uint
+	lines := make(map[uint][]string)
+	for _, change := range changes {
+		if _, ok := lines[change.EmployeeID]; !ok {
+			employeeIDs = append(employeeIDs, change.EmployeeID)
+		}
+		lines[change.EmployeeID] = append(lines[change.EmployeeID], fmt.Sprintf("%s: %s on %s",
+			change.Change, names[change.ShiftID], change.Date.Format("Mon 2006-01-02")))
+	}
+
+	period := fmt.Sprintf("%s to %s", draft.From.Format("2006-01-02"), draft.To.Format("2006-01-02"))
+	kind, subject := "schedule_published", "Your schedule for "+period+" is published"
+	if draft.Version > 1 {
+		kind, subject = "schedule_changed", "Your schedule for "+period+" changed"
+	}
+	for _, employeeID := range employeeIDs {
+		s.notifications.Notify(employeeID, kind, subject, strings.Join(lines[employeeID], "\n"))
+	}
+	return len(employeeIDs)
+}
+
+// diffAssignments lists the assignments after adds and the ones it removes
+// compared with before, ordered by date
+func diffAssignments(before, after []DraftAssignment) []ScheduleChange {
+	seen := make(map[string]bool, len(before))
+	for _, assignment := range before {
+		seen[changeKey(assignment.EmployeeID, assignment.ShiftID, assignment.Date)] = true
+	}
+	kept := make(map[string]bool, len(after))
+	changes := []ScheduleChange{}
+	for _, assignment := range after {
+		key := changeKey(assignment.EmployeeID, assignment.ShiftID, assignment.Date)
+		kept[key] = true
+		if !seen[key] {
+			changes = append(changes, ScheduleChange{Change: ChangeAdded, EmployeeID: assignment.EmployeeID, ShiftID: assignment.ShiftID, Date: dateOf(assignment.Date)})
+		}
+	}
+	for _, assignment := range before {
+		if !kept[changeKey(assignment.EmployeeID, assignment.ShiftID, assignment.Date)] {
+			changes = append(changes, ScheduleChange{Change: ChangeRemoved, EmployeeID: assignment.EmployeeID, ShiftID: assignment.ShiftID, Date: dateOf(assignment.Date)})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		switch {
+		case !a.Date.Equal(b.Date):
+			return a.Date.Before(b.Date)
+		case a.EmployeeID != b.EmployeeID:
+			return a.EmployeeID < b.EmployeeID
+		case a.ShiftID != b.ShiftID:
+			return a.ShiftID < b.ShiftID
+		default:
+			return a.Change > b.Change
+		}
+	})
+	return changes
+}
+
+func changeKey(employeeID, shiftID uint, date time.Time) string {
+	return fmt.Sprintf("%d/%s", employeeID, slotKey(shiftID, dateOf(date)))
+}
+
+func samePeriod(a, b ScheduleDraft) bool {
+	if !dateOf(a.From).Equal(dateOf(b.From)) || !dateOf(a.To).Equal(dateOf(b.To)) {
+		return false
+	}
+	if a.LocationID == nil || b.LocationID == nil {
+		return a.LocationID == nil && b.LocationID == nil
+	}
+	return *a.LocationID == *b.LocationID
+}
+
+func (s *staff) onLeave(day time.Time) bool {
+	for _, leave := range s.leave {
+		if !day.Before(leave[0]) && !day.After(leave[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func slotKey(shiftID uint, day time.Time) string {
+	return fmt.Sprintf("%d/%s", shiftID, day.Format("2006-01-02"))
+}
+
+// weekStart returns the Monday of the date's week
+func weekStart(date time.Time) time.Time {
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
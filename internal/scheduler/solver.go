@@ -0,0 +1,247 @@
+// internal/scheduler/solver.go
+package scheduler
+
+import (
+	"clinicplus/internal/coverage"
+	"clinicplus/internal/employee"
+	"clinicplus/internal/shared/config"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Policy holds the limits the solver respects when proposing assignments
+type Policy struct {
+	MaxWeeklyHours float64
+	MinRest        time.Duration
+	TimeBudget     time.Duration
+	NightStartHour int // Local hours that make a shift a night shift for fairness
+	NightEndHour   int
+}
+
+// PolicyFromConfig builds the policy from the environment
+func PolicyFromConfig() Policy {
+	nightStart, nightEnd := config.GetPayrollNightWindow()
+	return Policy{
+		MaxWeeklyHours: config.GetSchedulerMaxWeeklyHours(),
+		MinRest:        time.Duration(config.GetSchedulerMinRestHours() * float64(time.Hour)),
+		TimeBudget:     time.Duration(config.GetSchedulerTimeBudgetSeconds()) * time.Second,
+		NightStartHour: nightStart,
+		NightEndHour:   nightEnd,
+	}
+}
+
+// need is a coverage rule on one day still short of staff
+type need struct {
+	rule    coverage.CoverageRule
+	date    time.Time
+	window  employee.ShiftWindow
+	missing int
+}
+
+// staff is an employee's working state while the schedule is built
+type staff struct {
+	employee  employee.Employee
+	windows   []employee.ShiftWindow
+	weekHours map[string]float64
+	leave     [][2]time.Time
+	prefer    map[uint]int // Shift ID to 1 when preferred, -1 when to be avoided
+	hours     float64      // Hours proposed by the solver, for fairness
+	nights    int
+	weekends  int
+}
+
+type placement struct {
+	employeeID uint
+	shiftID    uint
+	date       time.Time
+}
+
+type solution struct {
+	placements []placement
+	gaps       []DraftGap
+	timedOut   bool
+}
+
+// guardFunc runs the assignment guards for a candidate
+type guardFunc func(emp employee.Employee, assignment employee.EmployeeShift) error
+
+// solve fills needs greedily in chronological order. Each slot goes to the
+// eligible employee with the fewest night or weekend shifts so far when the
+// slot is one, then the strongest preference for the shift, then the fewest
+// hours, then the lowest ID, so the same input always produces the same
+// schedule. Needs left when the deadline passes
+// are reported as gaps.
+func solve(needs []need, candidates []*staff, policy Policy, guard guardFunc, deadline time.Time) solution {
+	sort.SliceStable(needs, func(i, j int) bool {
+		a, b := needs[i], needs[j]
+		if !a.window.Start.Equal(b.window.Start) {
+			return a.window.Start.Before(b.window.Start)
+		}
+		if a.rule.ShiftID != b.rule.ShiftID {
+			return a.rule.ShiftID < b.rule.ShiftID
+		}
+		return a.rule.ID < b.rule.ID
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].employee.ID < candidates[j].employee.ID
+	})
+
+	var result solution
+	for i, n := range needs {
+		if time.Now().After(deadline) {
+			result.timedOut = true
+			for _, rest := range needs[i:] {
+				result.gaps = append(result.gaps, gapFor(rest, rest.missing))
+			}
+			break
+		}
+
+		night, weekend := isNight(n.window, policy), isWeekend(n.window)
+		for n.missing > 0 {
+			var best *staff
+			for _, candidate := range candidates {
+				if !eligible(candidate.employee, n.rule) {
+					continue
+				}
+				if best != nil && !fairer(candidate, best, n.rule.ShiftID, night, weekend) {
+					continue
+				}
+				if len(candidate.violations(n.rule.Shift, n.date, n.window, policy)) > 0 {
+					continue
+				}
+				// Guards may query the database, so they run last
+				if guard != nil && guard(candidate.employee, employee.EmployeeShift{
+					EmployeeID: candidate.employee.ID, ShiftID: n.rule.ShiftID, StartDate: n.date, EndDate: n.date, Shift: n.rule.Shift,
+				}) != nil {
+					continue
+				}
+				best = candidate
+			}
+			if best == nil {
+				break
+			}
+
+			best.place(n.rule.Shift, n.window, policy)
+			result.placements = append(result.placements, placement{employeeID: best.employee.ID, shiftID: n.rule.ShiftID, date: n.date})
+			n.missing--
+		}
+		if n.missing > 0 {
+			result.gaps = append(result.gaps, gapFor(n, n.missing))
+		}
+	}
+	return result
+}
+
+// violations lists the scheduling limits working the window would break
+func (s *staff) violations(shift employee.Shift, date time.Time, window employee.ShiftWindow, policy Policy) []string {
+	var broken []string
+	if !s.employee.CanWork() {
+		broken = append(broken, fmt.Sprintf("employee is %s", s.employee.EmploymentStatus))
+	}
+	if s.onLeave(date) {
+		broken = append(broken, "employee is on leave")
+	}
+	for _, other := range s.windows {
+		if other.Overlaps(window) {
+			broken = append(broken, "overlaps another shift")
+			break
+		}
+		if restBetween(other, window) < policy.MinRest {
+			broken = append(broken, fmt.Sprintf("less than %.0f hours rest from another shift", policy.MinRest.Hours()))
+			break
+		}
+	}
+	hours := shift.PaidDuration().Hours()
+	if policy.MaxWeeklyHours > 0 && s.weekHours[weekOf(window)]+hours > policy.MaxWeeklyHours {
+		broken = append(broken, fmt.Sprintf("more than %.0f hours in the week", policy.MaxWeeklyHours))
+	}
+	return broken
+}
+
+// place records that the employee works the window
+func (s *staff) place(shift employee.Shift, window employee.ShiftWindow, policy Policy) {
+	hours := shift.PaidDuration().Hours()
+	s.windows = append(s.windows, window)
+	s.weekHours[weekOf(window)] += hours
+	s.hours += hours
+	if isNight(window, policy) {
+		s.nights++
+	}
+	if isWeekend(window) {
+		s.weekends++
+	}
+}
+
+// fairer reports whether a should take a slot of the shift before b
+func fairer(a, b *staff, shiftID uint, night, weekend bool) bool {
+	if night && a.nights != b.nights {
+		return a.nights < b.nights
+	}
+	if weekend && a.weekends != b.weekends {
+		return a.weekends < b.weekends
+	}
+	if a.prefer[shiftID] != b.prefer[shiftID] {
+		return a.prefer[shiftID] > b.prefer[shiftID]
+	}
+	if a.hours != b.hours {
+		return a.hours < b.hours
+	}
+	return a.employee.ID < b.employee.ID
+}
+
+// eligible reports whether the employee has the rule's designation and works
+// at its location. Without a location on the rule or the shift, anyone
+// with the designation qualifies.
+func eligible(emp employee.Employee, rule coverage.CoverageRule) bool {
+	if !strings.EqualFold(emp.Designation, rule.Designation) {
+		return false
+	}
+	location := rule.LocationID
+	if location == nil {
+		location = rule.Shift.LocationID
+	}
+	return location == nil || emp.LocationID == nil || *emp.LocationID == *location
+}
+
+func gapFor(n need, shortfall int) DraftGap {
+	return DraftGap{RuleID: n.rule.ID, ShiftID: n.rule.ShiftID, Date: n.date, Designation: n.rule.Designation, Shortfall: shortfall}
+}
+
+// restBetween returns the time between two windows that do not overlap
+func restBetween(a, b employee.ShiftWindow) time.Duration {
+	if a.End.After(b.Start) {
+		return a.Start.Sub(b.End)
+	}
+	return b.Start.Sub(a.End)
+}
+
+// isNight reports whether any of the window falls in the local night hours
+func isNight(window employee.ShiftWindow, policy Policy) bool {
+	start := window.Start
+	for offset := -1; offset <= 0; offset++ {
+		day := time.Date(start.Year(), start.Month(), start.Day()+offset, 0, 0, 0, 0, start.Location())
+		nightStart := day.Add(time.Duration(policy.NightStartHour) * time.Hour)
+		nightEnd := day.Add(time.Duration(policy.NightEndHour) * time.Hour)
+		if policy.NightEndHour <= policy.NightStartHour {
+			nightEnd = nightEnd.AddDate(0, 0, 1)
+		}
+		if window.Overlaps(employee.ShiftWindow{Start: nightStart, End: nightEnd}) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWeekend reports whether the window starts on a local Saturday or Sunday
+func isWeekend(window employee.ShiftWindow) bool {
+	weekday := window.Start.Weekday()
+	return weekday == time.Saturday || weekday == time.Sunday
+}
+
+// weekOf returns the local ISO week the window starts in
+func weekOf(window employee.ShiftWindow) string {
+	year, week := window.Start.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
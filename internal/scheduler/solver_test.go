@@ -0,0 +1,293 @@
+package scheduler
+
+import (
+	"clinicplus/internal/coverage"
+	"clinicplus/internal/employee"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var testPolicy = Policy{
+	MaxWeeklyHours: 48,
+	MinRest:        11 * time.Hour,
+	NightStartHour: 22,
+	NightEndHour:   6,
+}
+
+var (
+	dayShift   = testShift(1, "09:00", "17:00")
+	lateShift  = testShift(2, "13:00", "21:00")
+	nightShift = testShift(3, "22:00", "06:00")
+)
+
+func testShift(id uint, start, end string) employee.Shift {
+	return employee.Shift{
+		Model:      gorm.Model{ID: id},
+		StartTime:  start,
+		EndTime:    end,
+		Timezone:   "UTC",
+		DaysOfWeek: "mon,tue,wed,thu,fri,sat,sun",
+	}
+}
+
+func testRule(shift employee.Shift, designation string) coverage.CoverageRule {
+	return coverage.CoverageRule{Model: gorm.Model{ID: shift.ID * 10}, ShiftID: shift.ID, Shift: shift, Designation: designation}
+}
+
+// day returns a date in March 2024; the 4th is a Monday
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func needOn(rule coverage.CoverageRule, d, missing int) need {
+	window, _ := rule.Shift.OccurrenceOn(day(d))
+	return need{rule: rule, date: day(d), window: window, missing: missing}
+}
+
+func nurse(id uint) *staff {
+	return &staff{
+		employee:  employee.Employee{Model: gorm.Model{ID: id}, Designation: "Nurse", EmploymentStatus: employee.EmploymentStatusActive},
+		weekHours: make(map[string]float64),
+		prefer:    make(map[uint]int),
+	}
+}
+
+func with(s *staff, change func(*staff)) *staff {
+	change(s)
+	return s
+}
+
+func TestSolve(t *testing.T) {
+	nurses := testRule(dayShift, "Nurse")
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		needs      []need
+		candidates []*staff
+		policy     Policy
+		guard      guardFunc
+		deadline   time.Time
+		placed     []string // employee/shift/date
+		gaps       []string // shift/date/shortfall
+		timedOut   bool
+	}{
+		{
+			name:       "ties go to the lowest ID",
+			needs:      []need{needOn(nurses, 4, 1)},
+			candidates: []*staff{nurse(2), nurse(1)},
+			placed:     []string{"1/1/04"},
+		},
+		{
+			name:       "several staff for one slot",
+			needs:      []need{needOn(nurses, 4, 2)},
+			candidates: []*staff{nurse(3), nurse(2), nurse(1)},
+			placed:     []string{"1/1/04", "2/1/04"},
+		},
+		{
+			name:       "designation must match",
+			needs:      []need{needOn(testRule(dayShift, "Doctor"), 4, 1)},
+			candidates: []*staff{nurse(1)},
+			gaps:       []string{"1/04/1"},
+		},
+		{
+			name:       "designation ignores case",
+			needs:      []need{needOn(testRule(dayShift, "NURSE"), 4, 1)},
+			candidates: []*staff{nurse(1)},
+			placed:     []string{"1/1/04"},
+		},
+		{
+			name:       "hours are spread",
+			needs:      []need{needOn(nurses, 5, 1), needOn(nurses, 4, 1)},
+			candidates: []*staff{nurse(1), nurse(2)},
+			placed:     []string{"1/1/04", "2/1/05"},
+		},
+		{
+			name:       "overlapping shifts",
+			needs:      []need{needOn(nurses, 4, 1), needOn(testRule(lateShift, "Nurse"), 4, 1)},
+			candidates: []*staff{nurse(1)},
+			placed:     []string{"1/1/04"},
+			gaps:       []string{"2/04/1"},
+		},
+		{
+			name:       "minimum rest after a night",
+			needs:      []need{needOn(testRule(nightShift, "Nurse"), 4, 1), needOn(nurses, 5, 1)},
+			candidates: []*staff{nurse(1)},
+			placed:     []string{"1/3/04"},
+			gaps:       []string{"1/05/1"},
+		},
+		{
+			name:       "weekly hours cap",
+			needs:      []need{needOn(nurses, 4, 1), needOn(nurses, 5, 1), needOn(nurses, 6, 1)},
+			candidates: []*staff{nurse(1)},
+			policy:     Policy{MaxWeeklyHours: 16},
+			placed:     []string{"1/1/04", "1/1/05"},
+			gaps:       []string{"1/06/1"},
+		},
+		{
+			name:  "the cap counts hours already scheduled that week",
+			needs: []need{needOn(nurses, 10, 1), needOn(nurses, 11, 1)},
+			candidates: []*staff{with(nurse(1), func(s *staff) {
+				s.weekHours["2024-W10"] = 44
+			})},
+			placed: []string{"1/1/11"},
+			gaps:   []string{"1/10/1"},
+		},
+		{
+			name:  "employees on leave are skipped",
+			needs: []need{needOn(nurses, 4, 1)},
+			candidates: []*staff{with(nurse(1), func(s *staff) {
+				s.leave = [][2]time.Time{{day(3), day(4)}}
+			}), nurse(2)},
+			placed: []string{"2/1/04"},
+		},
+		{
+			name:  "suspended employees are skipped",
+			needs: []need{needOn(nurses, 4, 1)},
+			candidates: []*staff{with(nurse(1), func(s *staff) {
+				s.employee.EmploymentStatus = employee.EmploymentStatusSuspended
+			})},
+			gaps: []string{"1/04/1"},
+		},
+		{
+			name:  "preferred shift wins over fewer hours",
+			needs: []need{needOn(nurses, 4, 1)},
+			candidates: []*staff{nurse(1), with(nurse(2), func(s *staff) {
+				s.prefer[dayShift.ID] = 1
+				s.hours = 40
+			})},
+			placed: []string{"2/1/04"},
+		},
+		{
+			name:  "avoided shift goes to someone else",
+			needs: []need{needOn(nurses, 4, 1)},
+			candidates: []*staff{with(nurse(1), func(s *staff) {
+				s.prefer[dayShift.ID] = -1
+			}), nurse(2)},
+			placed: []string{"2/1/04"},
+		},
+		{
+			name:  "nights go to whoever worked fewest",
+			needs: []need{needOn(testRule(nightShift, "Nurse"), 4, 1)},
+			candidates: []*staff{with(nurse(1), func(s *staff) {
+				s.nights = 2
+			}), with(nurse(2), func(s *staff) {
+				s.nights = 1
+				s.hours = 80
+				s.prefer[nightShift.ID] = -1
+			})},
+			placed: []string{"2/3/04"},
+		},
+		{
+			name:  "weekends go to whoever worked fewest",
+			needs: []need{needOn(nurses, 9, 1)},
+			candidates: []*staff{with(nurse(1), func(s *staff) {
+				s.weekends = 1
+			}), with(nurse(2), func(s *staff) {
+				s.hours = 80
+			})},
+			placed: []string{"2/1/09"},
+		},
+		{
+			name:  "weekend counts only matter on weekends",
+			needs: []need{needOn(nurses, 4, 1)},
+			candidates: []*staff{with(nurse(1), func(s *staff) {
+				s.weekends = 3
+			}), with(nurse(2), func(s *staff) {
+				s.hours = 8
+			})},
+			placed: []string{"1/1/04"},
+		},
+		{
+			name:       "guards can refuse a candidate",
+			needs:      []need{needOn(nurses, 4, 1)},
+			candidates: []*staff{nurse(1), nurse(2)},
+			guard: func(emp employee.Employee, assignment employee.EmployeeShift) error {
+				if emp.ID == 1 {
+					return errors.New("missing credential")
+				}
+				return nil
+			},
+			placed: []string{"2/1/04"},
+		},
+		{
+			name: "location of the rule",
+			needs: []need{needOn(func() coverage.CoverageRule {
+				rule := nurses
+				location := uint(5)
+				rule.LocationID = &location
+				return rule
+			}(), 4, 1)},
+			candidates: []*staff{with(nurse(1), func(s *staff) {
+				other := uint(6)
+				s.employee.LocationID = &other
+			}), nurse(2)},
+			placed: []string{"2/1/04"},
+		},
+		{
+			name:       "needs left at the deadline become gaps",
+			needs:      []need{needOn(nurses, 4, 2), needOn(nurses, 5, 1)},
+			candidates: []*staff{nurse(1)},
+			deadline:   past,
+			gaps:       []string{"1/04/2", "1/05/1"},
+			timedOut:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			if policy == (Policy{}) {
+				policy = testPolicy
+			}
+			deadline := tt.deadline
+			if deadline.IsZero() {
+				deadline = time.Now().Add(time.Minute)
+			}
+
+			result := solve(tt.needs, tt.candidates, policy, tt.guard, deadline)
+
+			var placed, gaps []string
+			for _, p := range result.placements {
+				placed = append(placed, fmt.Sprintf("%d/%d/%s", p.employeeID, p.shiftID, p.date.Format("02")))
+			}
+			for _, g := range result.gaps {
+				gaps = append(gaps, fmt.Sprintf("%d/%s/%d", g.ShiftID, g.Date.Format("02"), g.Shortfall))
+			}
+			if !reflect.DeepEqual(placed, tt.placed) {
+				t.Errorf("placements %v, want %v", placed, tt.placed)
+			}
+			if !reflect.DeepEqual(gaps, tt.gaps) {
+				t.Errorf("gaps %v, want %v", gaps, tt.gaps)
+			}
+			if result.timedOut != tt.timedOut {
+				t.Errorf("timedOut = %v, want %v", result.timedOut, tt.timedOut)
+			}
+		})
+	}
+}
+
+func TestIsNight(t *testing.T) {
+	tests := []struct {
+		shift employee.Shift
+		want  bool
+	}{
+		{dayShift, false},
+		{lateShift, false},
+		{nightShift, true},
+		{testShift(4, "04:00", "12:00"), true},  // Inside the window from the evening before
+		{testShift(5, "06:00", "14:00"), false}, // Starts as the window ends
+		{testShift(6, "18:00", "22:30"), true},
+	}
+	for _, tt := range tests {
+		window, _ := tt.shift.OccurrenceOn(day(4))
+		if got := isNight(window, testPolicy); got != tt.want {
+			t.Errorf("isNight(%s-%s) = %v, want %v", tt.shift.StartTime, tt.shift.EndTime, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,10 @@ package config
 import (
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -61,3 +65,255 @@ func GetServiceName() string {
 func GetServiceVersion() string {
 	return GetEnvString("SERVICE_VERSION", "1.0.0")
 }
+
+// GetEnvIntList retrieves a comma-separated list of integers, falling back to
+// the default when the variable is unset or malformed
+func GetEnvIntList(key string, defaultValue []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var values []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			log.Printf("Invalid value %q in %s, using defaults", part, key)
+			return defaultValue
+		}
+		values = append(values, n)
+	}
+	return values
+}
+
+// GetCredentialExpiryWindows retrieves the alert windows (in days before expiry)
+// for professional credentials, largest first
+func GetCredentialExpiryWindows() []int {
+	windows := GetEnvIntList("CREDENTIAL_EXPIRY_WINDOWS", []int{60, 30, 7})
+	sort.Sort(sort.Reverse(sort.IntSlice(windows)))
+	return windows
+}
+
+// GetEnvInt retrieves an integer environment variable with a default value
+func GetEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using default", value, key)
+		return defaultValue
+	}
+	return n
+}
+
+// GetEnvBool retrieves a boolean environment variable with a default value
+func GetEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using default", value, key)
+		return defaultValue
+	}
+	return b
+}
+
+// GetStorageDriver retrieves the blob storage backend ("local" or "s3")
+func GetStorageDriver() string {
+	return GetEnvString("STORAGE_DRIVER", "local")
+}
+
+// GetStorageLocalPath retrieves the directory used by the local storage backend
+func GetStorageLocalPath() string {
+	return GetEnvString("STORAGE_LOCAL_PATH", "./data/documents")
+}
+
+// GetS3Endpoint retrieves the S3-compatible endpoint, e.g. a local MinIO
+func GetS3Endpoint() string {
+	return GetEnvString("S3_ENDPOINT", "http://localhost:9000")
+}
+
+// GetS3Region retrieves the S3 region
+func GetS3Region() string {
+	return GetEnvString("S3_REGION", "us-east-1")
+}
+
+// GetS3Bucket retrieves the S3 bucket for documents
+func GetS3Bucket() string {
+	return GetEnvString("S3_BUCKET", "clinicplus-documents")
+}
+
+// GetS3AccessKey retrieves the S3 access key
+func GetS3AccessKey() string {
+	return GetEnvString("S3_ACCESS_KEY", "")
+}
+
+// GetS3SecretKey retrieves the S3 secret key
+func GetS3SecretKey() string {
+	return GetEnvString("S3_SECRET_KEY", "")
+}
+
+// GetDocumentMaxSize retrieves the maximum upload size for documents in bytes
+func GetDocumentMaxSize() int64 {
+	return int64(GetEnvInt("DOCUMENT_MAX_SIZE_MB", 10)) << 20
+}
+
+// GetEnvFloat retrieves a floating point environment variable with a default value
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using default", value, key)
+		return defaultValue
+	}
+	return f
+}
+
+// GetPayrollStandardWeeklyHours retrieves the contracted weekly hours used to
+// derive hourly rates from salaried pay
+func GetPayrollStandardWeeklyHours() float64 {
+	return GetEnvFloat("PAYROLL_STANDARD_WEEKLY_HOURS", 40)
+}
+
+// GetPayrollOvertimeThreshold retrieves the weekly hours after which overtime is paid
+func GetPayrollOvertimeThreshold() float64 {
+	return GetEnvFloat("PAYROLL_OVERTIME_THRESHOLD_HOURS", 40)
+}
+
+// GetPayrollOvertimeMultiplier retrieves the multiple of the hourly rate paid for overtime
+func GetPayrollOvertimeMultiplier() float64 {
+	return GetEnvFloat("PAYROLL_OVERTIME_MULTIPLIER", 1.5)
+}
+
+// GetPayrollNightWindow retrieves the local hours between which night
+// differential applies, e.g. 22 and 6
+func GetPayrollNightWindow() (int, int) {
+	return GetEnvInt("PAYROLL_NIGHT_START_HOUR", 22), GetEnvInt("PAYROLL_NIGHT_END_HOUR", 6)
+}
+
+// GetPayrollNightDifferential retrieves the premium, as a fraction of the
+// hourly rate, paid for night hours
+func GetPayrollNightDifferential() float64 {
+	return GetEnvFloat("PAYROLL_NIGHT_DIFFERENTIAL", 0.1)
+}
+
+// GetPayrollWeekendDifferential retrieves the premium, as a fraction of the
+// hourly rate, paid for weekend hours
+func GetPayrollWeekendDifferential() float64 {
+	return GetEnvFloat("PAYROLL_WEEKEND_DIFFERENTIAL", 0.25)
+}
+
+// GetRotationHorizonDays retrieves how many days ahead rotation patterns are
+// materialized into shift assignments
+func GetRotationHorizonDays() int {
+	return GetEnvInt("ROTATION_HORIZON_DAYS", 28)
+}
+
+// GetCoverageLookaheadDays retrieves how many days ahead the daily check looks
+// for understaffed shifts (at most 62)
+func GetCoverageLookaheadDays() int {
+	return GetEnvInt("COVERAGE_LOOKAHEAD_DAYS", 14)
+}
+
+// GetMaxWeeklyHours retrieves the most paid hours an employee should be
+// scheduled for in a week
+func GetMaxWeeklyHours() float64 {
+	return GetEnvFloat("MAX_WEEKLY_HOURS", 48)
+}
+
+// GetMaxConsecutiveDays retrieves the most days in a row an employee should
+// be scheduled to work
+func GetMaxConsecutiveDays() int {
+	return GetEnvInt("MAX_CONSECUTIVE_DAYS", 6)
+}
+
+// GetSchedulerMaxWeeklyHours retrieves the most paid hours the auto-scheduler
+// gives an employee in a week, by default MAX_WEEKLY_HOURS
+func GetSchedulerMaxWeeklyHours() float64 {
+	return GetEnvFloat("SCHEDULER_MAX_WEEKLY_HOURS", GetMaxWeeklyHours())
+}
+
+// GetMinRestHours retrieves the minimum rest an employee should have between
+// two shifts
+func GetMinRestHours() float64 {
+	return GetEnvFloat("MIN_REST_HOURS", 11)
+}
+
+// GetSchedulerMinRestHours retrieves the minimum rest the auto-scheduler
+// leaves between two shifts of an employee, by default MIN_REST_HOURS
+func GetSchedulerMinRestHours() float64 {
+	return GetEnvFloat("SCHEDULER_MIN_REST_HOURS", GetMinRestHours())
+}
+
+// GetSchedulerTimeBudgetSeconds retrieves how long the auto-scheduler may run
+func GetSchedulerTimeBudgetSeconds() int {
+	return GetEnvInt("SCHEDULER_TIME_BUDGET_SECONDS", 10)
+}
+
+// GetClockInEarlyMinutes retrieves how many minutes before a shift starts
+// employees may clock in for it
+func GetClockInEarlyMinutes() int {
+	return GetEnvInt("CLOCK_IN_EARLY_MINUTES", 30)
+}
+
+// GetClockInGraceMinutes retrieves how many minutes after a shift starts a
+// clock-in still counts as on time
+func GetClockInGraceMinutes() int {
+	return GetEnvInt("CLOCK_IN_GRACE_MINUTES", 5)
+}
+
+// GetBreakRequiredAfterHours retrieves how long an employee may work before a
+// break is mandatory (0 disables the rule)
+func GetBreakRequiredAfterHours() float64 {
+	return GetEnvFloat("BREAK_REQUIRED_AFTER_HOURS", 6)
+}
+
+// GetBreakMinMinutes retrieves how long the mandatory break lasts at least
+func GetBreakMinMinutes() int {
+	return GetEnvInt("BREAK_MIN_MINUTES", 30)
+}
+
+// GetBreakEnforcement retrieves whether a mandatory break that was not taken
+// is only flagged ("warn") or also deducted from worked time ("deduct")
+func GetBreakEnforcement() string {
+	return GetEnvString("BREAK_ENFORCEMENT", "warn")
+}
+
+// GetAttendanceCheckGraceMinutes retrieves how long after a shift ends a
+// missing clock-in or clock-out is reported
+func GetAttendanceCheckGraceMinutes() int {
+	return GetEnvInt("ATTENDANCE_CHECK_GRACE_MINUTES", 30)
+}
+
+// GetAttendanceAutoClose retrieves whether a missing clock-out is closed at
+// the end of the shift when it is reported
+func GetAttendanceAutoClose() bool {
+	return GetEnvBool("ATTENDANCE_AUTO_CLOSE", false)
+}
+
+// GetAvailabilityEnforcement retrieves whether shifts in a window an employee
+// marked unavailable are assigned with a warning ("warn") or rejected ("block")
+func GetAvailabilityEnforcement() string {
+	return GetEnvString("AVAILABILITY_ENFORCEMENT", "warn")
+}
+
+// GetLeaveWorkWeek retrieves the weekdays (0 is Sunday) counted as leave days
+// when an employee has no shift assigned on a day
+func GetLeaveWorkWeek() []time.Weekday {
+	var week []time.Weekday
+	for _, day := range GetEnvIntList("LEAVE_WORK_WEEK", []int{1, 2, 3, 4, 5}) {
+		if day < 0 || day > 6 {
+			log.Printf("Invalid weekday %d in LEAVE_WORK_WEEK, ignoring it", day)
+			continue
+		}
+		week = append(week, time.Weekday(day))
+	}
+	return week
+}
@@ -72,6 +72,23 @@ var (
 		},
 		[]string{"job_name"},
 	)
+
+	// Staffing metrics
+	staffingCoverageGaps = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "staffing_coverage_gaps",
+			Help: "Upcoming shift occurrences staffed below their coverage rule",
+		},
+		[]string{"location", "designation"},
+	)
+
+	staffingCoverageShortfall = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "staffing_coverage_shortfall",
+			Help: "Staff missing across upcoming shift occurrences",
+		},
+		[]string{"location", "designation"},
+	)
 )
 
 // InitMetrics initializes Prometheus metrics
@@ -94,6 +111,8 @@ func InitMetrics() *prometheus.Registry {
 		dbQueryDuration,
 		cronJobsTotal,
 		cronJobDuration,
+		staffingCoverageGaps,
+		staffingCoverageShortfall,
 	)
 
 	return reg
@@ -166,3 +185,16 @@ func RecordCronJob(jobName string, duration time.Duration, err error) {
 func SetDBConnections(count int) {
 	dbConnectionsActive.Set(float64(count))
 }
+
+// ResetStaffingCoverage clears the staffing gauges before they are recomputed
+func ResetStaffingCoverage() {
+	staffingCoverageGaps.Reset()
+	staffingCoverageShortfall.Reset()
+}
+
+// SetStaffingCoverage sets the upcoming coverage gaps and missing staff for a
+// location and designation
+func SetStaffingCoverage(location, designation string, gaps, shortfall int) {
+	staffingCoverageGaps.WithLabelValues(location, designation).Set(float64(gaps))
+	staffingCoverageShortfall.WithLabelValues(location, designation).Set(float64(shortfall))
+}
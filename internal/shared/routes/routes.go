@@ -1,10 +1,31 @@
 package routes
 
 import (
+	"clinicplus/internal/attendance"
+	"clinicplus/internal/availability"
+	"clinicplus/internal/calendar"
+	"clinicplus/internal/compensation"
+	"clinicplus/internal/coverage"
+	"clinicplus/internal/credential"
+	"clinicplus/internal/document"
 	"clinicplus/internal/employee"
+	"clinicplus/internal/holiday"
 	"clinicplus/internal/iam"
+	"clinicplus/internal/leave"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/onboarding"
+	"clinicplus/internal/openshift"
+	"clinicplus/internal/payroll"
+	"clinicplus/internal/roster"
+	"clinicplus/internal/rotation"
+	"clinicplus/internal/scheduler"
 	"clinicplus/internal/shared/config"
+	"clinicplus/internal/swap"
+	"clinicplus/internal/timesheet"
+	"clinicplus/internal/worktime"
+	"clinicplus/pkg/storage"
 	"log"
+	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
@@ -12,7 +33,25 @@ import (
 
 func RegisterRoutes(r *mux.Router, db *gorm.DB) {
 
-	db.AutoMigrate(&iam.User{}, &employee.Employee{}, &employee.Attendance{}, &employee.Shift{}, &employee.EmployeeShift{})
+	db.AutoMigrate(&iam.User{}, &employee.Employee{}, &employee.Attendance{}, &employee.AttendanceBreak{}, &employee.Shift{}, &employee.EmployeeShift{}, &employee.Location{}, &employee.EmploymentEvent{})
+	db.AutoMigrate(&notification.Notification{})
+	db.AutoMigrate(&credential.CredentialType{}, &credential.CredentialRequirement{}, &credential.Credential{})
+	db.AutoMigrate(&document.DocumentCategory{}, &document.Document{})
+	db.AutoMigrate(&leave.LeaveType{}, &leave.LeaveBalance{}, &leave.LeaveRequest{})
+	db.AutoMigrate(&holiday.Holiday{})
+	db.AutoMigrate(&onboarding.ChecklistTemplate{}, &onboarding.ChecklistTemplateItem{}, &onboarding.Checklist{}, &onboarding.ChecklistTask{})
+	db.AutoMigrate(&compensation.CompensationRecord{}, &compensation.CompensationComponent{}, &compensation.CompensationChangeRequest{}, &compensation.ChangeRequestComponent{})
+	db.AutoMigrate(&payroll.PayrollRun{}, &payroll.PayrollEntry{}, &payroll.PayrollLine{})
+	db.AutoMigrate(&calendar.FeedToken{})
+	db.AutoMigrate(&availability.AvailabilityWindow{}, &availability.ShiftPreference{})
+	db.AutoMigrate(&coverage.CoverageRule{}, &coverage.CoverageAlert{})
+	db.AutoMigrate(&worktime.WorkingTimeRule{})
+	db.AutoMigrate(&attendance.CorrectionRequest{})
+	db.AutoMigrate(&timesheet.Timesheet{}, &timesheet.TimesheetDay{})
+	db.AutoMigrate(&swap.SwapRequest{}, &swap.SwapEvent{})
+	db.AutoMigrate(&openshift.OpenShift{}, &openshift.ShiftBid{})
+	db.AutoMigrate(&scheduler.ScheduleDraft{}, &scheduler.DraftAssignment{}, &scheduler.DraftGap{})
+	db.AutoMigrate(&rotation.Team{}, &rotation.TeamMember{}, &rotation.RotationPattern{}, &rotation.RotationStep{}, &rotation.RotationAssignment{}, &rotation.RotationOverride{})
 
 	// Health Check Routes
 	r.HandleFunc("/health", HealthCheck).Methods("GET")
@@ -20,11 +59,32 @@ func RegisterRoutes(r *mux.Router, db *gorm.DB) {
 	// IAM Routes
 	iamService := iam.NewAuthService(db, config.GetJWTSecret())
 	iamHandler := iam.NewAuthHandler(iamService)
+	requireAuth := iam.RequireAuth(iamService)
+	requireManager := iam.RequireRole(iam.RoleAdmin, iam.RoleManager)
+	requireAdmin := iam.RequireRole(iam.RoleAdmin)
 	r.HandleFunc("/login", iamHandler.Login).Methods("POST")
 	r.HandleFunc("/logout", iamHandler.Logout).Methods("POST")
 
+	// Notification Routes
+	notificationService := notification.NewNotificationService(db)
+	notificationHandler := notification.NewNotificationHandler(notificationService)
+	r.Handle("/employees/{id}/notifications", requireAuth(http.HandlerFunc(notificationHandler.GetEmployeeNotifications))).Methods("GET")
+	r.Handle("/notifications/{id}/read", requireAuth(http.HandlerFunc(notificationHandler.MarkRead))).Methods("POST")
+
 	// Employee Management Routes
-	employeeService := employee.NewEmployeeService(db)
+	credentialService := credential.NewCredentialService(db, notificationService)
+	employeeService := employee.NewEmployeeService(db, employee.AttendancePolicyFromConfig())
+	employeeService.AddAssignmentGuard(credentialService)
+	availabilityService := availability.NewAvailabilityService(db, config.GetAvailabilityEnforcement())
+	employeeService.AddAssignmentGuard(availabilityService)
+	workingTimeService := worktime.NewWorkingTimeService(db, worktime.Defaults{
+		MinRestHours:       config.GetMinRestHours(),
+		MaxWeeklyHours:     config.GetMaxWeeklyHours(),
+		MaxConsecutiveDays: config.GetMaxConsecutiveDays(),
+	})
+	employeeService.AddAssignmentGuard(workingTimeService)
+	onboardingService := onboarding.NewOnboardingService(db, notificationService)
+	employeeService.AddLifecycleListener(onboardingService)
 	employeeHandler := employee.NewEmployeeHandler(employeeService)
 	employeeRouter := r.PathPrefix("/employees").Subrouter()
 	shiftRouter := r.PathPrefix("/shifts").Subrouter()
@@ -33,10 +93,19 @@ func RegisterRoutes(r *mux.Router, db *gorm.DB) {
 	employeeRouter.HandleFunc("", employeeHandler.CreateEmployee).Methods("POST")
 	employeeRouter.HandleFunc("/{id}", employeeHandler.GetEmployee).Methods("GET")
 	employeeRouter.HandleFunc("/{id}", employeeHandler.UpdateEmployee).Methods("PUT")
-	employeeRouter.HandleFunc("/{id}", employeeHandler.DeleteEmployee).Methods("DELETE")
+	// Termination disables the employee's login and cuts their assignments, so only admins end or restart employment
+	employeeRouter.Handle("/{id}", requireAuth(requireAdmin(http.HandlerFunc(employeeHandler.DeleteEmployee)))).Methods("DELETE")
+	employeeRouter.Handle("/{id}/status", requireAuth(requireManager(http.HandlerFunc(employeeHandler.ChangeEmploymentStatus)))).Methods("POST")
+	employeeRouter.Handle("/{id}/terminate", requireAuth(requireAdmin(http.HandlerFunc(employeeHandler.TerminateEmployee)))).Methods("POST")
+	employeeRouter.Handle("/{id}/rehire", requireAuth(requireAdmin(http.HandlerFunc(employeeHandler.RehireEmployee)))).Methods("POST")
+	employeeRouter.Handle("/{id}/employment_history", requireAuth(requireManager(http.HandlerFunc(employeeHandler.GetEmploymentHistory)))).Methods("GET")
 	employeeRouter.HandleFunc("/{id}/clockin", employeeHandler.ClockInEmployee).Methods("POST")
+	employeeRouter.Handle("/{id}/clockin/override", requireAuth(requireManager(http.HandlerFunc(employeeHandler.OverrideClockIn)))).Methods("POST")
 	employeeRouter.HandleFunc("/{id}/clockout", employeeHandler.ClockOutEmployee).Methods("POST")
+	employeeRouter.Handle("/{id}/break/start", requireAuth(http.HandlerFunc(employeeHandler.StartBreak))).Methods("POST")
+	employeeRouter.Handle("/{id}/break/end", requireAuth(http.HandlerFunc(employeeHandler.EndBreak))).Methods("POST")
 	employeeRouter.HandleFunc("/{id}/assign_shift", employeeHandler.AssignShift).Methods("POST")
+	employeeRouter.Handle("/{id}/assign_shift/override", requireAuth(requireManager(http.HandlerFunc(employeeHandler.OverrideAssignShift)))).Methods("POST")
 
 	// Shift Management Routes
 	shiftRouter.HandleFunc("", employeeHandler.GetShifts).Methods("GET")
@@ -45,6 +114,284 @@ func RegisterRoutes(r *mux.Router, db *gorm.DB) {
 	shiftRouter.HandleFunc("/{id}", employeeHandler.UpdateShift).Methods("PUT")
 	shiftRouter.HandleFunc("/{id}", employeeHandler.DeleteShift).Methods("DELETE")
 
+	// Location Routes
+	locationRouter := r.PathPrefix("/locations").Subrouter()
+	locationRouter.Use(requireAuth)
+	locationRouter.HandleFunc("", employeeHandler.GetLocations).Methods("GET")
+	locationRouter.Handle("", requireManager(http.HandlerFunc(employeeHandler.CreateLocation))).Methods("POST")
+	locationRouter.Handle("/{id}", requireManager(http.HandlerFunc(employeeHandler.UpdateLocation))).Methods("PUT")
+	locationRouter.Handle("/{id}", requireManager(http.HandlerFunc(employeeHandler.DeleteLocation))).Methods("DELETE")
+
+	// Holiday Routes
+	holidayService := holiday.NewHolidayService(db)
+	holidayHandler := holiday.NewHolidayHandler(holidayService)
+	holidayRouter := r.PathPrefix("/holidays").Subrouter()
+	holidayRouter.Use(requireAuth)
+	holidayRouter.HandleFunc("", holidayHandler.GetHolidays).Methods("GET")
+	// Holidays set pay multipliers, so only admins change them
+	holidayRouter.Handle("", requireAdmin(http.HandlerFunc(holidayHandler.CreateHoliday))).Methods("POST")
+	holidayRouter.Handle("/import", requireAdmin(http.HandlerFunc(holidayHandler.ImportICalendar))).Methods("POST")
+	holidayRouter.Handle("/{id}", requireAdmin(http.HandlerFunc(holidayHandler.UpdateHoliday))).Methods("PUT")
+	holidayRouter.Handle("/{id}", requireAdmin(http.HandlerFunc(holidayHandler.DeleteHoliday))).Methods("DELETE")
+
+	// Credential Routes
+	credentialHandler := credential.NewCredentialHandler(credentialService)
+	credentialRouter := r.PathPrefix("/credentials").Subrouter()
+	credentialRouter.Use(requireAuth)
+	credentialRouter.HandleFunc("/types", credentialHandler.GetCredentialTypes).Methods("GET")
+	credentialRouter.Handle("/types", requireManager(http.HandlerFunc(credentialHandler.CreateCredentialType))).Methods("POST")
+	credentialRouter.Handle("/types/{id}", requireManager(http.HandlerFunc(credentialHandler.UpdateCredentialType))).Methods("PUT")
+	credentialRouter.Handle("/types/{id}", requireManager(http.HandlerFunc(credentialHandler.DeleteCredentialType))).Methods("DELETE")
+	credentialRouter.Handle("/types/{id}/requirements", requireManager(http.HandlerFunc(credentialHandler.AddRequirement))).Methods("POST")
+	credentialRouter.Handle("/types/{id}/requirements/{designation}", requireManager(http.HandlerFunc(credentialHandler.RemoveRequirement))).Methods("DELETE")
+	credentialRouter.Handle("/expiring", requireManager(http.HandlerFunc(credentialHandler.GetExpiringCredentials))).Methods("GET")
+	credentialRouter.Handle("/{id}", requireManager(http.HandlerFunc(credentialHandler.UpdateCredential))).Methods("PUT")
+	credentialRouter.Handle("/{id}", requireManager(http.HandlerFunc(credentialHandler.DeleteCredential))).Methods("DELETE")
+	employeeRouter.Handle("/{id}/credentials", requireAuth(http.HandlerFunc(credentialHandler.GetEmployeeCredentials))).Methods("GET")
+	employeeRouter.Handle("/{id}/credentials", requireAuth(requireManager(http.HandlerFunc(credentialHandler.CreateCredential)))).Methods("POST")
+
+	// Document Routes
+	documentStore, err := storage.New(storage.Config{
+		Driver:      config.GetStorageDriver(),
+		LocalPath:   config.GetStorageLocalPath(),
+		S3Endpoint:  config.GetS3Endpoint(),
+		S3Region:    config.GetS3Region(),
+		S3Bucket:    config.GetS3Bucket(),
+		S3AccessKey: config.GetS3AccessKey(),
+		S3SecretKey: config.GetS3SecretKey(),
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize document storage: %v", err)
+	}
+	documentService := document.NewDocumentService(db, documentStore, config.GetDocumentMaxSize())
+	documentHandler := document.NewDocumentHandler(documentService, config.GetDocumentMaxSize())
+	employeeDocumentRouter := employeeRouter.PathPrefix("/{id}/documents").Subrouter()
+	employeeDocumentRouter.Use(requireAuth)
+	employeeDocumentRouter.HandleFunc("", documentHandler.GetDocuments).Methods("GET")
+	employeeDocumentRouter.HandleFunc("", documentHandler.UploadDocument).Methods("POST")
+	employeeDocumentRouter.HandleFunc("/{documentID}", documentHandler.DownloadDocument).Methods("GET")
+	employeeDocumentRouter.HandleFunc("/{documentID}", documentHandler.DeleteDocument).Methods("DELETE")
+	documentRouter := r.PathPrefix("/documents").Subrouter()
+	documentRouter.Use(requireAuth, requireManager)
+	documentRouter.HandleFunc("/categories", documentHandler.GetCategories).Methods("GET")
+	documentRouter.HandleFunc("/categories", documentHandler.CreateCategory).Methods("POST")
+	documentRouter.HandleFunc("/categories/{id}", documentHandler.UpdateCategory).Methods("PUT")
+	documentRouter.HandleFunc("/categories/{id}", documentHandler.DeleteCategory).Methods("DELETE")
+	documentRouter.HandleFunc("/expiring", documentHandler.GetExpiringDocuments).Methods("GET")
+
+	// Leave Routes
+	leaveService := leave.NewLeaveService(db, notificationService, holidayService, config.GetLeaveWorkWeek())
+	leaveHandler := leave.NewLeaveHandler(leaveService)
+	employeeLeaveRouter := employeeRouter.PathPrefix("/{id}/leave").Subrouter()
+	employeeLeaveRouter.Use(requireAuth)
+	employeeLeaveRouter.HandleFunc("/balances", leaveHandler.GetBalances).Methods("GET")
+	employeeLeaveRouter.Handle("/balances", requireManager(http.HandlerFunc(leaveHandler.AdjustBalance))).Methods("POST")
+	employeeLeaveRouter.HandleFunc("/requests", leaveHandler.GetLeaveRequests).Methods("GET")
+	employeeLeaveRouter.HandleFunc("/requests", leaveHandler.RequestLeave).Methods("POST")
+	leaveRouter := r.PathPrefix("/leave").Subrouter()
+	leaveRouter.Use(requireAuth)
+	leaveRouter.HandleFunc("/types", leaveHandler.GetLeaveTypes).Methods("GET")
+	leaveRouter.Handle("/types", requireManager(http.HandlerFunc(leaveHandler.CreateLeaveType))).Methods("POST")
+	leaveRouter.Handle("/types/{id}", requireManager(http.HandlerFunc(leaveHandler.UpdateLeaveType))).Methods("PUT")
+	leaveRouter.Handle("/types/{id}", requireManager(http.HandlerFunc(leaveHandler.DeleteLeaveType))).Methods("DELETE")
+	leaveRouter.HandleFunc("/requests/pending", leaveHandler.GetPendingRequests).Methods("GET")
+	leaveRouter.HandleFunc("/requests/{id}/approve", leaveHandler.ApproveRequest).Methods("POST")
+	leaveRouter.HandleFunc("/requests/{id}/reject", leaveHandler.RejectRequest).Methods("POST")
+	leaveRouter.HandleFunc("/requests/{id}/cancel", leaveHandler.CancelRequest).Methods("POST")
+
+	// Onboarding and Offboarding Checklist Routes
+	onboardingHandler := onboarding.NewOnboardingHandler(onboardingService)
+	employeeRouter.Handle("/{id}/checklists", requireAuth(http.HandlerFunc(onboardingHandler.GetChecklists))).Methods("GET")
+	checklistRouter := r.PathPrefix("/checklists").Subrouter()
+	checklistRouter.Use(requireAuth)
+	checklistRouter.Handle("/templates", requireManager(http.HandlerFunc(onboardingHandler.GetTemplates))).Methods("GET")
+	checklistRouter.Handle("/templates", requireManager(http.HandlerFunc(onboardingHandler.CreateTemplate))).Methods("POST")
+	checklistRouter.Handle("/templates/{id}", requireManager(http.HandlerFunc(onboardingHandler.UpdateTemplate))).Methods("PUT")
+	checklistRouter.Handle("/templates/{id}", requireManager(http.HandlerFunc(onboardingHandler.DeleteTemplate))).Methods("DELETE")
+	checklistRouter.HandleFunc("/tasks/mine", onboardingHandler.GetMyTasks).Methods("GET")
+	checklistRouter.Handle("/tasks/overdue", requireManager(http.HandlerFunc(onboardingHandler.GetOverdueTasks))).Methods("GET")
+	checklistRouter.HandleFunc("/tasks/{id}", onboardingHandler.UpdateTask).Methods("PUT")
+
+	// Compensation Routes
+	compensationService := compensation.NewCompensationService(db, notificationService)
+	compensationHandler := compensation.NewCompensationHandler(compensationService)
+	employeeCompensationRouter := employeeRouter.PathPrefix("/{id}/compensation").Subrouter()
+	employeeCompensationRouter.Use(requireAuth)
+	employeeCompensationRouter.HandleFunc("", compensationHandler.GetHistory).Methods("GET")
+	employeeCompensationRouter.HandleFunc("/current", compensationHandler.GetCurrent).Methods("GET")
+	employeeCompensationRouter.Handle("/requests", requireManager(http.HandlerFunc(compensationHandler.GetChangeRequests))).Methods("GET")
+	employeeCompensationRouter.Handle("/requests", requireManager(http.HandlerFunc(compensationHandler.RequestChange))).Methods("POST")
+	compensationRouter := r.PathPrefix("/compensation").Subrouter()
+	compensationRouter.Use(requireAuth, requireAdmin)
+	compensationRouter.HandleFunc("/requests/pending", compensationHandler.GetPendingChangeRequests).Methods("GET")
+	compensationRouter.HandleFunc("/requests/{id}/approve", compensationHandler.ApproveChange).Methods("POST")
+	compensationRouter.HandleFunc("/requests/{id}/reject", compensationHandler.RejectChange).Methods("POST")
+	compensationRouter.HandleFunc("/reports/changes", compensationHandler.ChangeReport).Methods("GET")
+
+	// Payroll Routes
+	payrollService := payroll.NewPayrollService(db, holidayService, payroll.PolicyFromConfig())
+	payrollHandler := payroll.NewPayrollHandler(payrollService)
+	payrollRouter := r.PathPrefix("/payroll").Subrouter()
+	payrollRouter.Use(requireAuth, requireAdmin)
+	payrollRouter.HandleFunc("/runs", payrollHandler.GetRuns).Methods("GET")
+	payrollRouter.HandleFunc("/runs", payrollHandler.CreateRun).Methods("POST")
+	payrollRouter.HandleFunc("/runs/{id}", payrollHandler.GetRun).Methods("GET")
+	payrollRouter.HandleFunc("/runs/{id}", payrollHandler.DeleteRun).Methods("DELETE")
+	payrollRouter.HandleFunc("/runs/{id}/rerun", payrollHandler.RerunPayroll).Methods("POST")
+	payrollRouter.HandleFunc("/runs/{id}/diff", payrollHandler.DiffRun).Methods("GET")
+	payrollRouter.HandleFunc("/runs/{id}/lock", payrollHandler.LockRun).Methods("POST")
+	payrollRouter.HandleFunc("/runs/{id}/export.csv", payrollHandler.ExportCSV).Methods("GET")
+
+	// Team and Rotation Routes
+	rotationService := rotation.NewRotationService(db, employeeService, config.GetRotationHorizonDays())
+	rotationHandler := rotation.NewRotationHandler(rotationService)
+	teamRouter := r.PathPrefix("/teams").Subrouter()
+	teamRouter.Use(requireAuth, requireManager)
+	teamRouter.HandleFunc("", rotationHandler.GetTeams).Methods("GET")
+	teamRouter.HandleFunc("", rotationHandler.CreateTeam).Methods("POST")
+	teamRouter.HandleFunc("/{id}", rotationHandler.GetTeam).Methods("GET")
+	teamRouter.HandleFunc("/{id}", rotationHandler.UpdateTeam).Methods("PUT")
+	teamRouter.HandleFunc("/{id}", rotationHandler.DeleteTeam).Methods("DELETE")
+	teamRouter.HandleFunc("/{id}/members", rotationHandler.AddTeamMember).Methods("POST")
+	teamRouter.HandleFunc("/{id}/members/{employee_id}", rotationHandler.RemoveTeamMember).Methods("DELETE")
+	rotationRouter := r.PathPrefix("/rotations").Subrouter()
+	rotationRouter.Use(requireAuth, requireManager)
+	rotationRouter.HandleFunc("", rotationHandler.GetPatterns).Methods("GET")
+	rotationRouter.HandleFunc("", rotationHandler.CreatePattern).Methods("POST")
+	rotationRouter.HandleFunc("/assignments/{id}", rotationHandler.DeleteAssignment).Methods("DELETE")
+	rotationRouter.HandleFunc("/assignments/{id}/regenerate", rotationHandler.Regenerate).Methods("POST")
+	rotationRouter.HandleFunc("/assignments/{id}/overrides", rotationHandler.SetOverride).Methods("POST")
+	rotationRouter.HandleFunc("/overrides/{id}", rotationHandler.DeleteOverride).Methods("DELETE")
+	rotationRouter.HandleFunc("/{id}", rotationHandler.GetPattern).Methods("GET")
+	rotationRouter.HandleFunc("/{id}", rotationHandler.UpdatePattern).Methods("PUT")
+	rotationRouter.HandleFunc("/{id}", rotationHandler.DeletePattern).Methods("DELETE")
+	rotationRouter.HandleFunc("/{id}/assignments", rotationHandler.GetAssignments).Methods("GET")
+	rotationRouter.HandleFunc("/{id}/assignments", rotationHandler.AssignPattern).Methods("POST")
+
+	// Availability and Shift Preference Routes
+	availabilityHandler := availability.NewAvailabilityHandler(availabilityService)
+	employeeAvailabilityRouter := employeeRouter.PathPrefix("/{id}/availability").Subrouter()
+	employeeAvailabilityRouter.Use(requireAuth)
+	employeeAvailabilityRouter.HandleFunc("", availabilityHandler.GetWindows).Methods("GET")
+	employeeAvailabilityRouter.HandleFunc("", availabilityHandler.CreateWindow).Methods("POST")
+	employeeAvailabilityRouter.HandleFunc("/{window_id}", availabilityHandler.UpdateWindow).Methods("PUT")
+	employeeAvailabilityRouter.HandleFunc("/{window_id}", availabilityHandler.DeleteWindow).Methods("DELETE")
+	employeePreferenceRouter := employeeRouter.PathPrefix("/{id}/shift_preferences").Subrouter()
+	employeePreferenceRouter.Use(requireAuth)
+	employeePreferenceRouter.HandleFunc("", availabilityHandler.GetPreferences).Methods("GET")
+	employeePreferenceRouter.HandleFunc("", availabilityHandler.SetPreference).Methods("POST")
+	employeePreferenceRouter.HandleFunc("/{preference_id}", availabilityHandler.DeletePreference).Methods("DELETE")
+
+	// Roster Routes
+	rosterService := roster.NewRosterService(db)
+	rosterHandler := roster.NewRosterHandler(rosterService)
+	r.Handle("/roster", requireAuth(requireManager(http.HandlerFunc(rosterHandler.GetRoster)))).Methods("GET")
+	employeeRouter.Handle("/{id}/schedule", requireAuth(http.HandlerFunc(rosterHandler.GetEmployeeSchedule))).Methods("GET")
+
+	// Coverage Routes
+	coverageService := coverage.NewCoverageService(db, rosterService, notificationService)
+	coverageHandler := coverage.NewCoverageHandler(coverageService)
+	coverageRouter := r.PathPrefix("/coverage").Subrouter()
+	coverageRouter.Use(requireAuth, requireManager)
+	coverageRouter.HandleFunc("", coverageHandler.Report).Methods("GET")
+	coverageRouter.HandleFunc("/rules", coverageHandler.GetRules).Methods("GET")
+	coverageRouter.HandleFunc("/rules", coverageHandler.CreateRule).Methods("POST")
+	coverageRouter.HandleFunc("/rules/{id}", coverageHandler.UpdateRule).Methods("PUT")
+	coverageRouter.HandleFunc("/rules/{id}", coverageHandler.DeleteRule).Methods("DELETE")
+
+	// Auto-scheduling Routes
+	schedulerService := scheduler.NewSchedulerService(db, employeeService, notificationService, scheduler.PolicyFromConfig())
+	schedulerService.AddGuard(credentialService)
+	schedulerService.AddGuard(availabilityService)
+	schedulerHandler := scheduler.NewSchedulerHandler(schedulerService)
+	scheduleRouter := r.PathPrefix("/schedules").Subrouter()
+	scheduleRouter.Use(requireAuth, requireManager)
+	scheduleRouter.HandleFunc("/drafts", schedulerHandler.GetDrafts).Methods("GET")
+	scheduleRouter.HandleFunc("/drafts", schedulerHandler.GenerateDraft).Methods("POST")
+	scheduleRouter.HandleFunc("/drafts/{id}", schedulerHandler.GetDraft).Methods("GET")
+	scheduleRouter.HandleFunc("/drafts/{id}", schedulerHandler.DeleteDraft).Methods("DELETE")
+	scheduleRouter.HandleFunc("/drafts/{id}/assignments", schedulerHandler.AddAssignment).Methods("POST")
+	scheduleRouter.HandleFunc("/drafts/{id}/assignments/{assignment_id}", schedulerHandler.RemoveAssignment).Methods("DELETE")
+	scheduleRouter.HandleFunc("/drafts/{id}/publish", schedulerHandler.PublishDraft).Methods("POST")
+	scheduleRouter.HandleFunc("/drafts/{id}/revise", schedulerHandler.ReviseDraft).Methods("POST")
+	scheduleRouter.HandleFunc("/drafts/{id}/diff", schedulerHandler.DiffDraft).Methods("GET")
+
+	// Working Time Rule Routes
+	workingTimeHandler := worktime.NewWorkingTimeHandler(workingTimeService)
+	workingTimeRouter := r.PathPrefix("/working_time_rules").Subrouter()
+	workingTimeRouter.Use(requireAuth)
+	workingTimeRouter.Handle("", requireManager(http.HandlerFunc(workingTimeHandler.GetRules))).Methods("GET")
+	workingTimeRouter.Handle("/{code}", requireAdmin(http.HandlerFunc(workingTimeHandler.UpdateRule))).Methods("PUT")
+
+	// Attendance Exception and Correction Routes
+	attendanceService := attendance.NewAttendanceService(db, employeeService, holidayService, notificationService, attendance.PolicyFromConfig())
+	attendanceHandler := attendance.NewAttendanceHandler(attendanceService)
+	attendanceRouter := r.PathPrefix("/attendance").Subrouter()
+	attendanceRouter.Use(requireAuth)
+	attendanceRouter.Handle("/exceptions", requireManager(http.HandlerFunc(attendanceHandler.GetExceptions))).Methods("GET")
+	attendanceRouter.HandleFunc("/corrections", attendanceHandler.GetCorrections).Methods("GET")
+	attendanceRouter.HandleFunc("/corrections", attendanceHandler.RequestCorrection).Methods("POST")
+	attendanceRouter.Handle("/corrections/pending", requireManager(http.HandlerFunc(attendanceHandler.GetPendingCorrections))).Methods("GET")
+	attendanceRouter.Handle("/corrections/direct", requireAdmin(http.HandlerFunc(attendanceHandler.CorrectAttendance))).Methods("POST")
+	attendanceRouter.HandleFunc("/corrections/{id}/cancel", attendanceHandler.CancelCorrection).Methods("POST")
+	attendanceRouter.Handle("/corrections/{id}/approve", requireManager(http.HandlerFunc(attendanceHandler.ApproveCorrection))).Methods("POST")
+	attendanceRouter.Handle("/corrections/{id}/reject", requireManager(http.HandlerFunc(attendanceHandler.RejectCorrection))).Methods("POST")
+
+	// Timesheet Routes
+	timesheetService := timesheet.NewTimesheetService(db, notificationService, timesheet.PolicyFromConfig())
+	timesheetHandler := timesheet.NewTimesheetHandler(timesheetService)
+	timesheetRouter := r.PathPrefix("/timesheets").Subrouter()
+	timesheetRouter.Use(requireAuth)
+	timesheetRouter.HandleFunc("", timesheetHandler.GetTimesheets).Methods("GET")
+	timesheetRouter.HandleFunc("", timesheetHandler.GenerateTimesheet).Methods("POST")
+	timesheetRouter.Handle("/pending", requireManager(http.HandlerFunc(timesheetHandler.GetPendingTimesheets))).Methods("GET")
+	timesheetRouter.HandleFunc("/{id}", timesheetHandler.GetTimesheet).Methods("GET")
+	timesheetRouter.HandleFunc("/{id}/export.pdf", timesheetHandler.ExportPDF).Methods("GET")
+	timesheetRouter.HandleFunc("/{id}/attest", timesheetHandler.Attest).Methods("POST")
+	timesheetRouter.Handle("/{id}/sign_off", requireManager(http.HandlerFunc(timesheetHandler.SignOff))).Methods("POST")
+	timesheetRouter.Handle("/{id}/reopen", requireAdmin(http.HandlerFunc(timesheetHandler.Reopen))).Methods("POST")
+
+	// Shift Swap Routes
+	swapService := swap.NewSwapService(db, employeeService, notificationService)
+	swapHandler := swap.NewSwapHandler(swapService)
+	swapRouter := r.PathPrefix("/swaps").Subrouter()
+	swapRouter.Use(requireAuth)
+	swapRouter.HandleFunc("", swapHandler.GetRequests).Methods("GET")
+	swapRouter.HandleFunc("", swapHandler.CreateRequest).Methods("POST")
+	swapRouter.HandleFunc("/open", swapHandler.GetOpenRequests).Methods("GET")
+	swapRouter.Handle("/pending", requireManager(http.HandlerFunc(swapHandler.GetPendingRequests))).Methods("GET")
+	swapRouter.HandleFunc("/{id}", swapHandler.GetRequest).Methods("GET")
+	swapRouter.HandleFunc("/{id}/accept", swapHandler.Accept).Methods("POST")
+	swapRouter.HandleFunc("/{id}/decline", swapHandler.Decline).Methods("POST")
+	swapRouter.HandleFunc("/{id}/volunteer", swapHandler.Volunteer).Methods("POST")
+	swapRouter.HandleFunc("/{id}/cancel", swapHandler.Cancel).Methods("POST")
+	swapRouter.Handle("/{id}/approve", requireManager(http.HandlerFunc(swapHandler.Approve))).Methods("POST")
+	swapRouter.Handle("/{id}/reject", requireManager(http.HandlerFunc(swapHandler.Reject))).Methods("POST")
+
+	// Open Shift Routes
+	openShiftService := openshift.NewOpenShiftService(db, employeeService, notificationService)
+	openShiftHandler := openshift.NewOpenShiftHandler(openShiftService)
+	openShiftRouter := r.PathPrefix("/open_shifts").Subrouter()
+	openShiftRouter.Use(requireAuth)
+	openShiftRouter.Handle("", requireManager(http.HandlerFunc(openShiftHandler.GetOpenShifts))).Methods("GET")
+	openShiftRouter.Handle("", requireManager(http.HandlerFunc(openShiftHandler.CreateOpenShift))).Methods("POST")
+	openShiftRouter.HandleFunc("/available", openShiftHandler.GetAvailableOpenShifts).Methods("GET")
+	openShiftRouter.HandleFunc("/{id}", openShiftHandler.GetOpenShift).Methods("GET")
+	openShiftRouter.Handle("/{id}/cancel", requireManager(http.HandlerFunc(openShiftHandler.CancelOpenShift))).Methods("POST")
+	openShiftRouter.Handle("/{id}/award", requireManager(http.HandlerFunc(openShiftHandler.Award))).Methods("POST")
+	openShiftRouter.HandleFunc("/{id}/bids", openShiftHandler.Bid).Methods("POST")
+	openShiftRouter.HandleFunc("/{id}/bids", openShiftHandler.WithdrawBid).Methods("DELETE")
+
+	// Calendar Feed Routes. Feeds are fetched by calendar apps, which
+	// authenticate with a feed token in the URL instead of a bearer token.
+	calendarService := calendar.NewCalendarService(db, rosterService)
+	calendarHandler := calendar.NewCalendarHandler(calendarService)
+	calendarRouter := r.PathPrefix("/calendar").Subrouter()
+	calendarRouter.Handle("/token", requireAuth(http.HandlerFunc(calendarHandler.IssueToken))).Methods("POST")
+	calendarRouter.Handle("/token", requireAuth(http.HandlerFunc(calendarHandler.RevokeToken))).Methods("DELETE")
+	calendarRouter.HandleFunc("/employees/{id:[0-9]+}.ics", calendarHandler.EmployeeFeed).Methods("GET")
+	calendarRouter.HandleFunc("/locations/{id:[0-9]+}.ics", calendarHandler.LocationFeed).Methods("GET")
+
 	r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
 		tpl, err1 := route.GetPathTemplate()
 		met, err2 := route.GetMethods()
@@ -0,0 +1,218 @@
+// Package testdb backs gorm with a fake database/sql driver for service
+// tests. Statements are recorded instead of run. Queries return the rows
+// scripted for them with Returns; otherwise INSERTs return a new ID, counts
+// return 0 and other queries return no rows.
+package testdb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Statement is a statement gorm sent, with runs of whitespace collapsed
+type Statement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// Has reports whether value is one of the statement's arguments. Times are
+// compared with Equal, and ints match the int64s drivers receive.
+func (s Statement) Has(value interface{}) bool {
+	if n, ok := value.(int); ok {
+		value = int64(n)
+	}
+	for _, arg := range s.Args {
+		if at, ok := arg.(time.Time); ok {
+			if want, ok := value.(time.Time); ok && at.Equal(want) {
+				return true
+			}
+			continue
+		}
+		if arg == value {
+			return true
+		}
+	}
+	return false
+}
+
+type result struct {
+	fragment string
+	columns  []string
+	rows     [][]driver.Value
+}
+
+// DB records statements and serves scripted results
+type DB struct {
+	mu         sync.Mutex
+	statements []Statement
+	results    []result
+	affected   map[string]int64
+	nextID     int64
+}
+
+var drivers int64
+
+// New returns a gorm handle on a fresh fake database
+func New(t *testing.T) (*gorm.DB, *DB) {
+	t.Helper()
+	fake := &DB{affected: make(map[string]int64), nextID: 100}
+	name := fmt.Sprintf("testdb-%d", atomic.AddInt64(&drivers, 1))
+	sql.Register(name, fake)
+
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open("postgres", sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.LogMode(false)
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// Returns scripts the rows of every query containing fragment. Scripts are
+// tried in the order they were added.
+func (d *DB) Returns(fragment string, columns []string, rows ...[]interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := result{fragment: fragment, columns: columns}
+	for _, row := range rows {
+		values := make([]driver.Value, len(row))
+		for i, value := range row {
+			if n, ok := value.(int); ok {
+				value = int64(n)
+			}
+			values[i] = value
+		}
+		r.rows = append(r.rows, values)
+	}
+	d.results = append(d.results, r)
+}
+
+// Affects sets the number of rows reported for statements containing
+// fragment, by default 1
+func (d *DB) Affects(fragment string, rows int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.affected[fragment] = rows
+}
+
+// Statements returns every statement run so far
+func (d *DB) Statements() []Statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Statement(nil), d.statements...)
+}
+
+// Find returns the statements containing fragment
+func (d *DB) Find(fragment string) []Statement {
+	var found []Statement
+	for _, statement := range d.Statements() {
+		if strings.Contains(statement.Query, fragment) {
+			found = append(found, statement)
+		}
+	}
+	return found
+}
+
+// Ran reports whether a statement containing fragment was run
+func (d *DB) Ran(fragment string) bool {
+	return len(d.Find(fragment)) > 0
+}
+
+// Open implements driver.Driver
+func (d *DB) Open(string) (driver.Conn, error) { return conn{d}, nil }
+
+func (d *DB) record(query string, args []driver.Value) string {
+	query = strings.Join(strings.Fields(query), " ")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, Statement{Query: query, Args: args})
+	return query
+}
+
+func (d *DB) rowsFor(query string) *rows {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, r := range d.results {
+		if strings.Contains(query, r.fragment) {
+			return &rows{columns: r.columns, values: r.rows}
+		}
+	}
+	switch {
+	case strings.HasPrefix(query, "INSERT"):
+		d.nextID++
+		return &rows{columns: []string{"id"}, values: [][]driver.Value{{d.nextID}}}
+	case strings.Contains(query, "count(*)"):
+		return &rows{columns: []string{"count"}, values: [][]driver.Value{{int64(0)}}}
+	}
+	return &rows{}
+}
+
+func (d *DB) rowsAffected(query string) int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for fragment, n := range d.affected {
+		if strings.Contains(query, fragment) {
+			return n
+		}
+	}
+	return 1
+}
+
+type conn struct{ db *DB }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{db: c.db, query: query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return tx{}, nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type stmt struct {
+	db    *DB
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	query := s.db.record(s.query, args)
+	return driver.RowsAffected(s.db.rowsAffected(query)), nil
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	query := s.db.record(s.query, args)
+	return s.db.rowsFor(query), nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,199 @@
+// internal/swap/handler.go
+package swap
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type SwapHandler struct {
+	service SwapService
+}
+
+func NewSwapHandler(service SwapService) *SwapHandler {
+	return &SwapHandler{service: service}
+}
+
+func (h *SwapHandler) CreateRequest(w http.ResponseWriter, r *http.Request) {
+	var request SwapRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	created, err := h.service.CreateRequest(user, request)
+	if err != nil {
+		sendSwapError(w, err, "Failed to create swap request")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, created, nil, nil)
+}
+
+// GetRequests lists the user's swap requests, or an employee's for managers
+// passing employee_id
+func (h *SwapHandler) GetRequests(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+	employeeID := user.EmployeeID
+	if raw := r.URL.Query().Get("employee_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+			return
+		}
+		employeeID = uint(id)
+	}
+	if !iam.CanAccessEmployee(user, employeeID) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's swap requests", nil)
+		return
+	}
+
+	requests, err := h.service.GetRequests(employeeID)
+	if err != nil {
+		sendSwapError(w, err, "Failed to retrieve swap requests")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, requests, nil, nil)
+}
+
+func (h *SwapHandler) GetOpenRequests(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+
+	requests, err := h.service.GetOpenRequests(user)
+	if err != nil {
+		sendSwapError(w, err, "Failed to retrieve open swap requests")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, requests, nil, nil)
+}
+
+func (h *SwapHandler) GetPendingRequests(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+
+	requests, err := h.service.GetPendingRequests(user)
+	if err != nil {
+		sendSwapError(w, err, "Failed to retrieve pending swap requests")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, requests, nil, nil)
+}
+
+func (h *SwapHandler) GetRequest(w http.ResponseWriter, r *http.Request) {
+	id, ok := requestID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, err := h.service.GetRequest(id, user)
+	if err != nil {
+		sendSwapError(w, err, "Failed to retrieve swap request")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, nil)
+}
+
+func (h *SwapHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, h.service.Accept, "Failed to accept swap request")
+}
+
+func (h *SwapHandler) Decline(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, h.service.Decline, "Failed to decline swap request")
+}
+
+func (h *SwapHandler) Volunteer(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, h.service.Volunteer, "Failed to volunteer for swap request")
+}
+
+func (h *SwapHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, h.service.Approve, "Failed to approve swap request")
+}
+
+func (h *SwapHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, h.service.Reject, "Failed to reject swap request")
+}
+
+func (h *SwapHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, ok := requestID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, err := h.service.Cancel(id, user)
+	if err != nil {
+		sendSwapError(w, err, "Failed to cancel swap request")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, nil)
+}
+
+// act runs a step of the swap workflow that takes the request ID and an
+// optional note
+func (h *SwapHandler) act(w http.ResponseWriter, r *http.Request, step func(uint, *iam.User, string) (*SwapRequest, error), fallback string) {
+	id, ok := requestID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("Error decoding request body: %v", err)
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+			return
+		}
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	request, err := step(id, user, body.Note)
+	if err != nil {
+		sendSwapError(w, err, fallback)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, request, nil, nil)
+}
+
+func requestID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid swap request ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid swap request ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func sendSwapError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrSwapNotFound), errors.Is(err, employee.ErrEmployeeNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case errors.Is(err, ErrInvalidSwap), errors.Is(err, ErrInvalidType), errors.Is(err, ErrMissingCover):
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case errors.Is(err, ErrAlreadyRequested), errors.Is(err, ErrNotEligible), errors.Is(err, ErrInvalidStatus):
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	case errors.Is(err, ErrNotParticipant):
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
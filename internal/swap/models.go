@@ -0,0 +1,59 @@
+package swap
+
+import (
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	TypeDirect = "direct" // Offered to a named colleague, optionally in exchange for one of theirs
+	TypeOpen   = "open"   // Offered to any eligible colleague
+)
+
+const (
+	StatusOpen      = "open"     // Open request waiting for a volunteer
+	StatusPending   = "pending"  // Direct request waiting for the colleague's answer
+	StatusAccepted  = "accepted" // Waiting for manager approval
+	StatusApproved  = "approved" // Applied to the shift assignments
+	StatusRejected  = "rejected"
+	StatusDeclined  = "declined" // The colleague turned a direct request down
+	StatusCancelled = "cancelled"
+)
+
+// SwapRequest asks to hand one occurrence of an assigned shift over to a
+// colleague. A direct request may take one of the colleague's occurrences in
+// return; otherwise the colleague simply covers the shift.
+type SwapRequest struct {
+	gorm.Model
+	Type         string    `gorm:"not null" json:"type"`
+	Status       string    `gorm:"not null;index" json:"status"`
+	RequesterID  uint      `gorm:"not null;index" json:"requester_id"` // Employee giving the shift away
+	AssignmentID uint      `gorm:"not null" json:"assignment_id"`      // Requester's EmployeeShift
+	ShiftID      uint      `gorm:"not null" json:"shift_id"`
+	Date         time.Time `gorm:"type:date;not null" json:"date"`
+	Reason       string    `json:"reason"`
+
+	CoverID               *uint          `gorm:"index" json:"cover_id"`        // Colleague asked, or who volunteered
+	ReturnAssignmentID    *uint          `json:"return_assignment_id"`         // Colleague's EmployeeShift taken in exchange
+	ReturnShiftID         *uint          `json:"return_shift_id"`              // Set with ReturnAssignmentID
+	ReturnDate            utils.NullTime `gorm:"type:date" json:"return_date"` // Set with ReturnAssignmentID
+	ApproverID            *uint          `json:"approver_id"`                  // Requester's manager; nil means any manager
+	DecidedBy             *uint          `json:"decided_by"`                   // iam.User who approved or rejected
+	DecidedAt             utils.NullTime `json:"decided_at"`
+	DecisionNote          string         `json:"decision_note"`
+	CoverEmployeeShiftID  *uint          `json:"cover_employee_shift_id"`  // EmployeeShift created for the colleague on approval
+	ReturnEmployeeShiftID *uint          `json:"return_employee_shift_id"` // EmployeeShift created for the requester on approval
+
+	Events []SwapEvent `gorm:"foreignkey:SwapRequestID" json:"events,omitempty"`
+}
+
+// SwapEvent is the audit trail of a swap request: who did what, and when
+type SwapEvent struct {
+	gorm.Model
+	SwapRequestID uint   `gorm:"not null;index" json:"swap_request_id"`
+	Action        string `gorm:"not null" json:"action"` // requested, accepted, declined, volunteered, approved, rejected, cancelled
+	ActorID       uint   `json:"actor_id"`               // iam.User who acted
+	Note          string `json:"note"`
+}
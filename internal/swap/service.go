@@ -0,0 +1,643 @@
+// internal/swap/service.go
+package swap
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/leave"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/rotation"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrSwapNotFound     = errors.New("swap request not found")
+	ErrInvalidSwap      = errors.New("swap needs a shift assigned on a future date")
+	ErrInvalidType      = errors.New("swap type must be direct or open")
+	ErrMissingCover     = errors.New("direct swap needs a colleague other than the requester")
+	ErrAlreadyRequested = errors.New("shift already has an active swap request")
+	ErrNotEligible      = errors.New("colleague is not eligible for the shift")
+	ErrNotParticipant   = errors.New("not allowed to act on this swap request")
+	ErrInvalidStatus    = errors.New("swap request can no longer be changed")
+)
+
+type SwapService interface {
+	CreateRequest(user *iam.User, request SwapRequest) (*SwapRequest, error)
+	GetRequests(employeeID uint) ([]SwapRequest, error)
+	GetOpenRequests(user *iam.User) ([]SwapRequest, error)
+	GetPendingRequests(approver *iam.User) ([]SwapRequest, error)
+	GetRequest(id uint, user *iam.User) (*SwapRequest, error)
+
+	Accept(id uint, user *iam.User, note string) (*SwapRequest, error)
+	Decline(id uint, user *iam.User, note string) (*SwapRequest, error)
+	Volunteer(id uint, user *iam.User, note string) (*SwapRequest, error)
+	Approve(id uint, approver *iam.User, note string) (*SwapRequest, error)
+	Reject(id uint, approver *iam.User, note string) (*SwapRequest, error)
+	Cancel(id uint, user *iam.User) (*SwapRequest, error)
+}
+
+type swapService struct {
+	db            *gorm.DB
+	employees     employee.EmployeeService
+	notifications notification.NotificationService
+}
+
+func NewSwapService(db *gorm.DB, employees employee.EmployeeService, notifications notification.NotificationService) SwapService {
+	return &swapService{db: db, employees: employees, notifications: notifications}
+}
+
+// CreateRequest offers a shift occurrence of the requester, who defaults to
+// the user's employee. Direct requests are checked for eligibility straight
+// away and go to the colleague; open requests wait for a volunteer.
+func (s *swapService) CreateRequest(user *iam.User, request SwapRequest) (*SwapRequest, error) {
+	if request.RequesterID == 0 {
+		request.RequesterID = user.EmployeeID
+	}
+	if !iam.CanAccessEmployee(user, request.RequesterID) {
+		return nil, ErrNotParticipant
+	}
+	request.Date = dateOf(request.Date)
+
+	assignment, err := s.occurrence(request.AssignmentID, request.RequesterID, request.Date)
+	if err != nil {
+		return nil, err
+	}
+	request.ShiftID = assignment.ShiftID
+
+	switch request.Type {
+	case TypeOpen:
+		request.Status = StatusOpen
+		request.CoverID, request.ReturnAssignmentID, request.ReturnShiftID = nil, nil, nil
+		request.ReturnDate = utils.NullTime{}
+	case TypeDirect:
+		request.Status = StatusPending
+		if request.CoverID == nil || *request.CoverID == request.RequesterID {
+			return nil, ErrMissingCover
+		}
+		if request.ReturnAssignmentID != nil {
+			request.ReturnDate.Time = dateOf(request.ReturnDate.Time)
+			returned, err := s.occurrence(*request.ReturnAssignmentID, *request.CoverID, request.ReturnDate.Time)
+			if err != nil {
+				return nil, err
+			}
+			request.ReturnShiftID = &returned.ShiftID
+			request.ReturnDate.Valid = true
+		} else {
+			request.ReturnShiftID = nil
+			request.ReturnDate = utils.NullTime{}
+		}
+	default:
+		return nil, ErrInvalidType
+	}
+
+	var active int
+	s.db.Model(&SwapRequest{}).
+		Where("assignment_id = ? AND date = ? AND status IN (?)", request.AssignmentID, request.Date, []string{StatusOpen, StatusPending, StatusAccepted}).
+		Count(&active)
+	if active > 0 {
+		return nil, ErrAlreadyRequested
+	}
+
+	requester, err := s.employee(request.RequesterID)
+	if err != nil {
+		return nil, err
+	}
+	request.ApproverID = requester.ManagerID
+
+	if request.Type == TypeDirect {
+		if err := s.checkEligibility(&request); err != nil {
+			return nil, err
+		}
+	}
+
+	request.ID = 0
+	request.DecidedBy, request.DecidedAt, request.DecisionNote = nil, utils.NullTime{}, ""
+	request.CoverEmployeeShiftID, request.ReturnEmployeeShiftID = nil, nil
+	request.Events = nil
+	if err := s.transition(&request, user, "requested", request.Reason); err != nil {
+		return nil, err
+	}
+
+	if request.Type == TypeDirect {
+		s.notifications.Notify(*request.CoverID, "swap_request",
+			fmt.Sprintf("%s asked you to take over a shift", requester.Name),
+			s.describe(&request))
+	}
+	return &request, nil
+}
+
+// GetRequests returns the swap requests an employee made or was asked to cover
+func (s *swapService) GetRequests(employeeID uint) ([]SwapRequest, error) {
+	var requests []SwapRequest
+	err := s.db.Where("requester_id = ? OR cover_id = ?", employeeID, employeeID).
+		Order("date DESC").
+		Find(&requests).Error
+	if err != nil {
+		log.Printf("Error fetching swap requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// GetOpenRequests returns the upcoming open requests of colleagues with the
+// user's designation, i.e. the shifts the user could pick up
+func (s *swapService) GetOpenRequests(user *iam.User) ([]SwapRequest, error) {
+	emp, err := s.employee(user.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []SwapRequest
+	err = s.db.Joins("JOIN employees ON employees.id = swap_requests.requester_id").
+		Where("swap_requests.status = ? AND swap_requests.date >= ? AND swap_requests.requester_id <> ?", StatusOpen, today(), emp.ID).
+		Where("LOWER(employees.designation) = ?", strings.ToLower(emp.Designation)).
+		Order("swap_requests.date").
+		Find(&requests).Error
+	if err != nil {
+		log.Printf("Error fetching open swap requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// GetPendingRequests returns the accepted requests the user is expected to
+// decide on. Admins see every one.
+func (s *swapService) GetPendingRequests(approver *iam.User) ([]SwapRequest, error) {
+	var requests []SwapRequest
+	query := s.db.Where("status = ?", StatusAccepted)
+	if approver.Role != iam.RoleAdmin {
+		query = query.Where("approver_id = ? OR approver_id IS NULL", approver.EmployeeID)
+	}
+
+	if err := query.Order("date").Find(&requests).Error; err != nil {
+		log.Printf("Error fetching pending swap requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// GetRequest returns a request with its audit trail. Participants and
+// managers may see it.
+func (s *swapService) GetRequest(id uint, user *iam.User) (*SwapRequest, error) {
+	request, err := s.request(s.db.Preload("Events", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}), id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsPrivileged() && !participant(request, user.EmployeeID) {
+		return nil, ErrNotParticipant
+	}
+	return request, nil
+}
+
+// Accept is the colleague's agreement to a direct request. The swap is
+// checked again and handed to the manager for approval.
+func (s *swapService) Accept(id uint, user *iam.User, note string) (*SwapRequest, error) {
+	request, err := s.request(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusPending {
+		return nil, ErrInvalidStatus
+	}
+	if request.CoverID == nil || *request.CoverID != user.EmployeeID {
+		return nil, ErrNotParticipant
+	}
+	if err := s.checkEligibility(request); err != nil {
+		return nil, err
+	}
+
+	request.Status = StatusAccepted
+	if err := s.transition(request, user, "accepted", note); err != nil {
+		return nil, err
+	}
+	s.notifyAccepted(request)
+	return request, nil
+}
+
+// Decline is the colleague's refusal of a direct request
+func (s *swapService) Decline(id uint, user *iam.User, note string) (*SwapRequest, error) {
+	request, err := s.request(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusPending {
+		return nil, ErrInvalidStatus
+	}
+	if request.CoverID == nil || *request.CoverID != user.EmployeeID {
+		return nil, ErrNotParticipant
+	}
+
+	request.Status = StatusDeclined
+	if err := s.transition(request, user, "declined", note); err != nil {
+		return nil, err
+	}
+	s.notifications.Notify(request.RequesterID, "swap_declined",
+		"Your shift swap request was declined", s.describe(request)+note)
+	return request, nil
+}
+
+// Volunteer takes up an open request for the user's employee when they are
+// eligible, and hands it to the manager for approval
+func (s *swapService) Volunteer(id uint, user *iam.User, note string) (*SwapRequest, error) {
+	request, err := s.request(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusOpen {
+		return nil, ErrInvalidStatus
+	}
+	if request.RequesterID == user.EmployeeID {
+		return nil, ErrNotParticipant
+	}
+
+	request.CoverID = &user.EmployeeID
+	if err := s.checkEligibility(request); err != nil {
+		return nil, err
+	}
+
+	// Only the first volunteer wins the request
+	result := s.db.Model(&SwapRequest{}).
+		Where("id = ? AND status = ?", request.ID, StatusOpen).
+		Updates(map[string]interface{}{"status": StatusAccepted, "cover_id": user.EmployeeID})
+	if result.Error != nil {
+		log.Printf("Error volunteering for swap request: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidStatus
+	}
+
+	request.Status = StatusAccepted
+	if err := s.transition(request, user, "volunteered", note); err != nil {
+		return nil, err
+	}
+	s.notifyAccepted(request)
+	return request, nil
+}
+
+// Approve applies an accepted request: in one transaction the occurrences
+// change hands, rotations stop regenerating them for their former owners and
+// the request records the new assignments
+func (s *swapService) Approve(id uint, approver *iam.User, note string) (*SwapRequest, error) {
+	request, err := s.acceptedRequestFor(id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+
+	originals, created, err := s.apply(tx, request)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for i, original := range originals {
+		if err := skipRotationDay(tx, original, created[i].StartDate, request.ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	request.CoverEmployeeShiftID = &created[0].ID
+	if len(created) > 1 {
+		request.ReturnEmployeeShiftID = &created[1].ID
+	}
+	s.decide(request, approver, StatusApproved, note)
+	if err := s.transitionIn(tx, request, approver, "approved", note); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+
+	s.notifyDecision(request, "approved", note)
+	return request, nil
+}
+
+func (s *swapService) Reject(id uint, approver *iam.User, note string) (*SwapRequest, error) {
+	request, err := s.acceptedRequestFor(id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	s.decide(request, approver, StatusRejected, note)
+	if err := s.transition(request, approver, "rejected", note); err != nil {
+		return nil, err
+	}
+
+	s.notifyDecision(request, "rejected", note)
+	return request, nil
+}
+
+// Cancel withdraws a request that has not been decided yet
+func (s *swapService) Cancel(id uint, user *iam.User) (*SwapRequest, error) {
+	request, err := s.request(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if !iam.CanAccessEmployee(user, request.RequesterID) {
+		return nil, ErrNotParticipant
+	}
+	if request.Status != StatusOpen && request.Status != StatusPending && request.Status != StatusAccepted {
+		return nil, ErrInvalidStatus
+	}
+
+	request.Status = StatusCancelled
+	if err := s.transition(request, user, "cancelled", ""); err != nil {
+		return nil, err
+	}
+
+	if request.CoverID != nil {
+		s.notifications.Notify(*request.CoverID, "swap_cancelled",
+			"A shift swap request was withdrawn", s.describe(request))
+	}
+	return request, nil
+}
+
+// checkEligibility applies the swap in a transaction that is always rolled
+// back, so every check of a real approval runs without changing anything
+func (s *swapService) checkEligibility(request *SwapRequest) error {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return tx.Error
+	}
+	defer tx.Rollback()
+
+	_, _, err := s.apply(tx, request)
+	return err
+}
+
+// apply moves the occurrences of a swap to their new owners within tx after
+// checking the colleagues may work them: same designation, not on leave, and
+// through ReassignOccurrences every assignment guard, working time rules
+// included. It returns the original assignments and the ones created, cover
+// first.
+func (s *swapService) apply(tx *gorm.DB, request *SwapRequest) ([]employee.EmployeeShift, []employee.EmployeeShift, error) {
+	if request.CoverID == nil {
+		return nil, nil, ErrMissingCover
+	}
+	requester, err := s.employee(request.RequesterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	cover, err := s.employee(*request.CoverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.EqualFold(requester.Designation, cover.Designation) {
+		return nil, nil, fmt.Errorf("%w: %s cannot cover a %s shift", ErrNotEligible, cover.Designation, requester.Designation)
+	}
+
+	original, err := s.occurrence(request.AssignmentID, request.RequesterID, request.Date)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkLeave(cover, request.Date); err != nil {
+		return nil, nil, err
+	}
+	originals := []employee.EmployeeShift{*original}
+	reassignments := []employee.Reassignment{{
+		AssignmentID: request.AssignmentID,
+		Date:         request.Date,
+		EmployeeID:   cover.ID,
+		Source:       employee.AssignmentSourceSwap,
+	}}
+
+	if request.ReturnAssignmentID != nil {
+		returned, err := s.occurrence(*request.ReturnAssignmentID, cover.ID, request.ReturnDate.Time)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.checkLeave(requester, request.ReturnDate.Time); err != nil {
+			return nil, nil, err
+		}
+		originals = append(originals, *returned)
+		reassignments = append(reassignments, employee.Reassignment{
+			AssignmentID: *request.ReturnAssignmentID,
+			Date:         request.ReturnDate.Time,
+			EmployeeID:   requester.ID,
+			Source:       employee.AssignmentSourceSwap,
+		})
+	}
+
+	created, err := s.employees.ReassignOccurrences(tx, reassignments)
+	if err != nil {
+		if errors.Is(err, employee.ErrAssignmentRejected) {
+			return nil, nil, fmt.Errorf("%w: %v", ErrNotEligible, err)
+		}
+		if errors.Is(err, employee.ErrOccurrenceNotFound) {
+			return nil, nil, ErrInvalidSwap
+		}
+		return nil, nil, err
+	}
+	return originals, created, nil
+}
+
+// checkLeave rejects a colleague with approved leave on the date
+func (s *swapService) checkLeave(emp *employee.Employee, date time.Time) error {
+	var onLeave int
+	s.db.Model(&leave.LeaveRequest{}).
+		Where("employee_id = ? AND status = ? AND start_date <= ? AND end_date >= ?", emp.ID, leave.StatusApproved, date, date).
+		Count(&onLeave)
+	if onLeave > 0 {
+		return fmt.Errorf("%w: %s is on leave on %s", ErrNotEligible, emp.Name, date.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// skipRotationDay records a rotation override taking the former owner off a
+// rotation-generated occurrence, so regenerating the rotation keeps the swap
+func skipRotationDay(tx *gorm.DB, original employee.EmployeeShift, date time.Time, requestID uint) error {
+	if original.RotationAssignmentID == nil {
+		return nil
+	}
+
+	override := rotation.RotationOverride{
+		AssignmentID: *original.RotationAssignmentID,
+		EmployeeID:   original.EmployeeID,
+		Date:         date,
+	}
+	err := tx.Where(override).
+		Assign(map[string]interface{}{"shift_id": nil, "reason": fmt.Sprintf("Shift swap #%d", requestID)}).
+		FirstOrCreate(&override).Error
+	if err != nil {
+		log.Printf("Error recording rotation override for swap: %v", err)
+	}
+	return err
+}
+
+// occurrence loads an assignment and checks it belongs to the employee and
+// puts them on shift on the date, which must not be in the past
+func (s *swapService) occurrence(assignmentID, employeeID uint, date time.Time) (*employee.EmployeeShift, error) {
+	var assignment employee.EmployeeShift
+	if err := s.db.Preload("Shift").First(&assignment, assignmentID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrInvalidSwap
+		}
+		log.Printf("Error fetching shift assignment: %v", err)
+		return nil, err
+	}
+
+	if assignment.EmployeeID != employeeID || date.Before(today()) ||
+		date.Before(dateOf(assignment.StartDate)) || date.After(dateOf(assignment.EndDate)) {
+		return nil, ErrInvalidSwap
+	}
+	if _, ok := assignment.Shift.OccurrenceOn(date); !ok {
+		return nil, ErrInvalidSwap
+	}
+	return &assignment, nil
+}
+
+func (s *swapService) employee(id uint) (*employee.Employee, error) {
+	var emp employee.Employee
+	if err := s.db.First(&emp, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+	return &emp, nil
+}
+
+func (s *swapService) request(db *gorm.DB, id uint) (*SwapRequest, error) {
+	var request SwapRequest
+	if err := db.First(&request, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrSwapNotFound
+		}
+		log.Printf("Error fetching swap request: %v", err)
+		return nil, err
+	}
+	return &request, nil
+}
+
+// acceptedRequestFor loads an accepted request and checks the user may decide
+// on it: the requester's manager, or any admin. Requests without a manager
+// can be decided by any manager. Nobody decides on a swap they take part in.
+func (s *swapService) acceptedRequestFor(id uint, approver *iam.User) (*SwapRequest, error) {
+	request, err := s.request(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusAccepted {
+		return nil, ErrInvalidStatus
+	}
+	if participant(request, approver.EmployeeID) {
+		return nil, ErrNotParticipant
+	}
+
+	if approver.Role == iam.RoleAdmin {
+		return request, nil
+	}
+	isManager := request.ApproverID != nil && *request.ApproverID == approver.EmployeeID
+	isFallback := request.ApproverID == nil && approver.IsPrivileged()
+	if !isManager && !isFallback {
+		return nil, ErrNotParticipant
+	}
+	return request, nil
+}
+
+func (s *swapService) decide(request *SwapRequest, approver *iam.User, status, note string) {
+	request.Status = status
+	request.DecidedBy = &approver.ID
+	request.DecidedAt = utils.NullTime{NullTime: sql.NullTime{Time: time.Now().UTC(), Valid: true}}
+	request.DecisionNote = note
+}
+
+// transition saves a request together with the audit event of the change
+func (s *swapService) transition(request *SwapRequest, user *iam.User, action, note string) error {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return tx.Error
+	}
+	if err := s.transitionIn(tx, request, user, action, note); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *swapService) transitionIn(tx *gorm.DB, request *SwapRequest, user *iam.User, action, note string) error {
+	if err := tx.Omit("Events").Save(request).Error; err != nil {
+		log.Printf("Error saving swap request: %v", err)
+		return err
+	}
+
+	event := SwapEvent{SwapRequestID: request.ID, Action: action, ActorID: user.ID, Note: note}
+	if err := tx.Create(&event).Error; err != nil {
+		log.Printf("Error recording swap event: %v", err)
+		return err
+	}
+	return nil
+}
+
+// notifyAccepted tells the requester their shift found a taker and the
+// manager that the swap awaits approval
+func (s *swapService) notifyAccepted(request *SwapRequest) {
+	s.notifications.Notify(request.RequesterID, "swap_accepted",
+		"Your shift swap request was accepted", s.describe(request)+"It now awaits manager approval.")
+	if request.ApproverID != nil {
+		s.notifications.Notify(*request.ApproverID, "swap_approval",
+			"A shift swap awaits your approval", s.describe(request))
+	}
+}
+
+func (s *swapService) notifyDecision(request *SwapRequest, decision, note string) {
+	subject := fmt.Sprintf("A shift swap was %s", decision)
+	body := s.describe(request) + note
+	s.notifications.Notify(request.RequesterID, "swap_decision", subject, body)
+	if request.CoverID != nil {
+		s.notifications.Notify(*request.CoverID, "swap_decision", subject, body)
+	}
+}
+
+// describe summarizes the shifts a request trades for notifications
+func (s *swapService) describe(request *SwapRequest) string {
+	var shift employee.Shift
+	s.db.First(&shift, request.ShiftID)
+	description := fmt.Sprintf("%s on %s", shift.Name, request.Date.Format("2006-01-02"))
+
+	if request.ReturnShiftID != nil {
+		var returned employee.Shift
+		s.db.First(&returned, *request.ReturnShiftID)
+		description += fmt.Sprintf(" in exchange for %s on %s", returned.Name, request.ReturnDate.Time.Format("2006-01-02"))
+	}
+	return description + ". "
+}
+
+// participant reports whether the employee requested or covers the swap
+func participant(request *SwapRequest, employeeID uint) bool {
+	return request.RequesterID == employeeID || (request.CoverID != nil && *request.CoverID == employeeID)
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func today() time.Time {
+	return dateOf(time.Now().UTC())
+}
@@ -0,0 +1,293 @@
+package swap
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/testdb"
+	"clinicplus/internal/shared/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+// reassigner stands in for the employee service, recording the occurrences
+// swaps move and creating one assignment for each
+type reassigner struct {
+	employee.EmployeeService
+	calls  [][]employee.Reassignment
+	reject bool // Whether the assignment guards refuse the reassignments
+}
+
+func (r *reassigner) ReassignOccurrences(tx *gorm.DB, reassignments []employee.Reassignment) ([]employee.EmployeeShift, error) {
+	r.calls = append(r.calls, reassignments)
+	if r.reject {
+		return nil, fmt.Errorf("%w: exceeds the weekly hours", employee.ErrAssignmentRejected)
+	}
+	created := make([]employee.EmployeeShift, len(reassignments))
+	for i, reassignment := range reassignments {
+		created[i] = employee.EmployeeShift{EmployeeID: reassignment.EmployeeID, StartDate: reassignment.Date, EndDate: reassignment.Date}
+		created[i].ID = uint(200 + i)
+	}
+	return created, nil
+}
+
+var (
+	requester = &iam.User{Model: gorm.Model{ID: 50}, EmployeeID: 5, Role: iam.RoleEmployee}
+	colleague = &iam.User{Model: gorm.Model{ID: 60}, EmployeeID: 6, Role: iam.RoleEmployee}
+	manager   = &iam.User{Model: gorm.Model{ID: 80}, EmployeeID: 8, Role: iam.RoleManager}
+)
+
+func uintPtr(n uint) *uint { return &n }
+
+// staff scripts nurses 5 (managed by 8) and 6, doctor 7, and their
+// assignments 10, 11 and 12 to a shift running every day. Assignment 10 was
+// generated by a rotation when rotated is set.
+func staff(fake *testdb.DB, rotated bool) {
+	employeeColumns := []string{"id", "name", "designation", "manager_id"}
+	fake.Returns(`"employees"."id" = 5`, employeeColumns, []interface{}{5, "Ana", "Nurse", 8})
+	fake.Returns(`"employees"."id" = 6`, employeeColumns, []interface{}{6, "Ben", "nurse", 8})
+	fake.Returns(`"employees"."id" = 7`, employeeColumns, []interface{}{7, "Cas", "Doctor", 8})
+
+	var rotation interface{}
+	if rotated {
+		rotation = 3
+	}
+	assignmentColumns := []string{"id", "employee_id", "shift_id", "start_date", "end_date", "rotation_assignment_id"}
+	start, end := today().AddDate(0, 0, -7), today().AddDate(0, 0, 30)
+	fake.Returns(`"employee_shifts"."id" = 10`, assignmentColumns, []interface{}{10, 5, 1, start, end, rotation})
+	fake.Returns(`"employee_shifts"."id" = 11`, assignmentColumns, []interface{}{11, 6, 1, start, end, nil})
+	fake.Returns(`"employee_shifts"."id" = 12`, assignmentColumns, []interface{}{12, 7, 1, start, end, nil})
+	fake.Returns(`FROM "shifts"`, []string{"id", "name", "start_time", "end_time", "days_of_week"},
+		[]interface{}{1, "Day", "08:00", "16:00", "mon,tue,wed,thu,fri,sat,sun"})
+}
+
+func TestCreateRequest(t *testing.T) {
+	tomorrow := today().AddDate(0, 0, 1)
+	direct := func(coverID uint) SwapRequest {
+		return SwapRequest{Type: TypeDirect, AssignmentID: 10, Date: tomorrow, CoverID: &coverID}
+	}
+
+	tests := []struct {
+		name       string
+		user       *iam.User
+		request    SwapRequest
+		onLeave    bool // The colleague has approved leave on the date
+		active     bool // The occurrence already has an active request
+		reject     bool
+		wantErr    error
+		wantStatus string
+		wantCheck  []employee.Reassignment // Reassignments checked before saving
+	}{
+		{
+			name: "open", user: requester,
+			request:    SwapRequest{Type: TypeOpen, AssignmentID: 10, Date: tomorrow},
+			wantStatus: StatusOpen,
+		},
+		{
+			name: "direct", user: requester, request: direct(6),
+			wantStatus: StatusPending,
+			wantCheck:  []employee.Reassignment{{AssignmentID: 10, Date: tomorrow, EmployeeID: 6, Source: employee.AssignmentSourceSwap}},
+		},
+		{
+			name: "direct in exchange", user: requester,
+			request: SwapRequest{
+				Type: TypeDirect, AssignmentID: 10, Date: tomorrow, CoverID: uintPtr(6),
+				ReturnAssignmentID: uintPtr(11), ReturnDate: utils.NullTime{NullTime: sql.NullTime{Time: tomorrow.AddDate(0, 0, 1)}},
+			},
+			wantStatus: StatusPending,
+			wantCheck: []employee.Reassignment{
+				{AssignmentID: 10, Date: tomorrow, EmployeeID: 6, Source: employee.AssignmentSourceSwap},
+				{AssignmentID: 11, Date: tomorrow.AddDate(0, 0, 1), EmployeeID: 5, Source: employee.AssignmentSourceSwap},
+			},
+		},
+		{
+			name: "by a manager on behalf of the requester", user: manager,
+			request:    SwapRequest{Type: TypeOpen, RequesterID: 5, AssignmentID: 10, Date: tomorrow},
+			wantStatus: StatusOpen,
+		},
+		{name: "for someone else", user: colleague, request: SwapRequest{Type: TypeOpen, RequesterID: 5, AssignmentID: 10, Date: tomorrow}, wantErr: ErrNotParticipant},
+		{name: "of someone else's shift", user: colleague, request: SwapRequest{Type: TypeOpen, AssignmentID: 10, Date: tomorrow}, wantErr: ErrInvalidSwap},
+		{name: "in the past", user: requester, request: SwapRequest{Type: TypeOpen, AssignmentID: 10, Date: today().AddDate(0, 0, -1)}, wantErr: ErrInvalidSwap},
+		{name: "unknown type", user: requester, request: SwapRequest{Type: "trade", AssignmentID: 10, Date: tomorrow}, wantErr: ErrInvalidType},
+		{name: "direct to the requester", user: requester, request: direct(5), wantErr: ErrMissingCover},
+		{name: "already requested", user: requester, request: direct(6), active: true, wantErr: ErrAlreadyRequested},
+		{name: "to another designation", user: requester, request: direct(7), wantErr: ErrNotEligible},
+		{name: "to a colleague on leave", user: requester, request: direct(6), onLeave: true, wantErr: ErrNotEligible},
+		{
+			name: "refused by the assignment guards", user: requester, request: direct(6), reject: true, wantErr: ErrNotEligible,
+			wantCheck: []employee.Reassignment{{AssignmentID: 10, Date: tomorrow, EmployeeID: 6, Source: employee.AssignmentSourceSwap}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			staff(fake, false)
+			if tt.onLeave {
+				fake.Returns(`FROM "leave_requests"`, []string{"count"}, []interface{}{1})
+			}
+			if tt.active {
+				fake.Returns(`FROM "swap_requests"`, []string{"count"}, []interface{}{1})
+			}
+			employees := &reassigner{reject: tt.reject}
+			service := NewSwapService(db, employees, notification.NewNotificationService(db))
+
+			request, err := service.CreateRequest(tt.user, tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateRequest error %v, want %v", err, tt.wantErr)
+			}
+			var checked []employee.Reassignment
+			if len(employees.calls) > 0 {
+				checked = employees.calls[0]
+			}
+			if !reflect.DeepEqual(checked, tt.wantCheck) {
+				t.Errorf("checked %+v, want %+v", checked, tt.wantCheck)
+			}
+			if saved := fake.Ran(`INSERT INTO "swap_requests"`); saved != (tt.wantErr == nil) {
+				t.Errorf("request saved: %v", saved)
+			}
+			if err != nil {
+				return
+			}
+
+			if request.Status != tt.wantStatus || request.RequesterID != 5 || request.ShiftID != 1 || request.ApproverID == nil || *request.ApproverID != 8 {
+				t.Errorf("request saved as %+v", request)
+			}
+			if events := fake.Find(`INSERT INTO "swap_events"`); len(events) != 1 || !events[0].Has("requested") || !events[0].Has(int(tt.user.ID)) {
+				t.Errorf("events recorded: %+v", events)
+			}
+			notified := fake.Ran(`INSERT INTO "notifications"`)
+			if notified != (tt.request.Type == TypeDirect) {
+				t.Errorf("colleague notified: %v", notified)
+			}
+		})
+	}
+}
+
+func TestVolunteer(t *testing.T) {
+	tomorrow := today().AddDate(0, 0, 1)
+
+	tests := []struct {
+		name    string
+		user    *iam.User
+		status  string
+		taken   bool // Another volunteer took the request first
+		wantErr error
+	}{
+		{name: "eligible colleague", user: colleague, status: StatusOpen},
+		{name: "requester", user: requester, status: StatusOpen, wantErr: ErrNotParticipant},
+		{name: "another designation", user: &iam.User{Model: gorm.Model{ID: 70}, EmployeeID: 7}, status: StatusOpen, wantErr: ErrNotEligible},
+		{name: "direct request", user: colleague, status: StatusPending, wantErr: ErrInvalidStatus},
+		{name: "taken by another volunteer", user: colleague, status: StatusOpen, taken: true, wantErr: ErrInvalidStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			staff(fake, false)
+			fake.Returns(`FROM "swap_requests"`, []string{"id", "type", "status", "requester_id", "assignment_id", "shift_id", "date", "approver_id"},
+				[]interface{}{1, TypeOpen, tt.status, 5, 10, 1, tomorrow, 8})
+			if tt.taken {
+				fake.Affects(`UPDATE "swap_requests" SET "cover_id"`, 0)
+			}
+			service := NewSwapService(db, &reassigner{}, notification.NewNotificationService(db))
+
+			request, err := service.Volunteer(1, tt.user, "Happy to")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Volunteer error %v, want %v", err, tt.wantErr)
+			}
+			if recorded := fake.Ran(`INSERT INTO "swap_events"`); recorded != (tt.wantErr == nil) {
+				t.Errorf("event recorded: %v", recorded)
+			}
+			if err != nil {
+				return
+			}
+			if request.Status != StatusAccepted || request.CoverID == nil || *request.CoverID != 6 {
+				t.Errorf("request %s covered by %v", request.Status, request.CoverID)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 2 || !notifications[0].Has(5) || !notifications[1].Has(8) {
+				t.Errorf("notifications %+v, want the requester and the manager", notifications)
+			}
+		})
+	}
+}
+
+func TestApprove(t *testing.T) {
+	tomorrow := today().AddDate(0, 0, 1)
+
+	tests := []struct {
+		name     string
+		approver *iam.User
+		status   string
+		exchange bool
+		rotated  bool
+		wantErr  error
+	}{
+		{name: "cover", approver: manager, status: StatusAccepted},
+		{name: "exchange", approver: manager, status: StatusAccepted, exchange: true},
+		{name: "rotation shift", approver: manager, status: StatusAccepted, rotated: true},
+		{name: "by an admin", approver: &iam.User{Model: gorm.Model{ID: 90}, EmployeeID: 9, Role: iam.RoleAdmin}, status: StatusAccepted},
+		{name: "by another manager", approver: &iam.User{Model: gorm.Model{ID: 91}, EmployeeID: 9, Role: iam.RoleManager}, status: StatusAccepted, wantErr: ErrNotParticipant},
+		{name: "by a participant", approver: &iam.User{Model: gorm.Model{ID: 61}, EmployeeID: 6, Role: iam.RoleAdmin}, status: StatusAccepted, wantErr: ErrNotParticipant},
+		{name: "before the colleague accepted", approver: manager, status: StatusPending, wantErr: ErrInvalidStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			staff(fake, tt.rotated)
+			var returnAssignment, returnDate interface{}
+			if tt.exchange {
+				returnAssignment, returnDate = 11, tomorrow.AddDate(0, 0, 1)
+			}
+			fake.Returns(`FROM "swap_requests"`,
+				[]string{"id", "type", "status", "requester_id", "assignment_id", "shift_id", "date", "cover_id", "return_assignment_id", "return_shift_id", "return_date", "approver_id"},
+				[]interface{}{1, TypeDirect, tt.status, 5, 10, 1, tomorrow, 6, returnAssignment, returnAssignment, returnDate, 8})
+			employees := &reassigner{}
+			service := NewSwapService(db, employees, notification.NewNotificationService(db))
+
+			request, err := service.Approve(1, tt.approver, "Fine by me")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Approve error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(employees.calls) > 0 || fake.Ran(`INSERT INTO "swap_events"`) {
+					t.Errorf("refused swap applied: %+v", employees.calls)
+				}
+				return
+			}
+
+			want := []employee.Reassignment{{AssignmentID: 10, Date: tomorrow, EmployeeID: 6, Source: employee.AssignmentSourceSwap}}
+			if tt.exchange {
+				want = append(want, employee.Reassignment{AssignmentID: 11, Date: tomorrow.AddDate(0, 0, 1), EmployeeID: 5, Source: employee.AssignmentSourceSwap})
+			}
+			if len(employees.calls) != 1 || !reflect.DeepEqual(employees.calls[0], want) {
+				t.Errorf("reassigned %+v, want %+v", employees.calls, want)
+			}
+			if request.Status != StatusApproved || request.DecidedBy == nil || *request.DecidedBy != tt.approver.ID {
+				t.Errorf("request decided as %s by %v", request.Status, request.DecidedBy)
+			}
+			if request.CoverEmployeeShiftID == nil || *request.CoverEmployeeShiftID != 200 {
+				t.Errorf("cover assignment %v, want 200", request.CoverEmployeeShiftID)
+			}
+			if gotReturn := request.ReturnEmployeeShiftID != nil && *request.ReturnEmployeeShiftID == 201; gotReturn != tt.exchange {
+				t.Errorf("return assignment %v", request.ReturnEmployeeShiftID)
+			}
+			overrides := fake.Find(`INSERT INTO "rotation_overrides"`)
+			if skipped := len(overrides) == 1 && overrides[0].Has(3) && overrides[0].Has(5) && overrides[0].Has("Shift swap #1"); skipped != tt.rotated {
+				t.Errorf("rotation overrides %+v, want one taking employee 5 off: %v", overrides, tt.rotated)
+			}
+			if events := fake.Find(`INSERT INTO "swap_events"`); len(events) != 1 || !events[0].Has("approved") {
+				t.Errorf("events recorded: %+v", events)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 2 || !notifications[0].Has(5) || !notifications[1].Has(6) {
+				t.Errorf("notifications %+v, want the requester and the colleague", notifications)
+			}
+		})
+	}
+}
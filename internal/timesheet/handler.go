@@ -0,0 +1,216 @@
+// internal/timesheet/handler.go
+package timesheet
+
+import (
+	"bytes"
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type TimesheetHandler struct {
+	service TimesheetService
+}
+
+func NewTimesheetHandler(service TimesheetService) *TimesheetHandler {
+	return &TimesheetHandler{service: service}
+}
+
+// GenerateTimesheet creates or brings up to date the timesheet of the
+// user's employee, or of employee_id for managers. The period defaults to
+// weekly and the start date to this week.
+func (h *TimesheetHandler) GenerateTimesheet(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		EmployeeID uint   `json:"employee_id"`
+		Period     string `json:"period"`
+		StartDate  string `json:"start_date"` // YYYY-MM-DD; any day of the first week
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("Error decoding request body: %v", err)
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+			return
+		}
+	}
+	user, _ := iam.UserFromContext(r.Context())
+	if body.EmployeeID == 0 {
+		body.EmployeeID = user.EmployeeID
+	}
+	if body.Period == "" {
+		body.Period = PeriodWeekly
+	}
+	start := time.Now().UTC()
+	if body.StartDate != "" {
+		var err error
+		if start, err = time.Parse("2006-01-02", body.StartDate); err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid start date, expected YYYY-MM-DD", nil)
+			return
+		}
+	}
+	if !iam.CanAccessEmployee(user, body.EmployeeID) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's timesheets", nil)
+		return
+	}
+
+	timesheet, err := h.service.GenerateTimesheet(body.EmployeeID, body.Period, start)
+	if err != nil {
+		sendTimesheetError(w, err, "Failed to generate timesheet")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, timesheet, nil, nil)
+}
+
+// GetTimesheets lists the user's timesheets, or an employee's for managers
+// passing employee_id
+func (h *TimesheetHandler) GetTimesheets(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+	employeeID := user.EmployeeID
+	if raw := r.URL.Query().Get("employee_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid employee ID", nil)
+			return
+		}
+		employeeID = uint(id)
+	}
+	if !iam.CanAccessEmployee(user, employeeID) {
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, "Not allowed to access this employee's timesheets", nil)
+		return
+	}
+
+	timesheets, err := h.service.GetTimesheets(employeeID)
+	if err != nil {
+		sendTimesheetError(w, err, "Failed to retrieve timesheets")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, timesheets, nil, nil)
+}
+
+func (h *TimesheetHandler) GetPendingTimesheets(w http.ResponseWriter, r *http.Request) {
+	user, _ := iam.UserFromContext(r.Context())
+
+	timesheets, err := h.service.GetPendingTimesheets(user)
+	if err != nil {
+		sendTimesheetError(w, err, "Failed to retrieve pending timesheets")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, timesheets, nil, nil)
+}
+
+func (h *TimesheetHandler) GetTimesheet(w http.ResponseWriter, r *http.Request) {
+	id, ok := timesheetID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	timesheet, err := h.service.GetTimesheet(id, user)
+	if err != nil {
+		sendTimesheetError(w, err, "Failed to retrieve timesheet")
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, timesheet, nil, nil)
+}
+
+// ExportPDF downloads a timesheet as a PDF
+func (h *TimesheetHandler) ExportPDF(w http.ResponseWriter, r *http.Request) {
+	id, ok := timesheetID(w, r)
+	if !ok {
+		return
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	// Buffer the document so errors can still be reported as JSON
+	var buf bytes.Buffer
+	if err := h.service.WritePDF(id, user, &buf); err != nil {
+		sendTimesheetError(w, err, "Failed to export timesheet")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("timesheet-%d.pdf", id)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Error writing timesheet export %d: %v", id, err)
+	}
+}
+
+func (h *TimesheetHandler) Attest(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, h.service.Attest, "Failed to attest timesheet")
+}
+
+func (h *TimesheetHandler) SignOff(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, h.service.SignOff, "Failed to sign off timesheet")
+}
+
+func (h *TimesheetHandler) Reopen(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, h.service.Reopen, "Failed to reopen timesheet")
+}
+
+// act runs a step of the sign-off workflow that takes the timesheet ID and
+// an optional note
+func (h *TimesheetHandler) act(w http.ResponseWriter, r *http.Request, step func(uint, *iam.User, string) (*Timesheet, error), fallback string) {
+	id, ok := timesheetID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("Error decoding request body: %v", err)
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+			return
+		}
+	}
+	user, _ := iam.UserFromContext(r.Context())
+
+	timesheet, err := step(id, user, body.Note)
+	if err != nil {
+		sendTimesheetError(w, err, fallback)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, timesheet, nil, nil)
+}
+
+func timesheetID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		log.Printf("Invalid timesheet ID: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid timesheet ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func sendTimesheetError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTimesheetNotFound), errors.Is(err, employee.ErrEmployeeNotFound):
+		utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+	case errors.Is(err, ErrInvalidPeriod):
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+	case errors.Is(err, ErrPeriodNotEnded), errors.Is(err, ErrOpenRecords), errors.Is(err, ErrInvalidStatus), errors.Is(err, ErrChanged):
+		utils.SendJSONResponse(w, http.StatusConflict, nil, err.Error(), nil)
+	case errors.Is(err, ErrNotAllowed):
+		utils.SendJSONResponse(w, http.StatusForbidden, nil, err.Error(), nil)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, fallback, nil)
+	}
+}
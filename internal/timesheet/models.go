@@ -0,0 +1,86 @@
+package timesheet
+
+import (
+	"clinicplus/internal/shared/utils"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	PeriodWeekly   = "weekly"
+	PeriodBiweekly = "biweekly"
+)
+
+const (
+	StatusOpen      = "open"       // Generated, waiting for the employee's attestation
+	StatusAttested  = "attested"   // Confirmed by the employee, waiting for manager sign-off
+	StatusSignedOff = "signed_off" // Signed off; its attendance records are locked
+)
+
+// Policy sets how attendance is classified on timesheets
+type Policy struct {
+	OvertimeThreshold time.Duration // Weekly worked time after which overtime starts
+	ClockInGrace      time.Duration // Clock-ins later than this after the start count as late
+}
+
+// Timesheet sums up an employee's scheduled and worked time over a week or
+// two, starting on a Monday. The days are recomputed from attendance and
+// shift assignments until the timesheet is signed off.
+type Timesheet struct {
+	gorm.Model
+	EmployeeID   uint      `gorm:"not null;index" json:"employee_id"`
+	EmployeeName string    `gorm:"-" json:"employee_name"`
+	Period       string    `gorm:"not null" json:"period"`
+	StartDate    time.Time `gorm:"type:date;not null" json:"start_date"`
+	EndDate      time.Time `gorm:"type:date;not null" json:"end_date"`
+	Status       string    `gorm:"not null;index" json:"status"`
+
+	ScheduledMinutes  int `json:"scheduled_minutes"`
+	WorkedMinutes     int `json:"worked_minutes"`
+	BreakMinutes      int `json:"break_minutes"`
+	OvertimeMinutes   int `json:"overtime_minutes"`
+	LateMinutes       int `json:"late_minutes"`
+	EarlyLeaveMinutes int `json:"early_leave_minutes"`
+	AbsentDays        int `json:"absent_days"`
+	LeaveDays         int `json:"leave_days"`
+	ManualEntries     int `json:"manual_entries"` // Days with corrected attendance
+	OpenRecords       int `json:"open_records"`   // Records without a clock-out, which block attestation
+
+	Digest          string         `json:"-"`           // Hash of the days, to tell when an attested timesheet changed
+	AttestedBy      *uint          `json:"attested_by"` // iam.User who attested
+	AttestedAt      utils.NullTime `json:"attested_at"`
+	AttestationNote string         `json:"attestation_note"`
+	ApproverID      *uint          `json:"approver_id"`   // Employee's manager; nil means any manager
+	SignedOffBy     *uint          `json:"signed_off_by"` // iam.User who signed off
+	SignedOffAt     utils.NullTime `json:"signed_off_at"`
+	SignOffNote     string         `json:"sign_off_note"`
+
+	Days []TimesheetDay `gorm:"foreignkey:TimesheetID" json:"days"`
+}
+
+// TimesheetDay is one calendar day of a timesheet. Times of day are in the
+// shift's time zone.
+type TimesheetDay struct {
+	gorm.Model
+	TimesheetID uint      `gorm:"not null;index" json:"timesheet_id"`
+	Date        time.Time `gorm:"type:date;not null" json:"date"`
+	Shifts      string    `json:"shifts"`    // Names of the shifts scheduled or worked
+	ClockIn     string    `json:"clock_in"`  // First clock-in, HH:MM
+	ClockOut    string    `json:"clock_out"` // Last clock-out, HH:MM
+
+	ScheduledMinutes     int `json:"scheduled_minutes"` // Paid length of the assigned shifts
+	WorkedMinutes        int `json:"worked_minutes"`    // Clocked time less unpaid and deducted breaks
+	BreakMinutes         int `json:"break_minutes"`     // Paid and unpaid breaks taken
+	UnpaidBreakMinutes   int `json:"unpaid_break_minutes"`
+	DeductedBreakMinutes int `json:"deducted_break_minutes"`
+	OvertimeMinutes      int `json:"overtime_minutes"`
+	LateMinutes          int `json:"late_minutes"`
+	EarlyLeaveMinutes    int `json:"early_leave_minutes"`
+
+	Absent          bool   `json:"absent"`
+	OnLeave         bool   `json:"on_leave"`
+	ManualEntry     bool   `json:"manual_entry"` // Attendance entered or changed by a correction
+	MissingClockOut bool   `json:"missing_clock_out"`
+	BreakViolation  string `json:"break_violation,omitempty"`
+}
@@ -0,0 +1,676 @@
+// internal/timesheet/service.go
+package timesheet
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/config"
+	"clinicplus/internal/shared/utils"
+	"clinicplus/pkg/pdf"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrTimesheetNotFound = errors.New("timesheet not found")
+	ErrInvalidPeriod     = errors.New("period must be weekly or biweekly")
+	ErrPeriodNotEnded    = errors.New("timesheet period has not ended yet")
+	ErrOpenRecords       = errors.New("timesheet has attendance records without a clock-out; correct them first")
+	ErrInvalidStatus     = errors.New("timesheet cannot be changed in its current status")
+	ErrChanged           = errors.New("timesheet changed since it was attested; the employee must attest it again")
+	ErrNotAllowed        = errors.New("not allowed to act on this timesheet")
+)
+
+// periodDays is the length of each kind of period
+var periodDays = map[string]int{PeriodWeekly: 7, PeriodBiweekly: 14}
+
+type TimesheetService interface {
+	GenerateTimesheet(employeeID uint, period string, start time.Time) (*Timesheet, error)
+	GetTimesheets(employeeID uint) ([]Timesheet, error)
+	GetPendingTimesheets(approver *iam.User) ([]Timesheet, error)
+	GetTimesheet(id uint, user *iam.User) (*Timesheet, error)
+	Attest(id uint, user *iam.User, note string) (*Timesheet, error)
+	SignOff(id uint, approver *iam.User, note string) (*Timesheet, error)
+	Reopen(id uint, user *iam.User, note string) (*Timesheet, error)
+	WritePDF(id uint, user *iam.User, w io.Writer) error
+}
+
+type timesheetService struct {
+	db            *gorm.DB
+	notifications notification.NotificationService
+	policy        Policy
+}
+
+func NewTimesheetService(db *gorm.DB, notifications notification.NotificationService, policy Policy) TimesheetService {
+	return &timesheetService{db: db, notifications: notifications, policy: policy}
+}
+
+// PolicyFromConfig builds the policy from the payroll overtime threshold and
+// the clock-in grace period
+func PolicyFromConfig() Policy {
+	return Policy{
+		OvertimeThreshold: time.Duration(config.GetPayrollOvertimeThreshold() * float64(time.Hour)),
+		ClockInGrace:      time.Duration(config.GetClockInGraceMinutes()) * time.Minute,
+	}
+}
+
+// SignedOff reports whether a signed-off timesheet of the employee covers
+// any day from from to to, so no attendance may be added or removed then
+func SignedOff(db *gorm.DB, employeeID uint, from, to time.Time) (bool, error) {
+	var count int
+	err := db.Model(&Timesheet{}).
+		Where("employee_id = ? AND status = ? AND start_date <= ? AND end_date >= ?", employeeID, StatusSignedOff, to, from).
+		Count(&count).Error
+	if err != nil {
+		log.Printf("Error checking signed-off timesheets: %v", err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GenerateTimesheet returns the employee's timesheet for the week or two
+// starting on the Monday of start's week, creating it or bringing it up to
+// date with attendance. Signed-off timesheets are returned as they are.
+func (s *timesheetService) GenerateTimesheet(employeeID uint, period string, start time.Time) (*Timesheet, error) {
+	length, ok := periodDays[period]
+	if !ok {
+		return nil, ErrInvalidPeriod
+	}
+	start = monday(dateOf(start))
+
+	emp, err := s.employee(employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var timesheet Timesheet
+	err = s.db.Where("employee_id = ? AND period = ? AND start_date = ?", employeeID, period, start).First(&timesheet).Error
+	switch {
+	case gorm.IsRecordNotFoundError(err):
+		timesheet = Timesheet{
+			EmployeeID: employeeID,
+			Period:     period,
+			StartDate:  start,
+			EndDate:    start.AddDate(0, 0, length-1),
+			Status:     StatusOpen,
+		}
+	case err != nil:
+		log.Printf("Error fetching timesheet: %v", err)
+		return nil, err
+	}
+
+	if timesheet.Status != StatusSignedOff {
+		timesheet.ApproverID = emp.ManagerID
+		tx := s.db.Begin()
+		if tx.Error != nil {
+			log.Printf("Error starting transaction: %v", tx.Error)
+			return nil, tx.Error
+		}
+		if err := s.refresh(tx, &timesheet); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			log.Printf("Error committing transaction: %v", err)
+			return nil, err
+		}
+	}
+	return s.timesheet(timesheet.ID)
+}
+
+// GetTimesheets returns an employee's timesheets without their days, latest first
+func (s *timesheetService) GetTimesheets(employeeID uint) ([]Timesheet, error) {
+	var timesheets []Timesheet
+	if err := s.db.Where("employee_id = ?", employeeID).Order("start_date DESC").Find(&timesheets).Error; err != nil {
+		log.Printf("Error fetching timesheets: %v", err)
+		return nil, err
+	}
+	return timesheets, nil
+}
+
+// GetPendingTimesheets returns the attested timesheets the user is expected
+// to sign off. Admins see every one.
+func (s *timesheetService) GetPendingTimesheets(approver *iam.User) ([]Timesheet, error) {
+	var timesheets []Timesheet
+	query := s.db.Where("status = ?", StatusAttested)
+	if approver.Role != iam.RoleAdmin {
+		query = query.Where("approver_id = ? OR approver_id IS NULL", approver.EmployeeID)
+	}
+
+	if err := query.Order("start_date").Find(&timesheets).Error; err != nil {
+		log.Printf("Error fetching pending timesheets: %v", err)
+		return nil, err
+	}
+	return timesheets, nil
+}
+
+// GetTimesheet returns a timesheet with its days to the employee or a manager
+func (s *timesheetService) GetTimesheet(id uint, user *iam.User) (*Timesheet, error) {
+	timesheet, err := s.timesheet(id)
+	if err != nil {
+		return nil, err
+	}
+	if !iam.CanAccessEmployee(user, timesheet.EmployeeID) {
+		return nil, ErrNotAllowed
+	}
+	return timesheet, nil
+}
+
+// Attest is the employee's confirmation of their timesheet once its period
+// is over. It is brought up to date first, and every record must be clocked
+// out.
+func (s *timesheetService) Attest(id uint, user *iam.User, note string) (*Timesheet, error) {
+	timesheet, err := s.timesheet(id)
+	if err != nil {
+		return nil, err
+	}
+	if timesheet.EmployeeID != user.EmployeeID {
+		return nil, ErrNotAllowed
+	}
+	if timesheet.Status != StatusOpen {
+		return nil, ErrInvalidStatus
+	}
+	if !timesheet.EndDate.Before(today()) {
+		return nil, ErrPeriodNotEnded
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+	if err := s.refresh(tx, timesheet); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if timesheet.OpenRecords > 0 {
+		tx.Rollback()
+		return nil, ErrOpenRecords
+	}
+
+	timesheet.Status = StatusAttested
+	timesheet.AttestedBy = &user.ID
+	timesheet.AttestedAt = now()
+	timesheet.AttestationNote = note
+	if err := s.save(tx, timesheet); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+
+	if timesheet.ApproverID != nil {
+		s.notifications.Notify(*timesheet.ApproverID, "timesheet_attested",
+			fmt.Sprintf("%s attested their timesheet", timesheet.EmployeeName),
+			s.describe(timesheet)+"It awaits your sign-off. "+note)
+	}
+	return timesheet, nil
+}
+
+// SignOff approves an attested timesheet and locks its attendance records
+// against further changes. A timesheet whose days changed since it was
+// attested goes back to the employee instead.
+func (s *timesheetService) SignOff(id uint, approver *iam.User, note string) (*Timesheet, error) {
+	timesheet, err := s.attestedTimesheetFor(id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+	if err := s.refresh(tx, timesheet); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if timesheet.Status != StatusAttested {
+		if err := tx.Commit().Error; err != nil {
+			log.Printf("Error committing transaction: %v", err)
+			return nil, err
+		}
+		s.notifications.Notify(timesheet.EmployeeID, "timesheet_changed",
+			"Your timesheet changed and needs attesting again", s.describe(timesheet))
+		return nil, ErrChanged
+	}
+
+	// Records already locked by an overlapping timesheet stay with it
+	err = tx.Model(&employee.Attendance{}).
+		Where("employee_id = ? AND date BETWEEN ? AND ? AND locked = ?", timesheet.EmployeeID, timesheet.StartDate, timesheet.EndDate, false).
+		Updates(map[string]interface{}{"locked": true, "timesheet_id": timesheet.ID}).Error
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error locking attendance records: %v", err)
+		return nil, err
+	}
+
+	timesheet.Status = StatusSignedOff
+	timesheet.SignedOffBy = &approver.ID
+	timesheet.SignedOffAt = now()
+	timesheet.SignOffNote = note
+	if err := s.save(tx, timesheet); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+
+	s.notifications.Notify(timesheet.EmployeeID, "timesheet_signed_off",
+		"Your timesheet was signed off", s.describe(timesheet)+note)
+	return timesheet, nil
+}
+
+// Reopen unlocks a signed-off timesheet so its attendance can be corrected;
+// it needs attesting and signing off again
+func (s *timesheetService) Reopen(id uint, user *iam.User, note string) (*Timesheet, error) {
+	timesheet, err := s.timesheet(id)
+	if err != nil {
+		return nil, err
+	}
+	if timesheet.Status != StatusSignedOff {
+		return nil, ErrInvalidStatus
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		return nil, tx.Error
+	}
+	err = tx.Model(&employee.Attendance{}).Where("timesheet_id = ?", timesheet.ID).
+		Updates(map[string]interface{}{"locked": false, "timesheet_id": nil}).Error
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error unlocking attendance records: %v", err)
+		return nil, err
+	}
+
+	timesheet.Status = StatusOpen
+	timesheet.AttestedBy, timesheet.AttestedAt, timesheet.AttestationNote = nil, utils.NullTime{}, ""
+	timesheet.SignedOffBy, timesheet.SignedOffAt, timesheet.SignOffNote = nil, utils.NullTime{}, ""
+	if err := s.refresh(tx, timesheet); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, err
+	}
+
+	s.notifications.Notify(timesheet.EmployeeID, "timesheet_reopened",
+		"Your timesheet was reopened", s.describe(timesheet)+note)
+	return timesheet, nil
+}
+
+// WritePDF renders a timesheet as a printable PDF
+func (s *timesheetService) WritePDF(id uint, user *iam.User, w io.Writer) error {
+	timesheet, err := s.GetTimesheet(id, user)
+	if err != nil {
+		return err
+	}
+
+	doc := pdf.New(fmt.Sprintf("Timesheet %s %s", timesheet.EmployeeName, timesheet.StartDate.Format("2006-01-02")), true)
+	doc.Heading("Timesheet: " + timesheet.EmployeeName)
+	doc.Text(fmt.Sprintf("Period: %s to %s (%s)", timesheet.StartDate.Format("Mon 2006-01-02"), timesheet.EndDate.Format("Mon 2006-01-02"), timesheet.Period))
+	doc.Text("Status: " + strings.Replace(timesheet.Status, "_", " ", -1))
+	if timesheet.AttestedAt.Valid {
+		doc.Text("Attested by the employee on " + timesheet.AttestedAt.Time.Format("2006-01-02 15:04 MST"))
+	}
+	if timesheet.SignedOffAt.Valid {
+		doc.Text("Signed off on " + timesheet.SignedOffAt.Time.Format("2006-01-02 15:04 MST"))
+	}
+	doc.Space(8)
+
+	columns := []pdf.Column{
+		{Title: "Date", Width: 80}, {Title: "Shifts", Width: 120},
+		{Title: "In", Width: 40}, {Title: "Out", Width: 40},
+		{Title: "Scheduled", Width: 55, Right: true}, {Title: "Worked", Width: 50, Right: true},
+		{Title: "Breaks", Width: 45, Right: true}, {Title: "Late", Width: 40, Right: true},
+		{Title: "Early", Width: 40, Right: true}, {Title: "Overtime", Width: 50, Right: true},
+		{Title: "Notes", Width: 200},
+	}
+	rows := make([][]string, 0, len(timesheet.Days)+1)
+	for _, day := range timesheet.Days {
+		rows = append(rows, []string{
+			day.Date.Format("Mon 2006-01-02"), day.Shifts, day.ClockIn, day.ClockOut,
+			hours(day.ScheduledMinutes), hours(day.WorkedMinutes), hours(day.BreakMinutes),
+			hours(day.LateMinutes), hours(day.EarlyLeaveMinutes), hours(day.OvertimeMinutes),
+			strings.Join(notes(day), "; "),
+		})
+	}
+	rows = append(rows, []string{
+		"Total", "", "", "",
+		hours(timesheet.ScheduledMinutes), hours(timesheet.WorkedMinutes), hours(timesheet.BreakMinutes),
+		hours(timesheet.LateMinutes), hours(timesheet.EarlyLeaveMinutes), hours(timesheet.OvertimeMinutes),
+		fmt.Sprintf("%d absent, %d on leave, %d corrected", timesheet.AbsentDays, timesheet.LeaveDays, timesheet.ManualEntries),
+	})
+	doc.Table(columns, rows, map[int]bool{len(rows) - 1: true})
+
+	return doc.Write(w)
+}
+
+// refresh recomputes the days of a timesheet within tx. An attested
+// timesheet whose days changed goes back to open.
+func (s *timesheetService) refresh(tx *gorm.DB, timesheet *Timesheet) error {
+	days, open, err := s.compute(tx, timesheet)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(days)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(encoded)
+	digest := hex.EncodeToString(sum[:])
+
+	changed := timesheet.ID == 0 || digest != timesheet.Digest
+	if changed && timesheet.Status == StatusAttested {
+		timesheet.Status = StatusOpen
+		timesheet.AttestedBy, timesheet.AttestedAt, timesheet.AttestationNote = nil, utils.NullTime{}, ""
+	}
+
+	timesheet.Digest = digest
+	timesheet.OpenRecords = open
+	timesheet.ScheduledMinutes, timesheet.WorkedMinutes, timesheet.BreakMinutes = 0, 0, 0
+	timesheet.OvertimeMinutes, timesheet.LateMinutes, timesheet.EarlyLeaveMinutes = 0, 0, 0
+	timesheet.AbsentDays, timesheet.LeaveDays, timesheet.ManualEntries = 0, 0, 0
+	for _, day := range days {
+		timesheet.ScheduledMinutes += day.ScheduledMinutes
+		timesheet.WorkedMinutes += day.WorkedMinutes
+		timesheet.BreakMinutes += day.BreakMinutes
+		timesheet.OvertimeMinutes += day.OvertimeMinutes
+		timesheet.LateMinutes += day.LateMinutes
+		timesheet.EarlyLeaveMinutes += day.EarlyLeaveMinutes
+		if day.Absent {
+			timesheet.AbsentDays++
+		}
+		if day.OnLeave {
+			timesheet.LeaveDays++
+		}
+		if day.ManualEntry {
+			timesheet.ManualEntries++
+		}
+	}
+	if err := s.save(tx, timesheet); err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := tx.Unscoped().Where("timesheet_id = ?", timesheet.ID).Delete(&TimesheetDay{}).Error; err != nil {
+		log.Printf("Error clearing timesheet days: %v", err)
+		return err
+	}
+	for i := range days {
+		days[i].TimesheetID = timesheet.ID
+		if err := tx.Create(&days[i]).Error; err != nil {
+			log.Printf("Error saving timesheet day: %v", err)
+			return err
+		}
+	}
+	timesheet.Days = days
+	return nil
+}
+
+// compute builds the days of a timesheet from the employee's shift
+// assignments and attendance, and counts the records not clocked out.
+// Overtime is worked time beyond the weekly threshold, counted from Monday.
+func (s *timesheetService) compute(db *gorm.DB, timesheet *Timesheet) ([]TimesheetDay, int, error) {
+	var assignments []employee.EmployeeShift
+	if err := db.Preload("Shift").
+		Where("employee_id = ? AND start_date <= ? AND end_date >= ?", timesheet.EmployeeID, timesheet.EndDate, timesheet.StartDate).
+		Find(&assignments).Error; err != nil {
+		log.Printf("Error fetching shift assignments: %v", err)
+		return nil, 0, err
+	}
+
+	var records []employee.Attendance
+	if err := db.Preload("Shift").Preload("Breaks").
+		Where("employee_id = ? AND date BETWEEN ? AND ?", timesheet.EmployeeID, timesheet.StartDate, timesheet.EndDate).
+		Order("clock_in_time").Find(&records).Error; err != nil {
+		log.Printf("Error fetching attendance records: %v", err)
+		return nil, 0, err
+	}
+	byDay := make(map[string][]employee.Attendance)
+	for _, record := range records {
+		key := dateOf(record.Date).Format("2006-01-02")
+		byDay[key] = append(byDay[key], record)
+	}
+
+	var days []TimesheetDay
+	var open int
+	var weekWorked time.Duration
+	for day := timesheet.StartDate; !day.After(timesheet.EndDate); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Monday {
+			weekWorked = 0
+		}
+		d := TimesheetDay{Date: day}
+		var shifts []string
+		addShift := func(name string) {
+			for _, shift := range shifts {
+				if shift == name {
+					return
+				}
+			}
+			shifts = append(shifts, name)
+		}
+
+		for _, assignment := range assignments {
+			if day.Before(dateOf(assignment.StartDate)) || day.After(dateOf(assignment.EndDate)) {
+				continue
+			}
+			if _, ok := assignment.Shift.OccurrenceOn(day); ok {
+				d.ScheduledMinutes += minutes(assignment.Shift.PaidDuration())
+				addShift(assignment.Shift.Name)
+			}
+		}
+
+		var worked time.Duration
+		for _, record := range byDay[day.Format("2006-01-02")] {
+			switch record.Status {
+			case employee.AttendanceStatusOnLeave:
+				d.OnLeave = true
+				continue
+			case employee.AttendanceStatusAbsent:
+				d.Absent = true
+				addShift(record.Shift.Name)
+				continue
+			}
+			addShift(record.Shift.Name)
+			d.ManualEntry = d.ManualEntry || record.ManualEntry
+			if record.BreakViolation != "" {
+				d.BreakViolation = record.BreakViolation
+			}
+
+			location := record.Shift.Location()
+			if d.ClockIn == "" {
+				d.ClockIn = record.ClockInTime.In(location).Format("15:04")
+			}
+			window, scheduled := record.Shift.OccurrenceOn(day)
+			if late := record.ClockInTime.Sub(window.Start); scheduled && late > s.policy.ClockInGrace {
+				d.LateMinutes += minutes(late)
+			}
+			for _, b := range record.Breaks {
+				if b.EndTime.Valid {
+					d.BreakMinutes += minutes(b.EndTime.Time.Sub(b.StartTime))
+				}
+			}
+
+			if record.Status == employee.AttendanceStatusMissingClockOut || !record.ClockOutTime.Valid {
+				d.MissingClockOut = true
+			}
+			if !record.ClockOutTime.Valid {
+				open++
+				continue
+			}
+			d.ClockOut = record.ClockOutTime.Time.In(location).Format("15:04")
+			if scheduled && record.ClockOutTime.Time.Before(window.End) {
+				d.EarlyLeaveMinutes += minutes(window.End.Sub(record.ClockOutTime.Time))
+			}
+
+			// Worked time as payroll counts it: clocked less unpaid breaks,
+			// less a mandatory break deducted for not being taken
+			var clocked time.Duration
+			for _, w := range record.WorkedWindows() {
+				clocked += w.End.Sub(w.Start)
+			}
+			deducted := time.Duration(record.DeductedBreakMinutes) * time.Minute
+			if deducted > clocked {
+				deducted = clocked
+			}
+			worked += clocked - deducted
+			d.UnpaidBreakMinutes += record.UnpaidBreakMinutes
+			d.DeductedBreakMinutes += record.DeductedBreakMinutes
+		}
+
+		d.WorkedMinutes = minutes(worked)
+		weekWorked += worked
+		if overtime := weekWorked - s.policy.OvertimeThreshold; overtime > 0 {
+			if overtime > worked {
+				overtime = worked
+			}
+			d.OvertimeMinutes = minutes(overtime)
+		}
+		d.Shifts = strings.Join(shifts, ", ")
+		days = append(days, d)
+	}
+	return days, open, nil
+}
+
+// attestedTimesheetFor loads an attested timesheet and checks the user may
+// sign it off: the employee's manager, or any admin. Timesheets without a
+// manager can be signed off by any manager. Nobody signs off their own.
+func (s *timesheetService) attestedTimesheetFor(id uint, approver *iam.User) (*Timesheet, error) {
+	timesheet, err := s.timesheet(id)
+	if err != nil {
+		return nil, err
+	}
+	if timesheet.Status != StatusAttested {
+		return nil, ErrInvalidStatus
+	}
+	if timesheet.EmployeeID == approver.EmployeeID {
+		return nil, ErrNotAllowed
+	}
+
+	if approver.Role == iam.RoleAdmin {
+		return timesheet, nil
+	}
+	isManager := timesheet.ApproverID != nil && *timesheet.ApproverID == approver.EmployeeID
+	isFallback := timesheet.ApproverID == nil && approver.IsPrivileged()
+	if !isManager && !isFallback {
+		return nil, ErrNotAllowed
+	}
+	return timesheet, nil
+}
+
+// timesheet loads a timesheet with its days and the employee's name
+func (s *timesheetService) timesheet(id uint) (*Timesheet, error) {
+	var timesheet Timesheet
+	err := s.db.Preload("Days", func(db *gorm.DB) *gorm.DB { return db.Order("date") }).First(&timesheet, id).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrTimesheetNotFound
+		}
+		log.Printf("Error fetching timesheet: %v", err)
+		return nil, err
+	}
+
+	emp, err := s.employee(timesheet.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	timesheet.EmployeeName = emp.Name
+	return &timesheet, nil
+}
+
+func (s *timesheetService) employee(id uint) (*employee.Employee, error) {
+	var emp employee.Employee
+	if err := s.db.First(&emp, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, employee.ErrEmployeeNotFound
+		}
+		log.Printf("Error fetching employee: %v", err)
+		return nil, err
+	}
+	return &emp, nil
+}
+
+func (s *timesheetService) save(tx *gorm.DB, timesheet *Timesheet) error {
+	if err := tx.Omit("Days").Save(timesheet).Error; err != nil {
+		log.Printf("Error saving timesheet: %v", err)
+		return err
+	}
+	return nil
+}
+
+// describe summarizes a timesheet for notifications
+func (s *timesheetService) describe(timesheet *Timesheet) string {
+	return fmt.Sprintf("Timesheet for %s to %s: %s worked of %s scheduled. ",
+		timesheet.StartDate.Format("2006-01-02"), timesheet.EndDate.Format("2006-01-02"),
+		hours(timesheet.WorkedMinutes), hours(timesheet.ScheduledMinutes))
+}
+
+// notes lists what stands out about a day
+func notes(day TimesheetDay) []string {
+	var notes []string
+	if day.Absent {
+		notes = append(notes, "Absent")
+	}
+	if day.OnLeave {
+		notes = append(notes, "On leave")
+	}
+	if day.ManualEntry {
+		notes = append(notes, "Corrected")
+	}
+	if day.MissingClockOut {
+		notes = append(notes, "Missing clock-out")
+	}
+	if day.BreakViolation != "" {
+		notes = append(notes, "Break not taken")
+	}
+	return notes
+}
+
+// hours formats minutes as H:MM, or blank for none
+func hours(minutes int) string {
+	if minutes == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
+
+func minutes(d time.Duration) int {
+	return int(d / time.Minute)
+}
+
+func monday(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+func now() utils.NullTime {
+	return utils.NullTime{NullTime: sql.NullTime{Time: time.Now().UTC(), Valid: true}}
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func today() time.Time {
+	return dateOf(time.Now().UTC())
+}
@@ -0,0 +1,213 @@
+package timesheet
+
+import (
+	"clinicplus/internal/employee"
+	"clinicplus/internal/iam"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/shared/testdb"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// at returns a time on a day in March 2024, in UTC
+func at(d, hour, minute int) time.Time {
+	return time.Date(2024, time.March, d, hour, minute, 0, 0, time.UTC)
+}
+
+func day(d int) time.Time { return at(d, 0, 0) }
+
+var (
+	policy    = Policy{OvertimeThreshold: 20 * time.Hour, ClockInGrace: 5 * time.Minute}
+	owner     = &iam.User{Model: gorm.Model{ID: 4}, EmployeeID: 5, Role: iam.RoleEmployee}
+	manager   = &iam.User{Model: gorm.Model{ID: 2}, EmployeeID: 8, Role: iam.RoleManager}
+	columns   = []string{"id", "employee_id", "period", "start_date", "end_date", "status", "approver_id", "digest"}
+	attended  = []string{"id", "employee_id", "shift_id", "date", "clock_in_time", "clock_out_time", "status", "manual_entry"}
+	employees = []string{"id", "name", "manager_id"}
+)
+
+// scriptWeek scripts the employee and their assignment to the Early shift,
+// 08:00 to 16:00 UTC on weekdays with an unpaid half-hour break, for the
+// week of Monday the 4th
+func scriptWeek(fake *testdb.DB) {
+	fake.Returns(`FROM "employees"`, employees, []interface{}{5, "Ana", 8})
+	fake.Returns(`FROM "employee_shifts"`, []string{"id", "employee_id", "shift_id", "start_date", "end_date"}, []interface{}{7, 5, 1, day(1), day(31)})
+	fake.Returns(`FROM "shifts"`, []string{"id", "name", "start_time", "end_time", "timezone", "days_of_week", "break_minutes"},
+		[]interface{}{1, "Early", "08:00", "16:00", "UTC", "mon,tue,wed,thu,fri", 30})
+}
+
+func TestAttest(t *testing.T) {
+	thisWeek := monday(today())
+
+	tests := []struct {
+		name    string
+		user    *iam.User
+		status  string
+		start   time.Time
+		friday  []interface{} // Friday's attendance record
+		wantErr error
+	}{
+		{name: "week worked", user: owner, status: StatusOpen, start: day(4), friday: []interface{}{15, 5, 1, day(8), at(8, 8, 0), at(8, 16, 0), employee.AttendanceStatusPresent, false}},
+		{name: "record without a clock-out", user: owner, status: StatusOpen, start: day(4), friday: []interface{}{15, 5, 1, day(8), at(8, 8, 0), nil, employee.AttendanceStatusPresent, false}, wantErr: ErrOpenRecords},
+		{name: "someone else's timesheet", user: &iam.User{Model: gorm.Model{ID: 6}, EmployeeID: 6, Role: iam.RoleAdmin}, status: StatusOpen, start: day(4), wantErr: ErrNotAllowed},
+		{name: "already attested", user: owner, status: StatusAttested, start: day(4), wantErr: ErrInvalidStatus},
+		{name: "before the period ends", user: owner, status: StatusOpen, start: thisWeek, wantErr: ErrPeriodNotEnded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			scriptWeek(fake)
+			fake.Returns(`FROM "timesheets"`, columns, []interface{}{3, 5, PeriodWeekly, tt.start, tt.start.AddDate(0, 0, 6), tt.status, 8, ""})
+			fake.Returns(`FROM "attendances"`, attended,
+				[]interface{}{11, 5, 1, day(4), at(4, 8, 10), at(4, 16, 0), employee.AttendanceStatusPresent, false},
+				[]interface{}{12, 5, 1, day(5), at(5, 8, 3), at(5, 15, 0), employee.AttendanceStatusPresent, true},
+				[]interface{}{13, 5, 1, day(6), at(6, 8, 0), nil, employee.AttendanceStatusAbsent, false},
+				[]interface{}{14, 5, 1, day(7), at(7, 8, 0), nil, employee.AttendanceStatusOnLeave, false},
+				tt.friday)
+			service := NewTimesheetService(db, notification.NewNotificationService(db), policy)
+
+			timesheet, err := service.Attest(3, tt.user, "All correct")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Attest error %v, want %v", err, tt.wantErr)
+			}
+			if notified := fake.Ran(`INSERT INTO "notifications"`); notified != (tt.wantErr == nil) {
+				t.Errorf("manager notified: %v", notified)
+			}
+			if err != nil {
+				return
+			}
+
+			if timesheet.Status != StatusAttested || timesheet.AttestedBy == nil || *timesheet.AttestedBy != owner.ID {
+				t.Errorf("timesheet %s by %v", timesheet.Status, timesheet.AttestedBy)
+			}
+			if len(timesheet.Days) != 7 || len(fake.Find(`INSERT INTO "timesheet_days"`)) != 7 {
+				t.Fatalf("got %d days, want 7", len(timesheet.Days))
+			}
+			got := [...]int{
+				timesheet.ScheduledMinutes, timesheet.WorkedMinutes, timesheet.OvertimeMinutes, timesheet.LateMinutes,
+				timesheet.EarlyLeaveMinutes, timesheet.AbsentDays, timesheet.LeaveDays, timesheet.ManualEntries,
+			}
+			// Five shifts of 7:30 paid; 7:50, 6:57 and 8:00 worked, the last
+			// 2:47 of it past the 20 hour threshold; 10 minutes late on Monday,
+			// within the grace on Tuesday, and an hour early on Tuesday
+			want := [...]int{5 * 450, 470 + 417 + 480, 167, 10, 60, 1, 1, 1}
+			if got != want {
+				t.Errorf("scheduled, worked, overtime, late, early, absent, leave, manual = %v, want %v", got, want)
+			}
+			if saturday := timesheet.Days[5]; saturday.Shifts != "" || saturday.ScheduledMinutes != 0 {
+				t.Errorf("saturday computed as %+v", saturday)
+			}
+		})
+	}
+}
+
+// digest returns the digest of an empty week starting on the 4th, as
+// refresh computes it
+func digest(t *testing.T) string {
+	db, _ := testdb.New(t)
+	timesheet := &Timesheet{EmployeeID: 5, StartDate: day(4), EndDate: day(10)}
+	if err := (&timesheetService{db: db, policy: policy}).refresh(db, timesheet); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	return timesheet.Digest
+}
+
+func TestSignOff(t *testing.T) {
+	attested := digest(t)
+
+	tests := []struct {
+		name     string
+		approver *iam.User
+		manager  interface{} // The timesheet's approver; nil lets any manager sign off
+		status   string
+		digest   string
+		wantErr  error
+	}{
+		{name: "by the employee's manager", approver: manager, manager: 8, status: StatusAttested, digest: attested},
+		{name: "by an admin", approver: &iam.User{Model: gorm.Model{ID: 1}, EmployeeID: 1, Role: iam.RoleAdmin}, manager: 8, status: StatusAttested, digest: attested},
+		{name: "by any manager when the employee has none", approver: &iam.User{Model: gorm.Model{ID: 3}, EmployeeID: 9, Role: iam.RoleManager}, status: StatusAttested, digest: attested},
+		{name: "by another manager", approver: &iam.User{Model: gorm.Model{ID: 3}, EmployeeID: 9, Role: iam.RoleManager}, manager: 8, status: StatusAttested, digest: attested, wantErr: ErrNotAllowed},
+		{name: "of the approver's own timesheet", approver: &iam.User{Model: gorm.Model{ID: 6}, EmployeeID: 5, Role: iam.RoleAdmin}, status: StatusAttested, digest: attested, wantErr: ErrNotAllowed},
+		{name: "not attested", approver: manager, manager: 8, status: StatusOpen, digest: attested, wantErr: ErrInvalidStatus},
+		{name: "changed since attested", approver: manager, manager: 8, status: StatusAttested, digest: "stale", wantErr: ErrChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "employees"`, employees, []interface{}{5, "Ana", tt.manager})
+			fake.Returns(`FROM "timesheets"`, columns, []interface{}{3, 5, PeriodWeekly, day(4), day(10), tt.status, tt.manager, tt.digest})
+			service := NewTimesheetService(db, notification.NewNotificationService(db), policy)
+
+			timesheet, err := service.SignOff(3, tt.approver, "Thanks")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SignOff error %v, want %v", err, tt.wantErr)
+			}
+			locks := fake.Find(`UPDATE "attendances"`)
+			if locked := len(locks) == 1 && locks[0].Has(true) && locks[0].Has(3); locked != (tt.wantErr == nil) {
+				t.Errorf("attendance locked with %+v", locks)
+			}
+
+			if errors.Is(err, ErrChanged) {
+				saved := fake.Find(`UPDATE "timesheets"`)
+				if len(saved) != 1 || !saved[0].Has(StatusOpen) {
+					t.Errorf("timesheet saved with %+v, want it back to open", saved)
+				}
+				if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 1 || !notifications[0].Has("timesheet_changed") {
+					t.Errorf("employee notified with %+v", notifications)
+				}
+			}
+			if err != nil {
+				return
+			}
+
+			if timesheet.Status != StatusSignedOff || timesheet.SignedOffBy == nil || *timesheet.SignedOffBy != tt.approver.ID {
+				t.Errorf("timesheet %s by %v", timesheet.Status, timesheet.SignedOffBy)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 1 || !notifications[0].Has(5) || !notifications[0].Has("timesheet_signed_off") {
+				t.Errorf("employee notified with %+v", notifications)
+			}
+		})
+	}
+}
+
+func TestReopen(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		wantErr error
+	}{
+		{name: "signed off", status: StatusSignedOff},
+		{name: "still open", status: StatusOpen, wantErr: ErrInvalidStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testdb.New(t)
+			fake.Returns(`FROM "employees"`, employees, []interface{}{5, "Ana", 8})
+			fake.Returns(`FROM "timesheets"`, columns, []interface{}{3, 5, PeriodWeekly, day(4), day(10), tt.status, 8, "signed"})
+			service := NewTimesheetService(db, notification.NewNotificationService(db), policy)
+
+			timesheet, err := service.Reopen(3, manager, "Wrong clock-out on Tuesday")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Reopen error %v, want %v", err, tt.wantErr)
+			}
+			unlocks := fake.Find(`UPDATE "attendances"`)
+			if unlocked := len(unlocks) == 1 && unlocks[0].Has(false) && unlocks[0].Has(3); unlocked != (tt.wantErr == nil) {
+				t.Errorf("attendance unlocked with %+v", unlocks)
+			}
+			if err != nil {
+				return
+			}
+			if timesheet.Status != StatusOpen || timesheet.SignedOffBy != nil || timesheet.AttestedBy != nil {
+				t.Errorf("timesheet reopened as %s, signed off by %v, attested by %v", timesheet.Status, timesheet.SignedOffBy, timesheet.AttestedBy)
+			}
+			if notifications := fake.Find(`INSERT INTO "notifications"`); len(notifications) != 1 || !notifications[0].Has("timesheet_reopened") {
+				t.Errorf("employee notified with %+v", notifications)
+			}
+		})
+	}
+}
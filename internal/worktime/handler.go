@@ -0,0 +1,56 @@
+// internal/worktime/handler.go
+package worktime
+
+import (
+	"clinicplus/internal/shared/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type WorkingTimeHandler struct {
+	service WorkingTimeService
+}
+
+func NewWorkingTimeHandler(service WorkingTimeService) *WorkingTimeHandler {
+	return &WorkingTimeHandler{service: service}
+}
+
+func (h *WorkingTimeHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.GetRules()
+	if err != nil {
+		log.Printf("Error fetching working time rules: %v", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to retrieve working time rules", nil)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, rules, nil, nil)
+}
+
+func (h *WorkingTimeHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	var rule WorkingTimeRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, nil, "Invalid request body", nil)
+		return
+	}
+
+	updated, err := h.service.UpdateRule(mux.Vars(r)["code"], rule)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRuleNotFound):
+			utils.SendJSONResponse(w, http.StatusNotFound, nil, err.Error(), nil)
+		case errors.Is(err, ErrInvalidSeverity), errors.Is(err, ErrInvalidThreshold):
+			utils.SendJSONResponse(w, http.StatusBadRequest, nil, err.Error(), nil)
+		default:
+			log.Printf("Error updating working time rule: %v", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, nil, "Failed to update working time rule", nil)
+		}
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, updated, nil, nil)
+}
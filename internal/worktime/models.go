@@ -0,0 +1,54 @@
+package worktime
+
+import (
+	"clinicplus/internal/employee"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Codes of the built-in rules
+const (
+	RuleNoOverlap          = "no_overlap"
+	RuleMinRest            = "min_rest"
+	RuleMaxWeeklyHours     = "max_weekly_hours"
+	RuleMaxConsecutiveDays = "max_consecutive_days"
+)
+
+const (
+	SeverityWarn  = "warn"  // The assignment is made with a warning
+	SeverityBlock = "block" // The assignment is rejected unless a manager overrides it
+)
+
+// WorkingTimeRule configures one rule of the engine. Rules without a stored
+// setting run with their defaults.
+type WorkingTimeRule struct {
+	gorm.Model
+	Code      string  `gorm:"unique;not null" json:"code"`
+	Enabled   bool    `json:"enabled"`
+	Severity  string  `gorm:"not null" json:"severity"`
+	Threshold float64 `json:"threshold"` // Hours for min_rest and max_weekly_hours, days for max_consecutive_days
+}
+
+// Violation is a rule an assignment breaks
+type Violation struct {
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+	Overridden bool   `json:"overridden"`
+}
+
+// Occurrence is one working day of an employee: a shift and when it runs
+type Occurrence struct {
+	Shift  employee.Shift
+	Date   time.Time // Local date the shift starts on
+	Window employee.ShiftWindow
+}
+
+// Schedule is what rules evaluate: the occurrences an assignment would add
+// and the employee's other occurrences around them
+type Schedule struct {
+	Employee employee.Employee
+	New      []Occurrence
+	Existing []Occurrence
+}
@@ -0,0 +1,153 @@
+package worktime
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rule is one check of the engine. Evaluate returns a message for each way
+// the schedule's new occurrences break the rule under its setting.
+type Rule interface {
+	Code() string
+	Default() WorkingTimeRule
+	Evaluate(schedule Schedule, setting WorkingTimeRule) []string
+}
+
+// noOverlap forbids being on two shifts at the same time
+type noOverlap struct{}
+
+func (noOverlap) Code() string { return RuleNoOverlap }
+
+func (noOverlap) Default() WorkingTimeRule {
+	return WorkingTimeRule{Code: RuleNoOverlap, Enabled: true, Severity: SeverityBlock}
+}
+
+func (noOverlap) Evaluate(schedule Schedule, setting WorkingTimeRule) []string {
+	var messages []string
+	for _, occurrence := range schedule.New {
+		for _, other := range schedule.Existing {
+			if occurrence.Window.Overlaps(other.Window) {
+				messages = append(messages, fmt.Sprintf("%s on %s overlaps %s on %s", occurrence.Shift.Name,
+					occurrence.Date.Format("2006-01-02"), other.Shift.Name, other.Date.Format("2006-01-02")))
+			}
+		}
+	}
+	return messages
+}
+
+// minRest requires a rest of Threshold hours between consecutive shifts
+type minRest struct {
+	hours float64
+}
+
+func (minRest) Code() string { return RuleMinRest }
+
+func (r minRest) Default() WorkingTimeRule {
+	return WorkingTimeRule{Code: RuleMinRest, Enabled: true, Severity: SeverityBlock, Threshold: r.hours}
+}
+
+func (minRest) Evaluate(schedule Schedule, setting WorkingTimeRule) []string {
+	limit := time.Duration(setting.Threshold * float64(time.Hour))
+	var messages []string
+	for _, occurrence := range schedule.New {
+		for _, other := range schedule.Existing {
+			if occurrence.Window.Overlaps(other.Window) {
+				continue
+			}
+			rest := occurrence.Window.Start.Sub(other.Window.End)
+			if other.Window.Start.After(occurrence.Window.Start) {
+				rest = other.Window.Start.Sub(occurrence.Window.End)
+			}
+			if rest < limit {
+				messages = append(messages, fmt.Sprintf("only %s rest between %s on %s and %s on %s, minimum %gh",
+					rest.Round(time.Minute), occurrence.Shift.Name, occurrence.Date.Format("2006-01-02"),
+					other.Shift.Name, other.Date.Format("2006-01-02"), setting.Threshold))
+			}
+		}
+	}
+	return messages
+}
+
+// maxWeeklyHours caps the paid hours in each ISO week with new occurrences
+type maxWeeklyHours struct {
+	hours float64
+}
+
+func (maxWeeklyHours) Code() string { return RuleMaxWeeklyHours }
+
+func (r maxWeeklyHours) Default() WorkingTimeRule {
+	return WorkingTimeRule{Code: RuleMaxWeeklyHours, Enabled: true, Severity: SeverityWarn, Threshold: r.hours}
+}
+
+func (maxWeeklyHours) Evaluate(schedule Schedule, setting WorkingTimeRule) []string {
+	hours := make(map[string]float64)
+	var weeks []string
+	for _, occurrence := range schedule.New {
+		week := weekOf(occurrence)
+		if _, ok := hours[week]; !ok {
+			weeks = append(weeks, week)
+		}
+		hours[week] += occurrence.Shift.PaidDuration().Hours()
+	}
+	for _, occurrence := range schedule.Existing {
+		if _, ok := hours[weekOf(occurrence)]; ok {
+			hours[weekOf(occurrence)] += occurrence.Shift.PaidDuration().Hours()
+		}
+	}
+
+	var messages []string
+	for _, week := range weeks {
+		if hours[week] > setting.Threshold {
+			messages = append(messages, fmt.Sprintf("%.1f hours in week %s, maximum %g", hours[week], week, setting.Threshold))
+		}
+	}
+	return messages
+}
+
+// maxConsecutiveDays caps how many days in a row an employee works
+type maxConsecutiveDays struct {
+	days int
+}
+
+func (maxConsecutiveDays) Code() string { return RuleMaxConsecutiveDays }
+
+func (r maxConsecutiveDays) Default() WorkingTimeRule {
+	return WorkingTimeRule{Code: RuleMaxConsecutiveDays, Enabled: true, Severity: SeverityWarn, Threshold: float64(r.days)}
+}
+
+func (maxConsecutiveDays) Evaluate(schedule Schedule, setting WorkingTimeRule) []string {
+	working := make(map[time.Time]bool)
+	for _, occurrences := range [][]Occurrence{schedule.New, schedule.Existing} {
+		for _, occurrence := range occurrences {
+			working[occurrence.Date] = true
+		}
+	}
+
+	var messages []string
+	reported := make(map[time.Time]bool)
+	for _, occurrence := range schedule.New {
+		first := occurrence.Date
+		for working[first.AddDate(0, 0, -1)] {
+			first = first.AddDate(0, 0, -1)
+		}
+		if reported[first] {
+			continue
+		}
+		days := 0
+		for day := first; working[day]; day = day.AddDate(0, 0, 1) {
+			days++
+		}
+		if float64(days) > setting.Threshold {
+			reported[first] = true
+			messages = append(messages, fmt.Sprintf("%d consecutive working days from %s, maximum %g",
+				days, first.Format("2006-01-02"), setting.Threshold))
+		}
+	}
+	return messages
+}
+
+// weekOf returns the ISO week an occurrence starts in, e.g. 2026-W43
+func weekOf(occurrence Occurrence) string {
+	year, week := occurrence.Date.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
@@ -0,0 +1,197 @@
+package worktime
+
+import (
+	"clinicplus/internal/employee"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	early = employee.Shift{Name: "Early", StartTime: "06:00", EndTime: "14:00", Timezone: "UTC", DaysOfWeek: "mon,tue,wed,thu,fri,sat,sun"}
+	late  = employee.Shift{Name: "Late", StartTime: "14:00", EndTime: "22:00", Timezone: "UTC", DaysOfWeek: "mon,tue,wed,thu,fri,sat,sun"}
+	night = employee.Shift{Name: "Night", StartTime: "22:00", EndTime: "06:00", Timezone: "UTC", DaysOfWeek: "mon,tue,wed,thu,fri,sat,sun"}
+	// Ten paid hours: 08:00-18:30 less a 30 minute break
+	long = employee.Shift{Name: "Long", StartTime: "08:00", EndTime: "18:30", BreakMinutes: 30, Timezone: "UTC", DaysOfWeek: "mon,tue,wed,thu,fri,sat,sun"}
+)
+
+// on returns the shift's occurrence on a day of March 2024; the 4th is a Monday
+func on(shift employee.Shift, day int) Occurrence {
+	date := time.Date(2024, time.March, day, 0, 0, 0, 0, time.UTC)
+	window, _ := shift.OccurrenceOn(date)
+	return Occurrence{Shift: shift, Date: date, Window: window}
+}
+
+func days(shift employee.Shift, from, to int) []Occurrence {
+	var result []Occurrence
+	for day := from; day <= to; day++ {
+		result = append(result, on(shift, day))
+	}
+	return result
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      Rule
+		threshold float64
+		new       []Occurrence
+		existing  []Occurrence
+		want      []string // Substrings of the messages, in order
+	}{
+		{
+			name:     "overlap on the same day",
+			rule:     noOverlap{},
+			new:      []Occurrence{on(long, 4)},
+			existing: []Occurrence{on(late, 4)},
+			want:     []string{"Long on 2024-03-04 overlaps Late on 2024-03-04"},
+		},
+		{
+			name:     "back to back shifts do not overlap",
+			rule:     noOverlap{},
+			new:      []Occurrence{on(early, 4)},
+			existing: []Occurrence{on(late, 4), on(night, 3)},
+		},
+		{
+			name:     "overnight shift overlaps the next morning",
+			rule:     noOverlap{},
+			new:      []Occurrence{on(long, 5)},
+			existing: []Occurrence{on(night, 4), on(early, 5)},
+			want:     []string{"overlaps Early on 2024-03-05"},
+		},
+		{
+			name:      "rest after an existing shift",
+			rule:      minRest{},
+			threshold: 11,
+			new:       []Occurrence{on(early, 5)},
+			existing:  []Occurrence{on(late, 4)},
+			want:      []string{"only 8h0m0s rest between Early on 2024-03-05 and Late on 2024-03-04, minimum 11h"},
+		},
+		{
+			name:      "rest before an existing shift",
+			rule:      minRest{},
+			threshold: 11,
+			new:       []Occurrence{on(late, 4)},
+			existing:  []Occurrence{on(early, 5)},
+			want:      []string{"only 8h0m0s rest"},
+		},
+		{
+			name:      "enough rest",
+			rule:      minRest{},
+			threshold: 11,
+			new:       []Occurrence{on(early, 5)},
+			existing:  []Occurrence{on(early, 4), on(late, 3)},
+		},
+		{
+			name:      "overlaps are left to no_overlap",
+			rule:      minRest{},
+			threshold: 11,
+			new:       []Occurrence{on(long, 4)},
+			existing:  []Occurrence{on(late, 4)},
+		},
+		{
+			name:      "weekly hours over the cap",
+			rule:      maxWeeklyHours{},
+			threshold: 48,
+			new:       []Occurrence{on(long, 9)},
+			existing:  days(long, 4, 8),
+			want:      []string{"60.0 hours in week 2024-W10, maximum 48"},
+		},
+		{
+			name:      "weekly hours at the cap",
+			rule:      maxWeeklyHours{},
+			threshold: 48,
+			new:       []Occurrence{on(early, 9)},
+			existing:  days(early, 4, 8),
+		},
+		{
+			name:      "other weeks do not count",
+			rule:      maxWeeklyHours{},
+			threshold: 40,
+			new:       []Occurrence{on(long, 11)},
+			existing:  days(long, 4, 10),
+		},
+		{
+			name:      "each week is reported",
+			rule:      maxWeeklyHours{},
+			threshold: 15,
+			new:       []Occurrence{on(long, 4), on(long, 5), on(long, 11), on(long, 12)},
+			want:      []string{"week 2024-W10", "week 2024-W11"},
+		},
+		{
+			name:      "too many consecutive days",
+			rule:      maxConsecutiveDays{},
+			threshold: 6,
+			new:       []Occurrence{on(early, 7)},
+			existing:  append(days(early, 4, 6), days(early, 8, 10)...),
+			want:      []string{"7 consecutive working days from 2024-03-04, maximum 6"},
+		},
+		{
+			name:      "a day off breaks the run",
+			rule:      maxConsecutiveDays{},
+			threshold: 6,
+			new:       []Occurrence{on(early, 7)},
+			existing:  append(days(early, 4, 6), days(early, 9, 11)...),
+		},
+		{
+			name:      "one run is reported once",
+			rule:      maxConsecutiveDays{},
+			threshold: 2,
+			new:       days(early, 4, 6),
+			want:      []string{"3 consecutive working days from 2024-03-04"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := tt.rule.Default()
+			setting.Threshold = tt.threshold
+			messages := tt.rule.Evaluate(Schedule{New: tt.new, Existing: tt.existing}, setting)
+
+			if len(messages) != len(tt.want) {
+				t.Fatalf("got %d messages %q, want %d", len(messages), messages, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(messages[i], want) {
+					t.Errorf("message %q does not contain %q", messages[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLookaround(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []WorkingTimeRule
+		want     int
+	}{
+		{"defaults to a week", nil, 7},
+		{"short runs", []WorkingTimeRule{{Code: RuleMaxConsecutiveDays, Enabled: true, Threshold: 5}}, 7},
+		{"long runs", []WorkingTimeRule{{Code: RuleMaxConsecutiveDays, Enabled: true, Threshold: 10}}, 11},
+		{"disabled", []WorkingTimeRule{{Code: RuleMaxConsecutiveDays, Threshold: 10}}, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lookaround(tt.settings); got != tt.want {
+				t.Errorf("lookaround = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	weekdays := early
+	weekdays.DaysOfWeek = "mon,tue,wed,thu,fri"
+	date := func(day int) time.Time { return time.Date(2024, time.March, day, 0, 0, 0, 0, time.UTC) }
+
+	// Assigned for two weeks, looked at from Thursday to the next Tuesday
+	got := occurrences(weekdays, date(4), date(17), date(7), date(12))
+	var dates []string
+	for _, occurrence := range got {
+		dates = append(dates, occurrence.Date.Format("02"))
+	}
+	if want := "07,08,11,12"; strings.Join(dates, ",") != want {
+		t.Errorf("occurrences on %s, want %s", strings.Join(dates, ","), want)
+	}
+}
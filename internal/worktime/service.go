@@ -0,0 +1,248 @@
+// internal/worktime/service.go
+package worktime
+
+import (
+	"clinicplus/internal/employee"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrRuleNotFound     = errors.New("working time rule not found")
+	ErrInvalidSeverity  = errors.New("severity must be warn or block")
+	ErrInvalidThreshold = errors.New("rule threshold must not be negative")
+)
+
+// maxMessages is how many violations of one rule are spelled out before the
+// rest are summarized, e.g. for a month-long assignment
+const maxMessages = 3
+
+type WorkingTimeService interface {
+	GetRules() ([]WorkingTimeRule, error)
+	UpdateRule(code string, rule WorkingTimeRule) (*WorkingTimeRule, error)
+	Register(rule Rule)
+
+	Evaluate(db *gorm.DB, emp employee.Employee, assignment employee.EmployeeShift) ([]Violation, error)
+	// CheckAssignment makes the service an employee.AssignmentGuard
+	CheckAssignment(db *gorm.DB, emp employee.Employee, assignment employee.EmployeeShift) error
+}
+
+type workingTimeService struct {
+	db    *gorm.DB
+	rules []Rule
+}
+
+// Defaults are the limits of the built-in rules before they are configured
+type Defaults struct {
+	MinRestHours       float64
+	MaxWeeklyHours     float64
+	MaxConsecutiveDays int
+}
+
+// NewWorkingTimeService creates the engine with the built-in rules: no
+// overlapping shifts, minimum rest, maximum weekly hours and maximum
+// consecutive days
+func NewWorkingTimeService(db *gorm.DB, defaults Defaults) WorkingTimeService {
+	s := &workingTimeService{db: db}
+	s.Register(noOverlap{})
+	s.Register(minRest{hours: defaults.MinRestHours})
+	s.Register(maxWeeklyHours{hours: defaults.MaxWeeklyHours})
+	s.Register(maxConsecutiveDays{days: defaults.MaxConsecutiveDays})
+	return s
+}
+
+// Register adds a rule to the engine
+func (s *workingTimeService) Register(rule Rule) {
+	s.rules = append(s.rules, rule)
+}
+
+// GetRules returns the setting of every registered rule
+func (s *workingTimeService) GetRules() ([]WorkingTimeRule, error) {
+	var stored []WorkingTimeRule
+	if err := s.db.Find(&stored).Error; err != nil {
+		log.Printf("Error fetching working time rules: %v", err)
+		return nil, err
+	}
+	byCode := make(map[string]WorkingTimeRule, len(stored))
+	for _, setting := range stored {
+		byCode[setting.Code] = setting
+	}
+
+	settings := make([]WorkingTimeRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		setting, ok := byCode[rule.Code()]
+		if !ok {
+			setting = rule.Default()
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+// UpdateRule stores the setting of a registered rule
+func (s *workingTimeService) UpdateRule(code string, rule WorkingTimeRule) (*WorkingTimeRule, error) {
+	if s.rule(code) == nil {
+		return nil, ErrRuleNotFound
+	}
+	if rule.Severity != SeverityWarn && rule.Severity != SeverityBlock {
+		return nil, ErrInvalidSeverity
+	}
+	if rule.Threshold < 0 {
+		return nil, ErrInvalidThreshold
+	}
+
+	var setting WorkingTimeRule
+	err := s.db.Where(WorkingTimeRule{Code: code}).
+		Assign(map[string]interface{}{"enabled": rule.Enabled, "severity": rule.Severity, "threshold": rule.Threshold}).
+		FirstOrCreate(&setting).Error
+	if err != nil {
+		log.Printf("Error saving working time rule: %v", err)
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// Evaluate runs the enabled rules against the occurrences of an assignment.
+// Blocking violations of an assignment a manager overrode with a reason are
+// marked overridden.
+func (s *workingTimeService) Evaluate(db *gorm.DB, emp employee.Employee, assignment employee.EmployeeShift) ([]Violation, error) {
+	settings, err := s.GetRules()
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.schedule(db, emp, assignment, lookaround(settings))
+	if err != nil {
+		return nil, err
+	}
+	overridden := assignment.OverrideReason != "" && assignment.OverriddenBy != nil
+
+	var violations []Violation
+	for i, rule := range s.rules {
+		setting := settings[i]
+		if !setting.Enabled {
+			continue
+		}
+		messages := rule.Evaluate(*schedule, setting)
+		if len(messages) > maxMessages {
+			messages = append(messages[:maxMessages], fmt.Sprintf("and %d more", len(messages)-maxMessages))
+		}
+		for _, message := range messages {
+			violations = append(violations, Violation{
+				Rule:       rule.Code(),
+				Severity:   setting.Severity,
+				Message:    message,
+				Overridden: overridden && setting.Severity == SeverityBlock,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// CheckAssignment rejects assignments breaking a blocking rule that was not
+// overridden, and lets the others through with a warning listing every
+// violation
+func (s *workingTimeService) CheckAssignment(db *gorm.DB, emp employee.Employee, assignment employee.EmployeeShift) error {
+	violations, err := s.Evaluate(db, emp, assignment)
+	if err != nil || len(violations) == 0 {
+		return err
+	}
+
+	blocked := false
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		message := violation.Message
+		switch {
+		case violation.Overridden:
+			message = "overridden: " + message
+		case violation.Severity == SeverityBlock:
+			blocked = true
+		}
+		messages = append(messages, message)
+	}
+
+	if blocked {
+		return fmt.Errorf("%w: %s", employee.ErrAssignmentRejected, strings.Join(messages, "; "))
+	}
+	return fmt.Errorf("%w: %s", employee.ErrAssignmentWarning, strings.Join(messages, "; "))
+}
+
+// schedule gathers the occurrences of the assignment and the employee's other
+// occurrences within days of it, as db sees them
+func (s *workingTimeService) schedule(db *gorm.DB, emp employee.Employee, assignment employee.EmployeeShift, days int) (*Schedule, error) {
+	shift := assignment.Shift
+	if shift.ID == 0 {
+		if err := db.First(&shift, assignment.ShiftID).Error; err != nil {
+			log.Printf("Error fetching shift: %v", err)
+			return nil, err
+		}
+	}
+
+	start, end := dateOf(assignment.StartDate), dateOf(assignment.EndDate)
+	from, to := start.AddDate(0, 0, -days), end.AddDate(0, 0, days)
+	schedule := &Schedule{Employee: emp, New: occurrences(shift, start, end, start, end)}
+
+	query := db.Preload("Shift").Where("employee_id = ? AND start_date <= ? AND end_date >= ?", emp.ID, to, from)
+	if assignment.ID != 0 {
+		query = query.Where("id <> ?", assignment.ID)
+	}
+	var others []employee.EmployeeShift
+	if err := query.Find(&others).Error; err != nil {
+		log.Printf("Error fetching shift assignments: %v", err)
+		return nil, err
+	}
+	for _, other := range others {
+		schedule.Existing = append(schedule.Existing, occurrences(other.Shift, dateOf(other.StartDate), dateOf(other.EndDate), from, to)...)
+	}
+	return schedule, nil
+}
+
+func (s *workingTimeService) rule(code string) Rule {
+	for _, rule := range s.rules {
+		if rule.Code() == code {
+			return rule
+		}
+	}
+	return nil
+}
+
+// occurrences expands a shift assigned from start to end into the days it
+// runs between from and to
+func occurrences(shift employee.Shift, start, end, from, to time.Time) []Occurrence {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+
+	var result []Occurrence
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if window, ok := shift.OccurrenceOn(day); ok {
+			result = append(result, Occurrence{Shift: shift, Date: day, Window: window})
+		}
+	}
+	return result
+}
+
+// lookaround returns how many days either side of an assignment the rules
+// need to see: a week for weekly hours, and the longest allowed run of
+// working days
+func lookaround(settings []WorkingTimeRule) int {
+	days := 7
+	for _, setting := range settings {
+		if setting.Code == RuleMaxConsecutiveDays && setting.Enabled && int(setting.Threshold) >= days {
+			days = int(setting.Threshold) + 1
+		}
+	}
+	return days
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE employees
+    ADD COLUMN IF NOT EXISTS employment_status VARCHAR(50) NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS termination_date DATE NULL,
+    ADD COLUMN IF NOT EXISTS termination_reason TEXT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_employees_employment_status ON employees (employment_status);
+
+-- Employees removed through DELETE /employees/{id} were soft-deleted; bring
+-- them back as terminated so their attendance history stays reachable.
+UPDATE employees
+SET employment_status = 'terminated',
+    termination_date = deleted_at::date,
+    deleted_at = NULL
+WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE employees
+SET deleted_at = COALESCE(termination_date::timestamp, CURRENT_TIMESTAMP)
+WHERE employment_status = 'terminated';
+
+DROP INDEX IF EXISTS idx_employees_employment_status;
+
+ALTER TABLE employees
+    DROP COLUMN IF EXISTS employment_status,
+    DROP COLUMN IF EXISTS termination_date,
+    DROP COLUMN IF EXISTS termination_reason;
+-- +goose StatementEnd
@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Shifts were stored with full timestamps of which only the UTC time of day
+-- was meaningful. Convert them to HH:MM windows that run every day, and add
+-- the timezone, weekday, break and location columns.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'shifts') THEN
+        RETURN;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'shifts' AND column_name = 'start_time' AND data_type LIKE 'timestamp%') THEN
+        ALTER TABLE shifts RENAME COLUMN start_time TO start_timestamp;
+        ALTER TABLE shifts RENAME COLUMN end_time TO end_timestamp;
+        ALTER TABLE shifts
+            ADD COLUMN start_time VARCHAR(5),
+            ADD COLUMN end_time VARCHAR(5);
+
+        UPDATE shifts
+        SET start_time = COALESCE(to_char(start_timestamp AT TIME ZONE 'UTC', 'HH24:MI'), '00:00'),
+            end_time = COALESCE(to_char(end_timestamp AT TIME ZONE 'UTC', 'HH24:MI'), '00:00');
+
+        ALTER TABLE shifts
+            ALTER COLUMN start_time SET NOT NULL,
+            ALTER COLUMN end_time SET NOT NULL,
+            DROP COLUMN start_timestamp,
+            DROP COLUMN end_timestamp;
+    END IF;
+
+    ALTER TABLE shifts
+        ADD COLUMN IF NOT EXISTS timezone VARCHAR(255) NOT NULL DEFAULT 'UTC',
+        ADD COLUMN IF NOT EXISTS days_of_week VARCHAR(255) NOT NULL DEFAULT 'mon,tue,wed,thu,fri,sat,sun',
+        ADD COLUMN IF NOT EXISTS break_minutes INT NOT NULL DEFAULT 0,
+        ADD COLUMN IF NOT EXISTS location_id INT NULL;
+END $$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Times of day are put back on an arbitrary date; overnight shifts end on the
+-- following day so that end_time stays after start_time.
+ALTER TABLE shifts
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS days_of_week,
+    DROP COLUMN IF EXISTS break_minutes,
+    DROP COLUMN IF EXISTS location_id;
+
+ALTER TABLE shifts RENAME COLUMN start_time TO start_clock;
+ALTER TABLE shifts RENAME COLUMN end_time TO end_clock;
+ALTER TABLE shifts
+    ADD COLUMN start_time TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN end_time TIMESTAMP WITH TIME ZONE;
+
+UPDATE shifts
+SET start_time = ('2000-01-01 ' || start_clock || ':00+00')::timestamptz,
+    end_time = ('2000-01-01 ' || end_clock || ':00+00')::timestamptz
+        + CASE WHEN end_clock <= start_clock THEN INTERVAL '1 day' ELSE INTERVAL '0' END;
+
+ALTER TABLE shifts
+    DROP COLUMN start_clock,
+    DROP COLUMN end_clock;
+-- +goose StatementEnd
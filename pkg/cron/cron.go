@@ -2,8 +2,26 @@
 package cron
 
 import (
+	"clinicplus/internal/attendance"
+	"clinicplus/internal/availability"
+	"clinicplus/internal/compensation"
+	"clinicplus/internal/coverage"
+	"clinicplus/internal/credential"
+	"clinicplus/internal/employee"
+	"clinicplus/internal/holiday"
+	"clinicplus/internal/leave"
+	"clinicplus/internal/notification"
+	"clinicplus/internal/onboarding"
+	"clinicplus/internal/openshift"
+	"clinicplus/internal/roster"
+	"clinicplus/internal/rotation"
+	"clinicplus/internal/shared/config"
+	"clinicplus/internal/shared/observability"
+	"clinicplus/internal/worktime"
 	"log"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/robfig/cron/v3"
 )
 
@@ -13,8 +31,106 @@ func cleanupOldRecords() {
 	log.Println("Cleaning up old records...")
 }
 
+// runJob executes a job and records its metrics
+func runJob(name string, job func() error) func() {
+	return func() {
+		start := time.Now()
+		err := job()
+		if err != nil {
+			log.Printf("Cron job %s failed: %v", name, err)
+		}
+		observability.RecordCronJob(name, time.Since(start), err)
+	}
+}
+
+// checkCredentialExpiry flags credentials entering an expiry alert window
+func checkCredentialExpiry(service credential.CredentialService) func() error {
+	return func() error {
+		alerts, err := service.FlagExpiringCredentials(time.Now(), config.GetCredentialExpiryWindows())
+		log.Printf("Credential expiry check sent %d alerts", alerts)
+		return err
+	}
+}
+
+// accrueLeave credits accruing leave balances for the current period
+func accrueLeave(service leave.LeaveService) func() error {
+	return func() error {
+		credited, err := service.AccrueBalances(time.Now().UTC())
+		log.Printf("Leave accrual credited %d balances", credited)
+		return err
+	}
+}
+
+// remindOverdueChecklistTasks nudges assignees of overdue onboarding and offboarding tasks
+func remindOverdueChecklistTasks(service onboarding.OnboardingService) func() error {
+	return func() error {
+		sent, err := service.SendOverdueReminders(time.Now().UTC())
+		log.Printf("Checklist reminders sent: %d", sent)
+		return err
+	}
+}
+
+// syncSalaries mirrors compensation changes taking effect today into Employee.Salary
+func syncSalaries(service compensation.CompensationService) func() error {
+	return func() error {
+		updated, err := service.SyncSalaries(time.Now().UTC())
+		log.Printf("Salary sync updated %d employees", updated)
+		return err
+	}
+}
+
+// applyTerminations terminates employees whose scheduled termination date has arrived
+func applyTerminations(service employee.EmployeeService) func() error {
+	return func() error {
+		terminated, err := service.ApplyScheduledTerminations(time.Now().UTC())
+		log.Printf("Scheduled terminations applied: %d", terminated)
+		return err
+	}
+}
+
+// extendRotations materializes rotation patterns up to the scheduling horizon
+func extendRotations(service rotation.RotationService) func() error {
+	return func() error {
+		result, err := service.ExtendAll(time.Now().UTC())
+		if result != nil {
+			log.Printf("Rotation extension created %d shift assignments, skipped %d days", result.Created, len(result.Skipped))
+		}
+		return err
+	}
+}
+
+// detectCoverageGaps alerts managers to understaffed shifts in the coming days
+func detectCoverageGaps(service coverage.CoverageService) func() error {
+	return func() error {
+		alerts, err := service.DetectGaps(time.Now().UTC(), config.GetCoverageLookaheadDays())
+		log.Printf("Coverage check sent %d gap alerts", alerts)
+		return err
+	}
+}
+
+// awardOpenShifts assigns open shifts whose bidding deadline has passed
+func awardOpenShifts(service openshift.OpenShiftService) func() error {
+	return func() error {
+		closed, err := service.AwardDue(time.Now())
+		log.Printf("Open shift awarding closed %d open shifts", closed)
+		return err
+	}
+}
+
+// checkAttendance records absences and missing clock-outs of shifts that ended
+func checkAttendance(service attendance.AttendanceService) func() error {
+	return func() error {
+		result, err := service.CheckAttendance(time.Now().UTC())
+		if result != nil {
+			log.Printf("Attendance check found %d absences and %d missing clock-outs (%d closed)",
+				result.Absences, result.MissingClockOuts, result.AutoClosed)
+		}
+		return err
+	}
+}
+
 // Function to initialize cron jobs
-func StartCronJobs() {
+func StartCronJobs(db *gorm.DB) {
 	c := cron.New()
 
 	// Schedule the cleanup job to run every day at midnight
@@ -23,6 +139,89 @@ func StartCronJobs() {
 		log.Fatalf("Error scheduling cleanup job: %v", err)
 	}
 
+	notificationService := notification.NewNotificationService(db)
+	credentialService := credential.NewCredentialService(db, notificationService)
+
+	// Check credential expiry every day at 06:00
+	_, err = c.AddFunc("0 6 * * *", runJob("credential_expiry", checkCredentialExpiry(credentialService)))
+	if err != nil {
+		log.Fatalf("Error scheduling credential expiry job: %v", err)
+	}
+
+	holidayService := holiday.NewHolidayService(db)
+	leaveService := leave.NewLeaveService(db, notificationService, holidayService, config.GetLeaveWorkWeek())
+
+	// Accrue leave at 00:30 on the first day of every month
+	_, err = c.AddFunc("30 0 1 * *", runJob("leave_accrual", accrueLeave(leaveService)))
+	if err != nil {
+		log.Fatalf("Error scheduling leave accrual job: %v", err)
+	}
+
+	onboardingService := onboarding.NewOnboardingService(db, notificationService)
+
+	// Remind assignees of overdue checklist tasks every day at 09:00
+	_, err = c.AddFunc("0 9 * * *", runJob("checklist_overdue_reminders", remindOverdueChecklistTasks(onboardingService)))
+	if err != nil {
+		log.Fatalf("Error scheduling checklist reminder job: %v", err)
+	}
+
+	compensationService := compensation.NewCompensationService(db, notificationService)
+
+	// Apply effective-dated compensation changes every day at 00:15
+	_, err = c.AddFunc("15 0 * * *", runJob("salary_sync", syncSalaries(compensationService)))
+	if err != nil {
+		log.Fatalf("Error scheduling salary sync job: %v", err)
+	}
+
+	employeeService := employee.NewEmployeeService(db, employee.AttendancePolicyFromConfig())
+	employeeService.AddAssignmentGuard(credentialService)
+	employeeService.AddAssignmentGuard(availability.NewAvailabilityService(db, config.GetAvailabilityEnforcement()))
+	employeeService.AddAssignmentGuard(worktime.NewWorkingTimeService(db, worktime.Defaults{
+		MinRestHours:       config.GetMinRestHours(),
+		MaxWeeklyHours:     config.GetMaxWeeklyHours(),
+		MaxConsecutiveDays: config.GetMaxConsecutiveDays(),
+	}))
+	employeeService.AddLifecycleListener(onboardingService)
+
+	// Apply scheduled terminations every day at 00:05, before rotations are extended
+	_, err = c.AddFunc("5 0 * * *", runJob("scheduled_terminations", applyTerminations(employeeService)))
+	if err != nil {
+		log.Fatalf("Error scheduling termination job: %v", err)
+	}
+
+	rotationService := rotation.NewRotationService(db, employeeService, config.GetRotationHorizonDays())
+
+	// Roll rotation patterns forward every day at 01:00
+	_, err = c.AddFunc("0 1 * * *", runJob("rotation_extend", extendRotations(rotationService)))
+	if err != nil {
+		log.Fatalf("Error scheduling rotation extension job: %v", err)
+	}
+
+	openShiftService := openshift.NewOpenShiftService(db, employeeService, notificationService)
+
+	// Award open shifts past their bidding deadline every 15 minutes
+	_, err = c.AddFunc("*/15 * * * *", runJob("open_shift_awards", awardOpenShifts(openShiftService)))
+	if err != nil {
+		log.Fatalf("Error scheduling open shift award job: %v", err)
+	}
+
+	attendanceService := attendance.NewAttendanceService(db, employeeService, holidayService, notificationService, attendance.PolicyFromConfig())
+
+	// Check attendance of shifts that ended every 15 minutes
+	_, err = c.AddFunc("*/15 * * * *", runJob("attendance_check", checkAttendance(attendanceService)))
+	if err != nil {
+		log.Fatalf("Error scheduling attendance check job: %v", err)
+	}
+
+	rosterService := roster.NewRosterService(db)
+	coverageService := coverage.NewCoverageService(db, rosterService, notificationService)
+
+	// Look for understaffed shifts every day at 07:00, after rotations are extended
+	_, err = c.AddFunc("0 7 * * *", runJob("coverage_gaps", detectCoverageGaps(coverageService)))
+	if err != nil {
+		log.Fatalf("Error scheduling coverage gap job: %v", err)
+	}
+
 	// Start the cron scheduler
 	c.Start()
 }
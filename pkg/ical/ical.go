@@ -0,0 +1,146 @@
+// /pkg/ical/ical.go
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event is the subset of a VEVENT the application cares about
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time // Exclusive; for all-day events the day after the last day
+	AllDay      bool
+	Location    string
+	Status      string // e.g., CONFIRMED, CANCELLED
+}
+
+// StatusCancelled marks an event that no longer takes place
+const StatusCancelled = "CANCELLED"
+
+// Parse reads the VEVENTs of an iCalendar (RFC 5545) stream. Timed events
+// without a TZID are interpreted as UTC; TZID parameters are resolved with
+// the system timezone database.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	for i, line := range lines {
+		name, params, value := splitLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &Event{}
+		case name == "END" && value == "VEVENT":
+			if current == nil {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN", i+1)
+			}
+			if current.Start.IsZero() {
+				return nil, fmt.Errorf("line %d: event %q has no DTSTART", i+1, current.Summary)
+			}
+			if current.End.IsZero() {
+				current.End = current.Start
+				if current.AllDay {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescape(value)
+		case name == "DESCRIPTION":
+			current.Description = unescape(value)
+		case name == "LOCATION":
+			current.Location = unescape(value)
+		case name == "STATUS":
+			current.Status = strings.ToUpper(strings.TrimSpace(value))
+		case name == "DTSTART", name == "DTEND":
+			t, allDay, err := parseTime(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if name == "DTSTART" {
+				current.Start, current.AllDay = t, allDay
+			} else {
+				current.End = t
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// unfold joins continuation lines (those starting with a space or tab)
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitLine splits "NAME;PARAM=x:VALUE" into its parts
+func splitLine(line string) (string, map[string]string, string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	params := make(map[string]string)
+	for _, param := range parts[1:] {
+		if eq := strings.Index(param, "="); eq > 0 {
+			params[strings.ToUpper(param[:eq])] = strings.Trim(param[eq+1:], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+func parseTime(params map[string]string, value string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.Parse("20060102", value)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	loc := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown TZID %q", tzid)
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,219 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func calendar(lines ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+}
+
+func TestParse(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []Event
+		wantErr string
+	}{
+		{
+			name: "all-day event",
+			input: calendar("BEGIN:VEVENT", "UID:republic-day@example.com", "SUMMARY:Republic Day",
+				"DTSTART;VALUE=DATE:20240126", "DTEND;VALUE=DATE:20240127", "END:VEVENT"),
+			want: []Event{{
+				UID: "republic-day@example.com", Summary: "Republic Day", AllDay: true,
+				Start: time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 1, 27, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name:  "all-day event without an end lasts a day",
+			input: calendar("BEGIN:VEVENT", "SUMMARY:Holi", "DTSTART;VALUE=DATE:20240325", "END:VEVENT"),
+			want: []Event{{
+				Summary: "Holi", AllDay: true,
+				Start: time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 26, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name:  "timed event in UTC",
+			input: calendar("BEGIN:VEVENT", "SUMMARY:Audit", "DTSTART:20240301T090000Z", "DTEND:20240301T103000Z", "END:VEVENT"),
+			want: []Event{{
+				Summary: "Audit",
+				Start:   time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), End: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "timed event with a TZID",
+			input: calendar("BEGIN:VEVENT", "SUMMARY:Drill", `DTSTART;TZID="Asia/Kolkata":20240301T090000`,
+				"DTEND;TZID=Asia/Kolkata:20240301T100000", "END:VEVENT"),
+			want: []Event{{
+				Summary: "Drill",
+				Start:   time.Date(2024, 3, 1, 9, 0, 0, 0, kolkata), End: time.Date(2024, 3, 1, 10, 0, 0, 0, kolkata),
+			}},
+		},
+		{
+			name: "status, location, escapes and folded lines",
+			input: calendar("BEGIN:VEVENT", "UID:1", "SUMMARY:Closed\\, staff\\; party", "DESCRIPTION:Line one\\nline",
+				"  two", "LOCATION:Main campus\\, Block A", "status:cancelled", "DTSTART;VALUE=DATE:20241225", "END:VEVENT"),
+			want: []Event{{
+				UID: "1", Summary: "Closed, staff; party", Description: "Line one\nline two",
+				Location: "Main campus, Block A", Status: StatusCancelled, AllDay: true,
+				Start: time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "properties outside events are ignored",
+			input: calendar("X-WR-CALNAME:Holidays", "SUMMARY:Not an event",
+				"BEGIN:VEVENT", "SUMMARY:A", "DTSTART;VALUE=DATE:20240101", "END:VEVENT",
+				"BEGIN:VEVENT", "SUMMARY:B", "DTSTART;VALUE=DATE:20240102", "END:VEVENT"),
+			want: []Event{
+				{Summary: "A", AllDay: true, Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+				{Summary: "B", AllDay: true, Start: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:    "missing DTSTART",
+			input:   calendar("BEGIN:VEVENT", "SUMMARY:Nowhen", "END:VEVENT"),
+			wantErr: `event "Nowhen" has no DTSTART`,
+		},
+		{
+			name:    "END without BEGIN",
+			input:   calendar("END:VEVENT"),
+			wantErr: "END:VEVENT without BEGIN",
+		},
+		{
+			name:    "unknown TZID",
+			input:   calendar("BEGIN:VEVENT", "DTSTART;TZID=Mars/Olympus:20240101T090000", "END:VEVENT"),
+			wantErr: `unknown TZID "Mars/Olympus"`,
+		},
+		{
+			name:    "bad date",
+			input:   calendar("BEGIN:VEVENT", "DTSTART;VALUE=DATE:2024-01-01", "END:VEVENT"),
+			wantErr: "line 4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := Parse(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d: %+v", len(events), len(tt.want), events)
+			}
+			for i := range events {
+				got, want := events[i], tt.want[i]
+				if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+					t.Errorf("event %d runs %s to %s, want %s to %s", i, got.Start, got.End, want.Start, want.End)
+				}
+				got.Start, got.End, want.Start, want.End = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("event %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	events := []Event{
+		{
+			UID:         "shift-1@clinicplus",
+			Summary:     "Night, ICU; ward 3",
+			Description: strings.Repeat("Bring your badge. ", 8) + "Parking: Gate ₹ 2\\B",
+			Location:    "Main campus",
+			Status:      "CONFIRMED",
+			Start:       time.Date(2024, 3, 4, 22, 0, 0, 0, kolkata),
+			End:         time.Date(2024, 3, 5, 6, 0, 0, 0, kolkata),
+		},
+		{
+			UID:     "holiday-2@clinicplus",
+			Summary: "Holi",
+			AllDay:  true,
+			Start:   time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2024, 3, 26, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, Calendar{ProductID: "-//ClinicPlus//Test//EN", Name: "Rota", Timezone: "Asia/Kolkata", Events: events}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := buf.String()
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+		if strings.Contains(line, "\n") {
+			t.Errorf("bare line feed in %q", line)
+		}
+	}
+	for _, want := range []string{"X-WR-CALNAME:Rota\r\n", "X-WR-TIMEZONE:Asia/Kolkata\r\n", "DTSTART:20240304T163000Z\r\n",
+		"DTSTART;VALUE=DATE:20240325\r\n", "SUMMARY:Night\\, ICU\\; ward 3\r\n", "STATUS:CONFIRMED\r\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	parsed, err := Parse(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("Parse of written calendar: %v", err)
+	}
+	if len(parsed) != len(events) {
+		t.Fatalf("parsed %d events, want %d", len(parsed), len(events))
+	}
+	for i, want := range events {
+		got := parsed[i]
+		if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+			t.Errorf("event %d runs %s to %s, want %s to %s", i, got.Start, got.End, want.Start, want.End)
+		}
+		got.Start, got.End, want.Start, want.End = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("event %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestWriteLineFoldsOnRuneBoundaries(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeLine(w, "SUMMARY:"+strings.Repeat("é", 100))
+	w.Flush()
+
+	var joined strings.Builder
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line %d has %d octets", i, len(line))
+		}
+		if i > 0 {
+			if !strings.HasPrefix(line, " ") {
+				t.Errorf("continuation line %d does not start with a space", i)
+			}
+			line = line[1:]
+		}
+		joined.WriteString(line)
+	}
+	if want := "SUMMARY:" + strings.Repeat("é", 100); joined.String() != want {
+		t.Errorf("unfolded line differs: %q", joined.String())
+	}
+}
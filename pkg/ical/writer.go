@@ -0,0 +1,88 @@
+// /pkg/ical/writer.go
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// Calendar is a VCALENDAR to be written as a subscribable feed
+type Calendar struct {
+	ProductID string // e.g., -//ClinicPlus//Schedules//EN
+	Name      string // Shown by clients as the calendar name
+	Timezone  string // IANA name clients should display the feed in
+	Events    []Event
+}
+
+// Write encodes the calendar as iCalendar (RFC 5545). Timed events are
+// written in UTC so no VTIMEZONE definitions are needed; the calendar
+// timezone is advertised with X-WR-TIMEZONE for display.
+func Write(w io.Writer, calendar Calendar) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC()
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:"+calendar.ProductID)
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	writeLine(bw, "METHOD:PUBLISH")
+	if calendar.Name != "" {
+		writeLine(bw, "X-WR-CALNAME:"+escape(calendar.Name))
+	}
+	if calendar.Timezone != "" {
+		writeLine(bw, "X-WR-TIMEZONE:"+calendar.Timezone)
+	}
+
+	for _, event := range calendar.Events {
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:"+event.UID)
+		writeLine(bw, "DTSTAMP:"+formatUTC(stamp))
+		if event.AllDay {
+			writeLine(bw, "DTSTART;VALUE=DATE:"+event.Start.Format("20060102"))
+			writeLine(bw, "DTEND;VALUE=DATE:"+event.End.Format("20060102"))
+		} else {
+			writeLine(bw, "DTSTART:"+formatUTC(event.Start))
+			writeLine(bw, "DTEND:"+formatUTC(event.End))
+		}
+		writeLine(bw, "SUMMARY:"+escape(event.Summary))
+		if event.Description != "" {
+			writeLine(bw, "DESCRIPTION:"+escape(event.Description))
+		}
+		if event.Location != "" {
+			writeLine(bw, "LOCATION:"+escape(event.Location))
+		}
+		if event.Status != "" {
+			writeLine(bw, "STATUS:"+event.Status)
+		}
+		writeLine(bw, "END:VEVENT")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// writeLine writes a content line terminated by CRLF, folded so no line
+// exceeds 75 octets without splitting a UTF-8 sequence
+func writeLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	w.WriteString(line + "\r\n")
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,234 @@
+// /pkg/pdf/pdf.go
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	a4Width  = 595.28 // Points
+	a4Height = 841.89
+	margin   = 40.0
+
+	textSize    = 9.0
+	headingSize = 14.0
+	lineHeight  = 1.4 // Multiple of the font size
+)
+
+// Column describes one column of a table
+type Column struct {
+	Title string
+	Width float64 // Points
+	Right bool    // Right-align, e.g. for numbers
+}
+
+// Document is a text document laid out top to bottom on A4 pages in the
+// standard Helvetica fonts, so no fonts need to be embedded. Text is encoded
+// as WinAnsi; characters outside Latin-1 are replaced.
+type Document struct {
+	title         string
+	width, height float64
+	pages         []*bytes.Buffer
+	y             float64 // Baseline of the next line, from the bottom
+}
+
+// New starts a document with the given title, in landscape when asked
+func New(title string, landscape bool) *Document {
+	d := &Document{title: title, width: a4Width, height: a4Height}
+	if landscape {
+		d.width, d.height = a4Height, a4Width
+	}
+	d.newPage()
+	return d
+}
+
+// Heading writes a line of bold, larger text
+func (d *Document) Heading(text string) {
+	d.ensure(headingSize * lineHeight)
+	d.y -= headingSize * lineHeight
+	d.text(margin, d.y, headingSize, true, text)
+}
+
+// Text writes a line of regular text, cut to the page width
+func (d *Document) Text(text string) {
+	d.ensure(textSize * lineHeight)
+	d.y -= textSize * lineHeight
+	d.text(margin, d.y, textSize, false, fit(text, d.width-2*margin, textSize))
+}
+
+// Space leaves some vertical space
+func (d *Document) Space(points float64) {
+	d.y -= points
+}
+
+// Table writes a header row in bold, a rule under it and the rows. Cells
+// too wide for their column are cut; the header is repeated on each page.
+// Rows listed in bold are written in bold, e.g. for totals.
+func (d *Document) Table(columns []Column, rows [][]string, bold map[int]bool) {
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Title
+	}
+	step := textSize * lineHeight
+
+	d.ensure(3 * step)
+	d.row(columns, header, true)
+	for i, row := range rows {
+		if d.y-step < margin {
+			d.newPage()
+			d.row(columns, header, true)
+		}
+		d.row(columns, row, bold[i])
+	}
+}
+
+// Write encodes the document as PDF 1.4
+func (d *Document) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var offsets []int
+	written := 0
+	object := func(body string) {
+		offsets = append(offsets, written)
+		n, _ := fmt.Fprintf(bw, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+		written += n
+	}
+
+	n, _ := bw.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	written += n
+
+	// Objects 1-4 are the catalog, page tree, fonts; then each page is
+	// followed by its content stream, and the info dictionary comes last
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			d.width, d.height, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+	object(fmt.Sprintf("<< /Title (%s) /Producer (ClinicPlus) >>", escape(d.title)))
+
+	n, _ = fmt.Fprintf(bw, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	xref := written
+	written += n
+	for _, offset := range offsets {
+		fmt.Fprintf(bw, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(bw, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, len(offsets), xref)
+	return bw.Flush()
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = d.height - margin
+}
+
+// ensure starts a new page unless the height still fits on this one
+func (d *Document) ensure(height float64) {
+	if d.y-height < margin {
+		d.newPage()
+	}
+}
+
+func (d *Document) row(columns []Column, cells []string, bold bool) {
+	d.y -= textSize * lineHeight
+	x := margin
+	for i, column := range columns {
+		if i < len(cells) {
+			cell := fit(cells[i], column.Width-4, textSize)
+			at := x
+			if column.Right {
+				at = x + column.Width - 4 - width(cell, textSize)
+			}
+			d.text(at, d.y, textSize, bold, cell)
+		}
+		x += column.Width
+	}
+	if bold {
+		page := d.pages[len(d.pages)-1]
+		fmt.Fprintf(page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", margin, d.y-3, x, d.y-3)
+	}
+}
+
+func (d *Document) text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escape(text))
+}
+
+// fit cuts text to the given width, marking the cut with an ellipsis
+func fit(text string, max, size float64) string {
+	if width(text, size) <= max {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && width(string(runes)+"...", size) > max {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// width approximates the width of text in Helvetica from the widths of its
+// common glyphs, in thousandths of the font size
+func width(text string, size float64) float64 {
+	total := 0
+	for _, r := range text {
+		w, ok := glyphWidths[r]
+		if !ok {
+			w = 556
+		}
+		total += w
+	}
+	return float64(total) * size / 1000
+}
+
+var glyphWidths = func() map[rune]int {
+	widths := map[rune]int{
+		' ': 278, '.': 278, ',': 278, ':': 278, ';': 278, '/': 278, '!': 278, '\'': 191,
+		'-': 333, '(': 333, ')': 333, '%': 889, '+': 584, '#': 556,
+	}
+	for r := '0'; r <= '9'; r++ {
+		widths[r] = 556
+	}
+	lower := []int{556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500}
+	upper := []int{667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611}
+	for i := 0; i < 26; i++ {
+		widths['a'+rune(i)] = lower[i]
+		widths['A'+rune(i)] = upper[i]
+	}
+	return widths
+}()
+
+// escape encodes text as a WinAnsi PDF string literal body
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '–' || r == '—':
+			b.WriteByte('-')
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
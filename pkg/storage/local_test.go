@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalStore(root)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+	key := "employees/7/id.png"
+
+	if err := store.Put(ctx, key, strings.NewReader("image"), 5, "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "employees", "7", "id.png")); err != nil {
+		t.Errorf("object not stored under the root: %v", err)
+	}
+
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "image" {
+		t.Errorf("Get returned %q, want %q", got, "image")
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Get after Delete returned %v, want ErrObjectNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("second Delete: %v", err)
+	}
+}
+
+func TestLocalStoreRejectsTraversal(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "store")
+	store, err := NewLocalStore(root)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"parent", "../outside.txt"},
+		{"nested parent", "employees/../../outside.txt"},
+		{"deep parent", "a/b/../../../outside.txt"},
+		{"sibling with root prefix", "../store-evil/outside.txt"},
+		{"root itself", "."},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.Put(ctx, tt.key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+				t.Errorf("Put(%q) succeeded", tt.key)
+			}
+			if _, err := store.Get(ctx, tt.key); err == nil || errors.Is(err, ErrObjectNotFound) {
+				t.Errorf("Get(%q) returned %v, want an invalid key error", tt.key, err)
+			}
+			if err := store.Delete(ctx, tt.key); err == nil {
+				t.Errorf("Delete(%q) succeeded", tt.key)
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "store" {
+		t.Errorf("files were written outside the root: %v", entries)
+	}
+}
+
+func TestLocalStoreKeepsInnerDotDot(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	// Keys that resolve inside the root are allowed
+	if err := store.Put(context.Background(), "a/../b.txt", strings.NewReader("x"), 1, "text/plain"); err != nil {
+		t.Errorf("Put of a key resolving inside the root: %v", err)
+	}
+}
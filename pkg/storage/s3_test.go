@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "eu-west-1"
+	testBucket    = "documents"
+)
+
+var authorization = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([^,]+), Signature=([0-9a-f]{64})$`)
+
+// fakeS3 is an in-memory bucket that checks each request's SigV4 signature
+// the way S3 does, from what arrives on the wire
+type fakeS3 struct {
+	t        *testing.T
+	rejected int // Requests refused for their signature
+	mu       sync.Mutex
+	objects  map[string][]byte
+	types    map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verify(r, time.Now()); err != nil {
+		f.mu.Lock()
+		f.rejected++
+		f.mu.Unlock()
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	prefix := "/" + testBucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		f.t.Errorf("path %q is not in the bucket", r.URL.Path)
+		http.Error(w, "no such bucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.ContentLength != int64(len(body)) {
+			f.t.Errorf("Content-Length %d, body of %d bytes", r.ContentLength, len(body))
+		}
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Write(body)
+	case http.MethodDelete:
+		if _, ok := f.objects[key]; !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify recomputes the Signature Version 4 of a received request, as of now
+func verify(r *http.Request, now time.Time) error {
+	match := authorization.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil {
+		return errors.New("malformed Authorization header " + r.Header.Get("Authorization"))
+	}
+	accessKey, day, region, signedHeaders, signature := match[1], match[2], match[3], match[4], match[5]
+	if accessKey != testAccessKey || region != testRegion {
+		return errors.New("wrong credential scope")
+	}
+	if signedHeaders != "host;x-amz-content-sha256;x-amz-date" {
+		return errors.New("unexpected signed headers " + signedHeaders)
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	at, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return errors.New("bad X-Amz-Date " + amzDate)
+	}
+	if at.Format("20060102") != day {
+		return errors.New("credential date does not match X-Amz-Date")
+	}
+	if skew := now.Sub(at); skew > 5*time.Minute || skew < -5*time.Minute {
+		return errors.New("request time too skewed")
+	}
+	payload := r.Header.Get("X-Amz-Content-Sha256")
+	if payload != "UNSIGNED-PAYLOAD" {
+		return errors.New("unexpected payload hash " + payload)
+	}
+
+	canonical := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		"host:" + r.Host + "\nx-amz-content-sha256:" + payload + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payload,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + day + "/" + region + "/s3/aws4_request\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = mac(key, part)
+	}
+	if want := hex.EncodeToString(mac(key, toSign)); want != signature {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func mac(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func newTestS3(t *testing.T) (*S3Store, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{t: t, objects: make(map[string][]byte), types: make(map[string]string)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Store(server.URL, testRegion, testBucket, testAccessKey, testSecretKey)
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	return store, fake
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	store, fake := newTestS3(t)
+	ctx := context.Background()
+	key := "employees/7/contract 2024.pdf"
+	body := "%PDF-1.4 signed contract"
+
+	if err := store.Put(ctx, key, strings.NewReader(body), int64(len(body)), "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := fake.types[key]; got != "application/pdf" {
+		t.Errorf("stored content type %q, want application/pdf", got)
+	}
+
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("reading object: %v", err)
+	}
+	if string(got) != body {
+		t.Errorf("Get returned %q, want %q", got, body)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := fake.objects[key]; ok {
+		t.Error("object still stored after Delete")
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Get after Delete returned %v, want ErrObjectNotFound", err)
+	}
+	// Deleting what is already gone is not an error
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("second Delete: %v", err)
+	}
+}
+
+func TestS3StoreRejectsWrongSecret(t *testing.T) {
+	store, fake := newTestS3(t)
+	store.secretKey = "wrong"
+
+	err := store.Put(context.Background(), "a.txt", strings.NewReader("x"), 1, "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with a wrong secret returned %v, want a 403 error", err)
+	}
+	if fake.rejected != 1 || len(fake.objects) != 0 {
+		t.Errorf("%d requests rejected and %d objects stored, want 1 and 0", fake.rejected, len(fake.objects))
+	}
+}
+
+func TestS3Sign(t *testing.T) {
+	store, err := NewS3Store("http://minio.local:9000/base/", testRegion, testBucket, testAccessKey, testSecretKey)
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	req, err := store.newRequest(context.Background(), http.MethodGet, "/a b/c.txt", nil)
+	if err != nil {
+		t.Fatalf("newRequest: %v", err)
+	}
+	if req.URL.Path != "/base/documents/a b/c.txt" {
+		t.Errorf("path %q, want /base/documents/a b/c.txt", req.URL.Path)
+	}
+	store.sign(req, now)
+
+	if got := req.Header.Get("X-Amz-Date"); got != "20240301T123000Z" {
+		t.Errorf("X-Amz-Date %q, want 20240301T123000Z", got)
+	}
+	if got := req.Header.Get("X-Amz-Content-Sha256"); got != unsignedPayload {
+		t.Errorf("X-Amz-Content-Sha256 %q, want %s", got, unsignedPayload)
+	}
+	if got := req.Header.Get("Authorization"); !strings.HasPrefix(got, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240301/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") {
+		t.Errorf("unexpected Authorization %q", got)
+	}
+
+	// As received by a server: the escaped path is part of the signature
+	req.Host = req.URL.Host
+	if err := verify(req, now); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	req.URL.Path = "/base/documents/other.txt"
+	if err := verify(req, now); err == nil {
+		t.Error("signature verified for a different path")
+	}
+}
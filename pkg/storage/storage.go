@@ -0,0 +1,42 @@
+// /pkg/storage/storage.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// Store is a blob store for uploaded files. Keys are slash-separated paths.
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selects and configures a Store implementation
+type Config struct {
+	Driver    string // "local" or "s3"
+	LocalPath string
+
+	S3Endpoint  string // e.g., http://localhost:9000 for MinIO
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// New creates the Store described by the config
+func New(cfg Config) (Store, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStore(cfg.LocalPath)
+	case "s3":
+		return NewS3Store(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}